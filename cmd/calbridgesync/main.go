@@ -20,6 +20,7 @@ import (
 	"github.com/macjediwizard/calbridgesync/internal/health"
 	"github.com/macjediwizard/calbridgesync/internal/notify"
 	"github.com/macjediwizard/calbridgesync/internal/scheduler"
+	"github.com/macjediwizard/calbridgesync/internal/validator"
 	"github.com/macjediwizard/calbridgesync/internal/web"
 )
 
@@ -83,13 +84,31 @@ func main() {
 		cfg.Security.OAuthStateMaxAgeSecs,
 	)
 
+	// Configure per-host throttling for CalDAV requests
+	throttleCfg := caldav.DefaultThrottleConfig()
+	throttleCfg.HostRPS = cfg.CalDAV.HostRPS
+	throttleCfg.MaxRetries = cfg.CalDAV.MaxRetries
+	caldav.ConfigureThrottling(throttleCfg)
+	caldav.ConfigureRequestTimeout(time.Duration(cfg.CalDAV.RequestTimeoutSecs) * time.Second)
+
+	// Block connections to private and link-local addresses unless allowlisted
+	addressPolicy, err := validator.NewAddressPolicy(cfg.Security.OutboundAllowlist)
+	if err != nil {
+		log.Fatalf("Invalid outbound allowlist: %v", err)
+	}
+	caldav.ConfigureAddressPolicy(addressPolicy)
+
 	// Initialize sync engine
-	syncEngine := caldav.NewSyncEngine(database, encryptor)
+	syncEngine := caldav.NewSyncEngine(database, encryptor,
+		caldav.WithConcurrencyLimits(cfg.Sync.MaxCalendarConcurrency, cfg.Sync.MaxEventConcurrency),
+		caldav.WithDiscoveryCacheTTL(time.Duration(cfg.CalDAV.DiscoveryCacheTTLSecs)*time.Second),
+		caldav.WithEventIndexLimit(cfg.CalDAV.EventIndexLimit))
 
 	// Initialize notifier for alerts
 	notifyCfg := &notify.Config{
 		WebhookEnabled: cfg.Alerts.WebhookEnabled,
 		WebhookURL:     cfg.Alerts.WebhookURL,
+		AddressPolicy:  addressPolicy,
 		EmailEnabled:   cfg.Alerts.EmailEnabled,
 		SMTPHost:       cfg.Alerts.SMTPHost,
 		SMTPPort:       cfg.Alerts.SMTPPort,
@@ -117,6 +136,12 @@ func main() {
 
 	// Initialize scheduler
 	sched := scheduler.New(database, syncEngine, notifier)
+	sched.SetRetryPolicy(scheduler.RetryPolicy{
+		MaxAttempts: cfg.Sync.RetryMaxAttempts,
+		BaseDelay:   time.Duration(cfg.Sync.RetryBaseDelaySecs) * time.Second,
+	})
+	sched.SetAuthFailureThreshold(cfg.Sync.AuthFailureThreshold)
+	sched.SetDriftAlertThreshold(cfg.Sync.DriftAlertThreshold)
 
 	// Initialize health checker
 	healthChecker := health.NewChecker(database, cfg.OIDC.Issuer, cfg.CalDAV.DefaultDestURL)
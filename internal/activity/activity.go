@@ -1,6 +1,8 @@
 package activity
 
 import (
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -9,7 +11,7 @@ import (
 type SyncActivity struct {
 	SourceID        string    `json:"source_id"`
 	SourceName      string    `json:"source_name"`
-	Status          string    `json:"status"` // "running", "completed", "error"
+	Status          string    `json:"status"` // "running", "completed", "partial", "error", "cancelled"
 	CurrentCalendar string    `json:"current_calendar,omitempty"`
 	TotalCalendars  int       `json:"total_calendars"`
 	Calendarssynced int       `json:"calendars_synced"`
@@ -23,6 +25,9 @@ type SyncActivity struct {
 	Duration        string    `json:"duration,omitempty"`
 	Message         string    `json:"message,omitempty"`
 	Errors          []string  `json:"errors,omitempty"`
+	ActiveCalendars []string  `json:"active_calendars,omitempty"` // Calendars in progress when syncing in parallel
+
+	calendarStatus map[string]string // calendar path -> status label, for parallel calendar syncs
 }
 
 // Tracker tracks sync activity across all sources.
@@ -67,6 +72,51 @@ func (t *Tracker) UpdateCalendar(sourceID, calendarName string, calendarIndex in
 	}
 }
 
+// SetCalendarStatus records the status label of a calendar that is being synced.
+// Several calendars may be in progress at once when calendar concurrency is enabled,
+// so CurrentCalendar shows all of them.
+func (t *Tracker) SetCalendarStatus(sourceID, calendarPath, label string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if activity, exists := t.active[sourceID]; exists {
+		if activity.calendarStatus == nil {
+			activity.calendarStatus = make(map[string]string)
+		}
+		activity.calendarStatus[calendarPath] = label
+		activity.refreshActiveCalendars()
+	}
+}
+
+// FinishCalendar removes a calendar from the in-progress set and counts it as synced.
+func (t *Tracker) FinishCalendar(sourceID, calendarPath string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if activity, exists := t.active[sourceID]; exists {
+		delete(activity.calendarStatus, calendarPath)
+		activity.Calendarssynced++
+		activity.refreshActiveCalendars()
+	}
+}
+
+// refreshActiveCalendars rebuilds ActiveCalendars and CurrentCalendar from calendarStatus.
+// A new slice is allocated each time so copies handed out by GetActive stay consistent.
+func (a *SyncActivity) refreshActiveCalendars() {
+	paths := make([]string, 0, len(a.calendarStatus))
+	for path := range a.calendarStatus {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	labels := make([]string, 0, len(paths))
+	for _, path := range paths {
+		labels = append(labels, a.calendarStatus[path])
+	}
+	a.ActiveCalendars = labels
+	a.CurrentCalendar = strings.Join(labels, ", ")
+}
+
 // UpdateProgress updates sync progress counters.
 func (t *Tracker) UpdateProgress(sourceID string, created, updated, deleted, skipped, processed int) {
 	t.mu.Lock()
@@ -97,6 +147,24 @@ func (t *Tracker) IncrementProgress(sourceID string, created, updated, deleted,
 
 // FinishSync marks a sync as completed and moves it to recent.
 func (t *Tracker) FinishSync(sourceID string, success bool, message string, errors []string) {
+	status := "error"
+	if success {
+		if len(errors) > 0 {
+			status = "partial"
+		} else {
+			status = "completed"
+		}
+	}
+	t.finish(sourceID, status, message, errors)
+}
+
+// CancelSync marks a sync as cancelled by the user and moves it to recent.
+func (t *Tracker) CancelSync(sourceID, message string) {
+	t.finish(sourceID, "cancelled", message, nil)
+}
+
+// finish records the final state of an active sync and moves it to recent.
+func (t *Tracker) finish(sourceID, status, message string, errors []string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -108,19 +176,12 @@ func (t *Tracker) FinishSync(sourceID string, success bool, message string, erro
 	now := time.Now()
 	activity.CompletedAt = &now
 	activity.Duration = now.Sub(activity.StartedAt).Round(time.Millisecond).String()
+	activity.Status = status
 	activity.Message = message
 	activity.Errors = errors
 	activity.CurrentCalendar = ""
-
-	if success {
-		if len(errors) > 0 {
-			activity.Status = "partial"
-		} else {
-			activity.Status = "completed"
-		}
-	} else {
-		activity.Status = "error"
-	}
+	activity.ActiveCalendars = nil
+	activity.calendarStatus = nil
 
 	// Move to recent list
 	t.recent = append([]*SyncActivity{activity}, t.recent...)
@@ -0,0 +1,74 @@
+package caldav
+
+import (
+	"log"
+	"path"
+	"strings"
+
+	"github.com/emersion/go-ical"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// newCalendarRecorder creates a recorder for the result of syncing one calendar in run
+// runID. Besides the counters, it collects the audit trail of the calendar's writes.
+func (se *SyncEngine) newCalendarRecorder(sourceID, runID, calendarHref string, result *SyncResult) *resultRecorder {
+	rec := se.newResultRecorder(sourceID, result)
+	rec.runID = runID
+	rec.calendarHref = calendarHref
+	return rec
+}
+
+// change records a write to event in the audit trail; err is the write's error.
+func (r *resultRecorder) change(event *Event, action db.ChangeAction, direction db.ChangeDirection, reason db.ChangeReason, err error) {
+	uid, summary := eventIdentity(event)
+	change := &db.EventChange{
+		RunID:        r.runID,
+		SourceID:     r.sourceID,
+		CalendarHref: r.calendarHref,
+		EventUID:     uid,
+		Summary:      db.RedactSummary(summary),
+		SummaryHash:  db.HashSummary(summary),
+		Action:       action,
+		Direction:    direction,
+		Reason:       reason,
+		Result:       db.ChangeSucceeded,
+	}
+	if err != nil {
+		change.Result = db.ChangeFailed
+		change.Error = sanitizeLogDetails(err.Error())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result.Changes = append(r.result.Changes, change)
+}
+
+// eventIdentity returns the UID and summary of an event, reading them from its data
+// if they weren't listed, and falling back to the name of its file for the UID.
+func eventIdentity(event *Event) (uid, summary string) {
+	uid, summary = event.UID, event.Summary
+	if (uid == "" || summary == "") && event.Data != "" {
+		if cal, err := parseICalendar(event.Data); err == nil && len(cal.Events()) > 0 {
+			props := cal.Events()[0].Props
+			if text, err := props.Text(ical.PropUID); err == nil && uid == "" {
+				uid = text
+			}
+			if text, err := props.Text(ical.PropSummary); err == nil && summary == "" {
+				summary = text
+			}
+		}
+	}
+	if uid == "" && event.Path != "" {
+		uid = strings.TrimSuffix(path.Base(event.Path), ".ics")
+	}
+	return uid, summary
+}
+
+// saveChanges stores the audit trail of a sync run.
+func (se *SyncEngine) saveChanges(changes []*db.EventChange) {
+	if err := retryDBOperation(func() error {
+		return se.db.CreateEventChanges(changes)
+	}, 5); err != nil {
+		log.Printf("Failed to save %d event changes: %v", len(changes), err)
+	}
+}
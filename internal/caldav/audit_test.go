@@ -0,0 +1,44 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+func TestChangeAuditTrail(t *testing.T) {
+	t.Run("stores the writes of a run when it finishes", func(t *testing.T) {
+		engine, database, sourceID := setupJournalTest(t)
+
+		result := &SyncResult{Success: true}
+		calResult := &SyncResult{}
+		rec := engine.newCalendarRecorder(sourceID, "run-1", "/cal/work/", calResult)
+		rec.change(&Event{UID: "dentist", Summary: "Dentist appointment"}, db.ChangeDelete, db.ChangeToDest, db.ReasonOrphan, nil)
+		rec.change(&Event{Path: "/cal/work/standup.ics"}, db.ChangeUpdate, db.ChangeToDest, db.ReasonChangedOnSource, errors.New("403 Forbidden"))
+		engine.newResultRecorder(sourceID, result).merge(calResult)
+
+		engine.finishSync(context.Background(), sourceID, result)
+
+		changes, total, err := database.GetEventChanges(sourceID, db.EventChangeFilter{SummaryHash: db.HashSummary("Dentist appointment")})
+		if err != nil || total != 1 {
+			t.Fatalf("expected the deletion to be found by summary, got %d (%v)", total, err)
+		}
+		if c := changes[0]; c.RunID != "run-1" || c.CalendarHref != "/cal/work/" || c.Summary != "Den…" || c.Result != db.ChangeSucceeded {
+			t.Errorf("unexpected change: %+v", c)
+		}
+
+		changes, _, _ = database.GetEventChanges(sourceID, db.EventChangeFilter{EventUID: "standup"})
+		if len(changes) != 1 || changes[0].Result != db.ChangeFailed || changes[0].Error != "403 Forbidden" {
+			t.Errorf("expected the failed update under the UID from its path, got %+v", changes)
+		}
+	})
+
+	t.Run("reads the identity of an event from its data", func(t *testing.T) {
+		uid, summary := eventIdentity(&Event{Path: "/cal/work/1.ics", Data: probeEventData})
+		if uid != "event-1" || summary != "Standup" {
+			t.Errorf("expected event-1 / Standup, got %s / %s", uid, summary)
+		}
+	})
+}
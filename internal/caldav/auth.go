@@ -0,0 +1,690 @@
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec // Required by HTTP Digest (RFC 7616) for servers without SHA-256
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/macjediwizard/calbridgesync/internal/crypto"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+	"golang.org/x/oauth2"
+)
+
+// ErrReconnectRequired is returned when an OAuth2 grant was revoked or has expired, so
+// the account must be authorized again; retrying with the stored tokens won't help.
+var ErrReconnectRequired = errors.New("reconnect required")
+
+// Authenticator adds credentials to the requests of a client.
+type Authenticator interface {
+	// Authorize sets the credentials on an outgoing request.
+	Authorize(ctx context.Context, req *http.Request) error
+
+	// Retry is called when the server answers 401 and reports whether the request
+	// should be sent once more, e.g. after refreshing a token or with a new Digest nonce.
+	Retry(ctx context.Context, resp *http.Response) (bool, error)
+}
+
+// Credentials describe how a client authenticates to its server.
+type Credentials struct {
+	Method   db.AuthMethod // Empty means basic
+	Username string
+	Password string             // Basic and Digest
+	Token    string             // Bearer token, or the current OAuth2 access token
+	OAuth2   *OAuth2Credentials // Only for db.AuthMethodOAuth2
+}
+
+// OAuth2Credentials hold what's needed to renew an OAuth2 access token.
+type OAuth2Credentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RefreshToken string
+	Expiry       time.Time // Expiry of Credentials.Token; zero if unknown
+
+	// OnRefresh is called with every renewed token so it can be stored.
+	OnRefresh func(*oauth2.Token)
+}
+
+// BasicCredentials returns username and password credentials for HTTP Basic auth.
+func BasicCredentials(username, password string) Credentials {
+	return Credentials{Method: db.AuthMethodBasic, Username: username, Password: password}
+}
+
+// method returns the auth method, defaulting to basic.
+func (c Credentials) method() db.AuthMethod {
+	if c.Method == "" {
+		return db.AuthMethodBasic
+	}
+	return c.Method
+}
+
+// Validate checks that the credentials are complete for their method.
+func (c Credentials) Validate() error {
+	switch c.method() {
+	case db.AuthMethodBasic, db.AuthMethodDigest:
+		return nil
+	case db.AuthMethodBearer:
+		if c.Token == "" {
+			return fmt.Errorf("%w: bearer token is required", ErrAuthFailed)
+		}
+		return nil
+	case db.AuthMethodOAuth2:
+		if c.OAuth2 == nil || (c.Token == "" && c.OAuth2.RefreshToken == "") {
+			return fmt.Errorf("%w: OAuth2 access or refresh token is required", ErrAuthFailed)
+		}
+		if c.OAuth2.RefreshToken != "" {
+			u, err := url.Parse(c.OAuth2.TokenURL)
+			if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+				return fmt.Errorf("%w: OAuth2 token URL is invalid", ErrAuthFailed)
+			}
+			// The client secret and refresh token are sent to it, so only a local
+			// token endpoint may be plain HTTP
+			if u.Scheme == "http" && !isLoopbackHost(u.Hostname()) {
+				return fmt.Errorf("%w: OAuth2 token URL must use HTTPS", ErrAuthFailed)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported auth method %q", ErrAuthFailed, c.Method)
+	}
+}
+
+// isLoopbackHost reports whether host is localhost or a loopback IP.
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// version returns a fingerprint of the credentials for client pool keys. OAuth2
+// credentials are identified by their refresh token, so renewed access tokens keep
+// using the same client.
+func (c Credentials) version() string {
+	parts := []string{string(c.method()), c.Username}
+	switch c.method() {
+	case db.AuthMethodBasic, db.AuthMethodDigest:
+		parts = append(parts, c.Password)
+	case db.AuthMethodBearer:
+		parts = append(parts, c.Token)
+	case db.AuthMethodOAuth2:
+		if c.OAuth2 != nil && c.OAuth2.RefreshToken != "" {
+			parts = append(parts, c.OAuth2.TokenURL, c.OAuth2.ClientID, c.OAuth2.RefreshToken)
+		} else {
+			parts = append(parts, c.Token)
+		}
+	}
+	return credentialVersion(strings.Join(parts, "\x00"))
+}
+
+// authenticator creates the authenticator for the credentials. Token requests are
+// sent with tokenClient, which must not add credentials itself.
+func (c Credentials) authenticator(tokenClient *http.Client) Authenticator {
+	switch c.method() {
+	case db.AuthMethodDigest:
+		return &digestAuth{username: c.Username, password: c.Password}
+	case db.AuthMethodBearer:
+		return bearerAuth(c.Token)
+	case db.AuthMethodOAuth2:
+		o := c.OAuth2
+		return &oauth2Auth{
+			config: &oauth2.Config{
+				ClientID:     o.ClientID,
+				ClientSecret: o.ClientSecret,
+				Scopes:       o.Scopes,
+				Endpoint:     oauth2.Endpoint{TokenURL: o.TokenURL},
+			},
+			token:     &oauth2.Token{AccessToken: c.Token, RefreshToken: o.RefreshToken, Expiry: o.Expiry, TokenType: "Bearer"},
+			client:    tokenClient,
+			onRefresh: o.OnRefresh,
+		}
+	default:
+		return basicAuth{username: c.Username, password: c.Password}
+	}
+}
+
+// basicAuth sends HTTP Basic credentials with every request.
+type basicAuth struct {
+	username, password string
+}
+
+func (a basicAuth) Authorize(_ context.Context, req *http.Request) error {
+	req.SetBasicAuth(a.username, a.password)
+	return nil
+}
+
+func (a basicAuth) Retry(context.Context, *http.Response) (bool, error) {
+	return false, nil
+}
+
+// bearerAuth sends a static bearer token with every request.
+type bearerAuth string
+
+func (a bearerAuth) Authorize(_ context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(a))
+	return nil
+}
+
+func (a bearerAuth) Retry(context.Context, *http.Response) (bool, error) {
+	return false, nil
+}
+
+// tokenRefreshTimeout bounds an OAuth2 token refresh, which requests wait on.
+const tokenRefreshTimeout = 30 * time.Second
+
+// oauth2Auth sends an OAuth2 access token, renewing it with the refresh token shortly
+// before it expires and once when the server rejects it. Concurrent requests share
+// one refresh, which runs without holding the mutex.
+type oauth2Auth struct {
+	mu        sync.Mutex
+	config    *oauth2.Config
+	token     *oauth2.Token
+	client    *http.Client
+	onRefresh func(*oauth2.Token)
+
+	refreshing chan struct{} // Closed when the refresh in progress is done; nil if none
+	refreshErr error         // Result of the last refresh
+}
+
+// current returns the token requests are sent with.
+func (a *oauth2Auth) current() *oauth2.Token {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.token
+}
+
+func (a *oauth2Auth) Authorize(ctx context.Context, req *http.Request) error {
+	token := a.current()
+	if !token.Valid() {
+		if err := a.refresh(ctx); err != nil {
+			return err
+		}
+		token = a.current()
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	return nil
+}
+
+func (a *oauth2Auth) Retry(ctx context.Context, resp *http.Response) (bool, error) {
+	token := a.current()
+	if token.RefreshToken == "" {
+		return false, nil
+	}
+	// Another request may have renewed the token since this one was sent
+	if resp.Request != nil && resp.Request.Header.Get("Authorization") != "Bearer "+token.AccessToken {
+		return true, nil
+	}
+	if err := a.refresh(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// refresh renews the access token, or waits for the refresh already in progress.
+func (a *oauth2Auth) refresh(ctx context.Context) error {
+	a.mu.Lock()
+	if done := a.refreshing; done != nil {
+		a.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrConnectionFailed, ctx.Err())
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.refreshErr
+	}
+	done := make(chan struct{})
+	a.refreshing = done
+	refreshToken := a.token.RefreshToken
+	a.mu.Unlock()
+
+	// The refresh serves every waiting request, so it isn't tied to this one
+	exchangeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tokenRefreshTimeout)
+	token, err := a.exchange(exchangeCtx, refreshToken)
+	cancel()
+	if err == nil && a.onRefresh != nil {
+		a.onRefresh(token)
+	}
+
+	a.mu.Lock()
+	if err == nil {
+		a.token = token
+	}
+	a.refreshErr = err
+	a.refreshing = nil
+	a.mu.Unlock()
+	close(done)
+	return err
+}
+
+// exchange requests a new access token for refreshToken.
+func (a *oauth2Auth) exchange(ctx context.Context, refreshToken string) (*oauth2.Token, error) {
+	if refreshToken == "" {
+		return nil, fmt.Errorf("%w: %w: OAuth2 access token expired and no refresh token is stored", ErrAuthFailed, ErrReconnectRequired)
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, a.client)
+	token, err := a.config.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	if err != nil {
+		// The token endpoint rejecting the refresh token means the grant was revoked
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.Response != nil && retrieveErr.Response.StatusCode < 500 {
+			return nil, fmt.Errorf("%w: %w: OAuth2 token refresh rejected: %s", ErrAuthFailed, ErrReconnectRequired, retrieveErr.ErrorCode)
+		}
+		return nil, fmt.Errorf("%w: OAuth2 token refresh failed: %w", ErrConnectionFailed, err)
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// digestAuth implements HTTP Digest auth (RFC 7616) with qop "auth". The first request
+// is sent without credentials; the server's challenge is then reused for later requests.
+type digestAuth struct {
+	username, password string
+
+	mu        sync.Mutex
+	challenge *digestChallenge
+	nc        uint32
+}
+
+// digestChallenge holds the parameters of a WWW-Authenticate: Digest header.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string // "auth" or empty for RFC 2069 servers
+	stale     bool
+}
+
+func (a *digestAuth) Authorize(_ context.Context, req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.challenge == nil {
+		return nil
+	}
+	a.nc++
+	header, err := a.challenge.authorization(a.username, a.password, req.Method, req.URL.RequestURI(), a.nc)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", header)
+	return nil
+}
+
+func (a *digestAuth) Retry(_ context.Context, resp *http.Response) (bool, error) {
+	var challenge *digestChallenge
+	for _, value := range resp.Header.Values("WWW-Authenticate") {
+		if c := parseDigestChallenge(value); c != nil {
+			challenge = c
+			break
+		}
+	}
+	if challenge == nil {
+		return false, nil
+	}
+
+	// Credentials that were rejected with a fresh nonce are wrong; only retry the first
+	// challenge and stale nonces
+	sentCredentials := resp.Request != nil && resp.Request.Header.Get("Authorization") != ""
+	if sentCredentials && !challenge.stale {
+		return false, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.challenge = challenge
+	a.nc = 0
+	return true, nil
+}
+
+// parseDigestChallenge parses a WWW-Authenticate header value, or returns nil if it
+// isn't a Digest challenge calbridge can answer.
+func parseDigestChallenge(value string) *digestChallenge {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+	if !strings.EqualFold(scheme, "Digest") {
+		return nil
+	}
+
+	params := parseAuthParams(rest)
+	c := &digestChallenge{
+		realm:     params["realm"],
+		nonce:     params["nonce"],
+		opaque:    params["opaque"],
+		algorithm: params["algorithm"],
+		stale:     strings.EqualFold(params["stale"], "true"),
+	}
+	if c.nonce == "" {
+		return nil
+	}
+	if c.algorithm == "" {
+		c.algorithm = "MD5"
+	}
+	if _, ok := digestHash(c.algorithm); !ok {
+		return nil
+	}
+
+	if qop, ok := params["qop"]; ok {
+		for _, option := range strings.Split(qop, ",") {
+			if strings.TrimSpace(option) == "auth" {
+				c.qop = "auth"
+			}
+		}
+		if c.qop == "" {
+			return nil // Only auth-int is offered
+		}
+	}
+	return c
+}
+
+// parseAuthParams parses comma-separated key=value pairs with optionally quoted values.
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s != "" {
+		s = strings.TrimLeft(s, " ,")
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimLeft(rest, " ")
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				b.WriteByte(rest[i])
+			}
+			value = b.String()
+			s = rest[min(i+1, len(rest)):]
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+		params[key] = value
+	}
+	return params
+}
+
+// digestHash returns the hash function for a Digest algorithm.
+func digestHash(algorithm string) (func() hash.Hash, bool) {
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		return md5.New, true
+	case "SHA-256":
+		return sha256.New, true
+	default:
+		return nil, false
+	}
+}
+
+// authorization builds the Authorization header for a request.
+func (c *digestChallenge) authorization(username, password, method, uri string, nc uint32) (string, error) {
+	newHash, _ := digestHash(c.algorithm)
+	h := func(s string) string {
+		sum := newHash()
+		io.WriteString(sum, s)
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	cnonceBytes := make([]byte, 8)
+	if _, err := rand.Read(cnonceBytes); err != nil {
+		return "", fmt.Errorf("failed to generate digest cnonce: %w", err)
+	}
+	cnonce := hex.EncodeToString(cnonceBytes)
+	ncValue := fmt.Sprintf("%08x", nc)
+
+	ha1 := h(username + ":" + c.realm + ":" + password)
+	if strings.HasSuffix(strings.ToUpper(c.algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	var response string
+	if c.qop == "" {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + ncValue + ":" + cnonce + ":" + c.qop + ":" + ha2)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username=%q, realm=%q, nonce=%q, uri=%q, algorithm=%s, response=%q`,
+		username, c.realm, c.nonce, uri, c.algorithm, response)
+	if c.qop != "" {
+		fmt.Fprintf(&b, `, qop=%s, nc=%s, cnonce=%q`, c.qop, ncValue, cnonce)
+	}
+	if c.opaque != "" {
+		fmt.Fprintf(&b, `, opaque=%q`, c.opaque)
+	}
+	return b.String(), nil
+}
+
+// authTransport authorizes requests and, when the server answers 401 and the
+// authenticator asks for it, sends the request a second time.
+type authTransport struct {
+	base http.RoundTripper
+	auth Authenticator
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Keep the body so the request can be replayed after a 401
+	getBody := req.GetBody
+	if req.Body != nil && req.Body != http.NoBody && getBody == nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		getBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+
+	resp, err := t.send(req, getBody)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	retry, err := t.auth.Retry(req.Context(), resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if !retry {
+		return resp, nil
+	}
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	return t.send(req, getBody)
+}
+
+// send authorizes a copy of req and sends it.
+func (t *authTransport) send(req *http.Request, getBody func() (io.ReadCloser, error)) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	if getBody != nil {
+		body, err := getBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		out.Body = body
+		out.GetBody = getBody
+	}
+	if err := t.auth.Authorize(req.Context(), out); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(out)
+}
+
+// EncryptCredentials converts the token-based parts of credentials to their stored
+// form. Passwords are stored separately and aren't included.
+func EncryptCredentials(enc *crypto.Encryptor, creds Credentials) (db.AuthSettings, error) {
+	settings := db.AuthSettings{Method: creds.method()}
+	if settings.Method == db.AuthMethodBasic {
+		return db.AuthSettings{}, nil
+	}
+	if settings.Method == db.AuthMethodDigest {
+		return settings, nil
+	}
+
+	var err error
+	if settings.AccessToken, err = encryptOptional(enc, creds.Token); err != nil {
+		return db.AuthSettings{}, err
+	}
+	if o := creds.OAuth2; o != nil && settings.Method == db.AuthMethodOAuth2 {
+		settings.TokenURL = o.TokenURL
+		settings.ClientID = o.ClientID
+		settings.Scopes = o.Scopes
+		if !o.Expiry.IsZero() {
+			expiry := o.Expiry.UTC()
+			settings.TokenExpiry = &expiry
+		}
+		if settings.RefreshToken, err = encryptOptional(enc, o.RefreshToken); err != nil {
+			return db.AuthSettings{}, err
+		}
+		if settings.ClientSecret, err = encryptOptional(enc, o.ClientSecret); err != nil {
+			return db.AuthSettings{}, err
+		}
+	}
+	return settings, nil
+}
+
+// DecryptCredentials builds credentials from a username, a decrypted password and
+// stored auth settings.
+func DecryptCredentials(enc *crypto.Encryptor, username, password string, settings db.AuthSettings) (Credentials, error) {
+	switch settings.Method {
+	case "", db.AuthMethodBasic:
+		return BasicCredentials(username, password), nil
+	case db.AuthMethodDigest:
+		return Credentials{Method: db.AuthMethodDigest, Username: username, Password: password}, nil
+	}
+
+	creds := Credentials{Method: settings.Method, Username: username}
+	var err error
+	if creds.Token, err = decryptOptional(enc, settings.AccessToken); err != nil {
+		return Credentials{}, err
+	}
+	if creds.Method != db.AuthMethodOAuth2 {
+		return creds, nil
+	}
+
+	o := &OAuth2Credentials{
+		TokenURL: settings.TokenURL,
+		ClientID: settings.ClientID,
+		Scopes:   settings.Scopes,
+	}
+	if settings.TokenExpiry != nil {
+		o.Expiry = *settings.TokenExpiry
+	}
+	if o.RefreshToken, err = decryptOptional(enc, settings.RefreshToken); err != nil {
+		return Credentials{}, err
+	}
+	if o.ClientSecret, err = decryptOptional(enc, settings.ClientSecret); err != nil {
+		return Credentials{}, err
+	}
+	creds.OAuth2 = o
+	return creds, nil
+}
+
+// SourceCredentials decrypts the credentials of one server of a source. If database
+// is set, OAuth2 tokens renewed by clients using the credentials are encrypted and
+// stored back on the source.
+func SourceCredentials(database *db.DB, enc *crypto.Encryptor, source *db.Source, endpoint db.Endpoint) (Credentials, error) {
+	username, encPassword, settings := source.SourceUsername, source.SourcePassword, source.SourceAuth
+	if endpoint == db.EndpointDest {
+		username, encPassword, settings = source.DestUsername, source.DestPassword, source.DestAuth
+	}
+
+	var password string
+	if settings.Method == "" || settings.Method == db.AuthMethodBasic || settings.Method == db.AuthMethodDigest {
+		var err error
+		if password, err = enc.Decrypt(encPassword); err != nil {
+			return Credentials{}, err
+		}
+	}
+
+	creds, err := DecryptCredentials(enc, username, password, settings)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if creds.OAuth2 != nil && database != nil {
+		creds.OAuth2.OnRefresh = tokenSaver(database, enc, source.ID, endpoint, settings)
+	}
+	return creds, nil
+}
+
+// tokenSaver returns a callback that stores renewed OAuth2 tokens on a source. The source
+// is read again before every save: if it was edited or re-authorized since the client was
+// created, the stored settings are no longer those the client renews and are left alone.
+func tokenSaver(database *db.DB, enc *crypto.Encryptor, sourceID string, endpoint db.Endpoint, settings db.AuthSettings) func(*oauth2.Token) {
+	var mu sync.Mutex
+	storedRefreshToken := settings.RefreshToken // Encrypted refresh token this client renews
+	return func(token *oauth2.Token) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		source, err := database.GetSourceByID(sourceID)
+		if err != nil {
+			log.Printf("Failed to load source %s to store refreshed OAuth2 token: %v", sourceID, err)
+			return
+		}
+		current := source.SourceAuth
+		if endpoint == db.EndpointDest {
+			current = source.DestAuth
+		}
+		if current.Method != db.AuthMethodOAuth2 || current.TokenURL != settings.TokenURL ||
+			current.ClientID != settings.ClientID || current.RefreshToken != storedRefreshToken {
+			log.Printf("Not storing refreshed OAuth2 token for source %s: its credentials changed", sourceID)
+			return
+		}
+
+		accessToken, err := enc.Encrypt(token.AccessToken)
+		if err != nil {
+			log.Printf("Failed to encrypt refreshed OAuth2 token for source %s: %v", sourceID, err)
+			return
+		}
+		refreshToken, err := enc.Encrypt(token.RefreshToken)
+		if err != nil {
+			log.Printf("Failed to encrypt refreshed OAuth2 token for source %s: %v", sourceID, err)
+			return
+		}
+
+		current.AccessToken = accessToken
+		current.RefreshToken = refreshToken
+		current.TokenExpiry = nil
+		if !token.Expiry.IsZero() {
+			expiry := token.Expiry.UTC()
+			current.TokenExpiry = &expiry
+		}
+		if err := database.UpdateSourceAuth(sourceID, endpoint, current); err != nil {
+			log.Printf("Failed to store refreshed OAuth2 token for source %s: %v", sourceID, err)
+			return
+		}
+		storedRefreshToken = refreshToken
+	}
+}
@@ -0,0 +1,375 @@
+package caldav
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // Digest test server
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/macjediwizard/calbridgesync/internal/crypto"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+	"golang.org/x/oauth2"
+)
+
+func TestCredentialsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		creds   Credentials
+		wantErr bool
+	}{
+		{"basic", BasicCredentials("user", "pass"), false},
+		{"empty method", Credentials{Username: "user"}, false},
+		{"digest", Credentials{Method: db.AuthMethodDigest, Username: "user", Password: "pass"}, false},
+		{"bearer", Credentials{Method: db.AuthMethodBearer, Token: "token"}, false},
+		{"bearer without token", Credentials{Method: db.AuthMethodBearer}, true},
+		{"oauth2 access token only", Credentials{Method: db.AuthMethodOAuth2, Token: "token", OAuth2: &OAuth2Credentials{}}, false},
+		{"oauth2 refresh token", Credentials{Method: db.AuthMethodOAuth2, OAuth2: &OAuth2Credentials{TokenURL: "https://oauth.example.com/token", RefreshToken: "r"}}, false},
+		{"oauth2 refresh without token URL", Credentials{Method: db.AuthMethodOAuth2, OAuth2: &OAuth2Credentials{RefreshToken: "r"}}, true},
+		{"oauth2 plain HTTP token URL", Credentials{Method: db.AuthMethodOAuth2, OAuth2: &OAuth2Credentials{TokenURL: "http://oauth.example.com/token", RefreshToken: "r"}}, true},
+		{"oauth2 local HTTP token URL", Credentials{Method: db.AuthMethodOAuth2, OAuth2: &OAuth2Credentials{TokenURL: "http://127.0.0.1:8080/token", RefreshToken: "r"}}, false},
+		{"oauth2 without tokens", Credentials{Method: db.AuthMethodOAuth2, OAuth2: &OAuth2Credentials{}}, true},
+		{"unknown method", Credentials{Method: "ntlm"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.creds.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	t.Run("parses quoted parameters", func(t *testing.T) {
+		c := parseDigestChallenge(`Digest realm="cal, dav", qop="auth,auth-int", nonce="abc", opaque="xyz", algorithm=SHA-256`)
+		if c == nil {
+			t.Fatal("expected challenge")
+		}
+		if c.realm != "cal, dav" || c.nonce != "abc" || c.opaque != "xyz" || c.qop != "auth" || c.algorithm != "SHA-256" {
+			t.Errorf("unexpected challenge: %+v", c)
+		}
+	})
+
+	t.Run("defaults to MD5", func(t *testing.T) {
+		c := parseDigestChallenge(`Digest realm="r", nonce="n", stale=TRUE`)
+		if c == nil || c.algorithm != "MD5" || !c.stale || c.qop != "" {
+			t.Errorf("unexpected challenge: %+v", c)
+		}
+	})
+
+	for _, value := range []string{
+		`Basic realm="r"`,
+		`Digest realm="r"`,
+		`Digest realm="r", nonce="n", algorithm=SHA-512-256`,
+		`Digest realm="r", nonce="n", qop="auth-int"`,
+	} {
+		t.Run("rejects "+value, func(t *testing.T) {
+			if c := parseDigestChallenge(value); c != nil {
+				t.Errorf("expected nil, got %+v", c)
+			}
+		})
+	}
+}
+
+// digestServer returns a handler that requires Digest auth (MD5, qop=auth) and
+// counts the requests it accepted.
+func digestServer(t *testing.T, username, password string, accepted *int32) http.HandlerFunc {
+	t.Helper()
+	const realm, nonce = "calbridge", "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+	md5hex := func(s string) string {
+		sum := md5.Sum([]byte(s)) //nolint:gosec // Digest test server
+		return hex.EncodeToString(sum[:])
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if strings.HasPrefix(header, "Digest ") {
+			params := parseAuthParams(strings.TrimPrefix(header, "Digest "))
+			ha1 := md5hex(username + ":" + realm + ":" + password)
+			ha2 := md5hex(r.Method + ":" + params["uri"])
+			expected := md5hex(ha1 + ":" + nonce + ":" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+			if params["username"] == username && params["response"] == expected && params["uri"] == r.URL.RequestURI() {
+				atomic.AddInt32(accepted, 1)
+				w.WriteHeader(http.StatusMultiStatus)
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", `Digest realm="`+realm+`", qop="auth", nonce="`+nonce+`", algorithm=MD5`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+}
+
+func TestClientAuthMethods(t *testing.T) {
+	t.Run("answers digest challenges", func(t *testing.T) {
+		var accepted int32
+		server := httptest.NewServer(digestServer(t, "user", "secret", &accepted))
+		defer server.Close()
+
+		client, err := NewClientWithCredentials(server.URL+"/", Credentials{Method: db.AuthMethodDigest, Username: "user", Password: "secret"}, TransportOptions{})
+		if err != nil {
+			t.Fatalf("NewClientWithCredentials failed: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := client.ListEventRefs(context.Background(), "/cal/"); err != nil {
+				t.Fatalf("request %d failed: %v", i, err)
+			}
+		}
+		if accepted != 2 {
+			t.Errorf("expected 2 accepted requests, got %d", accepted)
+		}
+	})
+
+	t.Run("does not loop on wrong digest credentials", func(t *testing.T) {
+		var accepted, requests int32
+		handler := digestServer(t, "user", "secret", &accepted)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			handler(w, r)
+		}))
+		defer server.Close()
+
+		client, _ := NewClientWithCredentials(server.URL+"/", Credentials{Method: db.AuthMethodDigest, Username: "user", Password: "wrong"}, TransportOptions{})
+		if _, err := client.ListEventRefs(context.Background(), "/cal/"); ClassifyError(err) != FailureAuth {
+			t.Errorf("expected auth failure, got %v", err)
+		}
+		if requests != 2 {
+			t.Errorf("expected 2 requests, got %d", requests)
+		}
+	})
+
+	t.Run("sends bearer tokens", func(t *testing.T) {
+		var header string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusMultiStatus)
+		}))
+		defer server.Close()
+
+		client, _ := NewClientWithCredentials(server.URL+"/", Credentials{Method: db.AuthMethodBearer, Token: "abc"}, TransportOptions{})
+		client.ListEventRefs(context.Background(), "/cal/")
+		if header != "Bearer abc" {
+			t.Errorf("expected bearer header, got %q", header)
+		}
+	})
+}
+
+// oauth2Servers starts a CalDAV server that accepts one access token and a token
+// endpoint that issues it for the refresh token "refresh".
+func oauth2Servers(t *testing.T, validToken string) (caldavServer, tokenServer *httptest.Server, refreshes *int32) {
+	t.Helper()
+	refreshes = new(int32)
+
+	tokenServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		atomic.AddInt32(refreshes, 1)
+		w.Write([]byte(`{"access_token":"` + validToken + `","token_type":"Bearer","expires_in":3600}`))
+	}))
+	t.Cleanup(tokenServer.Close)
+
+	caldavServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+validToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+	}))
+	t.Cleanup(caldavServer.Close)
+
+	return caldavServer, tokenServer, refreshes
+}
+
+func TestClientOAuth2(t *testing.T) {
+	t.Run("refreshes an expired token", func(t *testing.T) {
+		caldavServer, tokenServer, refreshes := oauth2Servers(t, "fresh")
+
+		var saved *oauth2.Token
+		creds := Credentials{
+			Method: db.AuthMethodOAuth2,
+			Token:  "old",
+			OAuth2: &OAuth2Credentials{
+				TokenURL:     tokenServer.URL,
+				ClientID:     "client",
+				RefreshToken: "refresh",
+				Expiry:       time.Now().Add(-time.Hour),
+				OnRefresh:    func(token *oauth2.Token) { saved = token },
+			},
+		}
+		client, err := NewClientWithCredentials(caldavServer.URL+"/", creds, TransportOptions{})
+		if err != nil {
+			t.Fatalf("NewClientWithCredentials failed: %v", err)
+		}
+		if _, err := client.ListEventRefs(context.Background(), "/cal/"); err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if *refreshes != 1 {
+			t.Errorf("expected 1 refresh, got %d", *refreshes)
+		}
+		if saved == nil || saved.AccessToken != "fresh" || saved.RefreshToken != "refresh" {
+			t.Errorf("expected refreshed token to be saved, got %+v", saved)
+		}
+	})
+
+	t.Run("refreshes and retries after a 401", func(t *testing.T) {
+		caldavServer, tokenServer, refreshes := oauth2Servers(t, "fresh")
+
+		creds := Credentials{
+			Method: db.AuthMethodOAuth2,
+			Token:  "revoked",
+			OAuth2: &OAuth2Credentials{TokenURL: tokenServer.URL, RefreshToken: "refresh"},
+		}
+		client, _ := NewClientWithCredentials(caldavServer.URL+"/", creds, TransportOptions{})
+		for i := 0; i < 2; i++ {
+			if _, err := client.ListEventRefs(context.Background(), "/cal/"); err != nil {
+				t.Fatalf("request %d failed: %v", i, err)
+			}
+		}
+		if *refreshes != 1 {
+			t.Errorf("expected 1 refresh, got %d", *refreshes)
+		}
+	})
+
+	t.Run("shares one refresh between concurrent requests", func(t *testing.T) {
+		caldavServer, tokenServer, refreshes := oauth2Servers(t, "fresh")
+
+		creds := Credentials{
+			Method: db.AuthMethodOAuth2,
+			Token:  "old",
+			OAuth2: &OAuth2Credentials{TokenURL: tokenServer.URL, RefreshToken: "refresh", Expiry: time.Now().Add(-time.Hour)},
+		}
+		client, _ := NewClientWithCredentials(caldavServer.URL+"/", creds, TransportOptions{})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := client.ListEventRefs(context.Background(), "/cal/"); err != nil {
+					t.Errorf("request failed: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if n := atomic.LoadInt32(refreshes); n != 1 {
+			t.Errorf("expected 1 refresh, got %d", n)
+		}
+	})
+
+	t.Run("reports a rejected refresh token as an auth failure", func(t *testing.T) {
+		caldavServer, tokenServer, _ := oauth2Servers(t, "fresh")
+
+		creds := Credentials{
+			Method: db.AuthMethodOAuth2,
+			OAuth2: &OAuth2Credentials{TokenURL: tokenServer.URL, RefreshToken: "revoked"},
+		}
+		client, _ := NewClientWithCredentials(caldavServer.URL+"/", creds, TransportOptions{})
+		if _, err := client.ListEventRefs(context.Background(), "/cal/"); ClassifyError(err) != FailureAuth {
+			t.Errorf("expected auth failure, got %v", err)
+		}
+	})
+}
+
+func TestTokenSaver(t *testing.T) {
+	_, database, sourceID := setupJournalTest(t)
+	enc, err := crypto.NewEncryptor(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create encryptor: %v", err)
+	}
+
+	creds := Credentials{
+		Method: db.AuthMethodOAuth2,
+		Token:  "access",
+		OAuth2: &OAuth2Credentials{TokenURL: "https://oauth.example.com/token", ClientID: "client", RefreshToken: "refresh"},
+	}
+	settings, _ := EncryptCredentials(enc, creds)
+	if err := database.UpdateSourceAuth(sourceID, db.EndpointDest, settings); err != nil {
+		t.Fatalf("failed to store auth settings: %v", err)
+	}
+	storedAccessToken := func() string {
+		source, _ := database.GetSourceByID(sourceID)
+		token, _ := decryptOptional(enc, source.DestAuth.AccessToken)
+		return token
+	}
+
+	save := tokenSaver(database, enc, sourceID, db.EndpointDest, settings)
+	save(&oauth2.Token{AccessToken: "renewed", RefreshToken: "refresh-2"})
+	if got := storedAccessToken(); got != "renewed" {
+		t.Fatalf("expected renewed token to be stored, got %q", got)
+	}
+	save(&oauth2.Token{AccessToken: "renewed-again", RefreshToken: "refresh-3"})
+	if got := storedAccessToken(); got != "renewed-again" {
+		t.Fatalf("expected later renewals to be stored, got %q", got)
+	}
+
+	// The source is re-authorized; renewals of the old grant must not overwrite it
+	creds.Token = "reconnected"
+	creds.OAuth2.RefreshToken = "new-grant"
+	reconnected, _ := EncryptCredentials(enc, creds)
+	if err := database.UpdateSourceAuth(sourceID, db.EndpointDest, reconnected); err != nil {
+		t.Fatalf("failed to store auth settings: %v", err)
+	}
+	save(&oauth2.Token{AccessToken: "stale", RefreshToken: "refresh-4"})
+	if got := storedAccessToken(); got != "reconnected" {
+		t.Errorf("expected the edited settings to be kept, got %q", got)
+	}
+}
+
+func TestEncryptCredentials(t *testing.T) {
+	enc, err := crypto.NewEncryptor(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create encryptor: %v", err)
+	}
+
+	t.Run("stores nothing for basic auth", func(t *testing.T) {
+		settings, err := EncryptCredentials(enc, BasicCredentials("user", "pass"))
+		if err != nil || !settings.IsZero() {
+			t.Errorf("expected zero settings, got %+v, %v", settings, err)
+		}
+	})
+
+	t.Run("round trips OAuth2 credentials", func(t *testing.T) {
+		expiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		creds := Credentials{
+			Method:   db.AuthMethodOAuth2,
+			Username: "user",
+			Token:    "access",
+			OAuth2: &OAuth2Credentials{
+				TokenURL:     "https://oauth.example.com/token",
+				ClientID:     "client",
+				ClientSecret: "secret",
+				Scopes:       []string{"calendar"},
+				RefreshToken: "refresh",
+				Expiry:       expiry,
+			},
+		}
+		settings, err := EncryptCredentials(enc, creds)
+		if err != nil {
+			t.Fatalf("EncryptCredentials failed: %v", err)
+		}
+		if settings.AccessToken == "access" || settings.RefreshToken == "refresh" || settings.ClientSecret == "secret" {
+			t.Error("expected tokens and client secret to be encrypted")
+		}
+
+		decrypted, err := DecryptCredentials(enc, "user", "", settings)
+		if err != nil {
+			t.Fatalf("DecryptCredentials failed: %v", err)
+		}
+		o := decrypted.OAuth2
+		if decrypted.Token != "access" || o == nil || o.RefreshToken != "refresh" || o.ClientSecret != "secret" ||
+			o.TokenURL != creds.OAuth2.TokenURL || !o.Expiry.Equal(expiry) || len(o.Scopes) != 1 {
+			t.Errorf("round trip mismatch: %+v %+v", decrypted, o)
+		}
+	})
+}
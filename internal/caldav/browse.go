@@ -0,0 +1,210 @@
+package caldav
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// diffFields are the properties a calendar diff compares between the copies of an event.
+// ETags can't be compared as they are assigned by each server.
+var diffFields = []db.DedupeField{db.DedupeSummary, db.DedupeStart, db.DedupeEnd, db.DedupeLocation, db.DedupeRRule}
+
+// eventContentHash fingerprints the properties of an event a calendar diff compares.
+func eventContentHash(e Event) string {
+	values := make([]string, len(diffFields))
+	for i, field := range diffFields {
+		values[i] = e.MatchKey([]db.DedupeField{field})
+	}
+	sum := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// DiffEvent is an event found on only one side of a calendar diff.
+type DiffEvent struct {
+	Event
+	Synced bool // Recorded in synced_events for the source calendar
+}
+
+// EventDifference is an event on both sides of a calendar diff whose content differs.
+type EventDifference struct {
+	UID        string
+	Summary    string // Summary on the source
+	SourcePath string
+	DestPath   string
+	Fields     []db.DedupeField // Compared properties that differ
+}
+
+// CalendarDiff compares a source calendar with its destination calendar as a sync
+// would see them: events are matched by UID and, if the source syncs only recent
+// events, older ones are left out.
+type CalendarDiff struct {
+	CalendarHref    string
+	DestHref        string
+	SourceEvents    int
+	DestEvents      int
+	MissingOnDest   []DiffEvent          // On the source only; synced ones were deleted on the destination
+	MissingOnSource []DiffEvent          // On the destination only; unsynced ones come from elsewhere
+	Differs         []EventDifference    // On both, with different content
+	Duplicates      []*db.DuplicateGroup // Duplicates calbridge wrote to the destination
+}
+
+// ListCalendarEvents returns the metadata of the events of a calendar on one endpoint
+// of a source, ordered by start time. Data is empty.
+func (se *SyncEngine) ListCalendarEvents(ctx context.Context, source *db.Source, endpoint db.Endpoint, calendarHref string) ([]Event, error) {
+	client, err := se.endpointClient(source, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := se.listEventMetadata(ctx, client, calendarHref, nil)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].StartTime != events[j].StartTime {
+			return events[i].StartTime < events[j].StartTime
+		}
+		return events[i].Path < events[j].Path
+	})
+	return events, nil
+}
+
+// GetCalendarEvent returns the iCalendar data and ETag of an event on one endpoint of
+// a source, as the server serves it.
+func (se *SyncEngine) GetCalendarEvent(ctx context.Context, source *db.Source, endpoint db.Endpoint, eventPath string) (string, string, error) {
+	client, err := se.endpointClient(source, endpoint)
+	if err != nil {
+		return "", "", err
+	}
+	return client.GetRawEvent(ctx, eventPath)
+}
+
+// DiffCalendar compares a source calendar with the destination calendar it syncs to.
+// Nothing is written: a destination calendar that doesn't exist yet isn't created.
+func (se *SyncEngine) DiffCalendar(ctx context.Context, source *db.Source, calendarHref string) (*CalendarDiff, error) {
+	sourceClient, err := se.endpointClient(source, db.EndpointSource)
+	if err != nil {
+		return nil, err
+	}
+	destClient, err := se.endpointClient(source, db.EndpointDest)
+	if err != nil {
+		return nil, err
+	}
+
+	readOnly := *source
+	readOnly.CreateCalendars = false
+	destHref, _ := se.destCalendarFor(ctx, &readOnly, sourceClient, destClient, Calendar{Path: calendarHref})
+
+	sourceEvents, err := se.listEventMetadata(ctx, sourceClient, calendarHref, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source events: %w", err)
+	}
+	destEvents, err := se.listEventMetadata(ctx, destClient, destHref, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get destination events: %w", err)
+	}
+	if source.SyncDaysPast > 0 {
+		cutoffDate := time.Now().AddDate(0, 0, -source.SyncDaysPast)
+		sourceEvents = filterEventsByDate(sourceEvents, cutoffDate)
+		destEvents = filterEventsByDate(destEvents, cutoffDate)
+	}
+
+	synced, err := se.db.GetSyncedEvents(source.ID, calendarHref)
+	if err != nil {
+		log.Printf("Failed to get synced events: %v", err)
+	}
+	syncedUIDs := make(map[string]bool, len(synced))
+	for _, event := range synced {
+		syncedUIDs[event.EventUID] = true
+	}
+
+	diff := compareCalendars(sourceEvents, destEvents, syncedUIDs, source.GetDedupeFields())
+	diff.CalendarHref = calendarHref
+	diff.DestHref = destHref
+	for _, group := range diff.Duplicates {
+		group.CalendarHref = calendarHref
+		group.DestHref = destHref
+		group.DetectedAt = time.Now().UTC()
+	}
+	return diff, nil
+}
+
+// compareCalendars matches source and destination events by UID and compares the
+// matches by content hash. Duplicates are looked for among the destination events
+// calbridge owns, as in a sync.
+func compareCalendars(sourceEvents, destEvents []Event, syncedUIDs map[string]bool, dedupeFields []db.DedupeField) *CalendarDiff {
+	diff := &CalendarDiff{SourceEvents: len(sourceEvents), DestEvents: len(destEvents)}
+
+	sourceEventMap := make(map[string]Event, len(sourceEvents))
+	for _, e := range sourceEvents {
+		if e.UID != "" {
+			sourceEventMap[e.UID] = e
+		}
+	}
+	destEventMap := make(map[string]Event, len(destEvents))
+	for _, e := range destEvents {
+		if e.UID != "" {
+			destEventMap[e.UID] = e
+		}
+	}
+
+	for _, sourceEvent := range sourceEvents {
+		if sourceEvent.UID == "" {
+			continue
+		}
+		destEvent, exists := destEventMap[sourceEvent.UID]
+		if !exists {
+			diff.MissingOnDest = append(diff.MissingOnDest, DiffEvent{Event: sourceEvent, Synced: syncedUIDs[sourceEvent.UID]})
+			continue
+		}
+		if eventContentHash(sourceEvent) == eventContentHash(destEvent) {
+			continue
+		}
+
+		var fields []db.DedupeField
+		for _, field := range diffFields {
+			only := []db.DedupeField{field}
+			if sourceEvent.MatchKey(only) != destEvent.MatchKey(only) {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) > 0 {
+			diff.Differs = append(diff.Differs, EventDifference{
+				UID:        sourceEvent.UID,
+				Summary:    sourceEvent.Summary,
+				SourcePath: sourceEvent.Path,
+				DestPath:   destEvent.Path,
+				Fields:     fields,
+			})
+		}
+	}
+
+	for _, destEvent := range destEvents {
+		if destEvent.UID == "" {
+			continue
+		}
+		if _, exists := sourceEventMap[destEvent.UID]; !exists {
+			diff.MissingOnSource = append(diff.MissingOnSource, DiffEvent{Event: destEvent, Synced: syncedUIDs[destEvent.UID]})
+		}
+	}
+
+	owned := make(map[string]bool, len(sourceEventMap)+len(syncedUIDs))
+	for uid := range sourceEventMap {
+		owned[uid] = true
+	}
+	for uid := range syncedUIDs {
+		owned[uid] = true
+	}
+	for _, group := range findDuplicates(destEvents, dedupeFields, owned, sourceEventMap) {
+		diff.Duplicates = append(diff.Duplicates, group.report(""))
+	}
+	return diff
+}
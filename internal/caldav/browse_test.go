@@ -0,0 +1,128 @@
+package caldav
+
+import (
+	"context"
+	"testing"
+
+	"github.com/macjediwizard/calbridgesync/internal/crypto"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+func TestCompareCalendars(t *testing.T) {
+	sourceEvents := []Event{
+		{Path: "/src/a.ics", UID: "a", Summary: "Standup", StartTime: "20240101T100000Z"},
+		{Path: "/src/b.ics", UID: "b", Summary: "Review", StartTime: "20240102T100000Z", Location: "Room 1"},
+		{Path: "/src/c.ics", UID: "c", Summary: "Lunch", StartTime: "20240103T120000Z"},
+		{Path: "/src/d.ics", UID: "d", Summary: "Retro", StartTime: "20240104T100000Z"},
+	}
+	destEvents := []Event{
+		{Path: "/dst/a.ics", UID: "a", Summary: "Standup", StartTime: "20240101T100000Z"},
+		{Path: "/dst/b.ics", UID: "b", Summary: "Review", StartTime: "20240102T110000Z", Location: "Room 2"},
+		{Path: "/dst/e.ics", UID: "e", Summary: "Gone", StartTime: "20240105T100000Z"},
+		{Path: "/dst/f.ics", UID: "f", Summary: "Other", StartTime: "20240106T100000Z"},
+		{Path: "/dst/a-copy.ics", UID: "a-copy", Summary: "Standup", StartTime: "20240101T100000Z"},
+	}
+	synced := map[string]bool{"a": true, "b": true, "d": true, "e": true, "a-copy": true}
+
+	diff := compareCalendars(sourceEvents, destEvents, synced, db.DefaultDedupeFields)
+
+	if diff.SourceEvents != 4 || diff.DestEvents != 5 {
+		t.Errorf("unexpected counts: %d source, %d dest", diff.SourceEvents, diff.DestEvents)
+	}
+
+	t.Run("missing on destination", func(t *testing.T) {
+		if len(diff.MissingOnDest) != 2 {
+			t.Fatalf("expected 2 events, got %+v", diff.MissingOnDest)
+		}
+		if c, d := diff.MissingOnDest[0], diff.MissingOnDest[1]; c.UID != "c" || c.Synced || d.UID != "d" || !d.Synced {
+			t.Errorf("unexpected events: %+v", diff.MissingOnDest)
+		}
+	})
+
+	t.Run("missing on source", func(t *testing.T) {
+		var uids []string
+		for _, e := range diff.MissingOnSource {
+			uids = append(uids, e.UID)
+		}
+		if len(uids) != 3 || uids[0] != "e" || uids[1] != "f" || uids[2] != "a-copy" {
+			t.Fatalf("unexpected events: %v", uids)
+		}
+		if !diff.MissingOnSource[0].Synced || diff.MissingOnSource[1].Synced {
+			t.Errorf("expected only e to be synced: %+v", diff.MissingOnSource)
+		}
+	})
+
+	t.Run("content differs", func(t *testing.T) {
+		if len(diff.Differs) != 1 {
+			t.Fatalf("expected 1 difference, got %+v", diff.Differs)
+		}
+		d := diff.Differs[0]
+		if d.UID != "b" || d.SourcePath != "/src/b.ics" || d.DestPath != "/dst/b.ics" {
+			t.Errorf("unexpected difference: %+v", d)
+		}
+		if len(d.Fields) != 2 || d.Fields[0] != db.DedupeStart || d.Fields[1] != db.DedupeLocation {
+			t.Errorf("expected dtstart and location to differ, got %v", d.Fields)
+		}
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		if len(diff.Duplicates) != 1 {
+			t.Fatalf("expected 1 duplicate group, got %d", len(diff.Duplicates))
+		}
+		g := diff.Duplicates[0]
+		if g.Keep.UID != "a" || len(g.Duplicates) != 1 || g.Duplicates[0].UID != "a-copy" {
+			t.Errorf("unexpected group: %+v", g)
+		}
+	})
+}
+
+func TestBrowseCalendars(t *testing.T) {
+	_, database, sourceID := setupJournalTest(t)
+	enc, _ := crypto.NewEncryptor(make([]byte, 32))
+	engine := NewSyncEngine(database, enc)
+	server := newProbeServer(t)
+
+	source, _ := database.GetSourceByID(sourceID)
+	source.SourceURL = server.URL + "/dav/"
+	source.SourcePassword, _ = enc.Encrypt("secret")
+	source.DestURL = server.URL + "/dav/"
+	source.DestPassword, _ = enc.Encrypt("secret")
+	database.UpdateSource(source)
+	ctx := context.Background()
+	const calendarPath = "/dav/calendars/alice/work/"
+
+	t.Run("lists calendar events", func(t *testing.T) {
+		events, err := engine.ListCalendarEvents(ctx, source, db.EndpointDest, calendarPath)
+		if err != nil {
+			t.Fatalf("ListCalendarEvents() error = %v", err)
+		}
+		if len(events) != 1 || events[0].UID != "event-1" || events[0].Summary != "Standup" || events[0].Data != "" {
+			t.Errorf("unexpected events: %+v", events)
+		}
+	})
+
+	t.Run("gets an event as served", func(t *testing.T) {
+		data, etag, err := engine.GetCalendarEvent(ctx, source, db.EndpointSource, calendarPath+"1.ics")
+		if err != nil || data != probeEventData || etag != `"e1"` {
+			t.Errorf("GetCalendarEvent() = %q, %q, %v", data, etag, err)
+		}
+	})
+
+	t.Run("diffs a calendar without writing", func(t *testing.T) {
+		database.UpsertSyncedEvent(&db.SyncedEvent{SourceID: sourceID, CalendarHref: calendarPath, EventUID: "event-1"})
+
+		diff, err := engine.DiffCalendar(ctx, source, calendarPath)
+		if err != nil {
+			t.Fatalf("DiffCalendar() error = %v", err)
+		}
+		if diff.DestHref != calendarPath || diff.SourceEvents != 1 || diff.DestEvents != 1 {
+			t.Errorf("unexpected diff: %+v", diff)
+		}
+		if len(diff.MissingOnDest)+len(diff.MissingOnSource)+len(diff.Differs)+len(diff.Duplicates) != 0 {
+			t.Errorf("expected identical calendars, got %+v", diff)
+		}
+		if n := server.count("PUT") + server.count("DELETE") + server.count("MKCALENDAR"); n != 0 {
+			t.Errorf("expected no writes, got %d", n)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package caldav
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// capabilityProbeInterval is how long a probed server profile is trusted before the
+// server is probed again.
+const capabilityProbeInterval = 24 * time.Hour
+
+// SetCapabilities makes the client choose its strategies from a probed server profile
+// instead of trying and falling back on every request. nil restores the defaults.
+func (c *Client) SetCapabilities(caps *db.ServerCapabilities) {
+	c.capabilities.Store(caps)
+}
+
+// Capabilities returns the server profile the client uses, or nil if it has none.
+func (c *Client) Capabilities() *db.ServerCapabilities {
+	return c.capabilities.Load()
+}
+
+// ProbeCapabilities detects what the server supports using the calendar at
+// calendarPath. Probes that fail count as unsupported. If write is set, conditional
+// PUT is probed by putting an event that must be rejected; if the server stores it
+// anyway, it is deleted again. SourceID and Endpoint are left to the caller.
+func (c *Client) ProbeCapabilities(ctx context.Context, calendarPath string, write bool) *db.ServerCapabilities {
+	caps := &db.ServerCapabilities{ProbedAt: time.Now().UTC()}
+
+	if header, err := c.options(ctx, calendarPath); err == nil {
+		caps.Server = header.Get("Server")
+		caps.SyncCollection = strings.Contains(header.Get("DAV"), "sync-collection")
+	}
+
+	// Servers that don't list their methods get the benefit of the doubt
+	caps.MKCalendar = true
+	if homeSet, err := c.findHomeSet(ctx); err == nil {
+		if header, err := c.options(ctx, homeSet); err == nil && header.Get("Allow") != "" {
+			caps.MKCalendar = strings.Contains(strings.ToUpper(header.Get("Allow")), "MKCALENDAR")
+		}
+	}
+
+	reports, ctag, syncToken, err := c.collectionFeatures(ctx, calendarPath)
+	if err == nil {
+		caps.SyncCollection = caps.SyncCollection || syncToken || strings.Contains(reports, "sync-collection")
+		caps.MultiGet = strings.Contains(reports, "calendar-multiget")
+		caps.CTag = ctag
+	}
+
+	refs, listErr := c.ListEventRefs(ctx, calendarPath)
+	queried, queryErr := c.queryEventRefs(ctx, calendarPath, false)
+	// SOGo answers calendar-query with nothing for calendars PROPFIND lists events in
+	caps.CalendarQuery = queryErr == nil && (listErr != nil || len(refs) == 0 || len(queried) > 0)
+	_, rangeErr := c.queryEventRefs(ctx, calendarPath, true)
+	caps.TimeRange = rangeErr == nil
+
+	if listErr == nil && len(refs) > 0 {
+		_, _, _, err := c.getEventsBatch(ctx, calendarPath, []string{refs[0].Path}, nil)
+		caps.MultiGet = err == nil
+	}
+
+	if write {
+		caps.ConditionalPut = c.probeConditionalPut(ctx, calendarPath)
+	}
+
+	log.Printf("Probed %s: sync-collection=%t calendar-query=%t time-range=%t multiget=%t ctag=%t mkcalendar=%t conditional-put=%t",
+		c.buildURL(calendarPath), caps.SyncCollection, caps.CalendarQuery, caps.TimeRange, caps.MultiGet,
+		caps.CTag, caps.MKCalendar, caps.ConditionalPut)
+	return caps
+}
+
+// options sends an OPTIONS request and returns the response headers.
+func (c *Client) options(ctx context.Context, path string) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodOptions, c.buildURL(path), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: OPTIONS returned status %d", statusSentinel(resp.StatusCode), resp.StatusCode)
+	}
+	return resp.Header, nil
+}
+
+// collectionFeatures reads the supported reports (as raw XML), and whether a CTag and
+// a sync token are present, from the properties of a calendar.
+func (c *Client) collectionFeatures(ctx context.Context, calendarPath string) (reports string, ctag, syncToken bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, "PROPFIND", c.buildURL(calendarPath), strings.NewReader(`<?xml version="1.0" encoding="utf-8" ?>
+<D:propfind xmlns:D="DAV:" xmlns:CS="http://calendarserver.org/ns/">
+  <D:prop>
+    <D:supported-report-set/>
+    <D:sync-token/>
+    <CS:getctag/>
+  </D:prop>
+</D:propfind>`))
+	if err != nil {
+		return "", false, false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", false, false, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return "", false, false, fmt.Errorf("%w: unexpected status %d", statusSentinel(resp.StatusCode), resp.StatusCode)
+	}
+
+	var ms struct {
+		Responses []struct {
+			PropStat []struct {
+				Prop struct {
+					Reports struct {
+						Inner string `xml:",innerxml"`
+					} `xml:"DAV: supported-report-set"`
+					SyncToken string `xml:"DAV: sync-token"`
+					CTag      string `xml:"http://calendarserver.org/ns/ getctag"`
+				} `xml:"prop"`
+				Status string `xml:"status"`
+			} `xml:"propstat"`
+		} `xml:"response"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&ms); err != nil {
+		return "", false, false, fmt.Errorf("%w: failed to decode PROPFIND response: %w", ErrInvalidResponse, err)
+	}
+
+	for _, r := range ms.Responses {
+		for _, ps := range r.PropStat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			reports += ps.Prop.Reports.Inner
+			ctag = ctag || strings.TrimSpace(ps.Prop.CTag) != ""
+			syncToken = syncToken || strings.TrimSpace(ps.Prop.SyncToken) != ""
+		}
+	}
+	return reports, ctag, syncToken, nil
+}
+
+// queryEventRefs lists the events of a calendar with a calendar-query REPORT that
+// returns only ETags. With timeRange, the query is limited to the year around now.
+func (c *Client) queryEventRefs(ctx context.Context, calendarPath string, timeRange bool) ([]EventRef, error) {
+	filter := ""
+	if timeRange {
+		now := time.Now().UTC()
+		filter = fmt.Sprintf(`<C:time-range start="%s" end="%s"/>`,
+			now.AddDate(0, -6, 0).Format("20060102T150405Z"), now.AddDate(0, 6, 0).Format("20060102T150405Z"))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "REPORT", c.buildURL(calendarPath), strings.NewReader(`<?xml version="1.0" encoding="utf-8" ?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop>
+    <D:getetag/>
+    <D:getcontenttype/>
+  </D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">`+filter+`</C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "1")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil, fmt.Errorf("%w: calendar-query returned status %d", statusSentinel(resp.StatusCode), resp.StatusCode)
+	}
+	return decodeEventRefs(resp.Body, calendarPath)
+}
+
+// probeConditionalPut puts an event with an If-Match that can't match. Servers that
+// honor preconditions reject it with 412 Precondition Failed.
+func (c *Client) probeConditionalPut(ctx context.Context, calendarPath string) bool {
+	uid := "calbridge-probe-" + uuid.New().String()
+	path := strings.TrimSuffix(calendarPath, "/") + "/" + uid + ".ics"
+	body := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//CalBridgeSync//Probe//EN\r\nBEGIN:VEVENT\r\nUID:" + uid +
+		"\r\nDTSTAMP:20000101T000000Z\r\nDTSTART:20000101T000000Z\r\nSUMMARY:CalBridgeSync probe\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.buildURL(path), strings.NewReader(body))
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	req.Header.Set("If-Match", `"calbridge-probe"`)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		log.Printf("Server ignored If-Match, removing probe event %s", path)
+		if err := c.DeleteEvent(ctx, path); err != nil {
+			log.Printf("Failed to remove probe event %s: %v", path, err)
+		}
+		return false
+	}
+	return resp.StatusCode == http.StatusPreconditionFailed
+}
+
+// loadCapabilities gives a client the stored profile of one endpoint of a source. The
+// server is probed first if there is no profile yet or it is older than
+// capabilityProbeInterval. calendarPath is a calendar of the endpoint to probe with.
+func (se *SyncEngine) loadCapabilities(ctx context.Context, source *db.Source, endpoint db.Endpoint, client *Client, calendarPath string) {
+	caps, err := se.db.GetServerCapabilities(source.ID, endpoint)
+	if err == nil && time.Since(caps.ProbedAt) < capabilityProbeInterval {
+		client.SetCapabilities(caps)
+		return
+	}
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.Printf("Failed to get server capabilities: %v", err)
+	}
+
+	// calbridge only writes to the source in two-way sync
+	write := endpoint == db.EndpointDest || source.SyncDirection == db.SyncDirectionTwoWay
+	for _, cfg := range source.SelectedCalendars {
+		write = write || cfg.SyncDirection == db.SyncDirectionTwoWay
+	}
+
+	probed := client.ProbeCapabilities(ctx, calendarPath, write)
+	if ctx.Err() != nil {
+		return // An interrupted probe would store a profile with everything unsupported
+	}
+	probed.SourceID, probed.Endpoint = source.ID, endpoint
+	if err := se.db.UpsertServerCapabilities(probed); err != nil {
+		log.Printf("Failed to save server capabilities: %v", err)
+	}
+	client.SetCapabilities(probed)
+}
+
+// probeCalendarPath returns a calendar of the client's server to probe with.
+func probeCalendarPath(ctx context.Context, client *Client) string {
+	if calendars, err := client.FindCalendars(ctx); err == nil && len(calendars) > 0 {
+		return calendars[0].Path
+	}
+	return client.GetCalendarPath()
+}
@@ -0,0 +1,267 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+const probeEventData = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\nBEGIN:VEVENT\r\nUID:event-1\r\nDTSTAMP:20240101T000000Z\r\nDTSTART:20240101T100000Z\r\nSUMMARY:Standup\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+// probeServer is a CalDAV server with one calendar holding one event, whose quirks
+// can be switched on.
+type probeServer struct {
+	*httptest.Server
+
+	emptyQuery  bool // calendar-query returns nothing, like SOGo
+	noMultiGet  bool // calendar-multiget fails
+	ignoreMatch bool // PUT ignores If-Match
+	noMkcal     bool // MKCALENDAR isn't allowed
+
+	mu       sync.Mutex
+	requests []string // Method and body kind of each request
+}
+
+func newProbeServer(t *testing.T) *probeServer {
+	t.Helper()
+	s := &probeServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *probeServer) count(request string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, r := range s.requests {
+		if r == request {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *probeServer) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/") + "/"
+	body, _ := io.ReadAll(r.Body)
+	kind := r.Method
+	for _, report := range []string{"calendar-query", "calendar-multiget"} {
+		if strings.Contains(string(body), report) {
+			kind += " " + report
+		}
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, kind)
+	s.mu.Unlock()
+
+	multistatus := func(responses string) {
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusMultiStatus)
+		io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav" xmlns:CS="http://calendarserver.org/ns/">`+responses+`</D:multistatus>`)
+	}
+	event := func(withData bool) string {
+		data := ""
+		if withData {
+			data = `<C:calendar-data>` + probeEventData + `</C:calendar-data>`
+		}
+		return `<D:response><D:href>/dav/calendars/alice/work/1.ics</D:href><D:propstat><D:prop>
+			<D:getetag>"e1"</D:getetag><D:getcontenttype>text/calendar</D:getcontenttype>` + data + `
+			</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`
+	}
+
+	switch {
+	case r.Method == http.MethodOptions:
+		w.Header().Set("Server", "FakeDAV/1.0")
+		w.Header().Set("DAV", "1, 2, calendar-access, sync-collection")
+		allow := "OPTIONS, GET, PUT, DELETE, PROPFIND, REPORT"
+		if !s.noMkcal {
+			allow += ", MKCALENDAR"
+		}
+		w.Header().Set("Allow", allow)
+	case r.Method == http.MethodPut:
+		if r.Header.Get("If-Match") != "" && !s.ignoreMatch {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("ETag", `"e1"`)
+		io.WriteString(w, probeEventData)
+	case r.Method == "REPORT" && strings.Contains(kind, "calendar-multiget"):
+		if s.noMultiGet {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		multistatus(event(true))
+	case r.Method == "REPORT":
+		if s.emptyQuery {
+			multistatus("")
+			return
+		}
+		multistatus(event(strings.Contains(string(body), "calendar-data")))
+	case path == "/dav/":
+		multistatus(`<D:response><D:href>/dav/</D:href><D:propstat><D:prop>
+			<D:current-user-principal><D:href>/dav/principals/alice/</D:href></D:current-user-principal>
+			</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`)
+	case path == "/dav/principals/alice/":
+		multistatus(`<D:response><D:href>/dav/principals/alice/</D:href><D:propstat><D:prop>
+			<C:calendar-home-set><D:href>/dav/calendars/alice/</D:href></C:calendar-home-set>
+			</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`)
+	case path == "/dav/calendars/alice/":
+		multistatus(`<D:response><D:href>/dav/calendars/alice/work/</D:href><D:propstat><D:prop>
+			<D:resourcetype><D:collection/><C:calendar/></D:resourcetype><D:displayname>Work</D:displayname>
+			</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`)
+	case path == "/dav/calendars/alice/work/" && r.Header.Get("Depth") == "0":
+		multistatus(`<D:response><D:href>/dav/calendars/alice/work/</D:href><D:propstat><D:prop>
+			<D:supported-report-set><D:supported-report><D:report><C:calendar-multiget/></D:report></D:supported-report></D:supported-report-set>
+			<CS:getctag>ctag-1</CS:getctag>
+			</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`)
+	case path == "/dav/calendars/alice/work/":
+		multistatus(event(false))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestProbeCapabilities(t *testing.T) {
+	const calendarPath = "/dav/calendars/alice/work/"
+
+	t.Run("detects a well-behaved server", func(t *testing.T) {
+		server := newProbeServer(t)
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+
+		caps := client.ProbeCapabilities(context.Background(), calendarPath, true)
+		want := db.ServerCapabilities{
+			Server: "FakeDAV/1.0", SyncCollection: true, CalendarQuery: true, TimeRange: true,
+			MultiGet: true, CTag: true, MKCalendar: true, ConditionalPut: true,
+		}
+		caps.ProbedAt = time.Time{}
+		if *caps != want {
+			t.Errorf("unexpected capabilities:\n got %+v\nwant %+v", *caps, want)
+		}
+	})
+
+	t.Run("detects quirks", func(t *testing.T) {
+		server := newProbeServer(t)
+		server.emptyQuery, server.noMultiGet, server.ignoreMatch, server.noMkcal = true, true, true, true
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+
+		caps := client.ProbeCapabilities(context.Background(), calendarPath, true)
+		if caps.CalendarQuery || caps.MultiGet || caps.ConditionalPut || caps.MKCalendar {
+			t.Errorf("expected quirks to be detected, got %+v", caps)
+		}
+		if server.count(http.MethodDelete) != 1 {
+			t.Error("expected the probe event to be removed")
+		}
+	})
+
+	t.Run("does not write without permission", func(t *testing.T) {
+		server := newProbeServer(t)
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+
+		if caps := client.ProbeCapabilities(context.Background(), calendarPath, false); caps.ConditionalPut {
+			t.Error("expected conditional PUT not to be probed")
+		}
+		if server.count(http.MethodPut) != 0 {
+			t.Error("expected no PUT request")
+		}
+	})
+}
+
+func TestCapabilityStrategies(t *testing.T) {
+	const calendarPath = "/dav/calendars/alice/work/"
+
+	t.Run("fetches events individually without MULTIGET", func(t *testing.T) {
+		server := newProbeServer(t)
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+		client.SetCapabilities(&db.ServerCapabilities{})
+
+		var fetched []Event
+		err := client.FetchEvents(context.Background(), calendarPath, []string{calendarPath + "1.ics"}, nil, func(batch []Event) error {
+			fetched = append(fetched, batch...)
+			return nil
+		})
+		if err != nil || len(fetched) != 1 {
+			t.Fatalf("expected 1 event, got %d (%v)", len(fetched), err)
+		}
+		if server.count("REPORT calendar-multiget") != 0 {
+			t.Error("expected MULTIGET not to be tried")
+		}
+	})
+
+	t.Run("lists events with PROPFIND when calendar-query is unreliable", func(t *testing.T) {
+		server := newProbeServer(t)
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+		client.SetCapabilities(&db.ServerCapabilities{MultiGet: true})
+
+		events, err := client.GetEvents(context.Background(), calendarPath, nil)
+		if err != nil || len(events) != 1 {
+			t.Fatalf("expected 1 event, got %d (%v)", len(events), err)
+		}
+		if server.count("REPORT calendar-query") != 0 {
+			t.Error("expected calendar-query not to be tried")
+		}
+	})
+
+	t.Run("answers WebDAV-Sync support from the profile", func(t *testing.T) {
+		server := newProbeServer(t)
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+		client.SetCapabilities(&db.ServerCapabilities{SyncCollection: true})
+
+		if !client.SupportsWebDAVSync(context.Background(), calendarPath) || server.count(http.MethodOptions) != 0 {
+			t.Error("expected WebDAV-Sync support to come from the profile")
+		}
+	})
+
+	t.Run("refuses MKCALENDAR where it isn't supported", func(t *testing.T) {
+		server := newProbeServer(t)
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+		client.SetCapabilities(&db.ServerCapabilities{})
+
+		if _, err := client.CreateCalendar(context.Background(), Calendar{Name: "Work"}); !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("expected ErrInvalidResponse, got %v", err)
+		}
+		if server.count("MKCALENDAR") != 0 {
+			t.Error("expected no MKCALENDAR request")
+		}
+	})
+}
+
+func TestLoadCapabilities(t *testing.T) {
+	engine, database, sourceID := setupJournalTest(t)
+	source, _ := database.GetSourceByID(sourceID)
+	server := newProbeServer(t)
+	server.noMultiGet = true
+	client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+
+	engine.loadCapabilities(context.Background(), source, db.EndpointSource, client, "/dav/calendars/alice/work/")
+	stored, err := database.GetServerCapabilities(sourceID, db.EndpointSource)
+	if err != nil || stored.MultiGet || !stored.CalendarQuery {
+		t.Fatalf("expected probed profile to be stored, got %+v (%v)", stored, err)
+	}
+	if caps := client.Capabilities(); caps == nil || caps.MultiGet {
+		t.Fatalf("expected client to use the profile, got %+v", caps)
+	}
+	if server.count(http.MethodPut) != 0 {
+		t.Error("expected one-way sources not to be written to")
+	}
+
+	probes := server.count(http.MethodOptions)
+	fresh, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+	engine.loadCapabilities(context.Background(), source, db.EndpointSource, fresh, "/dav/calendars/alice/work/")
+	if server.count(http.MethodOptions) != probes || fresh.Capabilities() == nil {
+		t.Error("expected the stored profile to be reused without probing")
+	}
+}
@@ -4,19 +4,17 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
-	"encoding/xml"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/emersion/go-ical"
-	"github.com/emersion/go-webdav"
 	"github.com/emersion/go-webdav/caldav"
+	"github.com/macjediwizard/calbridgesync/internal/db"
 )
 
 var (
@@ -34,12 +32,14 @@ const (
 
 // Calendar represents a CalDAV calendar.
 type Calendar struct {
-	Path        string `json:"path"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Color       string `json:"color"`
-	SyncToken   string `json:"sync_token"`
-	CTag        string `json:"ctag"`
+	Path        string   `json:"path"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Color       string   `json:"color"`
+	Order       string   `json:"order,omitempty"`      // Position in calendar lists (Apple calendar-order)
+	Components  []string `json:"components,omitempty"` // Supported component types, e.g. VEVENT
+	SyncToken   string   `json:"sync_token"`
+	CTag        string   `json:"ctag"`
 }
 
 // Event represents a calendar event.
@@ -50,6 +50,9 @@ type Event struct {
 	UID       string `json:"uid"`
 	Summary   string `json:"summary"`
 	StartTime string `json:"start_time"` // DTSTART value for deduplication
+	EndTime   string `json:"end_time,omitempty"`
+	Location  string `json:"location,omitempty"`
+	RRule     string `json:"rrule,omitempty"`
 }
 
 // DedupeKey returns a key for deduplication based on summary and start time.
@@ -57,10 +60,65 @@ func (e *Event) DedupeKey() string {
 	return e.Summary + "|" + e.StartTime
 }
 
+// MatchKey returns a key for deduplication based on the given fields, or "" if the
+// event has none of them.
+func (e *Event) MatchKey(fields []db.DedupeField) string {
+	values := make([]string, len(fields))
+	empty := true
+	for i, field := range fields {
+		switch field {
+		case db.DedupeSummary:
+			values[i] = e.Summary
+		case db.DedupeStart:
+			values[i] = e.StartTime
+		case db.DedupeEnd:
+			values[i] = e.EndTime
+		case db.DedupeLocation:
+			values[i] = e.Location
+		case db.DedupeRRule:
+			values[i] = e.RRule
+		}
+		if values[i] != "" {
+			empty = false
+		}
+	}
+	if empty {
+		return ""
+	}
+	return strings.Join(values, "|")
+}
+
+// readMetadata sets the UID, summary and the properties compared for deduplication
+// from the events of cal. Times are normalized to UTC.
+func (e *Event) readMetadata(cal *ical.Calendar) {
+	for _, evt := range cal.Events() {
+		if uid, err := evt.Props.Text(ical.PropUID); err == nil {
+			e.UID = uid
+		}
+		if summary, err := evt.Props.Text(ical.PropSummary); err == nil {
+			e.Summary = summary
+		}
+		if dtstart := evt.Props.Get(ical.PropDateTimeStart); dtstart != nil {
+			e.StartTime = normalizeStartTime(dtstart)
+		}
+		if dtend := evt.Props.Get(ical.PropDateTimeEnd); dtend != nil {
+			e.EndTime = normalizeStartTime(dtend)
+		}
+		if location, err := evt.Props.Text(ical.PropLocation); err == nil {
+			e.Location = location
+		}
+		if rrule := evt.Props.Get(ical.PropRecurrenceRule); rrule != nil {
+			e.RRule = rrule.Value
+		}
+	}
+}
+
 // MalformedEventInfo contains information about a corrupted calendar event.
 type MalformedEventInfo struct {
 	Path         string
 	ErrorMessage string
+	Data         string // Raw payload as served, if it could be fetched
+	ETag         string
 }
 
 // MalformedEventCollector collects malformed events during sync operations.
@@ -83,6 +141,16 @@ func (c *MalformedEventCollector) Add(path, errorMessage string) {
 	})
 }
 
+// AddPayload records a malformed event together with its raw payload.
+func (c *MalformedEventCollector) AddPayload(path, errorMessage, data, etag string) {
+	c.events = append(c.events, MalformedEventInfo{
+		Path:         path,
+		ErrorMessage: errorMessage,
+		Data:         data,
+		ETag:         etag,
+	})
+}
+
 // GetEvents returns all collected malformed events.
 func (c *MalformedEventCollector) GetEvents() []MalformedEventInfo {
 	return c.events
@@ -97,72 +165,149 @@ func (c *MalformedEventCollector) Count() int {
 type Client struct {
 	baseURL      string
 	username     string
-	password     string
-	httpClient   *http.Client
+	auth         Authenticator
+	httpClient   *http.Client // Authorizes requests with auth
 	caldavClient *caldav.Client
+	throttle     *throttleTransport
+	discovery    *discoveryCache // Only pooled clients cache discovery (non-zero TTL)
+
+	// Probed profile of the server that strategies are chosen from; nil until known
+	capabilities atomic.Pointer[db.ServerCapabilities]
 }
 
 // NewClient creates a new CalDAV client.
 func NewClient(baseURL, username, password string) (*Client, error) {
+	return NewClientWithOptions(baseURL, username, password, TransportOptions{})
+}
+
+// NewClientWithOptions creates a new CalDAV client with custom transport options.
+func NewClientWithOptions(baseURL, username, password string, opts TransportOptions) (*Client, error) {
+	return NewClientWithCredentials(baseURL, BasicCredentials(username, password), opts)
+}
+
+// NewClientWithCredentials creates a new CalDAV client that authenticates with creds.
+func NewClientWithCredentials(baseURL string, creds Credentials, opts TransportOptions) (*Client, error) {
+	return newClient(baseURL, creds, opts, nil)
+}
+
+// newClient creates a client whose base transport is wrapped with wrap, if set, so
+// every request that goes out, including retries, can be observed.
+func newClient(baseURL string, creds Credentials, opts TransportOptions, wrap func(http.RoundTripper) http.RoundTripper) (*Client, error) {
 	if baseURL == "" {
 		return nil, fmt.Errorf("%w: base URL is required", ErrConnectionFailed)
 	}
+	if err := creds.Validate(); err != nil {
+		return nil, err
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
 
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			MinVersion: minTLSVersion,
-		},
-		MaxIdleConns:        10,
-		IdleConnTimeout:     30 * time.Second,
-		TLSHandshakeTimeout: 10 * time.Second,
+	base, err := newHTTPTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	var transport http.RoundTripper = base
+	if wrap != nil {
+		transport = wrap(base)
 	}
 
+	// Space requests per host and retry throttled requests (429/503)
+	throttle := newThrottleTransport(newHeaderTransport(transport, opts), sharedLimiters)
+	timeout := time.Duration(requestTimeout.Load())
+
+	// OAuth2 token requests go through the same transport, without credentials
+	auth := creds.authenticator(&http.Client{Timeout: timeout, Transport: throttle})
+
 	httpClient := &http.Client{
-		Timeout:   defaultTimeout,
-		Transport: transport,
+		Timeout:   timeout,
+		Transport: &authTransport{base: throttle, auth: auth},
 	}
 
-	caldavClient, err := caldav.NewClient(
-		webdav.HTTPClientWithBasicAuth(httpClient, username, password),
-		baseURL,
-	)
+	caldavClient, err := caldav.NewClient(httpClient, baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("%w: failed to create CalDAV client: %w", ErrConnectionFailed, err)
 	}
 
 	return &Client{
 		baseURL:      baseURL,
-		username:     username,
-		password:     password,
+		username:     creds.Username,
+		auth:         auth,
 		httpClient:   httpClient,
 		caldavClient: caldavClient,
+		throttle:     throttle,
+		discovery:    &discoveryCache{now: time.Now},
 	}, nil
 }
 
-// TestConnection tests the connection to the CalDAV server.
+// ThrottleStats returns the throttling statistics for requests made by this client.
+func (c *Client) ThrottleStats() ThrottleStats {
+	if c.throttle == nil {
+		return ThrottleStats{}
+	}
+	return c.throttle.Stats()
+}
+
+// TestConnection tests the connection to the CalDAV server. It always sends a request,
+// even when a pooled client has the principal cached.
 func (c *Client) TestConnection(ctx context.Context) error {
-	_, err := c.caldavClient.FindCurrentUserPrincipal(ctx)
+	_, err := c.discoverPrincipal(ctx)
+	return connectionError(err)
+}
+
+// checkConnection tests the connection before a sync run. Pooled clients skip the
+// request while a previously found principal is still cached.
+func (c *Client) checkConnection(ctx context.Context) error {
+	_, err := c.findPrincipal(ctx)
+	return connectionError(err)
+}
+
+// connectionError marks a failed connection test as an auth or connection failure.
+func connectionError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if ClassifyError(err) == FailureAuth {
+		return fmt.Errorf("%w: %w", ErrAuthFailed, err)
+	}
+	return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+}
+
+// findPrincipal returns the current user principal, from the discovery cache if possible.
+func (c *Client) findPrincipal(ctx context.Context) (string, error) {
+	if principal, ok := lookup(c.discovery, &c.discovery.principal); ok {
+		return principal, nil
+	}
+	return c.discoverPrincipal(ctx)
+}
+
+// discoverPrincipal requests the current user principal and caches it.
+func (c *Client) discoverPrincipal(ctx context.Context) (string, error) {
+	ctx, status := withResponseStatus(ctx)
+	principal, err := c.caldavClient.FindCurrentUserPrincipal(ctx)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+		return "", status.wrap(err)
 	}
-	return nil
+	store(c.discovery, &c.discovery.principal, principal)
+	return principal, nil
 }
 
 // FindCalendars discovers all calendars for the current user.
+// Pooled clients return cached results until the discovery cache TTL expires.
 func (c *Client) FindCalendars(ctx context.Context) ([]Calendar, error) {
-	principal, err := c.caldavClient.FindCurrentUserPrincipal(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("%w: failed to find principal: %w", ErrConnectionFailed, err)
+	if calendars, ok := lookup(c.discovery, &c.discovery.calendars); ok {
+		return append([]Calendar(nil), calendars...), nil
 	}
 
-	homeSet, err := c.caldavClient.FindCalendarHomeSet(ctx, principal)
+	homeSet, err := c.findHomeSet(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("%w: failed to find home set: %w", ErrConnectionFailed, err)
+		return nil, err
 	}
 
+	ctx, status := withResponseStatus(ctx)
 	cals, err := c.caldavClient.FindCalendars(ctx, homeSet)
 	if err != nil {
-		return nil, fmt.Errorf("%w: failed to find calendars: %w", ErrConnectionFailed, err)
+		return nil, fmt.Errorf("%w: failed to find calendars: %w", ErrConnectionFailed, status.wrap(err))
 	}
 
 	calendars := make([]Calendar, 0, len(cals))
@@ -171,15 +316,51 @@ func (c *Client) FindCalendars(ctx context.Context) ([]Calendar, error) {
 			Path:        cal.Path,
 			Name:        cal.Name,
 			Description: cal.Description,
+			Components:  cal.SupportedComponentSet,
 		})
 	}
 
-	return calendars, nil
+	// Colors and order aren't standard CalDAV properties; calendars keep working without them
+	if props, err := c.findCalendarProps(ctx, homeSet, "1"); err != nil {
+		log.Printf("Failed to read calendar colors: %v", err)
+	} else {
+		for i := range calendars {
+			p := props[collectionKey(calendars[i].Path)]
+			calendars[i].Color, calendars[i].Order = p.color, p.order
+		}
+	}
+	store(c.discovery, &c.discovery.calendars, calendars)
+
+	return append([]Calendar(nil), calendars...), nil
+}
+
+// findHomeSet returns the user's calendar home set, from the discovery cache if possible.
+func (c *Client) findHomeSet(ctx context.Context) (string, error) {
+	if homeSet, ok := lookup(c.discovery, &c.discovery.homeSet); ok {
+		return homeSet, nil
+	}
+
+	principal, err := c.findPrincipal(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to find principal: %w", ErrConnectionFailed, err)
+	}
+	ctx, status := withResponseStatus(ctx)
+	homeSet, err := c.caldavClient.FindCalendarHomeSet(ctx, principal)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to find home set: %w", ErrConnectionFailed, status.wrap(err))
+	}
+	store(c.discovery, &c.discovery.homeSet, homeSet)
+	return homeSet, nil
 }
 
 // GetEvents retrieves all events from a calendar.
 // If collector is provided, malformed events will be recorded there.
 func (c *Client) GetEvents(ctx context.Context, calendarPath string, collector *MalformedEventCollector) ([]Event, error) {
+	// Servers known to answer calendar-query incompletely are listed with PROPFIND right away
+	if caps := c.capabilities.Load(); caps != nil && !caps.CalendarQuery {
+		return c.getEventsViaPropfind(ctx, calendarPath, collector)
+	}
+
 	// Try the standard calendar-query first
 	events, err := c.getEventsViaQuery(ctx, calendarPath)
 	if err == nil && len(events) > 0 {
@@ -207,9 +388,10 @@ func (c *Client) getEventsViaQuery(ctx context.Context, calendarPath string) ([]
 		},
 	}
 
+	ctx, status := withResponseStatus(ctx)
 	objects, err := c.caldavClient.QueryCalendar(ctx, calendarPath, query)
 	if err != nil {
-		return nil, fmt.Errorf("%w: failed to query calendar: %w", ErrConnectionFailed, err)
+		return nil, fmt.Errorf("%w: failed to query calendar: %w", ErrConnectionFailed, status.wrap(err))
 	}
 
 	return c.objectsToEvents(objects), nil
@@ -223,86 +405,29 @@ func (c *Client) getEventsViaPropfind(ctx context.Context, calendarPath string,
 
 // getEventsViaList lists calendar contents and fetches events using batch MULTIGET.
 func (c *Client) getEventsViaList(ctx context.Context, calendarPath string, collector *MalformedEventCollector) ([]Event, error) {
-	// Build the full URL - calendarPath might be absolute or relative
-	fullURL := c.buildURL(calendarPath)
-
-	// Make a simple PROPFIND request to list contents
-	req, err := http.NewRequestWithContext(ctx, "PROPFIND", fullURL, strings.NewReader(`<?xml version="1.0" encoding="utf-8" ?>
-<D:propfind xmlns:D="DAV:">
-  <D:prop>
-    <D:getetag/>
-    <D:getcontenttype/>
-  </D:prop>
-</D:propfind>`))
+	refs, err := c.ListEventRefs(ctx, calendarPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
-
-	req.SetBasicAuth(c.username, c.password)
-	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
-	req.Header.Set("Depth", "1")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	if len(refs) == 0 {
+		return []Event{}, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusMultiStatus && resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: unexpected status %d", ErrInvalidResponse, resp.StatusCode)
+	paths := make([]string, len(refs))
+	for i, ref := range refs {
+		paths[i] = ref.Path
 	}
 
-	// Parse the multistatus response to get event paths
-	body, err := io.ReadAll(resp.Body)
+	events := make([]Event, 0, len(paths))
+	err = c.FetchEvents(ctx, calendarPath, paths, collector, func(batch []Event) error {
+		events = append(events, batch...)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	log.Printf("PROPFIND response for %s: status=%d, body_len=%d", fullURL, resp.StatusCode, len(body))
-	eventPaths := parseEventPaths(body, calendarPath)
-	log.Printf("Parsed %d event paths from PROPFIND response (calendarPath=%s)", len(eventPaths), calendarPath)
-
-	if len(eventPaths) == 0 {
-		return []Event{}, nil
-	}
-
-	// Use batch MULTIGET to fetch events efficiently (50 events per batch)
-	const batchSize = 50
-	events := make([]Event, 0, len(eventPaths))
-	skippedMalformed := 0
-	skippedEmpty := 0
-	total := len(eventPaths)
-
-	for batchStart := 0; batchStart < total; batchStart += batchSize {
-		batchEnd := batchStart + batchSize
-		if batchEnd > total {
-			batchEnd = total
-		}
-		batchPaths := eventPaths[batchStart:batchEnd]
-
-		log.Printf("Fetching events batch: %d-%d of %d (%.0f%%)", batchStart+1, batchEnd, total, float64(batchEnd)/float64(total)*100)
-
-		// Try MULTIGET for this batch
-		batchEvents, malformed, empty, err := c.getEventsBatch(ctx, calendarPath, batchPaths, collector)
-		if err != nil {
-			// If MULTIGET fails, fall back to individual fetches for this batch
-			log.Printf("MULTIGET failed, falling back to individual fetches: %v", err)
-			batchEvents, malformed, empty = c.getEventsIndividually(ctx, batchPaths, collector)
-		}
-
-		events = append(events, batchEvents...)
-		skippedMalformed += malformed
-		skippedEmpty += empty
+		return nil, err
 	}
 
 	log.Printf("Fetched %d events complete", len(events))
-	if skippedMalformed > 0 {
-		log.Printf("Skipped %d malformed events (corrupted at source)", skippedMalformed)
-	}
-	if skippedEmpty > 0 {
-		log.Printf("Skipped %d empty events (no iCalendar data)", skippedEmpty)
-	}
-
 	return events, nil
 }
 
@@ -318,9 +443,10 @@ func (c *Client) getEventsBatch(ctx context.Context, calendarPath string, paths
 		},
 	}
 
+	ctx, status := withResponseStatus(ctx)
 	objects, err := c.caldavClient.MultiGetCalendar(ctx, calendarPath, multiGet)
 	if err != nil {
-		return nil, 0, 0, fmt.Errorf("MULTIGET failed: %w", err)
+		return nil, 0, 0, fmt.Errorf("MULTIGET failed: %w", status.wrap(err))
 	}
 
 	events := make([]Event, 0, len(objects))
@@ -336,23 +462,11 @@ func (c *Client) getEventsBatch(ctx context.Context, calendarPath string, paths
 		if obj.Data != nil {
 			event.Data = encodeCalendar(obj.Data)
 
-			for _, evt := range obj.Data.Events() {
-				if uid, err := evt.Props.Text(ical.PropUID); err == nil {
-					event.UID = uid
-				}
-				if summary, err := evt.Props.Text(ical.PropSummary); err == nil {
-					event.Summary = summary
-				}
-				if dtstart := evt.Props.Get(ical.PropDateTimeStart); dtstart != nil {
-					event.StartTime = normalizeStartTime(dtstart)
-				}
-			}
+			event.readMetadata(obj.Data)
 		}
 
 		if event.Data == "" {
-			if collector != nil {
-				collector.Add(obj.Path, "empty iCalendar data - event may be corrupted or deleted")
-			}
+			c.recordMalformed(ctx, collector, obj.Path, "empty iCalendar data - event may be corrupted or deleted")
 			skippedEmpty++
 			continue
 		}
@@ -373,9 +487,7 @@ func (c *Client) getEventsIndividually(ctx context.Context, paths []string, coll
 		event, err := c.GetEvent(ctx, path)
 		if err != nil {
 			if IsMalformedError(err) {
-				if collector != nil {
-					collector.Add(path, err.Error())
-				}
+				c.recordMalformed(ctx, collector, path, err.Error())
 				skippedMalformed++
 				continue
 			}
@@ -383,9 +495,7 @@ func (c *Client) getEventsIndividually(ctx context.Context, paths []string, coll
 			continue
 		}
 		if event.Data == "" {
-			if collector != nil {
-				collector.Add(path, "empty iCalendar data - event may be corrupted or deleted")
-			}
+			c.recordMalformed(ctx, collector, path, "empty iCalendar data - event may be corrupted or deleted")
 			skippedEmpty++
 			continue
 		}
@@ -408,19 +518,8 @@ func (c *Client) objectsToEvents(objects []caldav.CalendarObject) []Event {
 			// Encode the calendar to string
 			event.Data = encodeCalendar(obj.Data)
 
-			// Extract UID, Summary, and StartTime from events
-			for _, evt := range obj.Data.Events() {
-				if uid, err := evt.Props.Text(ical.PropUID); err == nil {
-					event.UID = uid
-				}
-				if summary, err := evt.Props.Text(ical.PropSummary); err == nil {
-					event.Summary = summary
-				}
-				// Extract start time for deduplication (normalized to UTC)
-				if dtstart := evt.Props.Get(ical.PropDateTimeStart); dtstart != nil {
-					event.StartTime = normalizeStartTime(dtstart)
-				}
-			}
+			// Extract UID, Summary and the properties compared for deduplication
+			event.readMetadata(obj.Data)
 		}
 
 		events = append(events, event)
@@ -430,52 +529,15 @@ func (c *Client) objectsToEvents(objects []caldav.CalendarObject) []Event {
 
 // parseEventPaths extracts .ics file paths from a PROPFIND multistatus response.
 func parseEventPaths(body []byte, basePath string) []string {
-	type propfindResponse struct {
-		XMLName   xml.Name `xml:"DAV: multistatus"`
-		Responses []struct {
-			Href     string `xml:"href"`
-			PropStat struct {
-				Prop struct {
-					ContentType string `xml:"getcontenttype"`
-				} `xml:"prop"`
-				Status string `xml:"status"`
-			} `xml:"propstat"`
-		} `xml:"response"`
-	}
-
-	var ms propfindResponse
-	if err := xml.Unmarshal(body, &ms); err != nil {
-		log.Printf("parseEventPaths: XML unmarshal failed: %v", err)
-		// Try to log a snippet of the response for debugging
-		if len(body) > 500 {
-			log.Printf("parseEventPaths: response body (first 500 bytes): %s", string(body[:500]))
-		} else {
-			log.Printf("parseEventPaths: response body: %s", string(body))
-		}
+	refs, err := decodeEventRefs(bytes.NewReader(body), basePath)
+	if err != nil {
+		log.Printf("parseEventPaths: %v", err)
 		return nil
 	}
 
-	log.Printf("parseEventPaths: found %d responses in multistatus (basePath=%s)", len(ms.Responses), basePath)
-	paths := make([]string, 0)
-	for _, resp := range ms.Responses {
-		// Skip the collection itself
-		if resp.Href == basePath || resp.Href+"/" == basePath || basePath+"/" == resp.Href {
-			log.Printf("parseEventPaths: skipping collection path: %s", resp.Href)
-			continue
-		}
-		// Check if it's a calendar object (ends with .ics or has calendar content type)
-		if strings.HasSuffix(resp.Href, ".ics") ||
-			strings.Contains(resp.PropStat.Prop.ContentType, "calendar") {
-			// URL-decode the path to avoid double-encoding when making requests
-			decodedPath, err := url.PathUnescape(resp.Href)
-			if err != nil {
-				// If decoding fails, use original path
-				decodedPath = resp.Href
-			}
-			paths = append(paths, decodedPath)
-		} else {
-			log.Printf("parseEventPaths: skipping non-event: href=%s contentType=%s", resp.Href, resp.PropStat.Prop.ContentType)
-		}
+	paths := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		paths = append(paths, ref.Path)
 	}
 	return paths
 }
@@ -532,6 +594,7 @@ func IsMalformedError(err error) bool {
 
 // GetEvent retrieves a single event by path.
 func (c *Client) GetEvent(ctx context.Context, eventPath string) (*Event, error) {
+	ctx, status := withResponseStatus(ctx)
 	obj, err := c.caldavClient.GetCalendarObject(ctx, eventPath)
 	if err != nil {
 		// Check for malformed content errors from the iCal parser
@@ -541,7 +604,7 @@ func (c *Client) GetEvent(ctx context.Context, eventPath string) (*Event, error)
 			strings.Contains(errStr, "invalid") && strings.Contains(errStr, "ical") {
 			return nil, fmt.Errorf("%w: %s", ErrMalformedContent, eventPath)
 		}
-		return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+		return nil, fmt.Errorf("%w: %w", ErrNotFound, status.wrap(err))
 	}
 
 	event := &Event{
@@ -552,18 +615,7 @@ func (c *Client) GetEvent(ctx context.Context, eventPath string) (*Event, error)
 	if obj.Data != nil {
 		event.Data = encodeCalendar(obj.Data)
 
-		for _, evt := range obj.Data.Events() {
-			if uid, err := evt.Props.Text(ical.PropUID); err == nil {
-				event.UID = uid
-			}
-			if summary, err := evt.Props.Text(ical.PropSummary); err == nil {
-				event.Summary = summary
-			}
-			// Extract start time for deduplication (normalized to UTC)
-			if dtstart := evt.Props.Get(ical.PropDateTimeStart); dtstart != nil {
-				event.StartTime = normalizeStartTime(dtstart)
-			}
-		}
+		event.readMetadata(obj.Data)
 	}
 
 	return event, nil
@@ -608,9 +660,10 @@ func (c *Client) PutEvent(ctx context.Context, calendarPath string, event *Event
 	}
 
 	log.Printf("PutEvent: putting to path %s", path)
+	ctx, status := withResponseStatus(ctx)
 	_, err = c.caldavClient.PutCalendarObject(ctx, path, cal)
 	if err != nil {
-		return fmt.Errorf("%w: failed to put event: %w", ErrConnectionFailed, err)
+		return fmt.Errorf("%w: failed to put event: %w", ErrConnectionFailed, status.wrap(err))
 	}
 
 	return nil
@@ -618,9 +671,10 @@ func (c *Client) PutEvent(ctx context.Context, calendarPath string, event *Event
 
 // DeleteEvent deletes an event.
 func (c *Client) DeleteEvent(ctx context.Context, eventPath string) error {
+	ctx, status := withResponseStatus(ctx)
 	err := c.caldavClient.RemoveAll(ctx, eventPath)
 	if err != nil {
-		return fmt.Errorf("%w: failed to delete event: %w", ErrConnectionFailed, err)
+		return fmt.Errorf("%w: failed to delete event: %w", ErrConnectionFailed, status.wrap(err))
 	}
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -1160,7 +1161,7 @@ func TestClientStruct(t *testing.T) {
 		if client.username != "user" {
 			t.Errorf("expected username to be set")
 		}
-		if client.password != "pass" {
+		if auth, ok := client.auth.(basicAuth); !ok || auth.password != "pass" {
 			t.Errorf("expected password to be set")
 		}
 		if client.httpClient == nil {
@@ -1260,7 +1261,7 @@ func TestSyncEngineTestConnection(t *testing.T) {
 	t.Run("returns error for invalid URL", func(t *testing.T) {
 		engine := NewSyncEngine(nil, nil)
 
-		err := engine.TestConnection(context.Background(), "", "user", "pass")
+		err := engine.TestConnection(context.Background(), "", BasicCredentials("user", "pass"), TransportOptions{})
 		if err == nil {
 			t.Error("expected error for empty URL")
 		}
@@ -1660,6 +1661,9 @@ func TestClientWithTestServer(t *testing.T) {
 		if err == nil {
 			t.Error("expected error for unauthorized response")
 		}
+		if !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("expected ErrAuthFailed, got %v", err)
+		}
 	})
 
 	t.Run("TestConnection returns error for server returning 500", func(t *testing.T) {
@@ -1696,3 +1700,123 @@ func TestClientWithTestServer(t *testing.T) {
 		}
 	})
 }
+
+func TestFullSyncCancelDuringWrite(t *testing.T) {
+	engine, database, sourceID := setupJournalTest(t)
+	source, _ := database.GetSourceByID(sourceID)
+	sourceServer := newProbeServer(t)
+
+	// The destination calendar is empty and holds every PUT until released
+	putStarted := make(chan struct{})
+	release := make(chan struct{})
+	var puts, completed atomic.Int32
+	destServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			if puts.Add(1) == 1 {
+				close(putStarted)
+			}
+			<-release
+			w.WriteHeader(http.StatusCreated)
+			completed.Add(1)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:"></D:multistatus>`)
+	}))
+	t.Cleanup(destServer.Close)
+
+	sourceClient, _ := NewClient(sourceServer.URL+"/dav/", "alice", "secret")
+	destClient, _ := NewClient(destServer.URL+"/dav/", "alice", "secret")
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	go func() {
+		<-putStarted
+		cancel(ErrSyncCancelled)
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+
+	calendar := Calendar{Path: "/dav/calendars/alice/work/", Name: "Work"}
+	result := engine.fullSync(ctx, source, sourceClient, destClient, calendar, "run-1")
+
+	if completed.Load() != 1 || result.Created != 1 {
+		t.Fatalf("expected the write in flight to complete, got %d completed and result %+v", completed.Load(), result)
+	}
+	synced, _ := database.GetSyncedEvents(sourceID, calendar.Path)
+	if len(synced) != 1 || synced[0].EventUID != "event-1" {
+		t.Errorf("expected the completed write to be recorded, got %+v", synced)
+	}
+	if puts.Load() != 1 {
+		t.Errorf("expected no write after the cancel, got %d", puts.Load())
+	}
+}
+
+func TestFullSyncDedupeAfterFailedCreate(t *testing.T) {
+	engine, database, sourceID := setupJournalTest(t)
+	source, _ := database.GetSourceByID(sourceID)
+
+	// Three source events share a summary and start, so only one of them is created
+	fake := newFakeCalendarServer(3)
+	fake.summary = "Standup"
+	sourceServer := httptest.NewServer(fake)
+	t.Cleanup(sourceServer.Close)
+
+	// The destination calendar is empty and rejects the first PUT
+	var puts atomic.Int32
+	destServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			if puts.Add(1) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:"></D:multistatus>`)
+	}))
+	t.Cleanup(destServer.Close)
+
+	sourceClient, _ := NewClient(sourceServer.URL+"/cal/", "alice", "secret")
+	destClient, _ := NewClient(destServer.URL+"/dav/", "alice", "secret")
+
+	calendar := Calendar{Path: "/cal/", Name: "Work"}
+	result := engine.fullSync(context.Background(), source, sourceClient, destClient, calendar, "run-1")
+
+	if puts.Load() != 2 {
+		t.Errorf("expected a second create after the first failed, got %d PUTs", puts.Load())
+	}
+	if result.Created != 1 || result.Skipped != 1 {
+		t.Errorf("expected 1 created and 1 skipped duplicate, got %+v", result)
+	}
+	synced, _ := database.GetSyncedEvents(sourceID, calendar.Path)
+	if len(synced) != 1 {
+		t.Errorf("expected the created event to be recorded, got %+v", synced)
+	}
+}
+
+func TestSyncCancelled(t *testing.T) {
+	t.Run("detects cancellation on request", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(ErrSyncCancelled)
+
+		if !syncCancelled(ctx) {
+			t.Error("expected sync to be reported as cancelled")
+		}
+	})
+
+	t.Run("ignores timeouts and shutdowns", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if syncCancelled(ctx) {
+			t.Error("plain cancellation should not count as a cancelled sync")
+		}
+		if syncCancelled(context.Background()) {
+			t.Error("active context should not count as a cancelled sync")
+		}
+	})
+}
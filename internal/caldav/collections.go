@@ -0,0 +1,250 @@
+package caldav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// appleICalNS is the namespace of the calendar-color property most servers support.
+const appleICalNS = "http://apple.com/ns/ical/"
+
+// CreateCalendar creates a calendar in the user's calendar home with MKCALENDAR
+// (RFC 4791) and returns its path. The display name, description, color and
+// supported components are taken from cal; empty values are left to the server.
+func (c *Client) CreateCalendar(ctx context.Context, cal Calendar) (string, error) {
+	if caps := c.capabilities.Load(); caps != nil && !caps.MKCalendar {
+		return "", fmt.Errorf("%w: server doesn't allow creating calendars", ErrInvalidResponse)
+	}
+
+	homeSet, err := c.findHomeSet(ctx)
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSuffix(homeSet, "/") + "/" + uuid.New().String() + "/"
+
+	req, err := http.NewRequestWithContext(ctx, "MKCALENDAR", c.buildURL(path), strings.NewReader(buildMkcalendarRequest(cal)))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusUnauthorized:
+		return "", fmt.Errorf("%w: MKCALENDAR returned status %d", ErrAuthFailed, resp.StatusCode)
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented:
+		return "", fmt.Errorf("%w: server doesn't allow creating calendars (status %d)", ErrInvalidResponse, resp.StatusCode)
+	default:
+		return "", fmt.Errorf("%w: MKCALENDAR returned status %d", ErrInvalidResponse, resp.StatusCode)
+	}
+
+	// The calendar list changed
+	c.discovery.reset()
+	return path, nil
+}
+
+// buildMkcalendarRequest builds the MKCALENDAR body setting the properties of cal.
+func buildMkcalendarRequest(cal Calendar) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8" ?>
+<C:mkcalendar xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav" xmlns:A="` + appleICalNS + `">
+  <D:set>
+    <D:prop>
+`)
+	writeCalendarProps(&b, cal)
+	b.WriteString(`    </D:prop>
+  </D:set>
+</C:mkcalendar>`)
+	return b.String()
+}
+
+// writeCalendarProps writes the non-empty metadata properties of cal.
+func writeCalendarProps(b *strings.Builder, cal Calendar) {
+	prop := func(name, value string) {
+		if value == "" {
+			return
+		}
+		b.WriteString("      <" + name + ">")
+		xml.EscapeText(b, []byte(value))
+		b.WriteString("</" + name + ">\n")
+	}
+	prop("D:displayname", cal.Name)
+	prop("C:calendar-description", cal.Description)
+	prop("A:calendar-color", cal.Color)
+	prop("A:calendar-order", cal.Order)
+
+	if len(cal.Components) > 0 {
+		b.WriteString("      <C:supported-calendar-component-set>")
+		for _, comp := range cal.Components {
+			b.WriteString(`<C:comp name="`)
+			xml.EscapeText(b, []byte(comp))
+			b.WriteString(`"/>`)
+		}
+		b.WriteString("</C:supported-calendar-component-set>\n")
+	}
+}
+
+// UpdateCalendarProperties sets the display name, description, color and order of a
+// calendar with PROPPATCH. Empty values are left unchanged.
+func (c *Client) UpdateCalendarProperties(ctx context.Context, calendarPath string, cal Calendar) error {
+	var props strings.Builder
+	cal.Components = nil // Can only be set when creating a calendar
+	writeCalendarProps(&props, cal)
+	if props.Len() == 0 {
+		return nil
+	}
+
+	body := `<?xml version="1.0" encoding="utf-8" ?>
+<D:propertyupdate xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav" xmlns:A="` + appleICalNS + `">
+  <D:set>
+    <D:prop>
+` + props.String() + `    </D:prop>
+  </D:set>
+</D:propertyupdate>`
+
+	req, err := http.NewRequestWithContext(ctx, "PROPPATCH", c.buildURL(calendarPath), strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	case http.StatusMultiStatus:
+		// Every property reports its own status; PROPPATCH is atomic, so one failure fails all
+		var ms struct {
+			Responses []struct {
+				PropStat []struct {
+					Status string `xml:"status"`
+				} `xml:"propstat"`
+			} `xml:"response"`
+		}
+		if err := xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&ms); err != nil {
+			return fmt.Errorf("%w: failed to decode PROPPATCH response: %w", ErrInvalidResponse, err)
+		}
+		for _, r := range ms.Responses {
+			for _, ps := range r.PropStat {
+				if !strings.Contains(ps.Status, " 200 ") {
+					return fmt.Errorf("%w: PROPPATCH rejected: %s", ErrInvalidResponse, strings.TrimSpace(ps.Status))
+				}
+			}
+		}
+	case http.StatusUnauthorized:
+		return fmt.Errorf("%w: PROPPATCH returned status %d", ErrAuthFailed, resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: PROPPATCH returned status %d", ErrInvalidResponse, resp.StatusCode)
+	}
+
+	c.discovery.reset()
+	return nil
+}
+
+// calendarProps holds the Apple iCal properties of a calendar, which go-webdav doesn't read.
+type calendarProps struct {
+	color string
+	order string
+}
+
+// findCalendarProps reads the color and order of the collection at path (depth "0")
+// or of its members (depth "1"), keyed by collectionKey of their paths.
+func (c *Client) findCalendarProps(ctx context.Context, path, depth string) (map[string]calendarProps, error) {
+	req, err := http.NewRequestWithContext(ctx, "PROPFIND", c.buildURL(path), strings.NewReader(`<?xml version="1.0" encoding="utf-8" ?>
+<D:propfind xmlns:D="DAV:" xmlns:A="`+appleICalNS+`">
+  <D:prop>
+    <A:calendar-color/>
+    <A:calendar-order/>
+  </D:prop>
+</D:propfind>`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", depth)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("%w: unexpected status %d", statusSentinel(resp.StatusCode), resp.StatusCode)
+	}
+
+	var ms struct {
+		Responses []struct {
+			Href     string `xml:"href"`
+			PropStat []struct {
+				Prop struct {
+					Color string `xml:"http://apple.com/ns/ical/ calendar-color"`
+					Order string `xml:"http://apple.com/ns/ical/ calendar-order"`
+				} `xml:"prop"`
+				Status string `xml:"status"`
+			} `xml:"propstat"`
+		} `xml:"response"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode PROPFIND response: %w", ErrInvalidResponse, err)
+	}
+
+	props := make(map[string]calendarProps, len(ms.Responses))
+	for _, r := range ms.Responses {
+		var p calendarProps
+		for _, ps := range r.PropStat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			if color := strings.TrimSpace(ps.Prop.Color); color != "" {
+				p.color = color
+			}
+			if order := strings.TrimSpace(ps.Prop.Order); order != "" {
+				p.order = order
+			}
+		}
+		props[collectionKey(r.Href)] = p
+	}
+	return props, nil
+}
+
+// collectionKey normalizes a collection href or path so both compare equal.
+func collectionKey(href string) string {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	return strings.TrimSuffix(href, "/")
+}
+
+// CalendarColor returns the color of a calendar, or "" if the server doesn't store one.
+func (c *Client) CalendarColor(ctx context.Context, calendarPath string) (string, error) {
+	props, err := c.findCalendarProps(ctx, calendarPath, "0")
+	if err != nil {
+		return "", err
+	}
+	for _, p := range props {
+		if p.color != "" {
+			return p.color, nil
+		}
+	}
+	return "", nil
+}
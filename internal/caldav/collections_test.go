@@ -0,0 +1,342 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// calendarServer is a CalDAV server with a calendar home at /dav/calendars/alice/
+// that supports MKCALENDAR and PROPPATCH.
+type calendarServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	calendars map[string]string // Path -> display name
+	colors    map[string]string // Path -> color
+	created   []string          // MKCALENDAR request bodies
+	mkStatus  int               // Status returned for MKCALENDAR; 0 means 201
+	patched   []string          // PROPPATCH request bodies
+	rejected  bool              // Reject PROPPATCH with a 403 propstat
+}
+
+var displayNamePattern = regexp.MustCompile(`<D:displayname>([^<]*)</D:displayname>`)
+
+func newCalendarServer(t *testing.T) *calendarServer {
+	t.Helper()
+	s := &calendarServer{calendars: map[string]string{}, colors: map[string]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *calendarServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimSuffix(r.URL.Path, "/") + "/"
+	body, _ := io.ReadAll(r.Body)
+
+	if r.Method == "MKCALENDAR" {
+		if s.mkStatus != 0 {
+			w.WriteHeader(s.mkStatus)
+			return
+		}
+		s.created = append(s.created, string(body))
+		name := ""
+		if m := displayNamePattern.FindSubmatch(body); m != nil {
+			name = string(m[1])
+		}
+		s.calendars[path] = name
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	if r.Method == "PROPPATCH" {
+		s.patched = append(s.patched, string(body))
+		status := "HTTP/1.1 200 OK"
+		if s.rejected {
+			status = "HTTP/1.1 403 Forbidden"
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusMultiStatus)
+		io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:"><D:response><D:href>`+path+
+			`</D:href><D:propstat><D:prop/><D:status>`+status+`</D:status></D:propstat></D:response></D:multistatus>`)
+		return
+	}
+
+	var responses string
+	switch {
+	case path == "/dav/":
+		responses = `<D:response><D:href>/dav/</D:href><D:propstat><D:prop>
+			<D:current-user-principal><D:href>/dav/principals/alice/</D:href></D:current-user-principal>
+			</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`
+	case path == "/dav/principals/alice/":
+		responses = `<D:response><D:href>/dav/principals/alice/</D:href><D:propstat><D:prop>
+			<C:calendar-home-set><D:href>/dav/calendars/alice/</D:href></C:calendar-home-set>
+			</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`
+	case path == "/dav/calendars/alice/":
+		for calPath, name := range s.calendars {
+			responses += `<D:response><D:href>` + calPath + `</D:href><D:propstat><D:prop>
+				<D:resourcetype><D:collection/><C:calendar/></D:resourcetype><D:displayname>` + name + `</D:displayname>
+				<A:calendar-color>` + s.colors[calPath] + `</A:calendar-color>
+				</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`
+		}
+	case s.calendars[path] != "" || s.colors[path] != "":
+		responses = `<D:response><D:href>` + path + `</D:href><D:propstat><D:prop>
+			<A:calendar-color>` + s.colors[path] + `</A:calendar-color>
+			</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav" xmlns:A="http://apple.com/ns/ical/">`+responses+`</D:multistatus>`)
+}
+
+func TestCreateCalendar(t *testing.T) {
+	t.Run("creates a calendar in the calendar home", func(t *testing.T) {
+		server := newCalendarServer(t)
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+
+		path, err := client.CreateCalendar(context.Background(), Calendar{
+			Name:        "Work & Play",
+			Description: "Team events",
+			Color:       "#FF0000FF",
+			Components:  []string{"VEVENT", "VTODO"},
+		})
+		if err != nil {
+			t.Fatalf("CreateCalendar failed: %v", err)
+		}
+		if !strings.HasPrefix(path, "/dav/calendars/alice/") || !strings.HasSuffix(path, "/") {
+			t.Errorf("unexpected path %q", path)
+		}
+
+		body := server.created[0]
+		for _, want := range []string{
+			"<D:displayname>Work &amp; Play</D:displayname>",
+			"<C:calendar-description>Team events</C:calendar-description>",
+			"<A:calendar-color>#FF0000FF</A:calendar-color>",
+			`<C:comp name="VEVENT"/><C:comp name="VTODO"/>`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("expected request body to contain %s, got:\n%s", want, body)
+			}
+		}
+
+		calendars, _ := client.FindCalendars(context.Background())
+		if len(calendars) != 1 || calendars[0].Path != path {
+			t.Errorf("expected created calendar to be listed, got %+v", calendars)
+		}
+	})
+
+	t.Run("reports servers that don't allow creating calendars", func(t *testing.T) {
+		server := newCalendarServer(t)
+		server.mkStatus = http.StatusForbidden
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+
+		if _, err := client.CreateCalendar(context.Background(), Calendar{Name: "Work"}); !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("expected ErrInvalidResponse, got %v", err)
+		}
+	})
+
+	t.Run("reads a calendar color", func(t *testing.T) {
+		server := newCalendarServer(t)
+		server.colors["/dav/calendars/alice/work/"] = "#00FF00"
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+
+		color, err := client.CalendarColor(context.Background(), "/dav/calendars/alice/work/")
+		if err != nil || color != "#00FF00" {
+			t.Errorf("expected #00FF00, got %q (%v)", color, err)
+		}
+	})
+}
+
+func TestDestCalendarFor(t *testing.T) {
+	setup := func(t *testing.T, createCalendars bool) (*SyncEngine, *db.Source, *calendarServer, *Client) {
+		engine, database, sourceID := setupJournalTest(t)
+		source, _ := database.GetSourceByID(sourceID)
+		source.CreateCalendars = createCalendars
+
+		server := newCalendarServer(t)
+		server.calendars["/dav/calendars/alice/default/"] = "Default"
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+		return engine, source, server, client
+	}
+	work := Calendar{Path: "/source/work/", Name: "Work", Components: []string{"VEVENT"}}
+
+	t.Run("uses the first calendar without opt-in", func(t *testing.T) {
+		engine, source, server, client := setup(t, false)
+
+		path, warning := engine.destCalendarFor(context.Background(), source, client, client, work)
+		if path != "/dav/calendars/alice/default/" || warning != "" {
+			t.Errorf("unexpected destination %q (%s)", path, warning)
+		}
+		if len(server.created) != 0 {
+			t.Error("expected no calendar to be created")
+		}
+	})
+
+	t.Run("creates a missing calendar once", func(t *testing.T) {
+		engine, source, server, client := setup(t, true)
+
+		path, warning := engine.destCalendarFor(context.Background(), source, client, client, work)
+		if warning != "" || path == "/dav/calendars/alice/default/" || server.calendars[path] != "Work" {
+			t.Fatalf("expected new calendar, got %q (%s)", path, warning)
+		}
+
+		mapping, err := engine.db.GetCalendarMapping(source.ID, work.Path)
+		if err != nil || mapping.DestHref != path || !mapping.Created {
+			t.Fatalf("expected mapping to be recorded, got %+v (%v)", mapping, err)
+		}
+
+		again, _ := engine.destCalendarFor(context.Background(), source, client, client, work)
+		if again != path || len(server.created) != 1 {
+			t.Errorf("expected mapped calendar to be reused, got %q after %d creations", again, len(server.created))
+		}
+	})
+
+	t.Run("maps a calendar with the same name", func(t *testing.T) {
+		engine, source, server, client := setup(t, true)
+
+		path, _ := engine.destCalendarFor(context.Background(), source, client, client, Calendar{Path: "/source/default/", Name: "default"})
+		if path != "/dav/calendars/alice/default/" || len(server.created) != 0 {
+			t.Errorf("expected existing calendar to be mapped, got %q", path)
+		}
+	})
+
+	t.Run("warns when creating fails", func(t *testing.T) {
+		engine, source, server, client := setup(t, true)
+		server.mkStatus = http.StatusForbidden
+
+		path, warning := engine.destCalendarFor(context.Background(), source, client, client, work)
+		if path != "/dav/calendars/alice/default/" || warning == "" {
+			t.Errorf("expected fallback with warning, got %q (%s)", path, warning)
+		}
+	})
+}
+
+func TestUpdateCalendarProperties(t *testing.T) {
+	t.Run("sets non-empty properties", func(t *testing.T) {
+		server := newCalendarServer(t)
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+
+		err := client.UpdateCalendarProperties(context.Background(), "/dav/calendars/alice/work/", Calendar{
+			Name:       "Work",
+			Color:      "#0000FF",
+			Order:      "3",
+			Components: []string{"VEVENT"},
+		})
+		if err != nil {
+			t.Fatalf("UpdateCalendarProperties failed: %v", err)
+		}
+
+		body := server.patched[0]
+		for _, want := range []string{"<D:propertyupdate", "<D:displayname>Work</D:displayname>", "<A:calendar-color>#0000FF</A:calendar-color>", "<A:calendar-order>3</A:calendar-order>"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("expected request body to contain %s, got:\n%s", want, body)
+			}
+		}
+		if strings.Contains(body, "calendar-description") || strings.Contains(body, "supported-calendar-component-set") {
+			t.Errorf("expected only set properties to be sent, got:\n%s", body)
+		}
+	})
+
+	t.Run("fails when the server rejects a property", func(t *testing.T) {
+		server := newCalendarServer(t)
+		server.rejected = true
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+
+		err := client.UpdateCalendarProperties(context.Background(), "/dav/calendars/alice/work/", Calendar{Color: "#0000FF"})
+		if !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("expected ErrInvalidResponse, got %v", err)
+		}
+	})
+
+	t.Run("finds calendar colors", func(t *testing.T) {
+		server := newCalendarServer(t)
+		server.calendars["/dav/calendars/alice/work/"] = "Work"
+		server.colors["/dav/calendars/alice/work/"] = "#00FF00"
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+
+		calendars, err := client.FindCalendars(context.Background())
+		if err != nil || len(calendars) != 1 || calendars[0].Color != "#00FF00" {
+			t.Errorf("expected calendar color to be read, got %+v (%v)", calendars, err)
+		}
+	})
+}
+
+func TestSyncCalendarMetadata(t *testing.T) {
+	setup := func(t *testing.T) (*SyncEngine, *db.Source, *calendarServer, *Client) {
+		engine, database, sourceID := setupJournalTest(t)
+		source, _ := database.GetSourceByID(sourceID)
+		source.SyncCalendarMeta = true
+
+		server := newCalendarServer(t)
+		client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+		m := &db.CalendarMapping{SourceID: source.ID, CalendarHref: "/source/work/", DestHref: "/dav/calendars/alice/work/"}
+		if err := database.UpsertCalendarMapping(m); err != nil {
+			t.Fatalf("failed to create mapping: %v", err)
+		}
+		return engine, source, server, client
+	}
+	work := Calendar{Path: "/source/work/", Name: "Work", Color: "#FF0000"}
+
+	t.Run("updates only when metadata changed", func(t *testing.T) {
+		engine, source, server, client := setup(t)
+		result := &SyncResult{}
+
+		engine.syncCalendarMetadata(context.Background(), source, client, work, result)
+		engine.syncCalendarMetadata(context.Background(), source, client, work, result)
+		if len(server.patched) != 1 {
+			t.Fatalf("expected 1 PROPPATCH, got %d", len(server.patched))
+		}
+
+		renamed := work
+		renamed.Name = "Office"
+		engine.syncCalendarMetadata(context.Background(), source, client, renamed, result)
+		if len(server.patched) != 2 || !strings.Contains(server.patched[1], "Office") {
+			t.Errorf("expected renamed calendar to be patched, got %v", server.patched)
+		}
+		if len(result.Warnings) != 0 {
+			t.Errorf("unexpected warnings: %v", result.Warnings)
+		}
+	})
+
+	t.Run("does nothing without opt-in", func(t *testing.T) {
+		engine, source, server, client := setup(t)
+		source.SyncCalendarMeta = false
+
+		engine.syncCalendarMetadata(context.Background(), source, client, work, &SyncResult{})
+		if len(server.patched) != 0 {
+			t.Error("expected no PROPPATCH")
+		}
+	})
+
+	t.Run("warns and retries when the update fails", func(t *testing.T) {
+		engine, source, server, client := setup(t)
+		server.rejected = true
+		result := &SyncResult{}
+
+		engine.syncCalendarMetadata(context.Background(), source, client, work, result)
+		if len(result.Warnings) != 1 {
+			t.Fatalf("expected a warning, got %v", result.Warnings)
+		}
+
+		server.rejected = false
+		engine.syncCalendarMetadata(context.Background(), source, client, work, result)
+		if len(server.patched) != 2 {
+			t.Errorf("expected update to be retried, got %d PROPPATCH requests", len(server.patched))
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package caldav
+
+import (
+	"context"
+	"sync"
+
+	"github.com/macjediwizard/calbridgesync/internal/activity"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// Concurrency defaults. Up to 4 calendars with 8 writes each allow 32 requests in
+// flight, but all of them share the per-host limit of the throttle transport
+// (defaultHostRPS, 5 requests per second): requests against one provider are started
+// no faster than that however many are in flight. Concurrency overlaps the latency of
+// slow responses; it doesn't raise the request rate against a host.
+const (
+	defaultMaxCalendarConcurrency = 4
+	defaultMaxEventConcurrency    = 8
+)
+
+// SyncEngineOption configures a SyncEngine.
+type SyncEngineOption func(*SyncEngine)
+
+// WithConcurrencyLimits sets the global caps applied to per-source concurrency settings.
+// Values below 1 leave the corresponding default in place.
+func WithConcurrencyLimits(maxCalendars, maxEvents int) SyncEngineOption {
+	return func(se *SyncEngine) {
+		if maxCalendars > 0 {
+			se.maxCalendarConcurrency = maxCalendars
+		}
+		if maxEvents > 0 {
+			se.maxEventConcurrency = maxEvents
+		}
+	}
+}
+
+// calendarConcurrency returns how many calendars of the source may sync at once.
+func (se *SyncEngine) calendarConcurrency(source *db.Source) int {
+	return clampConcurrency(source.CalendarConcurrency, se.maxCalendarConcurrency)
+}
+
+// eventConcurrency returns how many event writes may be in flight per calendar.
+func (se *SyncEngine) eventConcurrency(source *db.Source) int {
+	return clampConcurrency(source.EventConcurrency, se.maxEventConcurrency)
+}
+
+// clampConcurrency limits a requested concurrency to [1, limit].
+func clampConcurrency(requested, limit int) int {
+	if requested < 1 {
+		requested = 1
+	}
+	if limit > 0 && requested > limit {
+		requested = limit
+	}
+	return requested
+}
+
+// runBounded calls fn for every index in [0, n) with at most limit calls in flight.
+// No new calls are started once ctx is done; calls already running are waited for.
+func runBounded(ctx context.Context, n, limit int, fn func(i int)) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// resultRecorder serializes updates to a SyncResult made by concurrent writers
+// and mirrors every counter change into the activity tracker.
+type resultRecorder struct {
+	mu       sync.Mutex
+	result   *SyncResult
+	tracker  *activity.Tracker
+	sourceID string
+
+	// Run and calendar that changes are recorded for, see newCalendarRecorder
+	runID        string
+	calendarHref string
+}
+
+// newResultRecorder creates a recorder for a calendar-level result.
+func (se *SyncEngine) newResultRecorder(sourceID string, result *SyncResult) *resultRecorder {
+	return &resultRecorder{
+		result:   result,
+		tracker:  se.tracker,
+		sourceID: sourceID,
+	}
+}
+
+// add increments the result counters by the given amounts.
+func (r *resultRecorder) add(created, updated, deleted, skipped, processed int) {
+	r.mu.Lock()
+	r.result.Created += created
+	r.result.Updated += updated
+	r.result.Deleted += deleted
+	r.result.Skipped += skipped
+	r.result.EventsProcessed += processed
+	r.mu.Unlock()
+
+	if r.tracker != nil {
+		r.tracker.IncrementProgress(r.sourceID, created, updated, deleted, skipped, processed)
+	}
+}
+
+// warn records a non-critical warning.
+func (r *resultRecorder) warn(message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result.Warnings = append(r.result.Warnings, message)
+}
+
+// merge folds a calendar result into the source-level result.
+// Progress was already reported to the tracker while the calendar synced.
+func (r *resultRecorder) merge(other *SyncResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result.Created += other.Created
+	r.result.Updated += other.Updated
+	r.result.Deleted += other.Deleted
+	r.result.Skipped += other.Skipped
+	r.result.DuplicatesRemoved += other.DuplicatesRemoved
+	r.result.EventsProcessed += other.EventsProcessed
+	r.result.Errors = append(r.result.Errors, other.Errors...)
+	r.result.Warnings = append(r.result.Warnings, other.Warnings...)
+	r.result.Changes = append(r.result.Changes, other.Changes...)
+}
+
+// addCalendar records the summary of a finished calendar.
+func (r *resultRecorder) addCalendar(cal *db.CalendarSyncLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result.Calendars = append(r.result.Calendars, cal)
+}
+
+// keyedMutex hands out one mutex per key.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// Lock locks the mutex for key and returns its unlock function.
+func (k *keyedMutex) Lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*sync.Mutex)
+	}
+	lock, exists := k.locks[key]
+	if !exists {
+		lock = &sync.Mutex{}
+		k.locks[key] = lock
+	}
+	k.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
@@ -0,0 +1,209 @@
+package caldav
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+func TestClampConcurrency(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested int
+		limit     int
+		expected  int
+	}{
+		{"zero defaults to one", 0, 4, 1},
+		{"negative defaults to one", -3, 4, 1},
+		{"within limit", 3, 4, 3},
+		{"capped at limit", 10, 4, 4},
+		{"no limit", 10, 0, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clampConcurrency(tt.requested, tt.limit); got != tt.expected {
+				t.Errorf("clampConcurrency(%d, %d) = %d, want %d", tt.requested, tt.limit, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWithConcurrencyLimits(t *testing.T) {
+	t.Run("applies global caps to source settings", func(t *testing.T) {
+		engine := NewSyncEngine(nil, nil, WithConcurrencyLimits(2, 5))
+		source := &db.Source{CalendarConcurrency: 8, EventConcurrency: 3}
+
+		if got := engine.calendarConcurrency(source); got != 2 {
+			t.Errorf("expected calendar concurrency 2, got %d", got)
+		}
+		if got := engine.eventConcurrency(source); got != 3 {
+			t.Errorf("expected event concurrency 3, got %d", got)
+		}
+	})
+
+	t.Run("ignores non-positive limits", func(t *testing.T) {
+		engine := NewSyncEngine(nil, nil, WithConcurrencyLimits(0, -1))
+
+		if engine.maxCalendarConcurrency != defaultMaxCalendarConcurrency {
+			t.Errorf("expected default calendar limit, got %d", engine.maxCalendarConcurrency)
+		}
+		if engine.maxEventConcurrency != defaultMaxEventConcurrency {
+			t.Errorf("expected default event limit, got %d", engine.maxEventConcurrency)
+		}
+	})
+}
+
+func TestRunBounded(t *testing.T) {
+	t.Run("never exceeds limit", func(t *testing.T) {
+		var inFlight, peak, calls atomic.Int32
+
+		runBounded(context.Background(), 20, 3, func(i int) {
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			inFlight.Add(-1)
+			calls.Add(1)
+		})
+
+		if calls.Load() != 20 {
+			t.Errorf("expected 20 calls, got %d", calls.Load())
+		}
+		if peak.Load() > 3 {
+			t.Errorf("expected at most 3 in flight, got %d", peak.Load())
+		}
+	})
+
+	t.Run("stops dispatching when context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls atomic.Int32
+
+		runBounded(ctx, 10, 1, func(i int) {
+			if calls.Add(1) == 2 {
+				cancel()
+			}
+		})
+
+		if calls.Load() != 2 {
+			t.Errorf("expected 2 calls before cancellation, got %d", calls.Load())
+		}
+	})
+}
+
+func TestResultRecorder(t *testing.T) {
+	t.Run("counts concurrent updates correctly", func(t *testing.T) {
+		engine := NewSyncEngine(nil, nil)
+		engine.tracker.StartSync("src-1", "Test", 1)
+		result := &SyncResult{}
+		rec := engine.newResultRecorder("src-1", result)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rec.add(1, 1, 0, 0, 2)
+				rec.warn("warning")
+			}()
+		}
+		wg.Wait()
+
+		if result.Created != 50 || result.Updated != 50 || result.EventsProcessed != 100 {
+			t.Errorf("unexpected counters: %+v", result)
+		}
+		if len(result.Warnings) != 50 {
+			t.Errorf("expected 50 warnings, got %d", len(result.Warnings))
+		}
+
+		active := engine.tracker.GetActive()
+		if len(active) != 1 || active[0].EventsCreated != 50 || active[0].EventsProcessed != 100 {
+			t.Errorf("tracker not updated: %+v", active)
+		}
+	})
+
+	t.Run("merges calendar results", func(t *testing.T) {
+		engine := NewSyncEngine(nil, nil)
+		result := &SyncResult{}
+		rec := engine.newResultRecorder("src-2", result)
+
+		rec.merge(&SyncResult{Created: 2, DuplicatesRemoved: 1, Errors: []string{"e1"}})
+		rec.merge(&SyncResult{Deleted: 3, Warnings: []string{"w1"}})
+
+		if result.Created != 2 || result.Deleted != 3 || result.DuplicatesRemoved != 1 {
+			t.Errorf("unexpected merged counters: %+v", result)
+		}
+		if len(result.Errors) != 1 || len(result.Warnings) != 1 {
+			t.Errorf("unexpected merged messages: %+v", result)
+		}
+	})
+
+	t.Run("collects the summaries of calendars", func(t *testing.T) {
+		engine := NewSyncEngine(nil, nil)
+		result := &SyncResult{}
+		rec := engine.newResultRecorder("src-3", result)
+
+		calResult := &SyncResult{Created: 1, Warnings: []string{"Failed to sync event"}, Mode: db.SyncModeFull, Duration: time.Second}
+		rec.merge(calResult)
+		rec.addCalendar(calendarSyncLog(Calendar{Name: "Work", Path: "/cal/work/"}, calResult))
+		rec.addCalendar(calendarSyncLog(Calendar{Name: "Home", Path: "/cal/home/"}, &SyncResult{Errors: []string{"403 Forbidden"}}))
+
+		if len(result.Calendars) != 2 {
+			t.Fatalf("expected 2 calendars, got %d", len(result.Calendars))
+		}
+		work, home := result.Calendars[0], result.Calendars[1]
+		if work.Status != db.SyncStatusPartial || work.Mode != db.SyncModeFull || work.EventsCreated != 1 ||
+			work.Duration != time.Second || !strings.Contains(work.Details, "Failed to sync event") {
+			t.Errorf("unexpected summary of Work: %+v", work)
+		}
+		if home.Status != db.SyncStatusError || !strings.Contains(home.Details, "403 Forbidden") {
+			t.Errorf("unexpected summary of Home: %+v", home)
+		}
+	})
+}
+
+func TestDefaultConcurrencyWithinHostRate(t *testing.T) {
+	// Every write of every calendar reserves a slot on the same host limiter, as the
+	// throttle transport does for requests against one provider.
+	limiter := &hostLimiter{}
+	interval := time.Duration(float64(time.Second) / defaultHostRPS)
+	now := time.Now()
+
+	var mu sync.Mutex
+	var waits []time.Duration
+	runBounded(context.Background(), defaultMaxCalendarConcurrency, defaultMaxCalendarConcurrency, func(int) {
+		runBounded(context.Background(), defaultMaxEventConcurrency, defaultMaxEventConcurrency, func(int) {
+			wait := limiter.reserve(now, interval)
+			mu.Lock()
+			waits = append(waits, wait)
+			mu.Unlock()
+		})
+	})
+
+	total := defaultMaxCalendarConcurrency * defaultMaxEventConcurrency
+	if len(waits) != total {
+		t.Fatalf("expected %d requests, got %d", total, len(waits))
+	}
+
+	// The requests start one interval apart, so the host never sees more than
+	// defaultHostRPS requests per second however many are in flight.
+	starts := make(map[time.Duration]bool)
+	for _, wait := range waits {
+		if wait%interval != 0 || starts[wait] {
+			t.Fatalf("unexpected start offsets: %v", waits)
+		}
+		starts[wait] = true
+	}
+	if want := time.Duration(total-1) * interval; !starts[want] {
+		t.Errorf("expected the last request to start after %v, got %v", want, waits)
+	}
+}
@@ -0,0 +1,147 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// duplicateGroup is a set of destination events sharing a match key.
+type duplicateGroup struct {
+	key        string
+	keep       Event
+	duplicates []Event
+}
+
+// findDuplicates groups the events calbridge owns by their match key on fields and
+// returns the groups with more than one event. The event kept in each group is the
+// first one whose UID is on the source, else the first one by path.
+func findDuplicates(events []Event, fields []db.DedupeField, owned map[string]bool, sourceEventMap map[string]Event) []duplicateGroup {
+	byKey := make(map[string][]Event)
+	var keys []string
+	for _, event := range events {
+		if !owned[event.UID] {
+			continue // Never touch events calbridge didn't write
+		}
+		key := event.MatchKey(fields)
+		if key == "" {
+			continue
+		}
+		if byKey[key] == nil {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], event)
+	}
+	sort.Strings(keys)
+
+	var groups []duplicateGroup
+	for _, key := range keys {
+		events := byKey[key]
+		if len(events) <= 1 {
+			continue
+		}
+		sort.Slice(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+
+		keepIndex := 0
+		for i, event := range events {
+			if _, existsInSource := sourceEventMap[event.UID]; existsInSource {
+				keepIndex = i
+				break
+			}
+		}
+
+		group := duplicateGroup{key: key, keep: events[keepIndex]}
+		for i, event := range events {
+			if i != keepIndex {
+				group.duplicates = append(group.duplicates, event)
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// report converts a duplicate group of the destination calendar destCalendarPath
+// to its stored form, with the summary redacted.
+func (g duplicateGroup) report(destCalendarPath string) *db.DuplicateGroup {
+	report := &db.DuplicateGroup{
+		DestHref:  destCalendarPath,
+		Summary:   db.RedactSummary(g.keep.Summary),
+		StartTime: g.keep.StartTime,
+		Keep:      db.DuplicateEvent{Path: g.keep.Path, UID: g.keep.UID, ETag: g.keep.ETag},
+	}
+	for _, event := range g.duplicates {
+		report.Duplicates = append(report.Duplicates, db.DuplicateEvent{Path: event.Path, UID: event.UID, ETag: event.ETag})
+	}
+	return report
+}
+
+// reportDuplicates stores the duplicate groups of a calendar for review, replacing
+// those found before; nil clears them.
+func (se *SyncEngine) reportDuplicates(sourceID, calendarHref, destCalendarPath string, groups []duplicateGroup) {
+	var stored []*db.DuplicateGroup
+	for _, group := range groups {
+		stored = append(stored, group.report(destCalendarPath))
+	}
+
+	if err := retryDBOperation(func() error {
+		return se.db.ReplaceDuplicateGroups(sourceID, calendarHref, stored)
+	}, 5); err != nil {
+		log.Printf("Failed to store duplicate groups: %v", err)
+	}
+}
+
+// ResolveDuplicateGroup deletes the duplicates of a reported group from the destination
+// and forgets the group. It returns how many events were deleted; if some deletions
+// failed, the group is kept and an error returned.
+func (se *SyncEngine) ResolveDuplicateGroup(ctx context.Context, source *db.Source, group *db.DuplicateGroup) (int, error) {
+	destClient, err := se.endpointClient(source, db.EndpointDest)
+	if err != nil {
+		return 0, err
+	}
+
+	result := &SyncResult{}
+	rec := se.newCalendarRecorder(source.ID, uuid.New().String(), group.CalendarHref, result)
+	removed, failed := 0, 0
+	for _, duplicate := range group.Duplicates {
+		err := destClient.DeleteEvent(ctx, duplicate.Path)
+		rec.change(&Event{Path: duplicate.Path, UID: duplicate.UID}, db.ChangeDelete, db.ChangeToDest, db.ReasonDuplicate, err)
+		if err != nil {
+			log.Printf("Failed to delete duplicate event %s: %v", duplicate.Path, err)
+			failed++
+			continue
+		}
+		removed++
+	}
+	se.saveChanges(result.Changes)
+
+	if failed > 0 {
+		return removed, fmt.Errorf("failed to delete %d of %d duplicates", failed, len(group.Duplicates))
+	}
+	if err := se.db.DeleteDuplicateGroup(group.ID); err != nil {
+		return removed, err
+	}
+	return removed, nil
+}
+
+// endpointClient returns a pooled client for one endpoint of a saved source.
+func (se *SyncEngine) endpointClient(source *db.Source, endpoint db.Endpoint) (*Client, error) {
+	serverURL, settings := source.SourceURL, source.SourceTransport
+	if endpoint == db.EndpointDest {
+		serverURL, settings = source.DestURL, source.DestTransport
+	}
+
+	creds, err := SourceCredentials(se.db, se.encryptor, source, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := DecryptTransportOptions(se.encryptor, settings)
+	if err != nil {
+		return nil, err
+	}
+	return se.clients.Get(serverURL, creds, opts)
+}
@@ -0,0 +1,93 @@
+package caldav
+
+import (
+	"context"
+	"testing"
+
+	"github.com/macjediwizard/calbridgesync/internal/crypto"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+func TestEventMatchKey(t *testing.T) {
+	event := &Event{Summary: "Lunch", StartTime: "20240115T120000Z", Location: "Cafe"}
+
+	if key := event.MatchKey(db.DefaultDedupeFields); key != "Lunch|20240115T120000Z" {
+		t.Errorf("expected summary and start, got %q", key)
+	}
+	if key := event.MatchKey([]db.DedupeField{db.DedupeSummary, db.DedupeLocation, db.DedupeRRule}); key != "Lunch|Cafe|" {
+		t.Errorf("expected summary, location and empty rrule, got %q", key)
+	}
+	if key := event.MatchKey([]db.DedupeField{db.DedupeEnd, db.DedupeRRule}); key != "" {
+		t.Errorf("expected no key without values, got %q", key)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	events := []Event{
+		{Path: "/dest/b.ics", UID: "b", Summary: "Lunch", StartTime: "20240115T120000Z", Location: "Cafe"},
+		{Path: "/dest/a.ics", UID: "a", Summary: "Lunch", StartTime: "20240115T120000Z", Location: "Cafe"},
+		{Path: "/dest/c.ics", UID: "c", Summary: "Lunch", StartTime: "20240115T120000Z", Location: "Office"},
+		{Path: "/dest/mine.ics", UID: "mine", Summary: "Lunch", StartTime: "20240115T120000Z", Location: "Cafe"},
+	}
+	owned := map[string]bool{"a": true, "b": true, "c": true}
+	sourceEventMap := map[string]Event{"b": {UID: "b"}}
+
+	t.Run("groups only owned events", func(t *testing.T) {
+		groups := findDuplicates(events, db.DefaultDedupeFields, owned, sourceEventMap)
+		if len(groups) != 1 || len(groups[0].duplicates) != 2 {
+			t.Fatalf("expected one group of three owned events, got %+v", groups)
+		}
+		if groups[0].keep.UID != "b" {
+			t.Errorf("expected the event on the source to be kept, got %s", groups[0].keep.UID)
+		}
+		for _, d := range groups[0].duplicates {
+			if d.UID == "mine" {
+				t.Error("expected events calbridge didn't write to be left alone")
+			}
+		}
+	})
+
+	t.Run("compares the configured fields", func(t *testing.T) {
+		fields := []db.DedupeField{db.DedupeSummary, db.DedupeStart, db.DedupeLocation}
+		groups := findDuplicates(events, fields, owned, nil)
+		if len(groups) != 1 || len(groups[0].duplicates) != 1 {
+			t.Fatalf("expected the event at another location to be kept apart, got %+v", groups)
+		}
+		if groups[0].keep.Path != "/dest/a.ics" {
+			t.Errorf("expected the first event by path to be kept, got %s", groups[0].keep.Path)
+		}
+	})
+}
+
+func TestResolveDuplicateGroup(t *testing.T) {
+	_, database, sourceID := setupJournalTest(t)
+	enc, _ := crypto.NewEncryptor(make([]byte, 32))
+	engine := NewSyncEngine(database, enc)
+	server := newProbeServer(t)
+
+	source, _ := database.GetSourceByID(sourceID)
+	source.DestURL = server.URL + "/dav/"
+	source.DestPassword, _ = enc.Encrypt("secret")
+	database.UpdateSource(source)
+
+	database.ReplaceDuplicateGroups(sourceID, "/cal/work/", []*db.DuplicateGroup{{
+		DestHref:   "/dav/calendars/alice/work/",
+		Keep:       db.DuplicateEvent{Path: "/dav/calendars/alice/work/1.ics", UID: "event-1"},
+		Duplicates: []db.DuplicateEvent{{Path: "/dav/calendars/alice/work/2.ics", UID: "event-2"}},
+	}})
+	groups, _ := database.GetDuplicateGroups(sourceID)
+
+	removed, err := engine.ResolveDuplicateGroup(context.Background(), source, groups[0])
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 duplicate removed, got %d (%v)", removed, err)
+	}
+	if server.count("DELETE") != 1 {
+		t.Errorf("expected 1 DELETE, got %d", server.count("DELETE"))
+	}
+	if groups, _ := database.GetDuplicateGroups(sourceID); len(groups) != 0 {
+		t.Errorf("expected the group to be forgotten, got %d", len(groups))
+	}
+	if changes, _, _ := database.GetEventChanges(sourceID, db.EventChangeFilter{EventUID: "event-2"}); len(changes) != 1 || changes[0].Reason != db.ReasonDuplicate {
+		t.Errorf("expected the deletion in the audit trail, got %+v", changes)
+	}
+}
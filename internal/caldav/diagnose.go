@@ -0,0 +1,518 @@
+package caldav
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DiagnosticStatus is the outcome of one stage of a connection diagnosis.
+type DiagnosticStatus string
+
+const (
+	DiagnosticOK      DiagnosticStatus = "ok"
+	DiagnosticWarning DiagnosticStatus = "warning" // Works, but something needs attention
+	DiagnosticFailed  DiagnosticStatus = "failed"
+	DiagnosticSkipped DiagnosticStatus = "skipped" // Not run because an earlier stage failed or it wasn't requested
+)
+
+// Stages of a connection diagnosis, in the order they run.
+const (
+	StageDNS            = "dns"
+	StageTCP            = "tcp"
+	StageTLS            = "tls"
+	StageAuth           = "auth"
+	StagePrincipal      = "principal"
+	StageHomeSet        = "home_set"
+	StageCalendars      = "calendars"
+	StageSyncCollection = "sync_collection"
+	StageWrite          = "write"
+)
+
+var diagnosticStages = []string{
+	StageDNS, StageTCP, StageTLS, StageAuth, StagePrincipal, StageHomeSet, StageCalendars, StageSyncCollection, StageWrite,
+}
+
+const (
+	diagnoseTimeout        = 90 * time.Second
+	certExpiryWarning      = 14 * 24 * time.Hour
+	maxTranscriptEntries   = 100
+	maxDiagnosticCalendars = 50 // Calendars listed in the details of the calendars stage
+)
+
+// lookupHost is a variable so tests can stub DNS.
+var lookupHost = net.DefaultResolver.LookupHost
+
+// safeHeaders are the headers whose values are kept in transcripts. All other values
+// are redacted, since they may carry credentials, cookies or custom secrets.
+var safeHeaders = map[string]bool{
+	"Accept":           true,
+	"Allow":            true,
+	"Content-Length":   true,
+	"Content-Type":     true,
+	"Dav":              true,
+	"Date":             true,
+	"Depth":            true,
+	"Etag":             true,
+	"If-Match":         true,
+	"If-None-Match":    true,
+	"Location":         true,
+	"Retry-After":      true,
+	"Server":           true,
+	"User-Agent":       true,
+	"Www-Authenticate": true,
+}
+
+// DiagnosticStage is the result of one stage of a connection diagnosis.
+type DiagnosticStage struct {
+	Name     string
+	Status   DiagnosticStatus
+	Message  string
+	Details  []string
+	Duration time.Duration
+}
+
+// TranscriptEntry is one HTTP exchange made during a diagnosis. URLs lose their
+// credentials and query strings, header values not in safeHeaders are redacted and
+// bodies are never recorded.
+type TranscriptEntry struct {
+	Method          string
+	URL             string
+	Status          int
+	Duration        time.Duration
+	RequestHeaders  map[string]string
+	ResponseHeaders map[string]string
+	Error           string
+}
+
+// DiagnosticReport is the result of a connection diagnosis.
+type DiagnosticReport struct {
+	URL        string
+	Stages     []DiagnosticStage
+	Transcript []TranscriptEntry
+	Success    bool // No stage failed
+}
+
+// DiagnoseOptions controls the optional parts of a diagnosis.
+type DiagnoseOptions struct {
+	Write         bool   // Put and delete a test event
+	WriteCalendar string // Calendar for the write test; the path of the URL if empty
+}
+
+// Diagnose checks the connection to a CalDAV server step by step, from resolving its
+// name to listing its calendars, and reports every stage separately so the one that
+// fails can be told apart. Stages that depend on a failed one are skipped.
+func Diagnose(ctx context.Context, serverURL string, creds Credentials, opts TransportOptions, dopts DiagnoseOptions) *DiagnosticReport {
+	ctx, cancel := context.WithTimeout(ctx, diagnoseTimeout)
+	defer cancel()
+
+	d := &diagnosis{report: &DiagnosticReport{URL: redactURL(serverURL)}, recorder: &transcriptRecorder{}}
+	d.run(ctx, serverURL, creds, opts, dopts)
+
+	d.report.Transcript = d.recorder.transcript()
+	d.report.Success = true
+	for _, stage := range d.report.Stages {
+		if stage.Status == DiagnosticFailed {
+			d.report.Success = false
+		}
+	}
+	return d.report
+}
+
+// diagnosis collects the stages of a running diagnosis.
+type diagnosis struct {
+	report   *DiagnosticReport
+	recorder *transcriptRecorder
+}
+
+// stage runs fn as the named stage and reports whether later stages can run.
+func (d *diagnosis) stage(name string, fn func() (DiagnosticStatus, string, []string)) bool {
+	start := time.Now()
+	status, message, details := fn()
+	d.report.Stages = append(d.report.Stages, DiagnosticStage{
+		Name:     name,
+		Status:   status,
+		Message:  message,
+		Details:  details,
+		Duration: time.Since(start),
+	})
+	return status != DiagnosticFailed
+}
+
+// skip records the named stage as skipped.
+func (d *diagnosis) skip(name, reason string) {
+	d.report.Stages = append(d.report.Stages, DiagnosticStage{Name: name, Status: DiagnosticSkipped, Message: reason})
+}
+
+// skipRemaining records every stage that hasn't run yet as skipped.
+func (d *diagnosis) skipRemaining(reason string) {
+	done := make(map[string]bool, len(d.report.Stages))
+	for _, stage := range d.report.Stages {
+		done[stage.Name] = true
+	}
+	for _, name := range diagnosticStages {
+		if !done[name] {
+			d.skip(name, reason)
+		}
+	}
+}
+
+func (d *diagnosis) run(ctx context.Context, serverURL string, creds Credentials, opts TransportOptions, dopts DiagnoseOptions) {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Hostname() == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		d.stage(StageDNS, func() (DiagnosticStatus, string, []string) {
+			return DiagnosticFailed, "The URL is invalid; it must start with http:// or https:// and name a server", nil
+		})
+		d.skipRemaining("The URL is invalid")
+		return
+	}
+	if opts.ProxyURL != "" {
+		for _, name := range []string{StageDNS, StageTCP, StageTLS} {
+			d.skip(name, "Connections go through the configured proxy")
+		}
+	} else if !d.connect(ctx, u, opts) {
+		return
+	}
+
+	client, err := newClient(serverURL, creds, opts, d.recorder.wrap)
+	if err != nil {
+		d.stage(StageAuth, func() (DiagnosticStatus, string, []string) {
+			return DiagnosticFailed, "Failed to set up the connection: " + err.Error(), nil
+		})
+		d.skipRemaining("The connection couldn't be set up")
+		return
+	}
+	d.discover(ctx, client, dopts)
+}
+
+// connect runs the DNS, TCP and TLS stages.
+func (d *diagnosis) connect(ctx context.Context, u *url.URL, opts TransportOptions) bool {
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	ok := d.stage(StageDNS, func() (DiagnosticStatus, string, []string) {
+		addrs, err := lookupHost(ctx, host)
+		if err != nil {
+			return DiagnosticFailed, fmt.Sprintf("Failed to resolve %s: %v", host, err), nil
+		}
+		return DiagnosticOK, fmt.Sprintf("%s resolves to %d address(es)", host, len(addrs)), addrs
+	})
+	if !ok {
+		d.skipRemaining("The server name couldn't be resolved")
+		return false
+	}
+
+	var conn net.Conn
+	ok = d.stage(StageTCP, func() (DiagnosticStatus, string, []string) {
+		dial := (&net.Dialer{Timeout: 10 * time.Second}).DialContext
+		// The address policy applies here as it does to every client connection
+		if policy := addressPolicy.Load(); policy != nil {
+			dial = policy.DialContext
+		}
+		var err error
+		if conn, err = dial(ctx, "tcp", net.JoinHostPort(host, port)); err != nil {
+			return DiagnosticFailed, fmt.Sprintf("Failed to connect to port %s: %v", port, err), nil
+		}
+		return DiagnosticOK, "Connected to " + conn.RemoteAddr().String(), nil
+	})
+	if !ok {
+		d.skipRemaining("The server is unreachable")
+		return false
+	}
+	defer conn.Close()
+
+	if u.Scheme != "https" {
+		d.stage(StageTLS, func() (DiagnosticStatus, string, []string) {
+			return DiagnosticWarning, "The server is reached over plain HTTP; credentials are sent unencrypted", nil
+		})
+		return true
+	}
+
+	ok = d.stage(StageTLS, func() (DiagnosticStatus, string, []string) {
+		return checkTLS(ctx, conn, host, opts)
+	})
+	if !ok {
+		d.skipRemaining("The TLS connection failed")
+	}
+	return ok
+}
+
+// checkTLS performs a TLS handshake on conn and describes the certificate chain the
+// server presents. The chain is verified after the handshake rather than during it,
+// so an untrusted chain is still shown.
+func checkTLS(ctx context.Context, conn net.Conn, host string, opts TransportOptions) (DiagnosticStatus, string, []string) {
+	cfg, err := opts.tlsConfig()
+	if err != nil {
+		return DiagnosticFailed, err.Error(), nil
+	}
+	verify := !cfg.InsecureSkipVerify
+	cfg.InsecureSkipVerify = true //nolint:gosec // Verified below
+	cfg.ServerName = host
+
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return DiagnosticFailed, "TLS handshake failed: " + err.Error(), nil
+	}
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return DiagnosticFailed, "The server presented no certificate", nil
+	}
+
+	details := []string{tls.VersionName(state.Version) + ", " + tls.CipherSuiteName(state.CipherSuite)}
+	for _, cert := range state.PeerCertificates {
+		details = append(details, fmt.Sprintf("%s, issued by %s, valid until %s",
+			certName(cert.Subject.CommonName, cert.Subject.String()), certName(cert.Issuer.CommonName, cert.Issuer.String()),
+			cert.NotAfter.UTC().Format("2006-01-02")))
+	}
+
+	leaf := state.PeerCertificates[0]
+	if verify {
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: cfg.RootCAs, Intermediates: intermediates}); err != nil {
+			return DiagnosticFailed, "The certificate isn't trusted: " + err.Error(), details
+		}
+	}
+
+	switch remaining := time.Until(leaf.NotAfter); {
+	case remaining <= 0:
+		return DiagnosticWarning, "The certificate expired on " + leaf.NotAfter.UTC().Format("2006-01-02"), details
+	case remaining < certExpiryWarning:
+		return DiagnosticWarning, fmt.Sprintf("The certificate expires in %d day(s)", int(remaining.Hours()/24)), details
+	case !verify:
+		return DiagnosticWarning, "Certificate verification is disabled for this server", details
+	}
+	return DiagnosticOK, "The certificate is valid and trusted", details
+}
+
+// certName returns the common name of a certificate subject or issuer, or the full
+// distinguished name if it has none.
+func certName(commonName, dn string) string {
+	if commonName != "" {
+		return commonName
+	}
+	return dn
+}
+
+// discover runs the stages that talk CalDAV to the server.
+func (d *diagnosis) discover(ctx context.Context, client *Client, dopts DiagnoseOptions) {
+	ok := d.stage(StageAuth, func() (DiagnosticStatus, string, []string) {
+		status, err := client.checkAuth(ctx)
+		switch {
+		case err != nil && ClassifyError(err) == FailureAuth:
+			return DiagnosticFailed, "The credentials were rejected: " + err.Error(), nil
+		case err != nil:
+			return DiagnosticFailed, "The request failed: " + err.Error(), nil
+		case status == http.StatusUnauthorized:
+			return DiagnosticFailed, "The server rejected the credentials (401 Unauthorized)", nil
+		case status == http.StatusForbidden:
+			return DiagnosticFailed, "The server denied access (403 Forbidden)", nil
+		case status != http.StatusMultiStatus && status != http.StatusOK:
+			return DiagnosticWarning, fmt.Sprintf("The server answered with status %d, so the credentials couldn't be confirmed", status), nil
+		}
+		return DiagnosticOK, "The credentials were accepted", nil
+	})
+	if !ok {
+		d.skipRemaining("Authentication failed")
+		return
+	}
+
+	ok = d.stage(StagePrincipal, func() (DiagnosticStatus, string, []string) {
+		principal, err := client.findPrincipal(ctx)
+		if err != nil {
+			return DiagnosticFailed, "Failed to find the current user principal: " + err.Error(), nil
+		}
+		return DiagnosticOK, "Found the current user principal", []string{principal}
+	})
+	if !ok {
+		d.skipRemaining("The principal wasn't found")
+		return
+	}
+
+	ok = d.stage(StageHomeSet, func() (DiagnosticStatus, string, []string) {
+		homeSet, err := client.findHomeSet(ctx)
+		if err != nil {
+			return DiagnosticFailed, "Failed to find the calendar home set: " + err.Error(), nil
+		}
+		return DiagnosticOK, "Found the calendar home set", []string{homeSet}
+	})
+	if !ok {
+		d.skipRemaining("The calendar home set wasn't found")
+		return
+	}
+
+	var calendars []Calendar
+	d.stage(StageCalendars, func() (DiagnosticStatus, string, []string) {
+		var err error
+		if calendars, err = client.FindCalendars(ctx); err != nil {
+			return DiagnosticFailed, "Failed to list calendars: " + err.Error(), nil
+		}
+		if len(calendars) == 0 {
+			return DiagnosticWarning, "The calendar home set contains no calendars", nil
+		}
+		var details []string
+		for i, cal := range calendars {
+			if i == maxDiagnosticCalendars {
+				details = append(details, fmt.Sprintf("and %d more", len(calendars)-i))
+				break
+			}
+			details = append(details, fmt.Sprintf("%s (%s)", cal.Name, cal.Path))
+		}
+		return DiagnosticOK, fmt.Sprintf("Found %d calendar(s)", len(calendars)), details
+	})
+
+	calendarPath := client.GetCalendarPath()
+	if len(calendars) > 0 {
+		calendarPath = calendars[0].Path
+	}
+	d.stage(StageSyncCollection, func() (DiagnosticStatus, string, []string) {
+		supported := client.SupportsWebDAVSync(ctx, calendarPath)
+		if !supported {
+			reports, _, syncToken, err := client.collectionFeatures(ctx, calendarPath)
+			supported = err == nil && (syncToken || strings.Contains(reports, "sync-collection"))
+		}
+		if !supported {
+			return DiagnosticWarning, "WebDAV-Sync isn't supported; changes are found by comparing all events", []string{calendarPath}
+		}
+		return DiagnosticOK, "WebDAV-Sync is supported", []string{calendarPath}
+	})
+
+	if !dopts.Write {
+		d.skip(StageWrite, "Not requested")
+		return
+	}
+	d.stage(StageWrite, func() (DiagnosticStatus, string, []string) {
+		return client.checkWrite(ctx, dopts.WriteCalendar)
+	})
+}
+
+// checkAuth sends an authenticated PROPFIND for the base URL and returns the status.
+func (c *Client) checkAuth(ctx context.Context) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "PROPFIND", c.baseURL, strings.NewReader(`<?xml version="1.0" encoding="utf-8" ?>
+<D:propfind xmlns:D="DAV:">
+  <D:prop>
+    <D:resourcetype/>
+  </D:prop>
+</D:propfind>`))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// checkWrite puts a test event into a calendar and deletes it again.
+func (c *Client) checkWrite(ctx context.Context, calendarPath string) (DiagnosticStatus, string, []string) {
+	if calendarPath == "" {
+		calendarPath = c.GetCalendarPath()
+	}
+	uid := "calbridge-diagnose-" + uuid.New().String()
+	path := strings.TrimSuffix(calendarPath, "/") + "/" + uid + ".ics"
+	event := &Event{
+		UID:  uid,
+		Path: path,
+		Data: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//CalBridgeSync//Diagnose//EN\r\nBEGIN:VEVENT\r\nUID:" + uid +
+			"\r\nDTSTAMP:20000101T000000Z\r\nDTSTART:20000101T000000Z\r\nSUMMARY:CalBridgeSync test\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+	}
+
+	if err := c.PutEvent(ctx, calendarPath, event); err != nil {
+		return DiagnosticFailed, "Failed to create a test event: " + err.Error(), []string{path}
+	}
+	if err := c.DeleteEvent(ctx, path); err != nil {
+		return DiagnosticFailed, "The test event was created but couldn't be deleted; remove it manually: " + err.Error(), []string{path}
+	}
+	return DiagnosticOK, "Created and deleted a test event", []string{path}
+}
+
+// transcriptRecorder records the requests that go through it.
+type transcriptRecorder struct {
+	base http.RoundTripper
+
+	mu      sync.Mutex
+	entries []TranscriptEntry
+}
+
+// wrap makes the recorder send requests through base and returns it.
+func (r *transcriptRecorder) wrap(base http.RoundTripper) http.RoundTripper {
+	r.base = base
+	return r
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *transcriptRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := r.base.RoundTrip(req)
+
+	entry := TranscriptEntry{
+		Method:         req.Method,
+		URL:            redactURL(req.URL.String()),
+		Duration:       time.Since(start),
+		RequestHeaders: redactHeaders(req.Header),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = resp.StatusCode
+		entry.ResponseHeaders = redactHeaders(resp.Header)
+	}
+
+	r.mu.Lock()
+	if len(r.entries) < maxTranscriptEntries {
+		r.entries = append(r.entries, entry)
+	}
+	r.mu.Unlock()
+	return resp, err
+}
+
+// transcript returns the recorded requests.
+func (r *transcriptRecorder) transcript() []TranscriptEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]TranscriptEntry(nil), r.entries...)
+}
+
+// redactURL removes credentials, the query string and the fragment from a URL.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	u.User, u.RawQuery, u.ForceQuery, u.Fragment, u.RawFragment = nil, "", false, "", ""
+	return u.String()
+}
+
+// redactHeaders flattens headers, replacing values that aren't known to be safe.
+func redactHeaders(header http.Header) map[string]string {
+	redacted := make(map[string]string, len(header))
+	for name, values := range header {
+		if safeHeaders[http.CanonicalHeaderKey(name)] {
+			redacted[name] = strings.Join(values, ", ")
+		} else {
+			redacted[name] = "[redacted]"
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,135 @@
+package caldav
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stageStatuses maps the stages of a report to their status.
+func stageStatuses(report *DiagnosticReport) map[string]DiagnosticStatus {
+	statuses := make(map[string]DiagnosticStatus, len(report.Stages))
+	for _, stage := range report.Stages {
+		statuses[stage.Name] = stage.Status
+	}
+	return statuses
+}
+
+func TestDiagnose(t *testing.T) {
+	creds := BasicCredentials("alice", "secret")
+
+	t.Run("reports every stage of a working server", func(t *testing.T) {
+		server := newProbeServer(t)
+
+		report := Diagnose(context.Background(), server.URL+"/dav/?token=abc", creds, TransportOptions{},
+			DiagnoseOptions{Write: true, WriteCalendar: "/dav/calendars/alice/work/"})
+
+		want := map[string]DiagnosticStatus{
+			StageDNS: DiagnosticOK, StageTCP: DiagnosticOK, StageTLS: DiagnosticWarning, StageAuth: DiagnosticOK,
+			StagePrincipal: DiagnosticOK, StageHomeSet: DiagnosticOK, StageCalendars: DiagnosticOK,
+			StageSyncCollection: DiagnosticOK, StageWrite: DiagnosticOK,
+		}
+		if got := stageStatuses(report); len(got) != len(want) {
+			t.Fatalf("expected %d stages, got %+v", len(want), report.Stages)
+		} else {
+			for name, status := range want {
+				if got[name] != status {
+					t.Errorf("expected stage %s to be %s, got %s", name, status, got[name])
+				}
+			}
+		}
+		if !report.Success {
+			t.Error("expected the diagnosis to succeed")
+		}
+		if server.count(http.MethodPut) != 1 || server.count(http.MethodDelete) != 1 {
+			t.Error("expected a test event to be created and deleted")
+		}
+		if strings.Contains(report.URL, "token") {
+			t.Errorf("expected the query to be removed from %s", report.URL)
+		}
+	})
+
+	t.Run("redacts the transcript", func(t *testing.T) {
+		server := newProbeServer(t)
+		opts := TransportOptions{Headers: map[string]string{"X-Api-Key": "key-123"}}
+
+		report := Diagnose(context.Background(), server.URL+"/dav/", creds, opts, DiagnoseOptions{})
+		if len(report.Transcript) == 0 {
+			t.Fatal("expected a transcript")
+		}
+		for _, entry := range report.Transcript {
+			for name, value := range entry.RequestHeaders {
+				if strings.Contains(value, "key-123") || strings.Contains(value, "Basic ") {
+					t.Errorf("expected header %s to be redacted, got %q", name, value)
+				}
+			}
+		}
+		first := report.Transcript[0]
+		if first.RequestHeaders["Authorization"] != "[redacted]" || first.ResponseHeaders["Content-Type"] == "" {
+			t.Errorf("expected only unsafe headers to be redacted, got %+v", first)
+		}
+		if server.count(http.MethodPut) != 0 {
+			t.Error("expected no write without permission")
+		}
+	})
+
+	t.Run("stops at rejected credentials", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="dav"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		report := Diagnose(context.Background(), server.URL+"/dav/", creds, TransportOptions{}, DiagnoseOptions{Write: true})
+		statuses := stageStatuses(report)
+		if statuses[StageAuth] != DiagnosticFailed || report.Success {
+			t.Errorf("expected authentication to fail, got %+v", report.Stages)
+		}
+		for _, name := range []string{StagePrincipal, StageHomeSet, StageCalendars, StageSyncCollection, StageWrite} {
+			if statuses[name] != DiagnosticSkipped {
+				t.Errorf("expected stage %s to be skipped, got %s", name, statuses[name])
+			}
+		}
+	})
+
+	t.Run("reports DNS failures", func(t *testing.T) {
+		orig := lookupHost
+		t.Cleanup(func() { lookupHost = orig })
+		lookupHost = func(context.Context, string) ([]string, error) {
+			return nil, errors.New("no such host")
+		}
+
+		report := Diagnose(context.Background(), "https://calendar.invalid/dav/", creds, TransportOptions{}, DiagnoseOptions{})
+		statuses := stageStatuses(report)
+		if statuses[StageDNS] != DiagnosticFailed || statuses[StageTCP] != DiagnosticSkipped || len(report.Transcript) != 0 {
+			t.Errorf("expected only the DNS stage to run, got %+v", report.Stages)
+		}
+	})
+
+	t.Run("checks the certificate chain", func(t *testing.T) {
+		server := &probeServer{}
+		server.Server = httptest.NewTLSServer(http.HandlerFunc(server.serve))
+		defer server.Close()
+
+		report := Diagnose(context.Background(), server.URL+"/dav/", creds, TransportOptions{}, DiagnoseOptions{})
+		statuses := stageStatuses(report)
+		if statuses[StageTLS] != DiagnosticFailed || statuses[StageAuth] != DiagnosticSkipped {
+			t.Errorf("expected an untrusted certificate to fail, got %+v", report.Stages)
+		}
+
+		caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+		report = Diagnose(context.Background(), server.URL+"/dav/", creds, TransportOptions{CACert: caCert}, DiagnoseOptions{})
+		for _, stage := range report.Stages {
+			if stage.Name == StageTLS && (stage.Status != DiagnosticOK || len(stage.Details) < 2) {
+				t.Errorf("expected a trusted chain to be described, got %+v", stage)
+			}
+		}
+		if !report.Success {
+			t.Errorf("expected the diagnosis to succeed, got %+v", report.Stages)
+		}
+	})
+}
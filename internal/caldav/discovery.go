@@ -0,0 +1,251 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Discovery methods reported in ServiceDiscovery.Method, in the order they are tried.
+const (
+	DiscoveryDirect    = "direct"     // The URL entered by the user
+	DiscoveryWellKnown = "well-known" // /.well-known/caldav on the domain
+	DiscoverySRV       = "srv"        // _caldavs._tcp SRV and TXT records
+	DiscoveryPreset    = "preset"     // A known provider's base URL
+)
+
+const (
+	wellKnownCalDAV       = "/.well-known/caldav"
+	maxDiscoveryRedirects = 5
+	maxSRVTargets         = 3
+)
+
+// DNS lookups used for SRV discovery; replaced in tests.
+var (
+	lookupSRV = net.DefaultResolver.LookupSRV
+	lookupTXT = net.DefaultResolver.LookupTXT
+)
+
+// ServiceDiscovery is the CalDAV service found for a user.
+type ServiceDiscovery struct {
+	ServiceURL   string     // CalDAV context URL to use as the source URL
+	PrincipalURL string     // URL of the user's principal resource
+	Method       string     // How ServiceURL was found
+	Calendars    []Calendar // Calendars of the user
+}
+
+// DiscoverService locates the CalDAV service of a user following RFC 6764. target may
+// be an email address, a bare domain or a URL. Candidates are tried in order: the URL
+// itself when it has a path, /.well-known/caldav (following redirects), the URL named
+// by _caldavs._tcp SRV and TXT records, and finally the fallback base URLs. The first
+// candidate where the user's principal can be found wins.
+//
+// Discovery stops at the first authentication failure, so credentials are never sent
+// to further servers once one of them rejected them.
+func DiscoverService(ctx context.Context, target string, creds Credentials, opts TransportOptions, fallbacks []string) (*ServiceDiscovery, error) {
+	u, err := parseDiscoveryTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	tried := make(map[string]bool)
+	var lastErr error
+	try := func(method, serviceURL string) (*ServiceDiscovery, bool, error) {
+		if serviceURL == "" || tried[serviceURL] {
+			return nil, false, nil
+		}
+		tried[serviceURL] = true
+
+		result, err := probeService(ctx, serviceURL, creds, opts)
+		if err != nil {
+			if errors.Is(err, ErrAuthFailed) {
+				return nil, true, err
+			}
+			lastErr = err
+			return nil, false, nil
+		}
+		result.Method = method
+		return result, true, nil
+	}
+
+	if u.Path != "" && u.Path != "/" {
+		if result, done, err := try(DiscoveryDirect, u.String()); done {
+			return result, err
+		}
+	}
+
+	serviceURL, err := resolveWellKnown(ctx, origin, opts)
+	if err != nil {
+		lastErr = err
+	} else if result, done, err := try(DiscoveryWellKnown, serviceURL); done {
+		return result, err
+	}
+
+	for _, serviceURL := range lookupServiceRecords(ctx, u, opts) {
+		if result, done, err := try(DiscoverySRV, serviceURL); done {
+			return result, err
+		}
+	}
+
+	for _, serviceURL := range fallbacks {
+		if result, done, err := try(DiscoveryPreset, serviceURL); done {
+			return result, err
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no CalDAV service found for %s: %w", ErrConnectionFailed, u.Host, lastErr)
+}
+
+// parseDiscoveryTarget turns an email address, domain or URL into a URL. Schemeless
+// targets use HTTPS.
+func parseDiscoveryTarget(target string) (*url.URL, error) {
+	target = strings.TrimSpace(target)
+	if !strings.Contains(target, "://") {
+		if i := strings.LastIndex(target, "@"); i >= 0 {
+			target = target[i+1:]
+		}
+		target = "https://" + target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" || u.User != nil {
+		return nil, fmt.Errorf("%w: enter an email address, domain or URL", ErrConnectionFailed)
+	}
+	u.RawQuery, u.Fragment = "", ""
+	return u, nil
+}
+
+// probeService checks that serviceURL is a CalDAV service where the user has a
+// principal and lists the user's calendars.
+func probeService(ctx context.Context, serviceURL string, creds Credentials, opts TransportOptions) (*ServiceDiscovery, error) {
+	client, err := NewClientWithCredentials(serviceURL, creds, opts)
+	if err != nil {
+		return nil, err
+	}
+	principal, err := client.findPrincipal(ctx)
+	if err != nil {
+		if ClassifyError(err) == FailureAuth {
+			return nil, fmt.Errorf("%w: %w", ErrAuthFailed, err)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+
+	calendars, err := client.FindCalendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceDiscovery{
+		ServiceURL:   serviceURL,
+		PrincipalURL: client.buildURL(principal),
+		Calendars:    calendars,
+	}, nil
+}
+
+// resolveWellKnown follows the redirects of origin's /.well-known/caldav and returns
+// the context URL they lead to. Redirects from HTTPS to HTTP are not followed.
+func resolveWellKnown(ctx context.Context, origin string, opts TransportOptions) (string, error) {
+	transport, err := newHTTPTransport(opts)
+	if err != nil {
+		return "", err
+	}
+	client := &http.Client{
+		Timeout:   time.Duration(requestTimeout.Load()),
+		Transport: newHeaderTransport(transport, opts),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	current, err := url.Parse(origin + wellKnownCalDAV)
+	if err != nil {
+		return "", err
+	}
+	for hop := 0; hop <= maxDiscoveryRedirects; hop++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, current.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			if hop == 0 && resp.StatusCode == http.StatusNotFound {
+				return "", fmt.Errorf("%s returned %s", wellKnownCalDAV, resp.Status)
+			}
+			return current.String(), nil
+		}
+
+		next, err := current.Parse(location)
+		if err != nil {
+			return "", fmt.Errorf("invalid redirect from %s: %w", wellKnownCalDAV, err)
+		}
+		if current.Scheme == "https" && next.Scheme != "https" {
+			return "", fmt.Errorf("%s redirects to an insecure URL", wellKnownCalDAV)
+		}
+		current = next
+	}
+	return "", fmt.Errorf("%s redirects too often", wellKnownCalDAV)
+}
+
+// lookupServiceRecords returns the service URLs advertised by _caldavs._tcp SRV
+// records of the target's domain, ordered by priority. The context path comes from
+// the TXT "path" record; without one the target's well-known URL is resolved.
+func lookupServiceRecords(ctx context.Context, u *url.URL, opts TransportOptions) []string {
+	domain := u.Hostname()
+	if u.Port() != "" || net.ParseIP(domain) != nil {
+		return nil
+	}
+
+	_, records, err := lookupSRV(ctx, "caldavs", "tcp", domain)
+	if err != nil {
+		return nil
+	}
+
+	path := ""
+	if txts, err := lookupTXT(ctx, "_caldavs._tcp."+domain); err == nil {
+		for _, txt := range txts {
+			if p, ok := strings.CutPrefix(txt, "path="); ok && strings.HasPrefix(p, "/") {
+				path = p
+				break
+			}
+		}
+	}
+
+	var urls []string
+	for _, srv := range records {
+		target := strings.TrimSuffix(srv.Target, ".")
+		if target == "" {
+			continue // "." means the service is decidedly not available
+		}
+		origin := "https://" + net.JoinHostPort(target, strconv.Itoa(int(srv.Port)))
+		if srv.Port == 443 {
+			origin = "https://" + target
+		}
+
+		if path != "" {
+			urls = append(urls, origin+path)
+		} else if serviceURL, err := resolveWellKnown(ctx, origin, opts); err == nil {
+			urls = append(urls, serviceURL)
+		} else {
+			urls = append(urls, origin+"/")
+		}
+		if len(urls) == maxSRVTargets {
+			break
+		}
+	}
+	return urls
+}
@@ -0,0 +1,114 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// maxDriftUIDs caps the UIDs of mismatched events a drift report keeps per calendar.
+const maxDriftUIDs = 50
+
+// VerifyDrift compares every calendar a sync of the source covers with its destination
+// calendar, by UID and content hash, without writing anything. The report isn't stored;
+// a check that can't reach the source returns a failed report.
+func (se *SyncEngine) VerifyDrift(ctx context.Context, source *db.Source) *db.DriftReport {
+	start := time.Now()
+	report := &db.DriftReport{SourceID: source.ID, Status: db.DriftStatusOK}
+	fail := func(message string, err error) *db.DriftReport {
+		report.Status = db.DriftStatusFailed
+		report.Message = fmt.Sprintf("%s: %v", message, err)
+		report.Duration = time.Since(start)
+		return report
+	}
+
+	sourceClient, err := se.endpointClient(source, db.EndpointSource)
+	if err != nil {
+		return fail("Failed to connect to source", err)
+	}
+	calendars, err := sourceClient.FindCalendars(ctx)
+	if err != nil {
+		return fail("Failed to find source calendars", err)
+	}
+
+	failed := 0
+	for _, calendar := range selectedCalendars(source, calendars) {
+		if ctx.Err() != nil {
+			return fail("Drift check interrupted", ctx.Err())
+		}
+
+		diff, err := se.DiffCalendar(ctx, source, calendar.Path)
+		if err != nil {
+			failed++
+			report.Calendars = append(report.Calendars, db.CalendarDrift{
+				CalendarHref: calendar.Path,
+				CalendarName: calendar.Name,
+				Error:        err.Error(),
+			})
+			continue
+		}
+
+		drift := calendarDrift(diff)
+		drift.CalendarName = calendar.Name
+		report.Mismatches += drift.Mismatches()
+		report.Calendars = append(report.Calendars, drift)
+	}
+
+	switch {
+	case len(report.Calendars) > 0 && failed == len(report.Calendars):
+		report.Status = db.DriftStatusFailed
+		report.Message = "No calendar could be compared"
+	case report.Mismatches > 0:
+		report.Status = db.DriftStatusDrift
+		report.Message = fmt.Sprintf("%d mismatched events in %d calendars", report.Mismatches, len(report.Calendars)-failed)
+	default:
+		report.Message = fmt.Sprintf("%d calendars match", len(report.Calendars)-failed)
+	}
+	if failed > 0 && report.Status != db.DriftStatusFailed {
+		report.Message += fmt.Sprintf(" (%d could not be compared)", failed)
+	}
+
+	report.Duration = time.Since(start)
+	return report
+}
+
+// calendarDrift counts the mismatches of a calendar diff. Source events missing on the
+// destination count whether synced or not, as a failed create leaves no record; events
+// only on the destination count if calbridge synced them, as others come from elsewhere.
+func calendarDrift(diff *CalendarDiff) db.CalendarDrift {
+	drift := db.CalendarDrift{
+		CalendarHref: diff.CalendarHref,
+		DestHref:     diff.DestHref,
+		SourceEvents: diff.SourceEvents,
+		DestEvents:   diff.DestEvents,
+	}
+	addUID := func(uid string) {
+		if len(drift.UIDs) < maxDriftUIDs {
+			drift.UIDs = append(drift.UIDs, uid)
+		}
+	}
+
+	for _, e := range diff.MissingOnDest {
+		drift.MissingOnDest++
+		addUID(e.UID)
+	}
+	for _, e := range diff.MissingOnSource {
+		if e.Synced {
+			drift.MissingOnSource++
+			addUID(e.UID)
+		}
+	}
+	for _, d := range diff.Differs {
+		drift.Differs++
+		addUID(d.UID)
+	}
+	for _, group := range diff.Duplicates {
+		for _, dup := range group.Duplicates {
+			drift.Duplicates++
+			addUID(dup.UID)
+		}
+	}
+	return drift
+}
@@ -0,0 +1,89 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/macjediwizard/calbridgesync/internal/crypto"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+func TestCalendarDrift(t *testing.T) {
+	diff := &CalendarDiff{
+		CalendarHref: "/src/work/",
+		DestHref:     "/dst/work/",
+		SourceEvents: 5,
+		DestEvents:   6,
+		MissingOnDest: []DiffEvent{
+			{Event: Event{UID: "new"}},
+			{Event: Event{UID: "deleted-on-dest"}, Synced: true},
+		},
+		MissingOnSource: []DiffEvent{
+			{Event: Event{UID: "foreign"}},
+			{Event: Event{UID: "deleted-on-source"}, Synced: true},
+		},
+		Differs: []EventDifference{{UID: "edited"}},
+		Duplicates: []*db.DuplicateGroup{
+			{Keep: db.DuplicateEvent{UID: "a"}, Duplicates: []db.DuplicateEvent{{UID: "a-1"}, {UID: "a-2"}}},
+		},
+	}
+
+	drift := calendarDrift(diff)
+	if drift.CalendarHref != "/src/work/" || drift.DestHref != "/dst/work/" || drift.SourceEvents != 5 || drift.DestEvents != 6 {
+		t.Errorf("unexpected calendar: %+v", drift)
+	}
+	if drift.MissingOnDest != 2 || drift.MissingOnSource != 1 || drift.Differs != 1 || drift.Duplicates != 2 {
+		t.Errorf("unexpected counts: %+v", drift)
+	}
+	if drift.Mismatches() != 6 || len(drift.UIDs) != 6 {
+		t.Errorf("expected 6 mismatches, got %d with UIDs %v", drift.Mismatches(), drift.UIDs)
+	}
+
+	t.Run("caps the UIDs", func(t *testing.T) {
+		many := &CalendarDiff{}
+		for i := 0; i < maxDriftUIDs+10; i++ {
+			many.Differs = append(many.Differs, EventDifference{UID: fmt.Sprintf("uid-%d", i)})
+		}
+		drift := calendarDrift(many)
+		if drift.Differs != maxDriftUIDs+10 || len(drift.UIDs) != maxDriftUIDs {
+			t.Errorf("expected %d differences with %d UIDs, got %d with %d", maxDriftUIDs+10, maxDriftUIDs, drift.Differs, len(drift.UIDs))
+		}
+	})
+}
+
+func TestVerifyDrift(t *testing.T) {
+	_, database, sourceID := setupJournalTest(t)
+	enc, _ := crypto.NewEncryptor(make([]byte, 32))
+	engine := NewSyncEngine(database, enc)
+	server := newProbeServer(t)
+
+	source, _ := database.GetSourceByID(sourceID)
+	source.SourceURL = server.URL + "/dav/"
+	source.SourcePassword, _ = enc.Encrypt("secret")
+	source.DestURL = server.URL + "/dav/"
+	source.DestPassword, _ = enc.Encrypt("secret")
+	database.UpdateSource(source)
+
+	t.Run("matching calendars", func(t *testing.T) {
+		report := engine.VerifyDrift(context.Background(), source)
+		if report.Status != db.DriftStatusOK || report.Mismatches != 0 || len(report.Calendars) == 0 {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+		if cal := report.Calendars[0]; cal.Error != "" || cal.SourceEvents != cal.DestEvents {
+			t.Errorf("unexpected calendar: %+v", cal)
+		}
+		if n := server.count("PUT") + server.count("DELETE") + server.count("MKCALENDAR"); n != 0 {
+			t.Errorf("expected no writes, got %d", n)
+		}
+	})
+
+	t.Run("unreachable source", func(t *testing.T) {
+		unreachable := *source
+		unreachable.SourceURL = "http://127.0.0.1:1/dav/"
+		report := engine.VerifyDrift(context.Background(), &unreachable)
+		if report.Status != db.DriftStatusFailed || report.Message == "" {
+			t.Errorf("expected a failed report, got %+v", report)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package caldav
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+)
+
+// FailureKind classifies why a sync failed so callers can react differently,
+// e.g. stop retrying on bad credentials but back off on network blips.
+type FailureKind string
+
+const (
+	FailureNone    FailureKind = ""        // Sync succeeded or failure is unclassified
+	FailureAuth    FailureKind = "auth"    // Credentials rejected (401)
+	FailureNetwork FailureKind = "network" // DNS, connect, TLS or timeout errors
+	FailureServer  FailureKind = "server"  // Server reachable but returned an error
+)
+
+// ClassifyError returns the failure kind for an error returned by the client.
+// Only typed errors are considered; anything unrecognized counts as a server failure.
+func ClassifyError(err error) FailureKind {
+	if err == nil {
+		return FailureNone
+	}
+
+	if errors.Is(err, ErrAuthFailed) {
+		return FailureAuth
+	}
+
+	// A status code means the server was reached, whatever wraps it
+	if code := httpStatus(err); code != 0 {
+		if code == http.StatusUnauthorized {
+			return FailureAuth
+		}
+		return FailureServer
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return FailureNetwork
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return FailureNetwork
+	}
+	if isTLSError(err) {
+		return FailureNetwork
+	}
+	return FailureServer
+}
+
+// statusSentinel returns the sentinel error for an unexpected response status, so
+// rejected credentials surface as ErrAuthFailed.
+func statusSentinel(code int) error {
+	if code == http.StatusUnauthorized {
+		return ErrAuthFailed
+	}
+	return ErrInvalidResponse
+}
+
+// StatusError is an error the client returned for a request the server answered
+// with an error status. Err is the error as reported by the CalDAV library.
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+func (e *StatusError) Error() string { return e.Err.Error() }
+
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// responseStatus holds the status of the last response to the requests made with a
+// context from withResponseStatus; 0 if the last request got no response.
+type responseStatus struct {
+	code atomic.Int32
+}
+
+type responseStatusKey struct{}
+
+// withResponseStatus returns a context whose requests record their response status,
+// so errors of the CalDAV library can be wrapped in a StatusError.
+func withResponseStatus(ctx context.Context) (context.Context, *responseStatus) {
+	status := &responseStatus{}
+	return context.WithValue(ctx, responseStatusKey{}, status), status
+}
+
+// recordResponseStatus records the status of a response to req, if its context asks for it.
+func recordResponseStatus(req *http.Request, code int) {
+	if status, ok := req.Context().Value(responseStatusKey{}).(*responseStatus); ok {
+		status.code.Store(int32(code))
+	}
+}
+
+// wrap wraps err in a StatusError if the last response had an error status.
+func (s *responseStatus) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	if code := int(s.code.Load()); code >= http.StatusBadRequest {
+		return &StatusError{Code: code, Err: err}
+	}
+	return err
+}
+
+// httpStatus returns the status code of the first StatusError in err's chain, or 0
+// if there is none.
+func httpStatus(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code
+	}
+	return 0
+}
+
+// isTLSError reports whether err is a TLS handshake or certificate error.
+func isTLSError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verifyErr) || errors.As(err, &recordErr) || errors.As(err, &alertErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
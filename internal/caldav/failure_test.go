@@ -0,0 +1,77 @@
+package caldav
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/emersion/go-webdav"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected FailureKind
+	}{
+		{"nil", nil, FailureNone},
+		{"wrapped auth sentinel", fmt.Errorf("%w: bad password", ErrAuthFailed), FailureAuth},
+		{"401 from server", fmt.Errorf("propfind: %w", &StatusError{Code: http.StatusUnauthorized, Err: errors.New("401 Unauthorized")}), FailureAuth},
+		{"joined 401", errors.Join(errors.New("first"), &StatusError{Code: http.StatusUnauthorized, Err: errors.New("401 Unauthorized")}), FailureAuth},
+		{"dns failure", &net.DNSError{Err: "no such host", Name: "caldav.example.com", IsNotFound: true}, FailureNetwork},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, FailureNetwork},
+		{"bare connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), FailureNetwork},
+		{"timeout", fmt.Errorf("propfind: %w", context.DeadlineExceeded), FailureNetwork},
+		{"tls failure", &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}, FailureNetwork},
+		{"server error", &StatusError{Code: http.StatusInternalServerError, Err: errors.New("500 Internal Server Error")}, FailureServer},
+		{"forbidden", &StatusError{Code: http.StatusForbidden, Err: errors.New("tls required")}, FailureServer},
+		{"library error without status", webdav.NewHTTPError(http.StatusUnauthorized, nil), FailureServer},
+		{"401 in message only", errors.New("event 401 not found"), FailureServer},
+		{"tls in message only", errors.New("invalid tlsid property"), FailureServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.expected {
+				t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClientErrorStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		expected FailureKind
+	}{
+		{"rejected credentials", http.StatusUnauthorized, FailureAuth},
+		{"forbidden", http.StatusForbidden, FailureServer},
+		{"server error", http.StatusInternalServerError, FailureServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+			client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+
+			err := client.DeleteEvent(context.Background(), "/dav/calendars/alice/work/1.ics")
+			if httpStatus(err) != tt.status {
+				t.Errorf("expected status %d to be recorded, got %d (%v)", tt.status, httpStatus(err), err)
+			}
+			if got := ClassifyError(err); got != tt.expected {
+				t.Errorf("ClassifyError(%v) = %q, want %q", err, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package caldav
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// calendarResourceID returns the last segment of a calendar href. Servers usually keep
+// it when a calendar moves to another home, e.g. during iCloud account migrations.
+func calendarResourceID(href string) string {
+	return path.Base(collectionKey(href))
+}
+
+// calendarFingerprint identifies a calendar independently of its href.
+func calendarFingerprint(name, resourceID string) string {
+	return strings.ToLower(strings.TrimSpace(name)) + "\x00" + resourceID
+}
+
+// trackCalendars compares the calendars found on the source with the ones seen during
+// previous syncs. A calendar that disappeared while a new one with the same
+// fingerprint (or, failing that, the same resource ID) appeared is treated as moved:
+// its sync state and selection follow it to the new href. Selected calendars that are
+// gone are returned as warnings. source.SelectedCalendars is updated in place.
+func (se *SyncEngine) trackCalendars(source *db.Source, calendars []Calendar) []string {
+	known, err := se.db.GetCalendarIdentities(source.ID)
+	if err != nil {
+		log.Printf("Failed to get calendar identities: %v", err)
+		return nil
+	}
+
+	current := make(map[string]bool, len(calendars))
+	for _, cal := range calendars {
+		current[cal.Path] = true
+	}
+	seen := make(map[string]bool, len(known))
+	for _, ci := range known {
+		seen[ci.CalendarHref] = true
+	}
+
+	// Calendars that appeared since the last sync are candidates for moved ones
+	var appeared []Calendar
+	for _, cal := range calendars {
+		if !seen[cal.Path] {
+			appeared = append(appeared, cal)
+		}
+	}
+
+	var missing []*db.CalendarIdentity
+	for _, ci := range known {
+		if current[ci.CalendarHref] {
+			continue
+		}
+		newHref := matchMovedCalendar(ci, appeared)
+		if newHref == "" {
+			missing = append(missing, ci)
+			continue
+		}
+
+		if err := se.db.MoveCalendar(source.ID, ci.CalendarHref, newHref); err != nil {
+			log.Printf("Failed to move state of calendar %q: %v", ci.Name, err)
+			missing = append(missing, ci)
+			continue
+		}
+		log.Printf("Calendar %q moved from %s to %s, sync state migrated", ci.Name, ci.CalendarHref, newHref)
+		for i := range source.SelectedCalendars {
+			if source.SelectedCalendars[i].Path == ci.CalendarHref {
+				source.SelectedCalendars[i].Path = newHref
+			}
+		}
+		for i, cal := range appeared {
+			if cal.Path == newHref {
+				appeared = append(appeared[:i], appeared[i+1:]...)
+				break
+			}
+		}
+	}
+
+	for _, cal := range calendars {
+		resourceID := calendarResourceID(cal.Path)
+		if err := se.db.UpsertCalendarIdentity(&db.CalendarIdentity{
+			SourceID:     source.ID,
+			CalendarHref: cal.Path,
+			Name:         cal.Name,
+			ResourceID:   resourceID,
+			Fingerprint:  calendarFingerprint(cal.Name, resourceID),
+		}); err != nil {
+			log.Printf("Failed to save calendar identity: %v", err)
+		}
+	}
+
+	names := make(map[string]string, len(missing))
+	for _, ci := range missing {
+		names[ci.CalendarHref] = ci.Name
+	}
+	var warnings []string
+	for _, cfg := range source.SelectedCalendars {
+		if current[cfg.Path] {
+			continue
+		}
+		name := names[cfg.Path]
+		if name == "" {
+			name = cfg.Path
+		}
+		warnings = append(warnings, fmt.Sprintf("Selected calendar %q is no longer on the source (%s)", name, cfg.Path))
+	}
+	return warnings
+}
+
+// matchMovedCalendar returns the href of the calendar in appeared that a missing
+// calendar moved to, or "" if there is no unambiguous match.
+func matchMovedCalendar(ci *db.CalendarIdentity, appeared []Calendar) string {
+	var byFingerprint, byResourceID []string
+	for _, cal := range appeared {
+		resourceID := calendarResourceID(cal.Path)
+		if calendarFingerprint(cal.Name, resourceID) == ci.Fingerprint {
+			byFingerprint = append(byFingerprint, cal.Path)
+		}
+		if resourceID == ci.ResourceID {
+			byResourceID = append(byResourceID, cal.Path)
+		}
+	}
+
+	if len(byFingerprint) == 1 {
+		return byFingerprint[0]
+	}
+	if len(byFingerprint) == 0 && len(byResourceID) == 1 {
+		return byResourceID[0]
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package caldav
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+func TestTrackCalendars(t *testing.T) {
+	work := Calendar{Path: "/123/calendars/work/", Name: "Work"}
+	home := Calendar{Path: "/123/calendars/home/", Name: "Home"}
+
+	t.Run("migrates the state of a moved calendar", func(t *testing.T) {
+		engine, database, sourceID := setupJournalTest(t)
+		source, _ := database.GetSourceByID(sourceID)
+		source.SelectedCalendars = []db.CalendarConfig{{Path: work.Path, SyncDirection: db.SyncDirectionTwoWay}}
+		database.UpdateSource(source)
+
+		if warnings := engine.trackCalendars(source, []Calendar{work, home}); len(warnings) != 0 {
+			t.Fatalf("unexpected warnings: %v", warnings)
+		}
+		database.UpsertSyncState(&db.SyncState{SourceID: sourceID, CalendarHref: work.Path, SyncToken: "token-1"})
+		database.UpsertSyncedEvent(&db.SyncedEvent{SourceID: sourceID, CalendarHref: work.Path, EventUID: "event-1"})
+
+		moved := Calendar{Path: "/456/calendars/work/", Name: "Work"}
+		if warnings := engine.trackCalendars(source, []Calendar{moved, home}); len(warnings) != 0 {
+			t.Fatalf("unexpected warnings: %v", warnings)
+		}
+
+		if source.SelectedCalendars[0].Path != moved.Path {
+			t.Errorf("expected selection to follow the calendar, got %+v", source.SelectedCalendars)
+		}
+		stored, _ := database.GetSourceByID(sourceID)
+		if stored.SelectedCalendars[0].Path != moved.Path || stored.SelectedCalendars[0].SyncDirection != db.SyncDirectionTwoWay {
+			t.Errorf("expected stored selection to be migrated, got %+v", stored.SelectedCalendars)
+		}
+		if state, err := database.GetSyncState(sourceID, moved.Path); err != nil || state.SyncToken != "token-1" {
+			t.Errorf("expected sync state to be migrated, got %+v (%v)", state, err)
+		}
+		if events, _ := database.GetSyncedEvents(sourceID, moved.Path); len(events) != 1 {
+			t.Errorf("expected synced events to be migrated, got %d", len(events))
+		}
+		if events, _ := database.GetSyncedEvents(sourceID, work.Path); len(events) != 0 {
+			t.Errorf("expected no state under the old href, got %d events", len(events))
+		}
+	})
+
+	t.Run("matches a renamed and moved calendar by resource ID", func(t *testing.T) {
+		engine, database, sourceID := setupJournalTest(t)
+		source, _ := database.GetSourceByID(sourceID)
+
+		engine.trackCalendars(source, []Calendar{work})
+		database.UpsertSyncState(&db.SyncState{SourceID: sourceID, CalendarHref: work.Path, SyncToken: "token-1"})
+
+		moved := Calendar{Path: "/456/calendars/work/", Name: "Office"}
+		engine.trackCalendars(source, []Calendar{moved})
+		if state, err := database.GetSyncState(sourceID, moved.Path); err != nil || state.SyncToken != "token-1" {
+			t.Errorf("expected sync state to be migrated, got %+v (%v)", state, err)
+		}
+	})
+
+	t.Run("does not guess between ambiguous matches", func(t *testing.T) {
+		engine, database, sourceID := setupJournalTest(t)
+		source, _ := database.GetSourceByID(sourceID)
+
+		engine.trackCalendars(source, []Calendar{work})
+		database.UpsertSyncState(&db.SyncState{SourceID: sourceID, CalendarHref: work.Path, SyncToken: "token-1"})
+
+		engine.trackCalendars(source, []Calendar{{Path: "/456/calendars/work/", Name: "A"}, {Path: "/789/calendars/work/", Name: "B"}})
+		if _, err := database.GetSyncState(sourceID, work.Path); err != nil {
+			t.Errorf("expected state to stay in place, got %v", err)
+		}
+	})
+
+	t.Run("warns when a selected calendar disappears", func(t *testing.T) {
+		engine, database, sourceID := setupJournalTest(t)
+		source, _ := database.GetSourceByID(sourceID)
+		source.SelectedCalendars = []db.CalendarConfig{{Path: work.Path}}
+
+		engine.trackCalendars(source, []Calendar{work, home})
+		warnings := engine.trackCalendars(source, []Calendar{home})
+		if len(warnings) != 1 || !strings.Contains(warnings[0], `"Work"`) {
+			t.Errorf("expected a warning naming the calendar, got %v", warnings)
+		}
+	})
+}
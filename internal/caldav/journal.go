@@ -0,0 +1,222 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// writeJournal queues the writes of one sync phase and records them in the sync
+// journal before any of them is sent. Each write updates its entry as soon as it
+// returns, so after a crash the journal shows exactly which writes were in flight.
+type writeJournal struct {
+	db      *db.DB
+	entries []*db.JournalEntry
+	ops     []func() bool
+	bodies  []*Event // Event each write sends, nil for deletes
+
+	runID        string
+	sourceID     string
+	calendarHref string
+
+	// Where event bodies are fetched from, see loadBodiesFrom
+	bodyClient *Client
+	bodyPath   string
+	warn       func(string)
+}
+
+// newWriteJournal creates a journal for writes made while syncing a source calendar.
+func (se *SyncEngine) newWriteJournal(runID, sourceID, calendarHref string) *writeJournal {
+	return &writeJournal{
+		db:           se.db,
+		runID:        runID,
+		sourceID:     sourceID,
+		calendarHref: calendarHref,
+	}
+}
+
+// loadBodiesFrom makes the journal fetch the iCalendar data of queued events from
+// calendarPath on client just before they are written. Events that can't be fetched
+// are not written and reported through warn.
+func (j *writeJournal) loadBodiesFrom(client *Client, calendarPath string, warn func(string)) {
+	j.bodyClient = client
+	j.bodyPath = calendarPath
+	j.warn = warn
+}
+
+// add queues a write. fn performs it and reports whether the server accepted it.
+// body is the event fn sends (nil for deletes); its data is loaded before fn runs
+// and dropped again afterwards.
+func (j *writeJournal) add(op db.JournalOperation, uid, path string, body *Event, fn func() bool) {
+	j.entries = append(j.entries, &db.JournalEntry{
+		RunID:        j.runID,
+		SourceID:     j.sourceID,
+		CalendarHref: j.calendarHref,
+		EventUID:     uid,
+		Operation:    op,
+		EventPath:    path,
+	})
+	j.ops = append(j.ops, fn)
+	j.bodies = append(j.bodies, body)
+}
+
+// run records the queued writes and then executes them with at most limit in flight.
+// Writes run in chunks of eventBatchSize so only one chunk of event bodies is in memory.
+// If the journal can't be written the writes still run, with synced_events updated directly.
+func (j *writeJournal) run(ctx context.Context, limit int) {
+	if len(j.ops) == 0 {
+		return
+	}
+
+	journaled := true
+	if err := retryDBOperation(func() error {
+		return j.db.CreateJournalEntries(j.entries)
+	}, 5); err != nil {
+		log.Printf("Failed to write sync journal, continuing without it: %v", err)
+		journaled = false
+	}
+
+	for start := 0; start < len(j.ops) && ctx.Err() == nil; start += eventBatchSize {
+		end := min(start+eventBatchSize, len(j.ops))
+		j.loadBodies(ctx, j.bodies[start:end])
+
+		runBounded(ctx, end-start, limit, func(i int) {
+			entry := j.entries[start+i]
+			body := j.bodies[start+i]
+
+			ok := false
+			if body != nil && body.Data == "" {
+				j.warnf("Skipped writing event %s: its data could not be fetched", entry.EventUID)
+			} else {
+				ok = j.ops[start+i]()
+			}
+
+			var err error
+			switch {
+			case journaled && ok:
+				err = retryDBOperation(func() error { return j.db.CompleteJournalEntry(entry) }, 5)
+			case journaled:
+				err = retryDBOperation(func() error { return j.db.FailJournalEntry(entry) }, 5)
+			case ok && entry.Operation.IsPut():
+				err = retryDBOperation(func() error {
+					return j.db.UpsertSyncedEvent(&db.SyncedEvent{
+						SourceID:     entry.SourceID,
+						CalendarHref: entry.CalendarHref,
+						EventUID:     entry.EventUID,
+					})
+				}, 5)
+			}
+			if err != nil {
+				log.Printf("Failed to record write of event %s: %v", entry.EventUID, err)
+			}
+		})
+
+		for _, body := range j.bodies[start:end] {
+			if body != nil {
+				body.Data = ""
+			}
+		}
+	}
+
+	j.entries = nil
+	j.ops = nil
+	j.bodies = nil
+}
+
+// loadBodies fetches the data of the given events that don't have it yet.
+func (j *writeJournal) loadBodies(ctx context.Context, bodies []*Event) {
+	if j.bodyClient == nil {
+		return
+	}
+
+	var pending []*Event
+	for _, body := range bodies {
+		if body != nil && body.Data == "" {
+			pending = append(pending, body)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	if err := j.bodyClient.loadEventData(ctx, j.bodyPath, pending); err != nil {
+		log.Printf("Failed to fetch event data: %v", err)
+	}
+}
+
+func (j *writeJournal) warnf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	log.Print(msg)
+	if j.warn != nil {
+		j.warn(msg)
+	}
+}
+
+// JournalRecovery summarizes the journal of a sync run that was interrupted by a crash.
+type JournalRecovery struct {
+	SourceID string
+	RunID    string
+	Done     int // Writes confirmed before the crash (already in synced_events)
+	Failed   int // Writes the server rejected
+	Pending  int // Writes that may or may not have reached the server
+}
+
+// RecoverJournals reconciles journals left behind by sync runs that never finished.
+// Confirmed writes are replayed into synced_events (a no-op unless the record was lost)
+// and removed. Pending writes are kept: they make the next run of the source compare
+// their calendars in full, which records writes that reached the server and re-applies
+// the others by UID without creating duplicates (see syncCalendar). Runs without
+// pending writes are removed.
+func (se *SyncEngine) RecoverJournals() ([]JournalRecovery, error) {
+	entries, err := se.db.GetUnfinishedJournalEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveries []JournalRecovery
+	index := make(map[string]int) // run ID -> position in recoveries
+	for _, entry := range entries {
+		i, exists := index[entry.RunID]
+		if !exists {
+			i = len(recoveries)
+			index[entry.RunID] = i
+			recoveries = append(recoveries, JournalRecovery{SourceID: entry.SourceID, RunID: entry.RunID})
+		}
+		r := &recoveries[i]
+
+		switch entry.State {
+		case db.JournalDone:
+			r.Done++
+			if entry.Operation.IsPut() {
+				if err := se.db.UpsertSyncedEvent(&db.SyncedEvent{
+					SourceID:     entry.SourceID,
+					CalendarHref: entry.CalendarHref,
+					EventUID:     entry.EventUID,
+				}); err != nil {
+					return nil, err
+				}
+			}
+		case db.JournalFailed:
+			r.Failed++
+		default:
+			r.Pending++
+		}
+	}
+
+	for _, r := range recoveries {
+		log.Printf("Recovered interrupted sync run %s for source %s: %d writes done, %d failed, %d pending",
+			r.RunID, r.SourceID, r.Done, r.Failed, r.Pending)
+		if r.Pending > 0 {
+			err = se.db.DeleteFinishedJournalEntries(r.RunID)
+		} else {
+			err = se.db.DeleteJournalRun(r.RunID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return recoveries, nil
+}
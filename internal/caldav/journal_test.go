@@ -0,0 +1,175 @@
+package caldav
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// setupJournalTest creates a database with one source for journal tests.
+func setupJournalTest(t *testing.T) (*SyncEngine, *db.DB, string) {
+	t.Helper()
+
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	user, err := database.GetOrCreateUser("journal@example.com", "Journal")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	source := &db.Source{
+		UserID:           user.ID,
+		Name:             "Journal",
+		SourceType:       db.SourceTypeCustom,
+		SourceURL:        "https://source.example.com/",
+		SourceUsername:   "user",
+		SourcePassword:   "encrypted",
+		DestURL:          "https://dest.example.com/",
+		DestUsername:     "user",
+		DestPassword:     "encrypted",
+		SyncInterval:     300,
+		SyncDirection:    db.SyncDirectionOneWay,
+		ConflictStrategy: db.ConflictSourceWins,
+		Enabled:          true,
+	}
+	if err := database.CreateSource(source); err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+
+	return NewSyncEngine(database, nil), database, source.ID
+}
+
+func TestWriteJournal(t *testing.T) {
+	t.Run("records each write as it finishes", func(t *testing.T) {
+		engine, database, sourceID := setupJournalTest(t)
+
+		j := engine.newWriteJournal("run-1", sourceID, "/cal/")
+		j.add(db.JournalPutDest, "uid-ok", "/dest/", nil, func() bool { return true })
+		j.add(db.JournalPutDest, "uid-rejected", "/dest/", nil, func() bool { return false })
+		j.run(context.Background(), 2)
+
+		entries, err := database.GetUnfinishedJournalEntries()
+		if err != nil {
+			t.Fatalf("failed to get journal: %v", err)
+		}
+		states := make(map[string]db.JournalState)
+		for _, entry := range entries {
+			states[entry.EventUID] = entry.State
+		}
+		if states["uid-ok"] != db.JournalDone || states["uid-rejected"] != db.JournalFailed {
+			t.Errorf("unexpected journal states: %v", states)
+		}
+
+		synced, _ := database.GetSyncedEvents(sourceID, "/cal/")
+		if len(synced) != 1 || synced[0].EventUID != "uid-ok" {
+			t.Errorf("expected only the successful write in synced_events, got %+v", synced)
+		}
+	})
+
+	t.Run("leaves writes pending when the run stops", func(t *testing.T) {
+		engine, database, sourceID := setupJournalTest(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		j := engine.newWriteJournal("run-1", sourceID, "/cal/")
+		j.add(db.JournalPutDest, "uid-1", "/dest/", nil, func() bool {
+			cancel()
+			return true
+		})
+		j.add(db.JournalPutDest, "uid-2", "/dest/", nil, func() bool { return true })
+		j.run(ctx, 1)
+
+		entries, _ := database.GetUnfinishedJournalEntries()
+		states := make(map[string]db.JournalState)
+		for _, entry := range entries {
+			states[entry.EventUID] = entry.State
+		}
+		if states["uid-1"] != db.JournalDone || states["uid-2"] != db.JournalPending {
+			t.Errorf("unexpected journal states: %v", states)
+		}
+	})
+}
+
+func TestRecoverJournals(t *testing.T) {
+	engine, database, sourceID := setupJournalTest(t)
+
+	entries := []*db.JournalEntry{
+		{RunID: "crashed", SourceID: sourceID, CalendarHref: "/cal/", EventUID: "uid-done", Operation: db.JournalPutDest, EventPath: "/dest/"},
+		{RunID: "crashed", SourceID: sourceID, CalendarHref: "/cal/", EventUID: "uid-pending", Operation: db.JournalPutDest, EventPath: "/dest/"},
+	}
+	if err := database.CreateJournalEntries(entries); err != nil {
+		t.Fatalf("failed to create journal: %v", err)
+	}
+	if err := database.CompleteJournalEntry(entries[0]); err != nil {
+		t.Fatalf("failed to complete entry: %v", err)
+	}
+
+	recoveries, err := engine.RecoverJournals()
+	if err != nil {
+		t.Fatalf("RecoverJournals failed: %v", err)
+	}
+	if len(recoveries) != 1 {
+		t.Fatalf("expected 1 recovered run, got %d", len(recoveries))
+	}
+	r := recoveries[0]
+	if r.SourceID != sourceID || r.Done != 1 || r.Pending != 1 || r.Failed != 0 {
+		t.Errorf("unexpected recovery summary: %+v", r)
+	}
+
+	remaining, _ := database.GetUnfinishedJournalEntries()
+	if len(remaining) != 1 || remaining[0].EventUID != "uid-pending" {
+		t.Errorf("expected only the pending write to be kept, got %+v", remaining)
+	}
+
+	synced, _ := database.GetSyncedEvents(sourceID, "/cal/")
+	if len(synced) != 1 || synced[0].EventUID != "uid-done" {
+		t.Errorf("expected confirmed write in synced_events, got %+v", synced)
+	}
+}
+
+func TestSyncCalendarAfterInterruptedRun(t *testing.T) {
+	engine, database, sourceID := setupJournalTest(t)
+	source, _ := database.GetSourceByID(sourceID)
+	sourceServer := newProbeServer(t)
+	destServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusMultiStatus)
+		io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:"></D:multistatus>`)
+	}))
+	t.Cleanup(destServer.Close)
+	sourceClient, _ := NewClient(sourceServer.URL+"/dav/", "alice", "secret")
+	destClient, _ := NewClient(destServer.URL+"/dav/", "alice", "secret")
+	calendar := Calendar{Path: "/dav/calendars/alice/work/", Name: "Work"}
+
+	// The crashed run may or may not have written event-1
+	database.CreateJournalEntries([]*db.JournalEntry{
+		{RunID: "crashed", SourceID: sourceID, CalendarHref: calendar.Path, EventUID: "event-1", Operation: db.JournalPutDest, EventPath: "/dav/"},
+	})
+
+	result := engine.syncCalendar(context.Background(), source, sourceClient, destClient, calendar, "run-2")
+	if result.Mode != db.SyncModeFull {
+		t.Errorf("expected a full comparison despite WebDAV-Sync support, got %q", result.Mode)
+	}
+
+	synced, _ := database.GetSyncedEvents(sourceID, calendar.Path)
+	if len(synced) != 1 || synced[0].EventUID != "event-1" {
+		t.Errorf("expected the event to be recorded, got %+v", synced)
+	}
+	remaining, _ := database.GetUnfinishedJournalEntries()
+	for _, entry := range remaining {
+		if entry.RunID == "crashed" {
+			t.Errorf("expected the interrupted journal to be cleared, got %+v", entry)
+		}
+	}
+}
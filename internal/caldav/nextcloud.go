@@ -0,0 +1,146 @@
+package caldav
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// nextcloudAppName is sent as User-Agent when starting a login; Nextcloud shows it as
+// the name of the app password in the user's security settings.
+const nextcloudAppName = "CalBridgeSync"
+
+// ErrLoginPending is returned while the user hasn't finished a Nextcloud login yet.
+var ErrLoginPending = errors.New("login not completed yet")
+
+// NextcloudLoginFlow is a started Nextcloud Login Flow v2. The user opens LoginURL in
+// a browser while calbridge polls for the resulting app password.
+type NextcloudLoginFlow struct {
+	LoginURL     string
+	PollEndpoint string
+	PollToken    string
+}
+
+// NextcloudLogin is the outcome of a completed login flow.
+type NextcloudLogin struct {
+	Server      string // Base URL of the Nextcloud server
+	LoginName   string
+	AppPassword string
+}
+
+// CalDAVURL returns the CalDAV base URL of the server the login was made on.
+func (l *NextcloudLogin) CalDAVURL() string {
+	return NextcloudCalDAVURL(l.Server)
+}
+
+// NextcloudCalDAVURL derives the CalDAV base URL from a Nextcloud server URL, e.g.
+// https://cloud.example.com/nextcloud -> https://cloud.example.com/nextcloud/remote.php/dav/.
+func NextcloudCalDAVURL(server string) string {
+	server = strings.TrimRight(server, "/")
+	if i := strings.Index(server, "/remote.php/"); i >= 0 {
+		server = server[:i]
+	}
+	server = strings.TrimSuffix(server, "/index.php")
+	return server + "/remote.php/dav/"
+}
+
+// StartNextcloudLogin starts a Login Flow v2 on the server. serverURL may be the
+// server's base URL or any URL below it, such as a CalDAV URL.
+func StartNextcloudLogin(ctx context.Context, serverURL string, opts TransportOptions) (*NextcloudLoginFlow, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("%w: invalid server URL", ErrConnectionFailed)
+	}
+	base := strings.TrimSuffix(NextcloudCalDAVURL(u.Scheme+"://"+u.Host+u.Path), "/remote.php/dav/")
+
+	var resp struct {
+		Poll struct {
+			Token    string `json:"token"`
+			Endpoint string `json:"endpoint"`
+		} `json:"poll"`
+		Login string `json:"login"`
+	}
+	status, err := nextcloudPost(ctx, base+"/index.php/login/v2", nil, opts, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: server doesn't support Nextcloud Login Flow v2 (HTTP %d)", ErrConnectionFailed, status)
+	}
+	if resp.Login == "" || resp.Poll.Token == "" || resp.Poll.Endpoint == "" {
+		return nil, fmt.Errorf("%w: incomplete login flow response", ErrInvalidResponse)
+	}
+
+	return &NextcloudLoginFlow{
+		LoginURL:     resp.Login,
+		PollEndpoint: resp.Poll.Endpoint,
+		PollToken:    resp.Poll.Token,
+	}, nil
+}
+
+// PollNextcloudLogin checks once whether the user completed the login. It returns
+// ErrLoginPending until they did; Nextcloud answers a poll with the app password only
+// once.
+func PollNextcloudLogin(ctx context.Context, flow *NextcloudLoginFlow, opts TransportOptions) (*NextcloudLogin, error) {
+	var resp struct {
+		Server      string `json:"server"`
+		LoginName   string `json:"loginName"`
+		AppPassword string `json:"appPassword"`
+	}
+	status, err := nextcloudPost(ctx, flow.PollEndpoint, url.Values{"token": {flow.PollToken}}, opts, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, ErrLoginPending
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: login poll failed (HTTP %d)", ErrConnectionFailed, status)
+	}
+	if resp.Server == "" || resp.LoginName == "" || resp.AppPassword == "" {
+		return nil, fmt.Errorf("%w: incomplete login response", ErrInvalidResponse)
+	}
+
+	return &NextcloudLogin{Server: resp.Server, LoginName: resp.LoginName, AppPassword: resp.AppPassword}, nil
+}
+
+// nextcloudPost sends a form POST and decodes a successful JSON response into out.
+func nextcloudPost(ctx context.Context, endpoint string, form url.Values, opts TransportOptions, out any) (int, error) {
+	transport, err := newHTTPTransport(opts)
+	if err != nil {
+		return 0, err
+	}
+	client := &http.Client{
+		Timeout:   time.Duration(requestTimeout.Load()),
+		Transport: newHeaderTransport(transport, opts),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", nextcloudAppName)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return resp.StatusCode, nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+	return resp.StatusCode, nil
+}
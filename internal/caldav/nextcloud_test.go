@@ -0,0 +1,101 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNextcloudCalDAVURL(t *testing.T) {
+	tests := []struct {
+		server string
+		want   string
+	}{
+		{"https://cloud.example.com", "https://cloud.example.com/remote.php/dav/"},
+		{"https://cloud.example.com/", "https://cloud.example.com/remote.php/dav/"},
+		{"https://example.com/nextcloud", "https://example.com/nextcloud/remote.php/dav/"},
+		{"https://example.com/nextcloud/index.php", "https://example.com/nextcloud/remote.php/dav/"},
+		{"https://cloud.example.com/remote.php/dav/calendars/alice/", "https://cloud.example.com/remote.php/dav/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			if got := NextcloudCalDAVURL(tt.server); got != tt.want {
+				t.Errorf("NextcloudCalDAVURL(%q) = %q, want %q", tt.server, got, tt.want)
+			}
+		})
+	}
+}
+
+// nextcloudServer stands in for Nextcloud's Login Flow v2 endpoints. The login is
+// pending until approve is called.
+func nextcloudServer(t *testing.T) (server *httptest.Server, approve func()) {
+	t.Helper()
+	approved := false
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/nextcloud/index.php/login/v2":
+			if r.Method != http.MethodPost || r.UserAgent() != nextcloudAppName {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"poll":{"token":"poll-token","endpoint":"` + server.URL + `/nextcloud/index.php/login/v2/poll"},"login":"` + server.URL + `/nextcloud/index.php/login/v2/flow/abc"}`))
+		case "/nextcloud/index.php/login/v2/poll":
+			r.ParseForm()
+			if !approved || r.Form.Get("token") != "poll-token" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"server":"` + server.URL + `/nextcloud","loginName":"alice","appPassword":"app-password"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() { approved = true }
+}
+
+func TestNextcloudLogin(t *testing.T) {
+	t.Run("completes a login flow", func(t *testing.T) {
+		server, approve := nextcloudServer(t)
+
+		flow, err := StartNextcloudLogin(context.Background(), server.URL+"/nextcloud/remote.php/dav/", TransportOptions{})
+		if err != nil {
+			t.Fatalf("StartNextcloudLogin failed: %v", err)
+		}
+		if flow.LoginURL != server.URL+"/nextcloud/index.php/login/v2/flow/abc" || flow.PollToken != "poll-token" {
+			t.Errorf("unexpected flow: %+v", flow)
+		}
+
+		if _, err := PollNextcloudLogin(context.Background(), flow, TransportOptions{}); !errors.Is(err, ErrLoginPending) {
+			t.Fatalf("expected ErrLoginPending, got %v", err)
+		}
+
+		approve()
+		login, err := PollNextcloudLogin(context.Background(), flow, TransportOptions{})
+		if err != nil {
+			t.Fatalf("PollNextcloudLogin failed: %v", err)
+		}
+		if login.LoginName != "alice" || login.AppPassword != "app-password" {
+			t.Errorf("unexpected login: %+v", login)
+		}
+		if login.CalDAVURL() != server.URL+"/nextcloud/remote.php/dav/" {
+			t.Errorf("unexpected CalDAV URL %q", login.CalDAVURL())
+		}
+	})
+
+	t.Run("reports servers without login flow", func(t *testing.T) {
+		server, _ := nextcloudServer(t)
+		if _, err := StartNextcloudLogin(context.Background(), server.URL+"/other", TransportOptions{}); !errors.Is(err, ErrConnectionFailed) {
+			t.Errorf("expected ErrConnectionFailed, got %v", err)
+		}
+	})
+
+	t.Run("rejects invalid server URLs", func(t *testing.T) {
+		if _, err := StartNextcloudLogin(context.Background(), "ftp://cloud.example.com", TransportOptions{}); !errors.Is(err, ErrConnectionFailed) {
+			t.Errorf("expected ErrConnectionFailed, got %v", err)
+		}
+	})
+}
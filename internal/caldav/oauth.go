@@ -0,0 +1,122 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/macjediwizard/calbridgesync/internal/db"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+)
+
+const (
+	// GoogleCalendarScope grants read/write access to Google Calendar, including CalDAV.
+	GoogleCalendarScope = "https://www.googleapis.com/auth/calendar"
+
+	googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+)
+
+// OAuthProvider runs the OAuth2 authorization code flow that connects a source to an
+// account of a calendar provider. It is separate from the OIDC login of calbridge users.
+type OAuthProvider struct {
+	config    oauth2.Config
+	revokeURL string
+}
+
+// NewOAuthProvider creates a provider for any OAuth2 server. revokeURL may be empty if
+// the server doesn't support token revocation (RFC 7009).
+func NewOAuthProvider(clientID, clientSecret, redirectURL string, endpoint oauth2.Endpoint, revokeURL string, scopes []string) *OAuthProvider {
+	return &OAuthProvider{
+		config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint:     endpoint,
+			Scopes:       scopes,
+		},
+		revokeURL: revokeURL,
+	}
+}
+
+// NewGoogleOAuthProvider creates a provider for Google Calendar.
+func NewGoogleOAuthProvider(clientID, clientSecret, redirectURL string) *OAuthProvider {
+	return NewOAuthProvider(clientID, clientSecret, redirectURL, endpoints.Google, googleRevokeURL, []string{GoogleCalendarScope})
+}
+
+// AuthCodeURL returns the URL that asks the user to grant calbridge access. The
+// verifier must be kept until the callback and passed to Exchange (PKCE). Offline
+// access and the consent prompt are requested so a refresh token is always issued,
+// also when the user reconnects an account that granted access before.
+func (p *OAuthProvider) AuthCodeURL(state, verifier string) string {
+	return p.config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange trades an authorization code for tokens and returns them as credentials
+// that renew themselves through the provider's token endpoint.
+func (p *OAuthProvider) Exchange(ctx context.Context, code, verifier string) (Credentials, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.httpClient())
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.Response != nil && retrieveErr.Response.StatusCode < 500 {
+			return Credentials{}, fmt.Errorf("%w: authorization code rejected: %s", ErrAuthFailed, retrieveErr.ErrorCode)
+		}
+		return Credentials{}, fmt.Errorf("%w: token exchange failed: %w", ErrConnectionFailed, err)
+	}
+	if token.RefreshToken == "" {
+		return Credentials{}, fmt.Errorf("%w: no refresh token was issued", ErrAuthFailed)
+	}
+
+	return Credentials{
+		Method: db.AuthMethodOAuth2,
+		Token:  token.AccessToken,
+		OAuth2: &OAuth2Credentials{
+			TokenURL:     p.config.Endpoint.TokenURL,
+			ClientID:     p.config.ClientID,
+			ClientSecret: p.config.ClientSecret,
+			Scopes:       p.config.Scopes,
+			RefreshToken: token.RefreshToken,
+			Expiry:       token.Expiry,
+		},
+	}, nil
+}
+
+// Revoke invalidates a token at the provider. Revoking a refresh token also revokes
+// the access tokens issued with it. A token the provider no longer knows is not an error.
+func (p *OAuthProvider) Revoke(ctx context.Context, token string) error {
+	if p.revokeURL == "" || token == "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.revokeURL, strings.NewReader(url.Values{"token": {token}}.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create revocation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: token revocation failed: %w", ErrConnectionFailed, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	// Google answers 400 invalid_token for tokens that are already revoked or expired
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("%w: token revocation failed: %s", ErrConnectionFailed, resp.Status)
+	}
+	return nil
+}
+
+// httpClient returns the client for requests to the provider, subject to the
+// configured request timeout and address policy.
+func (p *OAuthProvider) httpClient() *http.Client {
+	transport, _ := newHTTPTransport(TransportOptions{}) // Can't fail without TLS or proxy settings
+	return &http.Client{Timeout: time.Duration(requestTimeout.Load()), Transport: transport}
+}
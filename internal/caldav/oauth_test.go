@@ -0,0 +1,118 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/macjediwizard/calbridgesync/internal/db"
+	"golang.org/x/oauth2"
+)
+
+// tokenEndpoint stands in for a provider's token and revocation endpoints.
+func tokenEndpoint(t *testing.T, response string, revoked *[]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.URL.Path == "/revoke" {
+			*revoked = append(*revoked, r.Form.Get("token"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Form.Get("code") != "good-code" || r.Form.Get("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOAuthProvider(t *testing.T) {
+	newProvider := func(server *httptest.Server) *OAuthProvider {
+		return NewOAuthProvider("client", "secret", "https://calbridge.example.com/auth/google/callback",
+			oauth2.Endpoint{AuthURL: server.URL + "/auth", TokenURL: server.URL + "/token"}, server.URL+"/revoke", []string{GoogleCalendarScope})
+	}
+
+	t.Run("requests offline access with PKCE", func(t *testing.T) {
+		p := NewGoogleOAuthProvider("client", "secret", "https://calbridge.example.com/auth/google/callback")
+		u, err := url.Parse(p.AuthCodeURL("state-1", oauth2.GenerateVerifier()))
+		if err != nil {
+			t.Fatalf("invalid auth URL: %v", err)
+		}
+		q := u.Query()
+		if q.Get("state") != "state-1" || q.Get("access_type") != "offline" || q.Get("prompt") != "consent" {
+			t.Errorf("unexpected auth URL parameters: %v", q)
+		}
+		if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+			t.Errorf("expected PKCE challenge, got %v", q)
+		}
+		if q.Get("scope") != GoogleCalendarScope {
+			t.Errorf("expected calendar scope, got %q", q.Get("scope"))
+		}
+	})
+
+	t.Run("exchanges a code for refreshable credentials", func(t *testing.T) {
+		server := tokenEndpoint(t, `{"access_token":"access","refresh_token":"refresh","token_type":"Bearer","expires_in":3600}`, nil)
+		p := newProvider(server)
+
+		creds, err := p.Exchange(context.Background(), "good-code", oauth2.GenerateVerifier())
+		if err != nil {
+			t.Fatalf("Exchange failed: %v", err)
+		}
+		if creds.Method != db.AuthMethodOAuth2 || creds.Token != "access" || creds.OAuth2 == nil {
+			t.Fatalf("unexpected credentials: %+v", creds)
+		}
+		o := creds.OAuth2
+		if o.RefreshToken != "refresh" || o.TokenURL != server.URL+"/token" || o.ClientID != "client" || o.ClientSecret != "secret" || o.Expiry.IsZero() {
+			t.Errorf("unexpected OAuth2 credentials: %+v", o)
+		}
+		if err := creds.Validate(); err != nil {
+			t.Errorf("expected valid credentials, got %v", err)
+		}
+	})
+
+	t.Run("rejects a bad code", func(t *testing.T) {
+		p := newProvider(tokenEndpoint(t, `{}`, nil))
+		if _, err := p.Exchange(context.Background(), "bad-code", "verifier"); !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("expected ErrAuthFailed, got %v", err)
+		}
+	})
+
+	t.Run("requires a refresh token", func(t *testing.T) {
+		p := newProvider(tokenEndpoint(t, `{"access_token":"access","token_type":"Bearer"}`, nil))
+		if _, err := p.Exchange(context.Background(), "good-code", "verifier"); !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("expected ErrAuthFailed, got %v", err)
+		}
+	})
+
+	t.Run("revokes tokens", func(t *testing.T) {
+		var revoked []string
+		p := newProvider(tokenEndpoint(t, `{}`, &revoked))
+		if err := p.Revoke(context.Background(), "refresh"); err != nil {
+			t.Fatalf("Revoke failed: %v", err)
+		}
+		if len(revoked) != 1 || revoked[0] != "refresh" {
+			t.Errorf("expected token to be revoked, got %v", revoked)
+		}
+	})
+}
+
+func TestOAuth2ReconnectRequired(t *testing.T) {
+	caldavServer, tokenServer, _ := oauth2Servers(t, "fresh")
+
+	creds := Credentials{
+		Method: db.AuthMethodOAuth2,
+		OAuth2: &OAuth2Credentials{TokenURL: tokenServer.URL, RefreshToken: "revoked"},
+	}
+	engine := NewSyncEngine(nil, nil)
+	err := engine.TestConnection(context.Background(), caldavServer.URL+"/", creds, TransportOptions{})
+	if !errors.Is(err, ErrReconnectRequired) || !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("expected ErrReconnectRequired and ErrAuthFailed, got %v", err)
+	}
+}
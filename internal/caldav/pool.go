@@ -0,0 +1,183 @@
+package caldav
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// Client pool defaults. Pooled clients keep their transport (and its idle connections)
+// between sync runs; discovery results are reused until they are older than the TTL.
+const (
+	defaultDiscoveryTTL = 5 * time.Minute
+	clientIdleTimeout   = time.Hour
+)
+
+// ClientPool reuses CalDAV clients across sync runs. Clients are keyed by server URL,
+// username and hashes of the credentials and transport options, so changing any of them
+// yields a fresh client.
+type ClientPool struct {
+	mu           sync.Mutex
+	clients      map[clientKey]*pooledClient
+	discoveryTTL time.Duration
+	now          func() time.Time
+}
+
+// clientKey identifies a pooled client.
+type clientKey struct {
+	baseURL    string
+	username   string
+	credential string // Fingerprint of the credentials, so changed credentials never share a client
+	transport  string // Fingerprint of the transport options
+}
+
+type pooledClient struct {
+	client   *Client
+	lastUsed time.Time
+}
+
+// NewClientPool creates a client pool. Discovery results are cached for discoveryTTL;
+// a TTL of 0 disables discovery caching (transports are still reused).
+func NewClientPool(discoveryTTL time.Duration) *ClientPool {
+	return &ClientPool{
+		clients:      make(map[clientKey]*pooledClient),
+		discoveryTTL: discoveryTTL,
+		now:          time.Now,
+	}
+}
+
+// Get returns the pooled client for an account, creating it if needed.
+// Clients that haven't been used for clientIdleTimeout are dropped first.
+func (p *ClientPool) Get(baseURL string, creds Credentials, opts TransportOptions) (*Client, error) {
+	key := clientKey{
+		baseURL:    baseURL,
+		username:   creds.Username,
+		credential: creds.version(),
+		transport:  opts.fingerprint(),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	for k, pc := range p.clients {
+		if now.Sub(pc.lastUsed) > clientIdleTimeout {
+			pc.client.closeIdleConnections()
+			delete(p.clients, k)
+		}
+	}
+
+	if pc, exists := p.clients[key]; exists {
+		pc.lastUsed = now
+		return pc.client, nil
+	}
+
+	client, err := NewClientWithCredentials(baseURL, creds, opts)
+	if err != nil {
+		return nil, err
+	}
+	client.discovery.ttl = p.discoveryTTL
+	client.discovery.now = p.now
+	p.clients[key] = &pooledClient{client: client, lastUsed: now}
+	return client, nil
+}
+
+// Invalidate drops all pooled clients for an account, whatever their credentials.
+// It returns the number of clients dropped.
+func (p *ClientPool) Invalidate(baseURL, username string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	dropped := 0
+	for k, pc := range p.clients {
+		if k.baseURL == baseURL && k.username == username {
+			pc.client.closeIdleConnections()
+			delete(p.clients, k)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// WithDiscoveryCacheTTL sets how long pooled clients reuse principal, home set and
+// calendar discovery results. A TTL of 0 disables discovery caching.
+func WithDiscoveryCacheTTL(ttl time.Duration) SyncEngineOption {
+	return func(se *SyncEngine) {
+		if ttl >= 0 {
+			se.clients = NewClientPool(ttl)
+		}
+	}
+}
+
+// InvalidateClients drops pooled clients for an account so the next sync connects
+// and discovers afresh. Call it when a source's URL or credentials change.
+func (se *SyncEngine) InvalidateClients(baseURL, username string) {
+	if n := se.clients.Invalidate(baseURL, username); n > 0 {
+		log.Printf("Dropped %d pooled CalDAV client(s) for %s", n, baseURL)
+	}
+}
+
+// credentialVersion returns a fingerprint of a credential for use in pool keys.
+func credentialVersion(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// discoveryCache holds the results of principal, home set and calendar discovery
+// for a client. Each result expires ttl after it was stored; clients created outside
+// a pool have a zero TTL and always discover.
+type discoveryCache struct {
+	mu  sync.Mutex
+	ttl time.Duration
+	now func() time.Time
+
+	principal cachedValue[string]
+	homeSet   cachedValue[string]
+	calendars cachedValue[[]Calendar]
+}
+
+// cachedValue is a discovery result and when it was stored.
+type cachedValue[T any] struct {
+	value    T
+	storedAt time.Time
+}
+
+// lookup returns a cached result if it hasn't expired.
+func lookup[T any](d *discoveryCache, v *cachedValue[T]) (T, bool) {
+	var zero T
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if v.storedAt.IsZero() || d.now().Sub(v.storedAt) >= d.ttl {
+		return zero, false
+	}
+	return v.value, true
+}
+
+// store caches a result.
+func store[T any](d *discoveryCache, v *cachedValue[T], value T) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	v.value = value
+	v.storedAt = d.now()
+}
+
+// reset discards all cached results.
+func (d *discoveryCache) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.principal = cachedValue[string]{}
+	d.homeSet = cachedValue[string]{}
+	d.calendars = cachedValue[[]Calendar]{}
+}
+
+// resetDiscovery drops cached discovery results so the next run rediscovers.
+func (c *Client) resetDiscovery() {
+	c.discovery.reset()
+}
+
+// closeIdleConnections closes idle connections of the client's transport.
+func (c *Client) closeIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
@@ -0,0 +1,170 @@
+package caldav
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientPool(t *testing.T) {
+	t.Run("reuses clients for the same account and credential", func(t *testing.T) {
+		pool := NewClientPool(time.Minute)
+
+		a, err := pool.Get("https://cal.example.com/", BasicCredentials("user", "secret"), TransportOptions{})
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		b, _ := pool.Get("https://cal.example.com/", BasicCredentials("user", "secret"), TransportOptions{})
+		if a != b {
+			t.Error("expected the pooled client to be reused")
+		}
+
+		c, _ := pool.Get("https://cal.example.com/", BasicCredentials("user", "changed"), TransportOptions{})
+		if c == a {
+			t.Error("expected a new client after the credential changed")
+		}
+		d, _ := pool.Get("https://cal.example.com/", BasicCredentials("other", "secret"), TransportOptions{})
+		if d == a {
+			t.Error("expected a separate client for another username")
+		}
+	})
+
+	t.Run("invalidate drops every credential version of an account", func(t *testing.T) {
+		pool := NewClientPool(time.Minute)
+		a, _ := pool.Get("https://cal.example.com/", BasicCredentials("user", "one"), TransportOptions{})
+		pool.Get("https://cal.example.com/", BasicCredentials("user", "two"), TransportOptions{})
+		pool.Get("https://other.example.com/", BasicCredentials("user", "one"), TransportOptions{})
+
+		if n := pool.Invalidate("https://cal.example.com/", "user"); n != 2 {
+			t.Errorf("expected 2 clients dropped, got %d", n)
+		}
+		if len(pool.clients) != 1 {
+			t.Errorf("expected 1 client left, got %d", len(pool.clients))
+		}
+
+		b, _ := pool.Get("https://cal.example.com/", BasicCredentials("user", "one"), TransportOptions{})
+		if b == a {
+			t.Error("expected a new client after invalidation")
+		}
+	})
+
+	t.Run("drops clients that have been idle too long", func(t *testing.T) {
+		pool := NewClientPool(time.Minute)
+		now := time.Now()
+		pool.now = func() time.Time { return now }
+
+		a, _ := pool.Get("https://cal.example.com/", BasicCredentials("user", "secret"), TransportOptions{})
+		now = now.Add(clientIdleTimeout + time.Second)
+		b, _ := pool.Get("https://cal.example.com/", BasicCredentials("user", "secret"), TransportOptions{})
+		if a == b {
+			t.Error("expected idle client to be replaced")
+		}
+	})
+
+	t.Run("returns error for invalid URL", func(t *testing.T) {
+		pool := NewClientPool(time.Minute)
+		if _, err := pool.Get("", BasicCredentials("user", "secret"), TransportOptions{}); err == nil {
+			t.Error("expected error for empty URL")
+		}
+		if len(pool.clients) != 0 {
+			t.Error("expected failed client not to be pooled")
+		}
+	})
+}
+
+func TestDiscoveryCache(t *testing.T) {
+	var principalRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principalRequests.Add(1)
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusMultiStatus)
+		io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:">
+  <D:response>
+    <D:href>/</D:href>
+    <D:propstat>
+      <D:prop><D:current-user-principal><D:href>/principals/user/</D:href></D:current-user-principal></D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+  </D:response>
+</D:multistatus>`)
+	}))
+	defer server.Close()
+
+	t.Run("pooled client reuses the principal until the TTL expires", func(t *testing.T) {
+		principalRequests.Store(0)
+		pool := NewClientPool(time.Minute)
+		now := time.Now()
+		pool.now = func() time.Time { return now }
+
+		client, err := pool.Get(server.URL+"/", BasicCredentials("user", "secret"), TransportOptions{})
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			if err := client.checkConnection(context.Background()); err != nil {
+				t.Fatalf("checkConnection failed: %v", err)
+			}
+		}
+		if n := principalRequests.Load(); n != 1 {
+			t.Errorf("expected 1 discovery request, got %d", n)
+		}
+
+		now = now.Add(2 * time.Minute)
+		if err := client.checkConnection(context.Background()); err != nil {
+			t.Fatalf("checkConnection failed: %v", err)
+		}
+		if n := principalRequests.Load(); n != 2 {
+			t.Errorf("expected rediscovery after TTL, got %d requests", n)
+		}
+
+		client.resetDiscovery()
+		if err := client.checkConnection(context.Background()); err != nil {
+			t.Fatalf("checkConnection failed: %v", err)
+		}
+		if n := principalRequests.Load(); n != 3 {
+			t.Errorf("expected rediscovery after reset, got %d requests", n)
+		}
+
+		if err := client.TestConnection(context.Background()); err != nil {
+			t.Fatalf("TestConnection failed: %v", err)
+		}
+		if n := principalRequests.Load(); n != 4 {
+			t.Errorf("expected an explicit connection test to bypass the cache, got %d requests", n)
+		}
+	})
+
+	t.Run("unpooled client always discovers", func(t *testing.T) {
+		principalRequests.Store(0)
+		client, err := NewClient(server.URL+"/", "user", "secret")
+		if err != nil {
+			t.Fatalf("NewClient failed: %v", err)
+		}
+		client.TestConnection(context.Background())
+		client.TestConnection(context.Background())
+		if n := principalRequests.Load(); n != 2 {
+			t.Errorf("expected 2 discovery requests, got %d", n)
+		}
+	})
+
+	t.Run("cached calendars are copies", func(t *testing.T) {
+		d := &discoveryCache{ttl: time.Minute, now: time.Now}
+		store(d, &d.calendars, []Calendar{{Path: "/cal/", Name: "Work"}})
+
+		client := &Client{discovery: d}
+		cals, err := client.FindCalendars(context.Background())
+		if err != nil {
+			t.Fatalf("FindCalendars failed: %v", err)
+		}
+		cals[0].Name = "Changed"
+
+		again, _ := client.FindCalendars(context.Background())
+		if again[0].Name != "Work" {
+			t.Errorf("expected cached calendars to be unaffected, got %q", again[0].Name)
+		}
+	})
+}
@@ -0,0 +1,503 @@
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/google/uuid"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// maxRawEventSize is the largest calendar object GetRawEvent reads.
+const maxRawEventSize = 1 << 20
+
+var (
+	// ErrNotRepairable is returned when automatic repair can't produce a valid event.
+	ErrNotRepairable = errors.New("event can't be repaired automatically")
+	// ErrPreconditionFailed is returned when an event changed on the server since it was read.
+	ErrPreconditionFailed = errors.New("event changed on the server")
+)
+
+// Repairs RepairICalendar can make, as reported in RepairResult.Fixes.
+const (
+	RepairLineEndings         = "line_endings"         // Bare CR or LF line breaks and blank lines
+	RepairLineFolding         = "line_folding"         // Continuation lines missing their leading space
+	RepairEscaping            = "escaping"             // Invalid backslash escapes in text values
+	RepairDuplicateProperties = "duplicate_properties" // Properties that may occur only once
+	RepairMissingDTStamp      = "missing_dtstamp"
+	RepairMissingUID          = "missing_uid"
+)
+
+// RepairTarget is where a repaired malformed event is written.
+type RepairTarget string
+
+const (
+	RepairToSource RepairTarget = "source" // Replace the event on the source if it hasn't changed since
+	RepairToDest   RepairTarget = "dest"   // Write the repaired event to the destination only
+)
+
+// RepairResult is a repaired calendar object.
+type RepairResult struct {
+	Event Event    // Data, UID and metadata of the repaired object; Path and ETag are empty
+	Fixes []string // Repairs that were made
+}
+
+// contentLine matches the start of a content line: an upper case property name,
+// optional parameters and the colon. Lines that don't match are taken to be
+// continuation lines that lost their leading space.
+var contentLine = regexp.MustCompile(`^[A-Z][A-Z0-9-]*(;[^:]*)?:`)
+
+// singleProps are the properties of a component that may occur at most once.
+var singleProps = map[string]map[string]bool{
+	ical.CompCalendar: setOf(ical.PropVersion, ical.PropProductID, ical.PropCalendarScale, ical.PropMethod),
+	ical.CompEvent: setOf(ical.PropClass, ical.PropCreated, ical.PropDescription, ical.PropDateTimeStart,
+		ical.PropGeo, ical.PropLastModified, ical.PropLocation, ical.PropOrganizer, ical.PropPriority,
+		ical.PropDateTimeStamp, ical.PropSequence, ical.PropStatus, ical.PropSummary, ical.PropTransparency,
+		ical.PropUID, ical.PropURL, ical.PropRecurrenceID, ical.PropRecurrenceRule, ical.PropDateTimeEnd,
+		ical.PropDuration),
+}
+
+// textProps are the single-valued text properties whose escaping is repaired.
+var textProps = setOf(ical.PropSummary, ical.PropDescription, ical.PropLocation, ical.PropComment)
+
+func setOf(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// RepairICalendar attempts to turn a calendar object the parser rejected into a valid
+// one. It fixes line endings, line folding, text escaping and duplicate properties and
+// adds a missing DTSTAMP or UID; events without a UID get the UID of another event in
+// the object, else fallbackUID. The result is checked by encoding it, so a returned
+// event can be written as is. It returns ErrNotRepairable if that still fails.
+func RepairICalendar(raw, fallbackUID string) (*RepairResult, error) {
+	var fixes []string
+	fix := func(name string) {
+		if !slices.Contains(fixes, name) {
+			fixes = append(fixes, name)
+		}
+	}
+
+	lines, err := unfoldRepairing(raw, fix)
+	if err != nil {
+		return nil, err
+	}
+
+	uid := fallbackUID
+	for _, line := range lines {
+		if name, value := splitContentLine(line); name == ical.PropUID && value != "" {
+			uid = value
+			break
+		}
+	}
+
+	type component struct {
+		name     string
+		seen     map[string]bool
+		hasUID   bool
+		hasStamp bool
+	}
+	var stack []*component
+	out := make([]string, 0, len(lines)+2)
+	for _, line := range lines {
+		name, value := splitContentLine(line)
+		switch name {
+		case "BEGIN":
+			stack = append(stack, &component{name: strings.ToUpper(value), seen: make(map[string]bool)})
+			out = append(out, line)
+			continue
+		case "END":
+			if len(stack) > 0 {
+				comp := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if comp.name == ical.CompEvent {
+					if !comp.hasStamp {
+						out = append(out, ical.PropDateTimeStamp+":"+time.Now().UTC().Format("20060102T150405Z"))
+						fix(RepairMissingDTStamp)
+					}
+					if !comp.hasUID {
+						if uid == "" {
+							return nil, fmt.Errorf("%w: event has no UID", ErrNotRepairable)
+						}
+						out = append(out, ical.PropUID+":"+uid)
+						fix(RepairMissingUID)
+					}
+				}
+			}
+			out = append(out, line)
+			continue
+		}
+		if len(stack) == 0 {
+			return nil, fmt.Errorf("%w: property %s outside of a component", ErrNotRepairable, name)
+		}
+
+		comp := stack[len(stack)-1]
+		if singleProps[comp.name][name] {
+			if comp.seen[name] {
+				fix(RepairDuplicateProperties)
+				continue
+			}
+			comp.seen[name] = true
+		}
+		switch name {
+		case ical.PropUID:
+			comp.hasUID = true
+		case ical.PropDateTimeStamp:
+			comp.hasStamp = true
+		}
+
+		if textProps[name] {
+			prefix := line[:len(line)-len(value)]
+			if escaped, changed := repairEscaping(value); changed {
+				line = prefix + escaped
+				fix(RepairEscaping)
+			}
+		}
+		out = append(out, line)
+	}
+
+	cal, err := parseICalendar(strings.Join(out, "\r\n") + "\r\n")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotRepairable, err)
+	}
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(cal); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotRepairable, err)
+	}
+	for _, evt := range cal.Events() {
+		for _, name := range []string{ical.PropSummary, ical.PropDescription, ical.PropLocation} {
+			if prop := evt.Props.Get(name); prop != nil {
+				if _, err := prop.Text(); err != nil {
+					return nil, fmt.Errorf("%w: %w", ErrNotRepairable, err)
+				}
+			}
+		}
+	}
+
+	result := &RepairResult{Event: Event{Data: buf.String()}, Fixes: fixes}
+	result.Event.readMetadata(cal)
+	return result, nil
+}
+
+// unfoldRepairing splits raw into unfolded content lines, accepting any mix of line
+// endings and joining continuation lines that lost their leading space.
+func unfoldRepairing(raw string, fix func(string)) ([]string, error) {
+	text := strings.ReplaceAll(raw, "\r\n", "\n")
+	if strings.ContainsRune(text, '\r') || strings.Count(raw, "\n") != strings.Count(raw, "\r\n") {
+		fix(RepairLineEndings)
+	}
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var lines []string
+	physical := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for _, line := range physical {
+		switch {
+		case line == "":
+			fix(RepairLineEndings)
+		case line[0] == ' ' || line[0] == '\t':
+			if len(lines) == 0 {
+				return nil, fmt.Errorf("%w: object starts with a continuation line", ErrNotRepairable)
+			}
+			lines[len(lines)-1] += line[1:]
+		case contentLine.MatchString(line):
+			lines = append(lines, line)
+		default:
+			if len(lines) == 0 {
+				return nil, fmt.Errorf("%w: object doesn't start with a property", ErrNotRepairable)
+			}
+			lines[len(lines)-1] += line
+			fix(RepairLineFolding)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: object is empty", ErrNotRepairable)
+	}
+	return lines, nil
+}
+
+// splitContentLine returns the upper-cased name and the value of a content line.
+// The value starts after the first colon outside of a quoted parameter value.
+func splitContentLine(line string) (string, string) {
+	end := strings.IndexAny(line, ";:")
+	if end < 0 {
+		return strings.ToUpper(line), ""
+	}
+	name := strings.ToUpper(line[:end])
+
+	quoted := false
+	for i := end; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				return name, line[i+1:]
+			}
+		}
+	}
+	return name, ""
+}
+
+// repairEscaping escapes backslashes in a text value that don't start a valid escape
+// sequence. It reports whether the value changed.
+func repairEscaping(value string) (string, bool) {
+	var b strings.Builder
+	changed := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		if i+1 < len(value) && strings.IndexByte(`\;,nN`, value[i+1]) >= 0 {
+			b.WriteByte(c)
+			b.WriteByte(value[i+1])
+			i++
+			continue
+		}
+		b.WriteString(`\\`)
+		changed = true
+	}
+	return b.String(), changed
+}
+
+// rawUID returns the first UID in an unparsable calendar object, or "".
+func rawUID(raw string) string {
+	for _, line := range strings.FieldsFunc(raw, func(r rune) bool { return r == '\r' || r == '\n' }) {
+		if name, value := splitContentLine(line); name == ical.PropUID {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// GetRawEvent fetches a calendar object as served, without parsing it, and returns
+// the data and its ETag.
+func (c *Client) GetRawEvent(ctx context.Context, eventPath string) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.buildURL(eventPath), nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return "", "", fmt.Errorf("%w: GET returned status %d", ErrAuthFailed, resp.StatusCode)
+	case resp.StatusCode == http.StatusNotFound:
+		return "", "", fmt.Errorf("%w: %s", ErrNotFound, eventPath)
+	case resp.StatusCode != http.StatusOK:
+		return "", "", fmt.Errorf("%w: GET returned status %d", ErrInvalidResponse, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRawEventSize+1))
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	if len(data) > maxRawEventSize {
+		return "", "", fmt.Errorf("%w: event is larger than %d bytes", ErrInvalidResponse, maxRawEventSize)
+	}
+	return string(data), resp.Header.Get("ETag"), nil
+}
+
+// PutRawEvent writes a calendar object as is. If etag isn't empty the object is
+// only replaced if it still has that ETag; otherwise ErrPreconditionFailed is returned.
+func (c *Client) PutRawEvent(ctx context.Context, eventPath, data, etag string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.buildURL(eventPath), strings.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return fmt.Errorf("%w: %s", ErrPreconditionFailed, eventPath)
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("%w: PUT returned status %d", ErrAuthFailed, resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: PUT returned status %d", ErrInvalidResponse, resp.StatusCode)
+	}
+}
+
+// recordMalformed records a malformed event in collector together with its raw
+// payload, if the payload can be fetched.
+func (c *Client) recordMalformed(ctx context.Context, collector *MalformedEventCollector, eventPath, errorMessage string) {
+	if collector == nil {
+		return
+	}
+	data, etag, err := c.GetRawEvent(ctx, eventPath)
+	if err != nil {
+		log.Printf("Failed to fetch raw payload of malformed event %s: %v", eventPath, err)
+	}
+	collector.AddPayload(eventPath, errorMessage, data, etag)
+}
+
+// recordMalformedEvents stores the malformed events of a calendar, each with its
+// encrypted payload and the outcome of repairing it, and clears the records of events
+// in the calendar that have since been fixed. It returns the UIDs of the
+// malformed events, which still exist on the source and must not be deleted elsewhere,
+// and the repaired events if the source syncs them.
+func (se *SyncEngine) recordMalformedEvents(source *db.Source, calendarHref, destHref string, collector *MalformedEventCollector) (map[string]bool, []Event) {
+	uids := make(map[string]bool)
+	var repaired []Event
+	var paths []string
+	for _, mf := range collector.GetEvents() {
+		record := &db.MalformedEvent{
+			SourceID:     source.ID,
+			EventPath:    mf.Path,
+			ErrorMessage: mf.ErrorMessage,
+			ETag:         mf.ETag,
+			CalendarHref: calendarHref,
+			DestHref:     destHref,
+		}
+
+		if mf.Data != "" {
+			record.EventUID = rawUID(mf.Data)
+			if result, err := RepairICalendar(mf.Data, malformedFallbackUID(mf.Path, record.EventUID)); err == nil {
+				record.Repairable = true
+				record.Repairs = result.Fixes
+				record.EventUID = result.Event.UID
+				if source.SyncRepairedEvents {
+					event := result.Event
+					event.Path = mf.Path
+					event.ETag = mf.ETag
+					repaired = append(repaired, event)
+				}
+			}
+			if se.encryptor != nil {
+				encrypted, err := se.encryptor.Encrypt(mf.Data)
+				if err != nil {
+					log.Printf("Failed to encrypt malformed event payload: %v", err)
+				} else {
+					record.RawPayload = encrypted
+				}
+			}
+		}
+		if record.EventUID != "" {
+			uids[record.EventUID] = true
+		}
+
+		if err := se.db.RecordMalformedEvent(record); err != nil {
+			log.Printf("Failed to save malformed event record: %v", err)
+		}
+		paths = append(paths, mf.Path)
+	}
+	if err := se.db.ClearResolvedMalformedEvents(source.ID, calendarHref, paths); err != nil {
+		log.Printf("Failed to clear resolved malformed events: %v", err)
+	}
+	return uids, repaired
+}
+
+// malformedFallbackUID is the UID given to a repaired event that has none: the UID
+// found in the raw payload, else the name of its file, which servers usually derive
+// from the UID.
+func malformedFallbackUID(eventPath, uid string) string {
+	if uid != "" {
+		return uid
+	}
+	name := strings.TrimSuffix(path.Base(eventPath), ".ics")
+	if name == "" || name == "." || name == "/" {
+		return uuid.New().String()
+	}
+	return name
+}
+
+// RepairMalformedEvent repairs the stored payload of a malformed event.
+func (se *SyncEngine) RepairMalformedEvent(mf *db.MalformedEvent) (*RepairResult, error) {
+	raw, err := se.MalformedEventPayload(mf)
+	if err != nil {
+		return nil, err
+	}
+	return RepairICalendar(raw, malformedFallbackUID(mf.EventPath, mf.EventUID))
+}
+
+// MalformedEventPayload returns the decrypted raw payload of a malformed event.
+func (se *SyncEngine) MalformedEventPayload(mf *db.MalformedEvent) (string, error) {
+	if mf.RawPayload == "" || se.encryptor == nil {
+		return "", fmt.Errorf("%w: payload of malformed event wasn't stored", ErrNotFound)
+	}
+	raw, err := se.encryptor.Decrypt(mf.RawPayload)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt malformed event payload: %w", err)
+	}
+	return raw, nil
+}
+
+// ApplyMalformedRepair repairs a malformed event and writes the result to target.
+// Writing to the source replaces the event only if it still has the ETag it had when
+// it was found, and forgets the record; writing to the destination leaves the source
+// untouched and keeps the record, as the source event is still malformed.
+func (se *SyncEngine) ApplyMalformedRepair(ctx context.Context, source *db.Source, mf *db.MalformedEvent, target RepairTarget) (*RepairResult, error) {
+	result, err := se.RepairMalformedEvent(mf)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &SyncResult{}
+	rec := se.newCalendarRecorder(source.ID, uuid.New().String(), mf.CalendarHref, changes)
+	event := result.Event
+	switch target {
+	case RepairToSource:
+		client, err := se.endpointClient(source, db.EndpointSource)
+		if err != nil {
+			return nil, err
+		}
+		event.Path = mf.EventPath
+		err = client.PutRawEvent(ctx, mf.EventPath, event.Data, mf.ETag)
+		rec.change(&event, db.ChangeUpdate, db.ChangeToSource, db.ReasonRepaired, err)
+		se.saveChanges(changes.Changes)
+		if err != nil {
+			return nil, err
+		}
+		if err := se.db.DeleteMalformedEvent(mf.ID); err != nil {
+			return nil, err
+		}
+	case RepairToDest:
+		if mf.DestHref == "" {
+			return nil, fmt.Errorf("%w: destination calendar of the event isn't known", ErrNotFound)
+		}
+		client, err := se.endpointClient(source, db.EndpointDest)
+		if err != nil {
+			return nil, err
+		}
+		err = client.PutEvent(ctx, mf.DestHref, &event)
+		rec.change(&event, db.ChangeCreate, db.ChangeToDest, db.ReasonRepaired, err)
+		se.saveChanges(changes.Changes)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid repair target %q", target)
+	}
+	return result, nil
+}
@@ -0,0 +1,277 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/macjediwizard/calbridgesync/internal/crypto"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// malformedEventData is missing its DTSTAMP, which the encoder requires.
+const malformedEventData = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\nBEGIN:VEVENT\r\nUID:event-1\r\nDTSTART:20240101T100000Z\r\nSUMMARY:Standup\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+func TestRepairICalendar(t *testing.T) {
+	const head = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\nBEGIN:VEVENT\r\n"
+	const tail = "END:VEVENT\r\nEND:VCALENDAR\r\n"
+	const stamp = "DTSTAMP:20240101T000000Z\r\nDTSTART:20240101T100000Z\r\n"
+
+	tests := []struct {
+		name    string
+		raw     string
+		fixes   []string
+		uid     string
+		summary string
+	}{
+		{
+			name:    "valid object is left as is",
+			raw:     probeEventData,
+			uid:     "event-1",
+			summary: "Standup",
+		},
+		{
+			name:    "bare line feeds and blank lines",
+			raw:     strings.ReplaceAll(probeEventData, "\r\n", "\n") + "\n\n",
+			fixes:   []string{RepairLineEndings},
+			uid:     "event-1",
+			summary: "Standup",
+		},
+		{
+			name:    "continuation line without leading space",
+			raw:     head + "UID:event-1\r\n" + stamp + "SUMMARY:Quarterly plan\r\nning session\r\n" + tail,
+			fixes:   []string{RepairLineFolding},
+			uid:     "event-1",
+			summary: "Quarterly planning session",
+		},
+		{
+			name:    "invalid escape sequences",
+			raw:     head + "UID:event-1\r\n" + stamp + `SUMMARY:Copy C:\temp\files` + "\r\n" + tail,
+			fixes:   []string{RepairEscaping},
+			uid:     "event-1",
+			summary: `Copy C:\temp\files`,
+		},
+		{
+			name:    "duplicate properties keep the first",
+			raw:     head + "UID:event-1\r\n" + stamp + "SUMMARY:Standup\r\nSUMMARY:Standup (copy)\r\n" + tail,
+			fixes:   []string{RepairDuplicateProperties},
+			uid:     "event-1",
+			summary: "Standup",
+		},
+		{
+			name:    "missing DTSTAMP and UID",
+			raw:     head + "DTSTART:20240101T100000Z\r\nSUMMARY:Standup\r\n" + tail,
+			fixes:   []string{RepairMissingDTStamp, RepairMissingUID},
+			uid:     "fallback-uid",
+			summary: "Standup",
+		},
+		{
+			name: "override without UID shares the master's",
+			raw: head + "UID:event-1\r\n" + stamp + "SUMMARY:Standup\r\nEND:VEVENT\r\nBEGIN:VEVENT\r\n" +
+				"RECURRENCE-ID:20240102T100000Z\r\n" + stamp + "SUMMARY:Standup\r\n" + tail,
+			fixes:   []string{RepairMissingUID},
+			uid:     "event-1",
+			summary: "Standup",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RepairICalendar(tt.raw, "fallback-uid")
+			if err != nil {
+				t.Fatalf("RepairICalendar() error = %v", err)
+			}
+			if !slices.Equal(result.Fixes, tt.fixes) {
+				t.Errorf("expected fixes %v, got %v", tt.fixes, result.Fixes)
+			}
+			if result.Event.UID != tt.uid || result.Event.Summary != tt.summary {
+				t.Errorf("expected UID %q and summary %q, got %q and %q", tt.uid, tt.summary, result.Event.UID, result.Event.Summary)
+			}
+			cal, err := parseICalendar(result.Event.Data)
+			if err != nil {
+				t.Fatalf("repaired data doesn't parse: %v", err)
+			}
+			if encodeCalendar(cal) == "" {
+				t.Errorf("repaired data doesn't encode:\n%s", result.Event.Data)
+			}
+		})
+	}
+
+	t.Run("unbalanced components can't be repaired", func(t *testing.T) {
+		raw := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\nBEGIN:VEVENT\r\nUID:event-1\r\nEND:VCALENDAR\r\n"
+		if _, err := RepairICalendar(raw, "fallback-uid"); !errors.Is(err, ErrNotRepairable) {
+			t.Errorf("expected ErrNotRepairable, got %v", err)
+		}
+	})
+
+	t.Run("other content can't be repaired", func(t *testing.T) {
+		if _, err := RepairICalendar("<html>Not found</html>", "fallback-uid"); !errors.Is(err, ErrNotRepairable) {
+			t.Errorf("expected ErrNotRepairable, got %v", err)
+		}
+	})
+}
+
+func TestRawEvents(t *testing.T) {
+	server := newProbeServer(t)
+	client, _ := NewClient(server.URL+"/dav/", "alice", "secret")
+	ctx := context.Background()
+
+	t.Run("GetRawEvent returns the payload and ETag", func(t *testing.T) {
+		data, etag, err := client.GetRawEvent(ctx, "/dav/calendars/alice/work/1.ics")
+		if err != nil {
+			t.Fatalf("GetRawEvent() error = %v", err)
+		}
+		if data != probeEventData || etag != `"e1"` {
+			t.Errorf("unexpected payload %q with ETag %q", data, etag)
+		}
+	})
+
+	t.Run("PutRawEvent honors the ETag", func(t *testing.T) {
+		err := client.PutRawEvent(ctx, "/dav/calendars/alice/work/1.ics", probeEventData, `"stale"`)
+		if !errors.Is(err, ErrPreconditionFailed) {
+			t.Errorf("expected ErrPreconditionFailed, got %v", err)
+		}
+		if err := client.PutRawEvent(ctx, "/dav/calendars/alice/work/1.ics", probeEventData, ""); err != nil {
+			t.Errorf("PutRawEvent() error = %v", err)
+		}
+	})
+}
+
+func TestRecordMalformedEvents(t *testing.T) {
+	_, database, sourceID := setupJournalTest(t)
+	enc, _ := crypto.NewEncryptor(make([]byte, 32))
+	engine := NewSyncEngine(database, enc)
+	source, _ := database.GetSourceByID(sourceID)
+	source.SyncRepairedEvents = true
+
+	collector := NewMalformedEventCollector()
+	collector.AddPayload("/cal/work/1.ics", "empty iCalendar data", malformedEventData, `"e1"`)
+	collector.AddPayload("/cal/work/2.ics", "missing colon", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:event-2\r\n", `"e2"`)
+	collector.Add("/cal/work/3.ics", "missing colon")
+
+	uids, repaired := engine.recordMalformedEvents(source, "/cal/work/", "/dest/work/", collector)
+	if !uids["event-1"] || !uids["event-2"] || len(uids) != 2 {
+		t.Errorf("expected the UIDs of both payloads to be protected, got %v", uids)
+	}
+	if len(repaired) != 1 || repaired[0].UID != "event-1" || repaired[0].Path != "/cal/work/1.ics" || repaired[0].ETag != `"e1"` {
+		t.Fatalf("expected the repaired event to be synced, got %+v", repaired)
+	}
+
+	user, _ := database.GetUserByID(source.UserID)
+	events, _ := database.GetMalformedEvents(user.ID)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(events))
+	}
+	byPath := make(map[string]*db.MalformedEvent)
+	for _, e := range events {
+		byPath[e.EventPath] = e
+	}
+
+	repairable := byPath["/cal/work/1.ics"]
+	if !repairable.Repairable || !slices.Equal(repairable.Repairs, []string{RepairMissingDTStamp}) || repairable.DestHref != "/dest/work/" {
+		t.Errorf("unexpected repairable record: %+v", repairable)
+	}
+	if repairable.RawPayload == malformedEventData {
+		t.Error("expected the payload to be stored encrypted")
+	}
+	if raw, err := engine.MalformedEventPayload(repairable); err != nil || raw != malformedEventData {
+		t.Errorf("expected the payload to decrypt, got %q (%v)", raw, err)
+	}
+
+	if broken := byPath["/cal/work/2.ics"]; broken.Repairable || broken.EventUID != "event-2" {
+		t.Errorf("unexpected unrepairable record: %+v", broken)
+	}
+	if _, err := engine.MalformedEventPayload(byPath["/cal/work/3.ics"]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound without a payload, got %v", err)
+	}
+
+	// The next run finds only the first event still malformed
+	collector = NewMalformedEventCollector()
+	collector.AddPayload("/cal/work/1.ics", "empty iCalendar data", malformedEventData, `"e1"`)
+	engine.recordMalformedEvents(source, "/cal/work/", "/dest/work/", collector)
+
+	events, _ = database.GetMalformedEvents(user.ID)
+	if len(events) != 1 || events[0].ID != repairable.ID {
+		t.Errorf("expected only the record of the first event to be kept under its ID, got %+v", events)
+	}
+}
+
+func TestApplyMalformedRepair(t *testing.T) {
+	_, database, sourceID := setupJournalTest(t)
+	enc, _ := crypto.NewEncryptor(make([]byte, 32))
+	engine := NewSyncEngine(database, enc)
+	server := newProbeServer(t)
+
+	source, _ := database.GetSourceByID(sourceID)
+	source.SourceURL = server.URL + "/dav/"
+	source.SourcePassword, _ = enc.Encrypt("secret")
+	source.DestURL = server.URL + "/dav/"
+	source.DestPassword, _ = enc.Encrypt("secret")
+	database.UpdateSource(source)
+
+	record := func(etag string) *db.MalformedEvent {
+		payload, _ := enc.Encrypt(malformedEventData)
+		database.RecordMalformedEvent(&db.MalformedEvent{
+			SourceID:     sourceID,
+			EventPath:    "/dav/calendars/alice/work/1.ics",
+			ErrorMessage: "empty iCalendar data",
+			RawPayload:   payload,
+			ETag:         etag,
+			EventUID:     "event-1",
+			CalendarHref: "/dav/calendars/alice/work/",
+			DestHref:     "/dav/calendars/alice/other/",
+			Repairable:   true,
+		})
+		user, _ := database.GetUserByID(source.UserID)
+		events, _ := database.GetMalformedEvents(user.ID)
+		return events[0]
+	}
+	ctx := context.Background()
+
+	t.Run("source changed since the event was found", func(t *testing.T) {
+		mf := record(`"stale"`)
+		if _, err := engine.ApplyMalformedRepair(ctx, source, mf, RepairToSource); !errors.Is(err, ErrPreconditionFailed) {
+			t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+		}
+		if _, err := database.GetMalformedEventByID(mf.ID); err != nil {
+			t.Errorf("expected the record to be kept, got %v", err)
+		}
+	})
+
+	t.Run("destination only", func(t *testing.T) {
+		mf := record("")
+		puts := server.count("PUT")
+		result, err := engine.ApplyMalformedRepair(ctx, source, mf, RepairToDest)
+		if err != nil || result.Event.UID != "event-1" {
+			t.Fatalf("ApplyMalformedRepair() = %+v, %v", result, err)
+		}
+		if server.count("PUT") != puts+1 {
+			t.Errorf("expected one PUT to the destination")
+		}
+		if _, err := database.GetMalformedEventByID(mf.ID); err != nil {
+			t.Errorf("expected the record to be kept while the source is malformed, got %v", err)
+		}
+	})
+
+	t.Run("source", func(t *testing.T) {
+		mf := record("")
+		if _, err := engine.ApplyMalformedRepair(ctx, source, mf, RepairToSource); err != nil {
+			t.Fatalf("ApplyMalformedRepair() error = %v", err)
+		}
+		if _, err := database.GetMalformedEventByID(mf.ID); !errors.Is(err, db.ErrNotFound) {
+			t.Errorf("expected the record to be forgotten, got %v", err)
+		}
+		changes, _, _ := database.GetEventChanges(sourceID, db.EventChangeFilter{EventUID: "event-1"})
+		repairs := 0
+		for _, change := range changes {
+			if change.Reason == db.ReasonRepaired {
+				repairs++
+			}
+		}
+		if repairs != 3 {
+			t.Errorf("expected every attempt in the audit trail, got %+v", changes)
+		}
+	})
+}
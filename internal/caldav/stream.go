@@ -0,0 +1,388 @@
+package caldav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// eventBatchSize is the number of events fetched per MULTIGET request, which also
+// bounds how many event bodies are held in memory at once.
+const eventBatchSize = 50
+
+// EventRef identifies a calendar object by path and ETag, as listed by PROPFIND.
+type EventRef struct {
+	Path string `json:"path"`
+	ETag string `json:"etag"`
+}
+
+// ListEventRefs lists the calendar objects of a collection with their ETags using a
+// Depth 1 PROPFIND. The response is decoded one entry at a time and no event bodies
+// are transferred, so memory use stays small even for very large calendars.
+func (c *Client) ListEventRefs(ctx context.Context, calendarPath string) ([]EventRef, error) {
+	fullURL := c.buildURL(calendarPath)
+
+	req, err := http.NewRequestWithContext(ctx, "PROPFIND", fullURL, strings.NewReader(`<?xml version="1.0" encoding="utf-8" ?>
+<D:propfind xmlns:D="DAV:">
+  <D:prop>
+    <D:getetag/>
+    <D:getcontenttype/>
+  </D:prop>
+</D:propfind>`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "1")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status %d", statusSentinel(resp.StatusCode), resp.StatusCode)
+	}
+
+	refs, err := decodeEventRefs(resp.Body, calendarPath)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Listed %d events via PROPFIND (calendarPath=%s)", len(refs), calendarPath)
+	return refs, nil
+}
+
+// decodeEventRefs reads a PROPFIND multistatus response one <response> element at a time
+// and returns the calendar objects it lists. The collection itself is skipped.
+func decodeEventRefs(r io.Reader, basePath string) ([]EventRef, error) {
+	type propfindEntry struct {
+		Href     string `xml:"href"`
+		PropStat []struct {
+			Prop struct {
+				ETag        string `xml:"getetag"`
+				ContentType string `xml:"getcontenttype"`
+			} `xml:"prop"`
+			Status string `xml:"status"`
+		} `xml:"propstat"`
+	}
+
+	refs := make([]EventRef, 0)
+	decoder := xml.NewDecoder(r)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to decode PROPFIND response: %w", ErrInvalidResponse, err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Space != "DAV:" || start.Name.Local != "response" {
+			continue
+		}
+
+		var entry propfindEntry
+		if err := decoder.DecodeElement(&entry, &start); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode PROPFIND response: %w", ErrInvalidResponse, err)
+		}
+
+		var etag, contentType string
+		for _, ps := range entry.PropStat {
+			if etag == "" {
+				etag = ps.Prop.ETag
+			}
+			if contentType == "" {
+				contentType = ps.Prop.ContentType
+			}
+		}
+
+		if isCollectionHref(entry.Href, basePath) {
+			continue
+		}
+		if !isCalendarObjectHref(entry.Href, contentType) {
+			log.Printf("decodeEventRefs: skipping non-event: href=%s contentType=%s", entry.Href, contentType)
+			continue
+		}
+		refs = append(refs, EventRef{Path: decodeHref(entry.Href), ETag: unquoteETag(etag)})
+	}
+
+	return refs, nil
+}
+
+// isCollectionHref reports whether href refers to the listed collection itself.
+func isCollectionHref(href, basePath string) bool {
+	return href == basePath || href+"/" == basePath || basePath+"/" == href
+}
+
+// isCalendarObjectHref reports whether a PROPFIND entry is a calendar object
+// (ends with .ics or has a calendar content type).
+func isCalendarObjectHref(href, contentType string) bool {
+	return strings.HasSuffix(href, ".ics") || strings.Contains(contentType, "calendar")
+}
+
+// unquoteETag strips the quotes from an ETag, matching the form go-webdav returns
+// from MULTIGET so both can be compared.
+func unquoteETag(etag string) string {
+	if unquoted, err := strconv.Unquote(etag); err == nil {
+		return unquoted
+	}
+	return etag
+}
+
+// decodeHref URL-decodes an href to avoid double-encoding when making requests.
+// If decoding fails the original href is returned.
+func decodeHref(href string) string {
+	decoded, err := url.PathUnescape(href)
+	if err != nil {
+		return href
+	}
+	return decoded
+}
+
+// FetchEvents retrieves the given calendar objects in MULTIGET batches of eventBatchSize
+// and hands each batch to fn; a batch whose MULTIGET fails is fetched one event at a time,
+// as is everything on servers whose profile says they don't support MULTIGET.
+// Only one batch of event bodies is held in memory at once. Malformed and empty events are
+// recorded in collector (if provided) and left out of the batches.
+func (c *Client) FetchEvents(ctx context.Context, calendarPath string, paths []string, collector *MalformedEventCollector, fn func([]Event) error) error {
+	skippedMalformed := 0
+	skippedEmpty := 0
+	total := len(paths)
+	caps := c.capabilities.Load()
+	multiGet := caps == nil || caps.MultiGet
+
+	for batchStart := 0; batchStart < total; batchStart += eventBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batchEnd := min(batchStart+eventBatchSize, total)
+		batchPaths := paths[batchStart:batchEnd]
+
+		log.Printf("Fetching events batch: %d-%d of %d (%.0f%%)", batchStart+1, batchEnd, total, float64(batchEnd)/float64(total)*100)
+
+		var batchEvents []Event
+		var malformed, empty int
+		var err error
+		if multiGet {
+			batchEvents, malformed, empty, err = c.getEventsBatch(ctx, calendarPath, batchPaths, collector)
+			if err != nil {
+				// If MULTIGET fails, fall back to individual fetches for this batch
+				log.Printf("MULTIGET failed, falling back to individual fetches: %v", err)
+			}
+		}
+		if !multiGet || err != nil {
+			batchEvents, malformed, empty = c.getEventsIndividually(ctx, batchPaths, collector)
+		}
+		skippedMalformed += malformed
+		skippedEmpty += empty
+
+		if err := fn(batchEvents); err != nil {
+			return err
+		}
+	}
+
+	if skippedMalformed > 0 {
+		log.Printf("Skipped %d malformed events (corrupted at source)", skippedMalformed)
+	}
+	if skippedEmpty > 0 {
+		log.Printf("Skipped %d empty events (no iCalendar data)", skippedEmpty)
+	}
+
+	return nil
+}
+
+// loadEventData fills in the iCalendar data of events that were listed without it.
+// Events whose body can't be fetched keep empty Data.
+func (c *Client) loadEventData(ctx context.Context, calendarPath string, events []*Event) error {
+	byPath := make(map[string]*Event, len(events))
+	paths := make([]string, 0, len(events))
+	for _, event := range events {
+		if event.Data != "" {
+			continue
+		}
+		byPath[event.Path] = event
+		paths = append(paths, event.Path)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	return c.FetchEvents(ctx, calendarPath, paths, nil, func(batch []Event) error {
+		for _, fetched := range batch {
+			if event, exists := byPath[fetched.Path]; exists {
+				event.Data = fetched.Data
+				event.ETag = fetched.ETag
+			}
+		}
+		return nil
+	})
+}
+
+// defaultEventIndexLimit caps the number of events the metadata index holds across all
+// calendars, so memory stays bounded however many large calendars are synced.
+const defaultEventIndexLimit = 50000
+
+// eventIndex caches event metadata (everything but the iCalendar data) per calendar
+// between sync runs. Entries are keyed by path and validated by ETag, so only new
+// or changed events have to be fetched to be compared. Once more than limit events
+// are cached, the least recently used calendars are evicted.
+type eventIndex struct {
+	mu        sync.Mutex
+	limit     int
+	size      int                         // Events cached across all calendars
+	calendars map[string]map[string]Event // calendar key -> path -> metadata
+	recent    []string                    // Calendar keys, least recently used first
+}
+
+func newEventIndex(limit int) *eventIndex {
+	return &eventIndex{limit: limit, calendars: make(map[string]map[string]Event)}
+}
+
+// WithEventIndexLimit sets how many events the metadata index keeps across all calendars.
+// Calendars with more events than the limit are fetched in full on every run; a limit
+// of 0 disables the index.
+func WithEventIndexLimit(limit int) SyncEngineOption {
+	return func(se *SyncEngine) {
+		if limit >= 0 {
+			se.index = newEventIndex(limit)
+		}
+	}
+}
+
+// indexKey identifies a calendar as seen by a particular account.
+func indexKey(client *Client, calendarPath string) string {
+	return client.username + "@" + client.buildURL(calendarPath)
+}
+
+// get returns the cached metadata of a calendar.
+func (x *eventIndex) get(key string) map[string]Event {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	events, exists := x.calendars[key]
+	if exists {
+		x.touch(key)
+	}
+	return events
+}
+
+// put replaces the cached metadata of a calendar. A calendar larger than the limit
+// isn't cached at all, which put reports by returning false.
+func (x *eventIndex) put(key string, events map[string]Event) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(key)
+	if len(events) > x.limit {
+		return false
+	}
+
+	x.calendars[key] = events
+	x.size += len(events)
+	x.recent = append(x.recent, key)
+	for x.size > x.limit {
+		x.remove(x.recent[0])
+	}
+	return true
+}
+
+// touch marks a calendar as most recently used.
+func (x *eventIndex) touch(key string) {
+	for i, k := range x.recent {
+		if k == key {
+			copy(x.recent[i:], x.recent[i+1:])
+			x.recent[len(x.recent)-1] = key
+			return
+		}
+	}
+}
+
+// remove drops a calendar from the index.
+func (x *eventIndex) remove(key string) {
+	events, exists := x.calendars[key]
+	if !exists {
+		return
+	}
+	delete(x.calendars, key)
+	x.size -= len(events)
+	for i, k := range x.recent {
+		if k == key {
+			x.recent = append(x.recent[:i], x.recent[i+1:]...)
+			break
+		}
+	}
+}
+
+// listEventMetadata returns metadata of every event in a calendar: Path, ETag, UID, Summary
+// and StartTime, with empty Data. Objects are listed with PROPFIND and only those not in the
+// index with the same ETag are fetched, in bounded batches whose bodies are dropped once their
+// metadata is extracted. Servers whose PROPFIND lists nothing fall back to a calendar-query
+// for ETags only, unless their profile says calendar-query isn't reliable; the events it
+// lists are fetched the same way.
+func (se *SyncEngine) listEventMetadata(ctx context.Context, client *Client, calendarPath string, collector *MalformedEventCollector) ([]Event, error) {
+	refs, err := client.ListEventRefs(ctx, calendarPath)
+	caps := client.capabilities.Load()
+	useQuery := caps == nil || caps.CalendarQuery
+	if err != nil && !useQuery {
+		return nil, err
+	}
+	if useQuery && (err != nil || len(refs) == 0) {
+		if err != nil {
+			log.Printf("PROPFIND listing failed, trying calendar query: %v", err)
+		}
+		queried, queryErr := client.queryEventRefs(ctx, calendarPath, false)
+		if queryErr != nil {
+			if err != nil {
+				return nil, err
+			}
+			// PROPFIND succeeded with an empty calendar; trust it
+			return []Event{}, nil
+		}
+		refs = queried
+	}
+
+	key := indexKey(client, calendarPath)
+	cached := se.index.get(key)
+
+	events := make([]Event, 0, len(refs))
+	current := make(map[string]Event, len(refs))
+	var missing []string
+	for _, ref := range refs {
+		if meta, exists := cached[ref.Path]; exists && ref.ETag != "" && meta.ETag == ref.ETag {
+			events = append(events, meta)
+			current[ref.Path] = meta
+			continue
+		}
+		missing = append(missing, ref.Path)
+	}
+	if len(missing) > 0 {
+		log.Printf("Fetching %d new or changed events (%d unchanged)", len(missing), len(refs)-len(missing))
+	}
+
+	err = client.FetchEvents(ctx, calendarPath, missing, collector, func(batch []Event) error {
+		for _, event := range batch {
+			event.Data = ""
+			events = append(events, event)
+			current[event.Path] = event
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !se.index.put(key, current) && se.index.limit > 0 {
+		log.Printf("Not indexing %d events of calendar %s: above the event index limit of %d, all events are fetched every run",
+			len(current), calendarPath, se.index.limit)
+	}
+
+	return events, nil
+}
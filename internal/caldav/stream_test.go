@@ -0,0 +1,330 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeCalendarServer serves a single calendar at /cal/ over PROPFIND, calendar-query
+// and MULTIGET and records how many event bodies each MULTIGET asked for.
+type fakeCalendarServer struct {
+	mu           sync.Mutex
+	etags        map[string]string // event path -> ETag
+	multiget     []int             // number of hrefs per MULTIGET request
+	hidePropfind bool              // PROPFIND lists no events, like some servers do
+	summary      string            // Summary of every event (default: its UID)
+}
+
+var hrefPattern = regexp.MustCompile(`<(?:[A-Za-z]+:)?href[^>]*>([^<]+)</(?:[A-Za-z]+:)?href>`)
+
+func newFakeCalendarServer(count int) *fakeCalendarServer {
+	f := &fakeCalendarServer{etags: make(map[string]string)}
+	for i := 0; i < count; i++ {
+		f.etags[fmt.Sprintf("/cal/event-%d.ics", i)] = `"1"`
+	}
+	return f
+}
+
+func (f *fakeCalendarServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?><D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">`)
+
+	switch r.Method {
+	case "PROPFIND":
+		b.WriteString(`<D:response><D:href>/cal/</D:href><D:propstat><D:prop><D:getetag>"c"</D:getetag></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`)
+		if !f.hidePropfind {
+			f.writeRefs(&b)
+		}
+	case "REPORT":
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "calendar-query") {
+			f.writeRefs(&b)
+			break
+		}
+		hrefs := hrefPattern.FindAllStringSubmatch(string(body), -1)
+		f.multiget = append(f.multiget, len(hrefs))
+		for _, m := range hrefs {
+			path := m[1]
+			uid := strings.TrimSuffix(strings.TrimPrefix(path, "/cal/"), ".ics")
+			summary := uid
+			if f.summary != "" {
+				summary = f.summary
+			}
+			data := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\nBEGIN:VEVENT\r\nUID:" + uid +
+				"\r\nDTSTAMP:20250101T000000Z\r\nDTSTART:20250101T100000Z\r\nSUMMARY:" + summary + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+			fmt.Fprintf(&b, `<D:response><D:href>%s</D:href><D:propstat><D:prop><D:getetag>%s</D:getetag><C:calendar-data>%s</C:calendar-data></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`,
+				path, f.etags[path], data)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	b.WriteString(`</D:multistatus>`)
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, b.String())
+}
+
+// writeRefs lists every event with its ETag and no data.
+func (f *fakeCalendarServer) writeRefs(b *strings.Builder) {
+	for path, etag := range f.etags {
+		fmt.Fprintf(b, `<D:response><D:href>%s</D:href><D:propstat><D:prop><D:getetag>%s</D:getetag><D:getcontenttype>text/calendar</D:getcontenttype></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`, path, etag)
+	}
+}
+
+// fetched returns the total number of bodies requested and resets the counter.
+func (f *fakeCalendarServer) fetched() (total int, requests []int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, n := range f.multiget {
+		total += n
+	}
+	requests = f.multiget
+	f.multiget = nil
+	return total, requests
+}
+
+func TestDecodeEventRefs(t *testing.T) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:">
+  <d:response>
+    <d:href>/calendars/user/cal/</d:href>
+    <d:propstat><d:prop><d:getetag>"collection"</d:getetag></d:prop></d:propstat>
+  </d:response>
+  <d:response>
+    <d:href>/calendars/user/cal/a%40b.ics</d:href>
+    <d:propstat><d:prop><d:getetag>"1"</d:getetag></d:prop></d:propstat>
+  </d:response>
+  <d:response>
+    <d:href>/calendars/user/cal/noext</d:href>
+    <d:propstat><d:prop><d:getetag>"2"</d:getetag><d:getcontenttype>text/calendar</d:getcontenttype></d:prop></d:propstat>
+  </d:response>
+  <d:response>
+    <d:href>/calendars/user/cal/notes.txt</d:href>
+    <d:propstat><d:prop><d:getcontenttype>text/plain</d:getcontenttype></d:prop></d:propstat>
+  </d:response>
+</d:multistatus>`
+
+	refs, err := decodeEventRefs(strings.NewReader(body), "/calendars/user/cal/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []EventRef{
+		{Path: "/calendars/user/cal/a@b.ics", ETag: "1"},
+		{Path: "/calendars/user/cal/noext", ETag: "2"},
+	}
+	if len(refs) != len(expected) {
+		t.Fatalf("expected %d refs, got %+v", len(expected), refs)
+	}
+	for i := range expected {
+		if refs[i] != expected[i] {
+			t.Errorf("ref %d: expected %+v, got %+v", i, expected[i], refs[i])
+		}
+	}
+
+	t.Run("rejects malformed XML", func(t *testing.T) {
+		if _, err := decodeEventRefs(strings.NewReader("<d:multistatus xmlns:d=\"DAV:\"><d:response>"), "/"); err == nil {
+			t.Error("expected error for truncated response")
+		}
+	})
+}
+
+func TestFetchEvents(t *testing.T) {
+	fake := newFakeCalendarServer(eventBatchSize + 10)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client, err := NewClient(server.URL+"/cal/", "user", "pass")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	refs, err := client.ListEventRefs(context.Background(), "/cal/")
+	if err != nil {
+		t.Fatalf("ListEventRefs failed: %v", err)
+	}
+	if len(refs) != eventBatchSize+10 {
+		t.Fatalf("expected %d refs, got %d", eventBatchSize+10, len(refs))
+	}
+
+	paths := make([]string, len(refs))
+	for i, ref := range refs {
+		paths[i] = ref.Path
+	}
+
+	var batches []int
+	total := 0
+	err = client.FetchEvents(context.Background(), "/cal/", paths, nil, func(batch []Event) error {
+		batches = append(batches, len(batch))
+		for _, event := range batch {
+			if event.Data == "" || event.UID == "" {
+				t.Errorf("expected parsed event, got %+v", event)
+			}
+		}
+		total += len(batch)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("FetchEvents failed: %v", err)
+	}
+
+	if total != eventBatchSize+10 {
+		t.Errorf("expected %d events, got %d", eventBatchSize+10, total)
+	}
+	if len(batches) != 2 || batches[0] != eventBatchSize || batches[1] != 10 {
+		t.Errorf("expected batches of %d and 10, got %v", eventBatchSize, batches)
+	}
+}
+
+func TestListEventMetadata(t *testing.T) {
+	fake := newFakeCalendarServer(5)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client, err := NewClient(server.URL+"/cal/", "user", "pass")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	engine := NewSyncEngine(nil, nil)
+
+	t.Run("fetches metadata without keeping bodies", func(t *testing.T) {
+		events, err := engine.listEventMetadata(context.Background(), client, "/cal/", nil)
+		if err != nil {
+			t.Fatalf("listEventMetadata failed: %v", err)
+		}
+		if len(events) != 5 {
+			t.Fatalf("expected 5 events, got %d", len(events))
+		}
+		for _, event := range events {
+			if event.Data != "" {
+				t.Errorf("expected no data for %s", event.Path)
+			}
+			if event.UID == "" || event.Summary == "" || event.ETag == "" {
+				t.Errorf("expected metadata for %s, got %+v", event.Path, event)
+			}
+		}
+		if total, _ := fake.fetched(); total != 5 {
+			t.Errorf("expected 5 bodies fetched, got %d", total)
+		}
+	})
+
+	t.Run("only fetches changed events on the next run", func(t *testing.T) {
+		fake.mu.Lock()
+		fake.etags["/cal/event-2.ics"] = `"2"`
+		fake.mu.Unlock()
+
+		events, err := engine.listEventMetadata(context.Background(), client, "/cal/", nil)
+		if err != nil {
+			t.Fatalf("listEventMetadata failed: %v", err)
+		}
+		if len(events) != 5 {
+			t.Fatalf("expected 5 events, got %d", len(events))
+		}
+		if total, _ := fake.fetched(); total != 1 {
+			t.Errorf("expected only the changed event to be fetched, got %d", total)
+		}
+		for _, event := range events {
+			if event.Path == "/cal/event-2.ics" && event.ETag != "2" {
+				t.Errorf("expected updated ETag, got %s", event.ETag)
+			}
+		}
+	})
+
+	t.Run("loads bodies on demand", func(t *testing.T) {
+		events, err := engine.listEventMetadata(context.Background(), client, "/cal/", nil)
+		if err != nil {
+			t.Fatalf("listEventMetadata failed: %v", err)
+		}
+
+		wanted := []*Event{&events[0], &events[1]}
+		if err := client.loadEventData(context.Background(), "/cal/", wanted); err != nil {
+			t.Fatalf("loadEventData failed: %v", err)
+		}
+		for _, event := range wanted {
+			if !strings.Contains(event.Data, "UID:"+event.UID) {
+				t.Errorf("expected data for %s, got %q", event.Path, event.Data)
+			}
+		}
+		if _, requests := fake.fetched(); len(requests) != 1 || requests[0] != 2 {
+			t.Errorf("expected one MULTIGET for 2 events, got %v", requests)
+		}
+	})
+}
+
+func TestListEventMetadataViaQuery(t *testing.T) {
+	fake := newFakeCalendarServer(eventBatchSize + 10)
+	fake.hidePropfind = true
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client, err := NewClient(server.URL+"/cal/", "user", "pass")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	engine := NewSyncEngine(nil, nil)
+
+	events, err := engine.listEventMetadata(context.Background(), client, "/cal/", nil)
+	if err != nil {
+		t.Fatalf("listEventMetadata failed: %v", err)
+	}
+	if len(events) != eventBatchSize+10 {
+		t.Fatalf("expected %d events, got %d", eventBatchSize+10, len(events))
+	}
+	for _, event := range events {
+		if event.Data != "" || event.UID == "" {
+			t.Errorf("expected metadata only for %s, got %+v", event.Path, event)
+		}
+	}
+	if _, requests := fake.fetched(); len(requests) != 2 || requests[0] != eventBatchSize || requests[1] != 10 {
+		t.Errorf("expected bodies to be fetched in batches of %d and 10, got %v", eventBatchSize, requests)
+	}
+}
+
+func TestEventIndexLimit(t *testing.T) {
+	metadata := func(n int) map[string]Event {
+		events := make(map[string]Event, n)
+		for i := 0; i < n; i++ {
+			path := fmt.Sprintf("/cal/event-%d.ics", i)
+			events[path] = Event{Path: path, ETag: "1"}
+		}
+		return events
+	}
+
+	index := newEventIndex(10)
+	index.put("a", metadata(4))
+	index.put("b", metadata(4))
+	index.get("a")
+	index.put("c", metadata(4))
+
+	if index.get("b") != nil {
+		t.Error("expected the least recently used calendar to be evicted")
+	}
+	if index.get("a") == nil || index.get("c") == nil {
+		t.Error("expected recently used calendars to be kept")
+	}
+	if index.size != 8 {
+		t.Errorf("expected 8 cached events, got %d", index.size)
+	}
+
+	if index.put("a", metadata(11)) {
+		t.Error("expected put to report that the calendar wasn't cached")
+	}
+	if index.get("a") != nil {
+		t.Error("expected a calendar above the limit not to be cached")
+	}
+	if index.size != 4 {
+		t.Errorf("expected 4 cached events, got %d", index.size)
+	}
+}
@@ -2,12 +2,16 @@ package caldav
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/macjediwizard/calbridgesync/internal/activity"
 	"github.com/macjediwizard/calbridgesync/internal/crypto"
 	"github.com/macjediwizard/calbridgesync/internal/db"
@@ -44,6 +48,27 @@ func getSyncDirectionForCalendar(source *db.Source, calendarPath string) db.Sync
 	return source.SyncDirection
 }
 
+// selectedCalendars returns the calendars a sync of the source covers: the selected
+// ones, or all of them if none are selected.
+func selectedCalendars(source *db.Source, calendars []Calendar) []Calendar {
+	if len(source.SelectedCalendars) == 0 {
+		return calendars
+	}
+
+	selectedSet := make(map[string]bool)
+	for _, calConfig := range source.SelectedCalendars {
+		selectedSet[calConfig.Path] = true
+	}
+
+	var filtered []Calendar
+	for _, cal := range calendars {
+		if selectedSet[cal.Path] {
+			filtered = append(filtered, cal)
+		}
+	}
+	return filtered
+}
+
 // SyncResult represents the result of a sync operation.
 type SyncResult struct {
 	Success           bool          `json:"success"`
@@ -58,6 +83,37 @@ type SyncResult struct {
 	Errors            []string      `json:"errors,omitempty"`   // Critical errors that prevent sync
 	Warnings          []string      `json:"warnings,omitempty"` // Non-critical issues (individual event failures)
 	Duration          time.Duration `json:"duration"`
+	Throttle          ThrottleStats `json:"throttle"`                     // HTTP throttling observed on source and destination
+	FailureKind       FailureKind   `json:"failure_kind,omitempty"`       // Why the sync failed (auth, network, server)
+	Cancelled         bool          `json:"cancelled,omitempty"`          // Sync was stopped on request before it finished
+	ReconnectRequired bool          `json:"reconnect_required,omitempty"` // OAuth2 grant revoked or expired; the account must be authorized again
+	Mode              db.SyncMode   `json:"mode,omitempty"`               // How a calendar was synced; empty for sources
+
+	// Audit trail of the writes made, stored when the run finishes
+	Changes []*db.EventChange `json:"-"`
+
+	// Results of the individual calendars of a source, stored with its sync log
+	Calendars []*db.CalendarSyncLog `json:"-"`
+}
+
+// ErrSyncCancelled is the cancellation cause used to stop a running sync on request.
+// Callers cancel the sync context with context.WithCancelCause and this error.
+var ErrSyncCancelled = errors.New("sync cancelled")
+
+// syncCancelled reports whether ctx was cancelled with ErrSyncCancelled.
+func syncCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrSyncCancelled)
+}
+
+// writeContext returns the context event writes of a sync run are sent with. Cancelling
+// the run doesn't reach it, so a write already in flight completes and is recorded; the
+// run stops between writes instead. The run's deadline still bounds every write.
+func writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	writeCtx := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(writeCtx, deadline)
+	}
+	return context.WithCancel(writeCtx)
 }
 
 // sanitizeLogDetails removes potentially sensitive information from sync log details.
@@ -108,15 +164,31 @@ type SyncEngine struct {
 	db        *db.DB
 	encryptor *crypto.Encryptor
 	tracker   *activity.Tracker
+
+	maxCalendarConcurrency int         // Global cap on calendars synced in parallel per source
+	maxEventConcurrency    int         // Global cap on event writes in flight per calendar
+	destLocks              keyedMutex  // Serializes duplicate cleanup per destination calendar
+	index                  *eventIndex // Event metadata from previous runs, validated by ETag
+	clients                *ClientPool // CalDAV clients reused across runs
 }
 
 // NewSyncEngine creates a new sync engine.
-func NewSyncEngine(database *db.DB, encryptor *crypto.Encryptor) *SyncEngine {
-	return &SyncEngine{
-		db:        database,
-		encryptor: encryptor,
-		tracker:   activity.NewTracker(),
+func NewSyncEngine(database *db.DB, encryptor *crypto.Encryptor, opts ...SyncEngineOption) *SyncEngine {
+	se := &SyncEngine{
+		db:                     database,
+		encryptor:              encryptor,
+		tracker:                activity.NewTracker(),
+		maxCalendarConcurrency: defaultMaxCalendarConcurrency,
+		maxEventConcurrency:    defaultMaxEventConcurrency,
+		index:                  newEventIndex(defaultEventIndexLimit),
+		clients:                NewClientPool(defaultDiscoveryTTL),
+	}
+
+	for _, opt := range opts {
+		opt(se)
 	}
+
+	return se
 }
 
 // GetActivityTracker returns the activity tracker for external use.
@@ -140,58 +212,89 @@ func (se *SyncEngine) SyncSource(ctx context.Context, source *db.Source) *SyncRe
 	}
 
 	// Decrypt credentials - NEVER log these
-	sourcePassword, err := se.encryptor.Decrypt(source.SourcePassword)
+	sourceCreds, err := SourceCredentials(se.db, se.encryptor, source, db.EndpointSource)
 	if err != nil {
 		result.Message = "Failed to decrypt source credentials"
 		result.Errors = append(result.Errors, err.Error())
 		result.Duration = time.Since(start)
-		se.finishSync(source.ID, result)
+		se.finishSync(ctx, source.ID, result)
 		return result
 	}
 
-	destPassword, err := se.encryptor.Decrypt(source.DestPassword)
+	destCreds, err := SourceCredentials(se.db, se.encryptor, source, db.EndpointDest)
 	if err != nil {
 		result.Message = "Failed to decrypt destination credentials"
 		result.Errors = append(result.Errors, err.Error())
 		result.Duration = time.Since(start)
-		se.finishSync(source.ID, result)
+		se.finishSync(ctx, source.ID, result)
+		return result
+	}
+
+	sourceTransport, err := DecryptTransportOptions(se.encryptor, source.SourceTransport)
+	if err != nil {
+		result.Message = "Failed to decrypt source transport settings"
+		result.Errors = append(result.Errors, err.Error())
+		result.Duration = time.Since(start)
+		se.finishSync(ctx, source.ID, result)
 		return result
 	}
 
-	// Create source client
-	sourceClient, err := NewClient(source.SourceURL, source.SourceUsername, sourcePassword)
+	destTransport, err := DecryptTransportOptions(se.encryptor, source.DestTransport)
+	if err != nil {
+		result.Message = "Failed to decrypt destination transport settings"
+		result.Errors = append(result.Errors, err.Error())
+		result.Duration = time.Since(start)
+		se.finishSync(ctx, source.ID, result)
+		return result
+	}
+
+	// Get clients from the pool; their connections and discovery results are reused across runs
+	sourceClient, err := se.clients.Get(source.SourceURL, sourceCreds, sourceTransport)
 	if err != nil {
 		result.Message = "Failed to connect to source"
 		result.Errors = append(result.Errors, err.Error())
+		result.FailureKind = ClassifyError(err)
+		result.ReconnectRequired = errors.Is(err, ErrReconnectRequired)
 		result.Duration = time.Since(start)
-		se.finishSync(source.ID, result)
+		se.finishSync(ctx, source.ID, result)
 		return result
 	}
 
-	// Create destination client
-	destClient, err := NewClient(source.DestURL, source.DestUsername, destPassword)
+	destClient, err := se.clients.Get(source.DestURL, destCreds, destTransport)
 	if err != nil {
 		result.Message = "Failed to connect to destination"
 		result.Errors = append(result.Errors, err.Error())
+		result.FailureKind = ClassifyError(err)
+		result.ReconnectRequired = errors.Is(err, ErrReconnectRequired)
 		result.Duration = time.Since(start)
-		se.finishSync(source.ID, result)
+		se.finishSync(ctx, source.ID, result)
 		return result
 	}
 
+	// Pooled clients accumulate throttle stats, so report only what this run added.
+	// Runs of other sources sharing a client may be counted too.
+	throttleBaseline := collectThrottleStats(sourceClient, destClient)
+
 	// Test connections
-	if err := sourceClient.TestConnection(ctx); err != nil {
+	if err := sourceClient.checkConnection(ctx); err != nil {
 		result.Message = "Source connection test failed"
 		result.Errors = append(result.Errors, err.Error())
+		result.FailureKind = ClassifyError(err)
+		result.ReconnectRequired = errors.Is(err, ErrReconnectRequired)
 		result.Duration = time.Since(start)
-		se.finishSync(source.ID, result)
+		result.Throttle = collectThrottleStats(sourceClient, destClient).Sub(throttleBaseline)
+		se.finishSync(ctx, source.ID, result)
 		return result
 	}
 
-	if err := destClient.TestConnection(ctx); err != nil {
+	if err := destClient.checkConnection(ctx); err != nil {
 		result.Message = "Destination connection test failed"
 		result.Errors = append(result.Errors, err.Error())
+		result.FailureKind = ClassifyError(err)
+		result.ReconnectRequired = errors.Is(err, ErrReconnectRequired)
 		result.Duration = time.Since(start)
-		se.finishSync(source.ID, result)
+		result.Throttle = collectThrottleStats(sourceClient, destClient).Sub(throttleBaseline)
+		se.finishSync(ctx, source.ID, result)
 		return result
 	}
 
@@ -200,8 +303,11 @@ func (se *SyncEngine) SyncSource(ctx context.Context, source *db.Source) *SyncRe
 	if err != nil {
 		result.Message = "Failed to find source calendars"
 		result.Errors = append(result.Errors, err.Error())
+		result.FailureKind = ClassifyError(err)
+		result.ReconnectRequired = errors.Is(err, ErrReconnectRequired)
 		result.Duration = time.Since(start)
-		se.finishSync(source.ID, result)
+		result.Throttle = collectThrottleStats(sourceClient, destClient).Sub(throttleBaseline)
+		se.finishSync(ctx, source.ID, result)
 		return result
 	}
 
@@ -211,43 +317,56 @@ func (se *SyncEngine) SyncSource(ctx context.Context, source *db.Source) *SyncRe
 		log.Printf("  [%d] Name: %q, Path: %s", i+1, cal.Name, cal.Path)
 	}
 
+	// Follow calendars that moved and warn about selected calendars that are gone
+	result.Warnings = append(result.Warnings, se.trackCalendars(source, sourceCalendars)...)
+
 	// Filter calendars based on selected_calendars setting
 	if len(source.SelectedCalendars) > 0 {
-		selectedSet := make(map[string]bool)
-		for _, calConfig := range source.SelectedCalendars {
-			selectedSet[calConfig.Path] = true
-		}
-
-		var filteredCalendars []Calendar
-		for _, cal := range sourceCalendars {
-			if selectedSet[cal.Path] {
-				filteredCalendars = append(filteredCalendars, cal)
-			}
-		}
-
+		filteredCalendars := selectedCalendars(source, sourceCalendars)
 		log.Printf("Filtered to %d selected calendars (from %d discovered)", len(filteredCalendars), len(sourceCalendars))
 		sourceCalendars = filteredCalendars
 	}
 
+	// Pick strategies from the servers' profiles, probing them when they're unknown or old
+	if len(sourceCalendars) > 0 {
+		se.loadCapabilities(ctx, source, db.EndpointSource, sourceClient, sourceCalendars[0].Path)
+	}
+	se.loadCapabilities(ctx, source, db.EndpointDest, destClient, probeCalendarPath(ctx, destClient))
+
 	// Start activity tracking
 	se.tracker.StartSync(source.ID, source.Name, len(sourceCalendars))
 
-	// Sync each calendar
-	for i, cal := range sourceCalendars {
-		// Update activity tracker with current calendar
-		se.tracker.UpdateCalendar(source.ID, cal.Name, i+1)
-
-		calResult := se.syncCalendar(ctx, source, sourceClient, destClient, cal, i+1)
-		result.Created += calResult.Created
-		result.Updated += calResult.Updated
-		result.Deleted += calResult.Deleted
-		result.Skipped += calResult.Skipped
-		result.EventsProcessed += calResult.EventsProcessed
-		result.Errors = append(result.Errors, calResult.Errors...)
-		result.Warnings = append(result.Warnings, calResult.Warnings...)
+	// Writes of this run are journaled under runID until the run finishes.
+	// A journal that survives (because the process died) is reconciled by RecoverJournals.
+	runID := uuid.New().String()
+	defer func() {
+		if err := se.db.DeleteJournalRun(runID); err != nil {
+			log.Printf("Failed to clear sync journal: %v", err)
+		}
+	}()
 
-		// Update progress in activity tracker
-		se.tracker.UpdateProgress(source.ID, result.Created, result.Updated, result.Deleted, result.Skipped, result.EventsProcessed)
+	// Sync calendars, several at a time if the source allows it.
+	// Calendar results are merged as they finish; progress is reported to the tracker live.
+	calendarLimit := se.calendarConcurrency(source)
+	if calendarLimit > 1 {
+		log.Printf("Syncing %d calendars with concurrency %d", len(sourceCalendars), calendarLimit)
+	}
+	sourceRecorder := se.newResultRecorder(source.ID, result)
+	runBounded(ctx, len(sourceCalendars), calendarLimit, func(i int) {
+		cal := sourceCalendars[i]
+		se.tracker.SetCalendarStatus(source.ID, cal.Path, cal.Name)
+
+		calStart := time.Now()
+		calResult := se.syncCalendar(ctx, source, sourceClient, destClient, cal, runID)
+		se.syncCalendarMetadata(ctx, source, destClient, cal, calResult)
+		calResult.Duration = time.Since(calStart)
+		sourceRecorder.merge(calResult)
+		sourceRecorder.addCalendar(calendarSyncLog(cal, calResult))
+
+		se.tracker.FinishCalendar(source.ID, cal.Path)
+	})
+	if ctx.Err() != nil && !syncCancelled(ctx) {
+		result.Errors = append(result.Errors, fmt.Sprintf("Sync interrupted: %v", ctx.Err()))
 	}
 
 	result.CalendarsSynced = len(sourceCalendars)
@@ -261,15 +380,64 @@ func (se *SyncEngine) SyncSource(ctx context.Context, source *db.Source) *SyncRe
 			len(sourceCalendars), len(result.Warnings), result.Created, result.Updated, result.Deleted, result.Skipped)
 	} else {
 		result.Message = fmt.Sprintf("Sync failed with %d errors", len(result.Errors))
+		// Calendars may have moved or credentials changed; rediscover next run
+		sourceClient.resetDiscovery()
+		destClient.resetDiscovery()
 	}
 
 	result.Duration = time.Since(start)
-	se.finishSync(source.ID, result)
+	result.Throttle = collectThrottleStats(sourceClient, destClient).Sub(throttleBaseline)
+	if result.Throttle.Throttled > 0 {
+		log.Printf("Sync for %s was throttled: %d throttled responses, %d retries, waited %s",
+			source.Name, result.Throttle.Throttled, result.Throttle.Retries, result.Throttle.WaitTime.Round(time.Millisecond))
+	}
+	se.finishSync(ctx, source.ID, result)
 
 	return result
 }
 
-func (se *SyncEngine) syncCalendar(ctx context.Context, source *db.Source, sourceClient, destClient *Client, calendar Calendar, calendarIndex int) *SyncResult {
+// calendarSyncLog summarizes the result of syncing one calendar for the sync log.
+func calendarSyncLog(cal Calendar, result *SyncResult) *db.CalendarSyncLog {
+	status := db.SyncStatusSuccess
+	if len(result.Errors) > 0 {
+		status = db.SyncStatusError
+	} else if len(result.Warnings) > 0 {
+		status = db.SyncStatusPartial
+	}
+
+	var details []string
+	if len(result.Errors) > 0 {
+		details = append(details, fmt.Sprintf("Errors: %v", result.Errors))
+	}
+	if len(result.Warnings) > 0 {
+		details = append(details, fmt.Sprintf("Warnings: %v", result.Warnings))
+	}
+
+	return &db.CalendarSyncLog{
+		CalendarHref:    cal.Path,
+		CalendarName:    cal.Name,
+		Status:          status,
+		Mode:            result.Mode,
+		EventsCreated:   result.Created,
+		EventsUpdated:   result.Updated,
+		EventsDeleted:   result.Deleted,
+		EventsSkipped:   result.Skipped,
+		EventsProcessed: result.EventsProcessed,
+		Duration:        result.Duration,
+		Details:         sanitizeLogDetails(strings.Join(details, "\n")),
+	}
+}
+
+// collectThrottleStats sums the throttling statistics of the given clients.
+func collectThrottleStats(clients ...*Client) ThrottleStats {
+	var stats ThrottleStats
+	for _, c := range clients {
+		stats = stats.Add(c.ThrottleStats())
+	}
+	return stats
+}
+
+func (se *SyncEngine) syncCalendar(ctx context.Context, source *db.Source, sourceClient, destClient *Client, calendar Calendar, runID string) *SyncResult {
 	result := &SyncResult{
 		Errors:   make([]string, 0),
 		Warnings: make([]string, 0),
@@ -287,13 +455,32 @@ func (se *SyncEngine) syncCalendar(ctx context.Context, source *db.Source, sourc
 		syncToken = syncState.SyncToken
 	}
 
-	// Get the destination calendar path from the destination client's base URL
+	// Get the destination calendar path from the calendar mapping, or the destination client's base URL
 	destCalendarPath := destClient.GetCalendarPath()
+	if mapping, err := se.db.GetCalendarMapping(source.ID, calendar.Path); err == nil {
+		destCalendarPath = mapping.DestHref
+	}
+
+	// Writes an interrupted run left pending may or may not have reached the server;
+	// only a full comparison tells, so WebDAV-Sync waits until one has run
+	interrupted, err := se.db.HasInterruptedJournalEntries(source.ID, calendar.Path, runID)
+	if err != nil {
+		log.Printf("Failed to check sync journal: %v", err)
+	}
+	if interrupted {
+		log.Printf("Calendar %q has writes pending from an interrupted run, comparing in full", calendar.Name)
+	}
 
 	// Try WebDAV-Sync if supported
-	if sourceClient.SupportsWebDAVSync(ctx, calendar.Path) {
+	if !interrupted && sourceClient.SupportsWebDAVSync(ctx, calendar.Path) {
 		syncResult, err := sourceClient.SyncCollection(ctx, calendar.Path, syncToken)
 		if err == nil {
+			result.Mode = db.SyncModeIncremental
+			rec := se.newCalendarRecorder(source.ID, runID, calendar.Path, result)
+			writeCtx, cancelWrites := writeContext(ctx)
+			defer cancelWrites()
+			writes := se.newWriteJournal(runID, source.ID, calendar.Path)
+
 			// Process changes
 			for _, item := range syncResult.Changed {
 				if item.Data != "" {
@@ -301,22 +488,40 @@ func (se *SyncEngine) syncCalendar(ctx context.Context, source *db.Source, sourc
 						Path: item.Path,
 						ETag: item.ETag,
 						Data: item.Data,
+						UID:  rawUID(item.Data),
 					}
-					if err := destClient.PutEvent(ctx, destCalendarPath, event); err != nil {
-						result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to sync event: %v", err))
-					} else {
-						result.Updated++
-					}
+					writes.add(db.JournalPutDest, event.UID, destCalendarPath, event, func() bool {
+						err := destClient.PutEvent(writeCtx, destCalendarPath, event)
+						if err != nil {
+							rec.warn(fmt.Sprintf("Failed to sync event: %v", err))
+						} else {
+							rec.add(0, 1, 0, 0, 0)
+						}
+						rec.change(event, db.ChangeUpdate, db.ChangeToDest, db.ReasonChangedOnSource, err)
+						return err == nil
+					})
 				}
 			}
 
 			for _, path := range syncResult.Deleted {
-				if err := destClient.DeleteEvent(ctx, path); err != nil {
-					// Don't count as error if event doesn't exist on destination
-					log.Printf("Failed to delete event %s: %v", path, err)
-				} else {
-					result.Deleted++
-				}
+				writes.add(db.JournalDeleteDest, "", path, nil, func() bool {
+					err := destClient.DeleteEvent(writeCtx, path)
+					if err != nil {
+						// Don't count as error if event doesn't exist on destination
+						log.Printf("Failed to delete event %s: %v", path, err)
+					} else {
+						rec.add(0, 0, 1, 0, 0)
+					}
+					rec.change(&Event{Path: path}, db.ChangeDelete, db.ChangeToDest, db.ReasonDeletedOnSource, err)
+					return err == nil
+				})
+			}
+
+			writes.run(ctx, se.eventConcurrency(source))
+
+			// Keep the old token if the run was stopped so skipped changes are fetched again
+			if ctx.Err() != nil {
+				return result
 			}
 
 			// Update sync state
@@ -336,7 +541,15 @@ func (se *SyncEngine) syncCalendar(ctx context.Context, source *db.Source, sourc
 	}
 
 	// Full sync fallback
-	return se.fullSync(ctx, source, sourceClient, destClient, calendar, calendarIndex)
+	result = se.fullSync(ctx, source, sourceClient, destClient, calendar, runID)
+
+	// The comparison re-applied or recorded the pending writes of the interrupted run
+	if interrupted && ctx.Err() == nil && len(result.Errors) == 0 {
+		if err := se.db.DeleteInterruptedJournalEntries(source.ID, calendar.Path, runID); err != nil {
+			log.Printf("Failed to clear sync journal: %v", err)
+		}
+	}
+	return result
 }
 
 // filterEventsByDate filters events to only include those with start time after cutoff date.
@@ -384,37 +597,164 @@ func filterEventsByDate(events []Event, cutoffDate time.Time) []Event {
 	return filtered
 }
 
-func (se *SyncEngine) fullSync(ctx context.Context, source *db.Source, sourceClient, destClient *Client, calendar Calendar, calendarIndex int) *SyncResult {
+// destCalendarFor returns the destination calendar that receives a source calendar's
+// events. A mapped calendar that still exists is reused. Otherwise, if the source opted
+// in, a destination calendar with the same name is mapped, or a new one is created
+// with MKCALENDAR. Without a mapping the first destination calendar is used, or the
+// destination URL's path if there are none. The warning is set when creating a
+// calendar failed.
+func (se *SyncEngine) destCalendarFor(ctx context.Context, source *db.Source, sourceClient, destClient *Client, calendar Calendar) (string, string) {
+	mapping, err := se.db.GetCalendarMapping(source.ID, calendar.Path)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.Printf("Failed to get calendar mapping: %v", err)
+	}
+
+	destCalendars, err := destClient.FindCalendars(ctx)
+	if err != nil {
+		if mapping != nil {
+			return mapping.DestHref, ""
+		}
+		log.Printf("Failed to discover destination calendars, falling back to URL path: %v", err)
+		return destClient.GetCalendarPath(), ""
+	}
+
+	log.Printf("Found %d calendar(s) on destination:", len(destCalendars))
+	for i, cal := range destCalendars {
+		log.Printf("  [%d] Name: %q, Path: %s", i+1, cal.Name, cal.Path)
+	}
+
+	if mapping != nil {
+		for _, cal := range destCalendars {
+			if cal.Path == mapping.DestHref {
+				return mapping.DestHref, ""
+			}
+		}
+		log.Printf("Mapped destination calendar %s no longer exists", mapping.DestHref)
+	}
+
+	warning := ""
+	if source.CreateCalendars {
+		path, created, err := se.createDestCalendar(ctx, sourceClient, destClient, calendar, destCalendars)
+		if err == nil {
+			m := &db.CalendarMapping{SourceID: source.ID, CalendarHref: calendar.Path, DestHref: path, Created: created}
+			if err := se.db.UpsertCalendarMapping(m); err != nil {
+				log.Printf("Failed to save calendar mapping: %v", err)
+			}
+			return path, ""
+		}
+		log.Printf("Failed to create destination calendar for %q: %v", calendar.Name, err)
+		warning = fmt.Sprintf("Failed to create destination calendar for %q: %v", calendar.Name, err)
+	}
+
+	if len(destCalendars) == 0 {
+		log.Printf("No calendars found on destination, using URL path as fallback")
+		return destClient.GetCalendarPath(), warning
+	}
+	// Use the first calendar found (most destinations have a single calendar for syncing)
+	if len(destCalendars) > 1 {
+		log.Printf("WARNING: Multiple destination calendars found, using first one: %s", destCalendars[0].Path)
+	}
+	return destCalendars[0].Path, warning
+}
+
+// syncCalendarMetadata copies the display name, description, color and order of a
+// source calendar onto its mapped destination calendar when the source opted in. The
+// metadata is fingerprinted so it is only written when it changed since the last run.
+func (se *SyncEngine) syncCalendarMetadata(ctx context.Context, source *db.Source, destClient *Client, calendar Calendar, result *SyncResult) {
+	if !source.SyncCalendarMeta || ctx.Err() != nil {
+		return
+	}
+	mapping, err := se.db.GetCalendarMapping(source.ID, calendar.Path)
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			log.Printf("Failed to get calendar mapping: %v", err)
+		}
+		return
+	}
+
+	hash := calendarMetadataHash(calendar)
+	if hash == mapping.MetadataHash {
+		return
+	}
+
+	props := Calendar{Name: calendar.Name, Description: calendar.Description, Color: calendar.Color, Order: calendar.Order}
+	if err := destClient.UpdateCalendarProperties(ctx, mapping.DestHref, props); err != nil {
+		log.Printf("Failed to update metadata of destination calendar %s: %v", mapping.DestHref, err)
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to update calendar details of %q: %v", calendar.Name, err))
+		return
+	}
+	if err := se.db.SetCalendarMappingMetadata(source.ID, calendar.Path, hash); err != nil {
+		log.Printf("Failed to save calendar metadata fingerprint: %v", err)
+	}
+	log.Printf("Updated metadata of destination calendar %s from %q", mapping.DestHref, calendar.Name)
+}
+
+// calendarMetadataHash fingerprints the metadata copied by syncCalendarMetadata.
+func calendarMetadataHash(cal Calendar) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{cal.Name, cal.Description, cal.Color, cal.Order}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// createDestCalendar finds the destination calendar with the same name as a source
+// calendar, or creates one with the source calendar's properties. created reports
+// whether a calendar was created.
+func (se *SyncEngine) createDestCalendar(ctx context.Context, sourceClient, destClient *Client, calendar Calendar, destCalendars []Calendar) (path string, created bool, err error) {
+	for _, cal := range destCalendars {
+		if calendar.Name != "" && strings.EqualFold(strings.TrimSpace(cal.Name), strings.TrimSpace(calendar.Name)) {
+			log.Printf("Mapped calendar %q to existing destination calendar %s", calendar.Name, cal.Path)
+			return cal.Path, false, nil
+		}
+	}
+
+	if calendar.Color == "" {
+		if color, err := sourceClient.CalendarColor(ctx, calendar.Path); err == nil {
+			calendar.Color = color
+		}
+	}
+	path, err = destClient.CreateCalendar(ctx, calendar)
+	if err != nil {
+		return "", false, err
+	}
+	log.Printf("Created destination calendar %s for %q", path, calendar.Name)
+	return path, true, nil
+}
+
+// dedupeClaim tracks the source events of a full sync that share a dedupe key:
+// the one being created on the destination and the ones waiting on its result.
+type dedupeClaim struct {
+	created bool     // Set by the create once the destination accepted it
+	waiting []*Event // Events created in turn if the create fails
+}
+
+func (se *SyncEngine) fullSync(ctx context.Context, source *db.Source, sourceClient, destClient *Client, calendar Calendar, runID string) *SyncResult {
 	result := &SyncResult{
 		Errors:   make([]string, 0),
 		Warnings: make([]string, 0),
+		Mode:     db.SyncModeFull,
 	}
 
 	// Get the effective sync direction for this calendar (may be per-calendar or source default)
 	syncDirection := getSyncDirectionForCalendar(source, calendar.Path)
 	log.Printf("Calendar %q sync direction: %s (source default: %s)", calendar.Name, syncDirection, source.SyncDirection)
 
-	// Helper to update activity tracker with current progress
-	updateProgress := func() {
-		se.tracker.UpdateProgress(source.ID, result.Created, result.Updated, result.Deleted, result.Skipped, result.EventsProcessed)
-	}
+	// Counter updates go through the recorder so concurrent writes stay consistent
+	rec := se.newCalendarRecorder(source.ID, runID, calendar.Path, result)
+	writeLimit := se.eventConcurrency(source)
+	writeCtx, cancelWrites := writeContext(ctx)
+	defer cancelWrites()
 
 	// Helper to update status message during loading phases
 	updateStatus := func(status string) {
-		se.tracker.UpdateCalendar(source.ID, fmt.Sprintf("%s (%s)", calendar.Name, status), calendarIndex)
+		se.tracker.SetCalendarStatus(source.ID, calendar.Path, fmt.Sprintf("%s (%s)", calendar.Name, status))
 	}
 
 	// Create collector for malformed events from source
 	malformedCollector := NewMalformedEventCollector()
 
-	// Clear old malformed events for this source before sync
-	if err := se.db.ClearMalformedEventsForSource(source.ID); err != nil {
-		log.Printf("Failed to clear old malformed events: %v", err)
-	}
-
 	// Get all events from source
 	updateStatus("fetching source events")
-	sourceEvents, err := sourceClient.GetEvents(ctx, calendar.Path, malformedCollector)
+	// Only metadata is listed here; event bodies are fetched in batches when they're written
+	sourceEvents, err := se.listEventMetadata(ctx, sourceClient, calendar.Path, malformedCollector)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Failed to get source events: %v", err))
 		return result
@@ -433,40 +773,26 @@ func (se *SyncEngine) fullSync(ctx context.Context, source *db.Source, sourceCli
 		}
 	}
 
-	// Store any malformed events found
-	for _, mf := range malformedCollector.GetEvents() {
-		if err := se.db.SaveMalformedEvent(source.ID, mf.Path, mf.ErrorMessage); err != nil {
-			log.Printf("Failed to save malformed event record: %v", err)
-		}
+	destCalendarPath, warning := se.destCalendarFor(ctx, source, sourceClient, destClient, calendar)
+	if warning != "" {
+		result.Warnings = append(result.Warnings, warning)
 	}
+	log.Printf("Using destination calendar path: %s", destCalendarPath)
 
-	// Discover destination calendar path - try calendar discovery first, then fall back to URL path
-	destCalendarPath := ""
-	destCalendars, err := destClient.FindCalendars(ctx)
-	if err != nil {
-		log.Printf("Failed to discover destination calendars, falling back to URL path: %v", err)
-		destCalendarPath = destClient.GetCalendarPath()
-	} else if len(destCalendars) == 0 {
-		log.Printf("No calendars found on destination, using URL path as fallback")
-		destCalendarPath = destClient.GetCalendarPath()
-	} else {
-		log.Printf("Found %d calendar(s) on destination:", len(destCalendars))
-		for i, cal := range destCalendars {
-			log.Printf("  [%d] Name: %q, Path: %s", i+1, cal.Name, cal.Path)
-		}
-		// Use the first calendar found (most destinations have a single calendar for syncing)
-		// Note: Future enhancement could match calendars by name for multi-calendar destinations.
-		// Current behavior: Uses first available calendar, which works for typical single-calendar setups.
-		destCalendarPath = destCalendars[0].Path
-		if len(destCalendars) > 1 {
-			log.Printf("WARNING: Multiple destination calendars found, using first one: %s", destCalendarPath)
+	// Store any malformed events found. They still exist on the source, so their copies
+	// on the destination are kept; repaired versions are synced if the source asks for it.
+	malformedUIDs, repairedEvents := se.recordMalformedEvents(source, calendar.Path, destCalendarPath, malformedCollector)
+	if len(repairedEvents) > 0 {
+		if source.SyncDaysPast > 0 {
+			repairedEvents = filterEventsByDate(repairedEvents, time.Now().AddDate(0, 0, -source.SyncDaysPast))
 		}
+		log.Printf("Syncing %d repaired malformed events", len(repairedEvents))
+		sourceEvents = append(sourceEvents, repairedEvents...)
 	}
-	log.Printf("Using destination calendar path: %s", destCalendarPath)
 
 	// Get all events from destination (no collector needed - we only track source issues)
 	updateStatus("fetching destination events")
-	destEvents, err := destClient.GetEvents(ctx, destCalendarPath, nil)
+	destEvents, err := se.listEventMetadata(ctx, destClient, destCalendarPath, nil)
 	if err != nil {
 		log.Printf("Failed to get destination events (path: %s): %v", destCalendarPath, err)
 		destEvents = []Event{}
@@ -514,20 +840,31 @@ func (se *SyncEngine) fullSync(ctx context.Context, source *db.Source, sourceCli
 		}
 	}
 
-	// Create deduplication map using summary + start time
+	// Create deduplication map using the source's dedupe fields; empty if dedupe is off
+	dedupeFields := source.GetDedupeFields()
 	destDedupeMap := make(map[string]bool)
 	for _, e := range destEvents {
-		key := e.DedupeKey()
-		if key != "|" {
+		key := e.MatchKey(dedupeFields)
+		if key != "" && source.DedupePolicy != db.DedupeOff {
 			destDedupeMap[key] = true
-			log.Printf("Dest dedupe key: %q (UID: %s)", key, e.UID)
 		}
 	}
 
 	skippedDupes := 0
 
-	// Track UIDs that exist in current sync (for updating synced_events table)
-	currentUIDs := make(map[string]bool)
+	// Record unchanged events in synced_events right away; written events are
+	// recorded by the journal as each write succeeds
+	trackUID := func(uid string) {
+		if err := retryDBOperation(func() error {
+			return se.db.UpsertSyncedEvent(&db.SyncedEvent{
+				SourceID:     source.ID,
+				CalendarHref: calendar.Path,
+				EventUID:     uid,
+			})
+		}, 5); err != nil {
+			log.Printf("Failed to upsert synced event: %v", err)
+		}
+	}
 
 	// Update status to show processing phase
 	updateStatus(fmt.Sprintf("processing %d events", len(sourceEvents)))
@@ -546,23 +883,28 @@ func (se *SyncEngine) fullSync(ctx context.Context, source *db.Source, sourceCli
 	}
 
 	if syncDirection == db.SyncDirectionTwoWay && !skipTwoWayDeletion {
+		deletions := se.newWriteJournal(runID, source.ID, calendar.Path)
 		for uid, syncedEvent := range previouslySyncedMap {
 			_, existsOnSource := sourceEventMap[uid]
 			destEvent, existsOnDest := destEventMap[uid]
 
-			if !existsOnSource && existsOnDest {
+			if !existsOnSource && existsOnDest && !malformedUIDs[uid] {
 				// Event was deleted from source - delete from destination too
 				log.Printf("Event %s deleted from source, deleting from destination", uid)
-				if err := destClient.DeleteEvent(ctx, destEvent.Path); err != nil {
-					result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to delete event from dest: %v", err))
-				} else {
-					result.Deleted++
-					updateProgress()
-				}
-				// Remove from synced_events
-				if err := se.db.DeleteSyncedEvent(source.ID, calendar.Path, uid); err != nil {
-					log.Printf("Failed to delete synced event record: %v", err)
-				}
+				deletions.add(db.JournalDeleteDest, uid, destEvent.Path, nil, func() bool {
+					err := destClient.DeleteEvent(writeCtx, destEvent.Path)
+					if err != nil {
+						rec.warn(fmt.Sprintf("Failed to delete event from dest: %v", err))
+					} else {
+						rec.add(0, 0, 1, 0, 0)
+					}
+					rec.change(&destEvent, db.ChangeDelete, db.ChangeToDest, db.ReasonDeletedOnSource, err)
+					// Remove from synced_events
+					if err := se.db.DeleteSyncedEvent(source.ID, calendar.Path, uid); err != nil {
+						log.Printf("Failed to delete synced event record: %v", err)
+					}
+					return err == nil
+				})
 				delete(destEventMap, uid)
 				continue
 			}
@@ -578,30 +920,56 @@ func (se *SyncEngine) fullSync(ctx context.Context, source *db.Source, sourceCli
 
 				// Event was deleted from destination - delete from source too
 				log.Printf("Event %s deleted from destination, deleting from source", uid)
-				if err := sourceClient.DeleteEvent(ctx, sourceEvent.Path); err != nil {
-					result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to delete event from source: %v", err))
-				} else {
-					result.Deleted++
-					updateProgress()
-				}
-				// Remove from synced_events
-				if err := se.db.DeleteSyncedEvent(source.ID, calendar.Path, uid); err != nil {
-					log.Printf("Failed to delete synced event record: %v", err)
-				}
+				deletions.add(db.JournalDeleteSource, uid, sourceEvent.Path, nil, func() bool {
+					err := sourceClient.DeleteEvent(writeCtx, sourceEvent.Path)
+					if err != nil {
+						rec.warn(fmt.Sprintf("Failed to delete event from source: %v", err))
+					} else {
+						rec.add(0, 0, 1, 0, 0)
+					}
+					rec.change(&sourceEvent, db.ChangeDelete, db.ChangeToSource, db.ReasonDeletedOnDest, err)
+					// Remove from synced_events
+					if err := se.db.DeleteSyncedEvent(source.ID, calendar.Path, uid); err != nil {
+						log.Printf("Failed to delete synced event record: %v", err)
+					}
+					return err == nil
+				})
 				delete(sourceEventMap, uid)
 				continue
 			}
 
-			if !existsOnSource && !existsOnDest {
+			if !existsOnSource && !existsOnDest && !malformedUIDs[uid] {
 				// Event deleted from both - just clean up the record
 				if err := se.db.DeleteSyncedEvent(source.ID, calendar.Path, syncedEvent.EventUID); err != nil {
 					log.Printf("Failed to delete synced event record: %v", err)
 				}
 			}
 		}
+		deletions.run(ctx, writeLimit)
 	}
 
-	// Sync source events to destination
+	// Sync source events to destination.
+	// Decisions (and dedupe key claims) are made serially; the PUTs run in parallel.
+	// Each successful PUT records the event in synced_events through the journal.
+	writes := se.newWriteJournal(runID, source.ID, calendar.Path)
+	writes.loadBodiesFrom(sourceClient, calendar.Path, rec.warn)
+	claims := make(map[string]*dedupeClaim)
+	create := func(event *Event, claim *dedupeClaim) {
+		writes.add(db.JournalPutDest, event.UID, destCalendarPath, event, func() bool {
+			err := destClient.PutEvent(writeCtx, destCalendarPath, event)
+			rec.change(event, db.ChangeCreate, db.ChangeToDest, db.ReasonNewOnSource, err)
+			if err != nil {
+				rec.warn(fmt.Sprintf("Failed to create event on dest: %v", err))
+				rec.add(0, 0, 0, 0, 1)
+				return false
+			}
+			if claim != nil {
+				claim.created = true
+			}
+			rec.add(1, 0, 0, 0, 1)
+			return true
+		})
+	}
 	for _, sourceEvent := range sourceEvents {
 		if sourceEvent.UID == "" {
 			continue
@@ -611,48 +979,75 @@ func (se *SyncEngine) fullSync(ctx context.Context, source *db.Source, sourceCli
 
 		if !existsByUID {
 			// Check for duplicate by content
-			dedupeKey := sourceEvent.DedupeKey()
-			log.Printf("Source dedupe key: %q (UID: %s)", dedupeKey, sourceEvent.UID)
-			if dedupeKey != "|" && destDedupeMap[dedupeKey] {
+			dedupeKey := sourceEvent.MatchKey(dedupeFields)
+			if dedupeKey != "" && destDedupeMap[dedupeKey] {
 				skippedDupes++
-				result.Skipped++
-				result.EventsProcessed++
-				updateProgress()
+				rec.add(0, 0, 0, 1, 1)
 				log.Printf("Skipping duplicate event: %s at %s (dedupe key match)", sourceEvent.Summary, sourceEvent.StartTime)
 				continue
 			}
 
-			// Create new event on destination
-			if err := destClient.PutEvent(ctx, destCalendarPath, &sourceEvent); err != nil {
-				result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to create event on dest: %v", err))
-			} else {
-				result.Created++
-				if dedupeKey != "|" {
-					destDedupeMap[dedupeKey] = true
+			// Only the first source event with a dedupe key is created; the others wait
+			// for its create and are created in its place if it fails
+			var claim *dedupeClaim
+			if dedupeKey != "" && source.DedupePolicy != db.DedupeOff {
+				if existing, claimed := claims[dedupeKey]; claimed {
+					existing.waiting = append(existing.waiting, &sourceEvent)
+					continue
 				}
-				currentUIDs[sourceEvent.UID] = true
+				claim = &dedupeClaim{}
+				claims[dedupeKey] = claim
 			}
-			result.EventsProcessed++
-			updateProgress()
+
+			// Create new event on destination
+			create(&sourceEvent, claim)
 		} else if sourceEvent.ETag != destEvent.ETag {
-			// Update existing event
-			sourceEvent.Path = destEvent.Path
-			if err := destClient.PutEvent(ctx, destCalendarPath, &sourceEvent); err != nil {
-				result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to update event on dest: %v", err))
-			} else {
-				result.Updated++
-				currentUIDs[sourceEvent.UID] = true
-			}
-			result.EventsProcessed++
-			updateProgress()
+			// Update existing event at its destination path (the body is fetched by source path)
+			writes.add(db.JournalPutDest, sourceEvent.UID, destEvent.Path, &sourceEvent, func() bool {
+				event := sourceEvent
+				event.Path = destEvent.Path
+				err := destClient.PutEvent(writeCtx, destCalendarPath, &event)
+				rec.change(&event, db.ChangeUpdate, db.ChangeToDest, db.ReasonChangedOnSource, err)
+				if err != nil {
+					rec.warn(fmt.Sprintf("Failed to update event on dest: %v", err))
+					rec.add(0, 0, 0, 0, 1)
+					return false
+				}
+				rec.add(0, 1, 0, 0, 1)
+				return true
+			})
 		} else {
 			// Event unchanged, still track it
-			currentUIDs[sourceEvent.UID] = true
-			result.EventsProcessed++
-			updateProgress()
+			trackUID(sourceEvent.UID)
+			rec.add(0, 0, 0, 0, 1)
 		}
 		delete(destEventMap, sourceEvent.UID)
 	}
+	writes.run(ctx, writeLimit)
+
+	// Retry failed claims with the next waiting event until one is created
+	for ctx.Err() == nil {
+		for _, claim := range claims {
+			if !claim.created && len(claim.waiting) > 0 {
+				create(claim.waiting[0], claim)
+				claim.waiting = claim.waiting[1:]
+			}
+		}
+		if len(writes.ops) == 0 {
+			break
+		}
+		writes.run(ctx, writeLimit)
+	}
+	for _, claim := range claims {
+		if !claim.created {
+			continue
+		}
+		for _, event := range claim.waiting {
+			skippedDupes++
+			rec.add(0, 0, 0, 1, 1)
+			log.Printf("Skipping duplicate event: %s at %s (dedupe key match)", event.Summary, event.StartTime)
+		}
+	}
 
 	if skippedDupes > 0 {
 		log.Printf("Skipped %d duplicate events", skippedDupes)
@@ -665,8 +1060,9 @@ func (se *SyncEngine) fullSync(ctx context.Context, source *db.Source, sourceCli
 	// 3. This prevents the bug where calendar A deletes events synced by calendar B
 	if syncDirection == db.SyncDirectionTwoWay {
 		log.Printf("Two-way sync enabled, syncing destination events to source")
-		skippedAlreadyExists := 0
-		skippedForbidden := 0
+		var skippedAlreadyExists, skippedForbidden atomic.Int64
+		updates := se.newWriteJournal(runID, source.ID, calendar.Path)
+		updates.loadBodiesFrom(destClient, destCalendarPath, rec.warn)
 		for _, destEvent := range destEvents {
 			if destEvent.UID == "" {
 				continue
@@ -686,130 +1082,134 @@ func (se *SyncEngine) fullSync(ctx context.Context, source *db.Source, sourceCli
 				// Only sync back events that we can verify belong to this source
 				// Skip creating on source to avoid cross-calendar pollution
 				continue
+			} else if malformedUIDs[destEvent.UID] {
+				// The source event is malformed; never overwrite it from the destination
+				continue
 			} else if destEvent.ETag != sourceEvent.ETag {
 				// Event exists on both - this is a legitimate update scenario
 				if source.ConflictStrategy == db.ConflictDestWins {
-					destEvent.Path = sourceEvent.Path
-					if err := sourceClient.PutEvent(ctx, calendar.Path, &destEvent); err != nil {
-						if isAlreadyExistsError(err) {
-							skippedAlreadyExists++
-						} else if isForbiddenError(err) {
-							skippedForbidden++
-						} else {
-							result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to update event on source: %v", err))
+					updates.add(db.JournalPutSource, destEvent.UID, sourceEvent.Path, &destEvent, func() bool {
+						event := destEvent
+						event.Path = sourceEvent.Path
+						err := sourceClient.PutEvent(writeCtx, calendar.Path, &event)
+						rec.change(&event, db.ChangeUpdate, db.ChangeToSource, db.ReasonChangedOnDest, err)
+						if err != nil {
+							if isAlreadyExistsError(err) {
+								skippedAlreadyExists.Add(1)
+							} else if isForbiddenError(err) {
+								skippedForbidden.Add(1)
+							} else {
+								rec.warn(fmt.Sprintf("Failed to update event on source: %v", err))
+							}
+							return false
 						}
-					} else {
-						result.Updated++
-					}
+						rec.add(0, 1, 0, 0, 0)
+						return true
+					})
 				}
 				// Don't add to currentUIDs - already tracked from source→dest sync
-				updateProgress()
 			}
 			// If ETags match, event is unchanged - nothing to do
 		}
-		if skippedAlreadyExists > 0 {
-			log.Printf("Two-way sync: %d events already exist on source (skipped)", skippedAlreadyExists)
+		updates.run(ctx, writeLimit)
+		if n := skippedAlreadyExists.Load(); n > 0 {
+			log.Printf("Two-way sync: %d events already exist on source (skipped)", n)
 		}
-		if skippedForbidden > 0 {
-			log.Printf("Two-way sync: %d events skipped (source calendar read-only)", skippedForbidden)
+		if n := skippedForbidden.Load(); n > 0 {
+			log.Printf("Two-way sync: %d events skipped (source calendar read-only)", n)
 		}
 	}
 
 	// One-way sync: delete orphan events on destination
 	if syncDirection == db.SyncDirectionOneWay && source.ConflictStrategy == db.ConflictSourceWins {
+		orphans := se.newWriteJournal(runID, source.ID, calendar.Path)
 		for _, event := range destEventMap {
-			if err := destClient.DeleteEvent(ctx, event.Path); err != nil {
-				result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to delete orphan event: %v", err))
-			} else {
-				result.Deleted++
-				updateProgress()
+			if malformedUIDs[event.UID] {
+				continue
 			}
+			orphans.add(db.JournalDeleteDest, event.UID, event.Path, nil, func() bool {
+				err := destClient.DeleteEvent(writeCtx, event.Path)
+				rec.change(&event, db.ChangeDelete, db.ChangeToDest, db.ReasonOrphan, err)
+				if err != nil {
+					rec.warn(fmt.Sprintf("Failed to delete orphan event: %v", err))
+					return false
+				}
+				rec.add(0, 0, 1, 0, 0)
+				return true
+			})
 		}
+		orphans.run(ctx, writeLimit)
 	}
 
-	// Clean up duplicate events on destination
-	duplicatesRemoved := se.cleanupDuplicates(ctx, destClient, destCalendarPath, sourceEventMap)
-	result.DuplicatesRemoved = duplicatesRemoved
-	if duplicatesRemoved > 0 {
-		log.Printf("Removed %d duplicate events from destination", duplicatesRemoved)
-	}
-
-	// Update synced_events table with current state
-	for uid := range currentUIDs {
-		syncedEvent := &db.SyncedEvent{
-			SourceID:     source.ID,
-			CalendarHref: calendar.Path,
-			EventUID:     uid,
+	// Clean up duplicate events on destination.
+	// Calendars syncing in parallel may share a destination, so cleanup is serialized per path.
+	if source.DedupePolicy == db.DedupeOff {
+		se.reportDuplicates(source.ID, calendar.Path, destCalendarPath, nil)
+	} else {
+		// calbridge owns the events it copies from the source and those it synced before
+		owned := make(map[string]bool, len(sourceEventMap)+len(previouslySyncedMap))
+		for uid := range sourceEventMap {
+			owned[uid] = true
 		}
-		if err := se.db.UpsertSyncedEvent(syncedEvent); err != nil {
-			log.Printf("Failed to upsert synced event: %v", err)
+		for uid := range previouslySyncedMap {
+			owned[uid] = true
+		}
+
+		unlock := se.destLocks.Lock(destCalendarPath)
+		duplicatesRemoved := se.cleanupDuplicates(ctx, source, destClient, calendar.Path, destCalendarPath, owned, sourceEventMap, rec)
+		unlock()
+		result.DuplicatesRemoved = duplicatesRemoved
+		if duplicatesRemoved > 0 {
+			log.Printf("Removed %d duplicate events from destination", duplicatesRemoved)
 		}
 	}
 
 	return result
 }
 
-// cleanupDuplicates removes duplicate events from destination calendar.
-// It groups events by Summary+StartTime and keeps the one matching a source UID,
-// or the first one if no match. Returns the number of duplicates removed.
-func (se *SyncEngine) cleanupDuplicates(ctx context.Context, destClient *Client, destCalendarPath string, sourceEventMap map[string]Event) int {
+// cleanupDuplicates looks for duplicate events calbridge wrote to the destination
+// calendar, comparing the source's dedupe fields. Events calbridge doesn't own are
+// never touched. With the auto policy duplicates are deleted, keeping the one matching
+// a source UID; with the report policy the groups are stored for review instead.
+// Returns the number of duplicates removed.
+func (se *SyncEngine) cleanupDuplicates(ctx context.Context, source *db.Source, destClient *Client, calendarHref, destCalendarPath string, owned map[string]bool, sourceEventMap map[string]Event, rec *resultRecorder) int {
 	log.Printf("Starting duplicate cleanup for destination: %s", destCalendarPath)
 
-	// Re-fetch destination events to get current state
-	destEvents, err := destClient.GetEvents(ctx, destCalendarPath, nil)
+	// Re-list destination events to get current state; grouping only needs metadata,
+	// and events unchanged since the comparison are served from the index
+	destEvents, err := se.listEventMetadata(ctx, destClient, destCalendarPath, nil)
 	if err != nil {
 		log.Printf("Failed to get destination events for duplicate cleanup: %v", err)
 		return 0
 	}
 	log.Printf("Fetched %d destination events for duplicate check", len(destEvents))
 
-	// Group events by dedupe key (Summary + StartTime)
-	type eventGroup struct {
-		events []Event
-	}
-	groups := make(map[string]*eventGroup)
-
-	for _, event := range destEvents {
-		key := event.DedupeKey()
-		if key == "|" { // Empty summary and start time
-			continue
-		}
-		if groups[key] == nil {
-			groups[key] = &eventGroup{events: make([]Event, 0)}
-		}
-		groups[key].events = append(groups[key].events, event)
+	groups := findDuplicates(destEvents, source.GetDedupeFields(), owned, sourceEventMap)
+	if source.DedupePolicy == db.DedupeReport {
+		log.Printf("Duplicate check complete: found %d duplicate groups for review", len(groups))
+		se.reportDuplicates(source.ID, calendarHref, destCalendarPath, groups)
+		return 0
 	}
+	se.reportDuplicates(source.ID, calendarHref, destCalendarPath, nil)
 
-	// Find and delete duplicates
+	writeCtx, cancelWrites := writeContext(ctx)
+	defer cancelWrites()
 	duplicatesRemoved := 0
-	duplicateGroups := 0
-	for key, group := range groups {
-		if len(group.events) <= 1 {
-			continue // No duplicates
-		}
-		duplicateGroups++
-		log.Printf("Found %d duplicates for: %s", len(group.events), key)
-
-		// Determine which event to keep:
-		// 1. Prefer event with UID matching a source event
-		// 2. Otherwise keep the first one (arbitrary but consistent)
-		keepIndex := 0
-		for i, event := range group.events {
-			if _, existsInSource := sourceEventMap[event.UID]; existsInSource {
-				keepIndex = i
-				break
-			}
+	for _, group := range groups {
+		if ctx.Err() != nil {
+			break
 		}
+		log.Printf("Found %d duplicates for: %s", len(group.duplicates)+1, group.key)
+		log.Printf("Keeping event: %s (UID: %s)", group.keep.Path, group.keep.UID)
 
-		// Delete all except the one we're keeping
-		for i, event := range group.events {
-			if i == keepIndex {
-				log.Printf("Keeping event: %s (UID: %s)", event.Path, event.UID)
-				continue
+		for _, event := range group.duplicates {
+			if ctx.Err() != nil {
+				break
 			}
-
 			log.Printf("Deleting duplicate event: %s (UID: %s)", event.Path, event.UID)
-			if err := destClient.DeleteEvent(ctx, event.Path); err != nil {
+			err := destClient.DeleteEvent(writeCtx, event.Path)
+			rec.change(&event, db.ChangeDelete, db.ChangeToDest, db.ReasonDuplicate, err)
+			if err != nil {
 				log.Printf("Failed to delete duplicate event %s: %v", event.Path, err)
 			} else {
 				duplicatesRemoved++
@@ -817,14 +1217,24 @@ func (se *SyncEngine) cleanupDuplicates(ctx context.Context, destClient *Client,
 		}
 	}
 
-	log.Printf("Duplicate cleanup complete: found %d duplicate groups, removed %d events", duplicateGroups, duplicatesRemoved)
+	log.Printf("Duplicate cleanup complete: found %d duplicate groups, removed %d events", len(groups), duplicatesRemoved)
 	return duplicatesRemoved
 }
 
-func (se *SyncEngine) finishSync(sourceID string, result *SyncResult) {
-	// Determine status: error > partial > success
+func (se *SyncEngine) finishSync(ctx context.Context, sourceID string, result *SyncResult) {
+	if syncCancelled(ctx) {
+		result.Cancelled = true
+		result.Success = false
+		result.FailureKind = FailureNone
+		result.Message = fmt.Sprintf("Sync cancelled: %d created, %d updated, %d deleted before stopping",
+			result.Created, result.Updated, result.Deleted)
+	}
+
+	// Determine status: cancelled > error > partial > success
 	var status db.SyncStatus
-	if !result.Success {
+	if result.Cancelled {
+		status = db.SyncStatusCancelled
+	} else if !result.Success {
 		status = db.SyncStatusError
 	} else if len(result.Warnings) > 0 {
 		status = db.SyncStatusPartial
@@ -851,6 +1261,12 @@ func (se *SyncEngine) finishSync(sourceID string, result *SyncResult) {
 		EventsSkipped:   result.Skipped,
 		CalendarsSynced: result.CalendarsSynced,
 		EventsProcessed: result.EventsProcessed,
+
+		RequestsThrottled: int(result.Throttle.Throttled),
+		RequestsRetried:   int(result.Throttle.Retries),
+		ThrottleWait:      result.Throttle.WaitTime,
+
+		Calendars: result.Calendars,
 	}
 
 	// Include both errors and warnings in details (sanitized to remove sensitive info)
@@ -861,6 +1277,10 @@ func (se *SyncEngine) finishSync(sourceID string, result *SyncResult) {
 	if len(result.Warnings) > 0 {
 		details = append(details, fmt.Sprintf("Warnings: %v", result.Warnings))
 	}
+	if result.Throttle.Throttled > 0 {
+		details = append(details, fmt.Sprintf("Throttling: %d throttled responses, %d retries, waited %s",
+			result.Throttle.Throttled, result.Throttle.Retries, result.Throttle.WaitTime.Round(time.Millisecond)))
+	}
 	if len(details) > 0 {
 		syncLog.Details = sanitizeLogDetails(strings.Join(details, "\n"))
 	}
@@ -871,14 +1291,19 @@ func (se *SyncEngine) finishSync(sourceID string, result *SyncResult) {
 	}, 5); err != nil {
 		log.Printf("Failed to create sync log after retries: %v", err)
 	}
+	se.saveChanges(result.Changes)
 
 	// Finish activity tracking
+	if result.Cancelled {
+		se.tracker.CancelSync(sourceID, result.Message)
+		return
+	}
 	se.tracker.FinishSync(sourceID, result.Success, result.Message, result.Errors)
 }
 
 // TestConnection tests connection to a CalDAV endpoint.
-func (se *SyncEngine) TestConnection(ctx context.Context, url, username, password string) error {
-	client, err := NewClient(url, username, password)
+func (se *SyncEngine) TestConnection(ctx context.Context, url string, creds Credentials, opts TransportOptions) error {
+	client, err := NewClientWithCredentials(url, creds, opts)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,327 @@
+package caldav
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Throttling defaults. Providers like iCloud and Google answer bursts with 429/503,
+// so requests are spaced per host and throttled responses are retried with backoff.
+// The host limit applies to all concurrent calendar and event writes together (see the
+// concurrency defaults), so it alone bounds the request rate against a provider.
+const (
+	defaultHostRPS       = 5.0
+	defaultMaxRetries    = 3
+	defaultBaseBackoff   = 1 * time.Second
+	defaultMaxBackoff    = 30 * time.Second
+	defaultMaxRetryAfter = 2 * time.Minute
+	maxDrainBytes        = 64 << 10
+)
+
+// ThrottleConfig configures per-host throttling of CalDAV requests.
+type ThrottleConfig struct {
+	HostRPS       float64       // Maximum requests per second per host (0 disables spacing)
+	MaxRetries    int           // Retries for throttled idempotent requests
+	BaseBackoff   time.Duration // First backoff delay when no Retry-After is given
+	MaxBackoff    time.Duration // Upper bound for computed backoff delays
+	MaxRetryAfter time.Duration // Retry-After values above this are not waited for
+}
+
+// DefaultThrottleConfig returns the default throttling configuration.
+func DefaultThrottleConfig() ThrottleConfig {
+	return ThrottleConfig{
+		HostRPS:       defaultHostRPS,
+		MaxRetries:    defaultMaxRetries,
+		BaseBackoff:   defaultBaseBackoff,
+		MaxBackoff:    defaultMaxBackoff,
+		MaxRetryAfter: defaultMaxRetryAfter,
+	}
+}
+
+// ThrottleStats summarizes throttling observed by a client.
+type ThrottleStats struct {
+	Requests  int64         `json:"requests"`  // HTTP requests sent, including retries
+	Throttled int64         `json:"throttled"` // 429/503 responses received
+	Retries   int64         `json:"retries"`   // Requests retried after throttling
+	WaitTime  time.Duration `json:"wait_time"` // Time spent waiting on rate limits and backoff
+}
+
+// Add returns the sum of two stats.
+func (s ThrottleStats) Add(other ThrottleStats) ThrottleStats {
+	return ThrottleStats{
+		Requests:  s.Requests + other.Requests,
+		Throttled: s.Throttled + other.Throttled,
+		Retries:   s.Retries + other.Retries,
+		WaitTime:  s.WaitTime + other.WaitTime,
+	}
+}
+
+// Sub returns the difference of two stats.
+func (s ThrottleStats) Sub(other ThrottleStats) ThrottleStats {
+	return ThrottleStats{
+		Requests:  s.Requests - other.Requests,
+		Throttled: s.Throttled - other.Throttled,
+		Retries:   s.Retries - other.Retries,
+		WaitTime:  s.WaitTime - other.WaitTime,
+	}
+}
+
+// hostLimiter spaces requests to a single host and tracks Retry-After pauses.
+type hostLimiter struct {
+	mu           sync.Mutex
+	next         time.Time // Earliest start time for the next request
+	blockedUntil time.Time // Set from Retry-After; all requests wait until then
+}
+
+// reserve returns how long the caller must wait before sending a request.
+func (h *hostLimiter) reserve(now time.Time, interval time.Duration) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	start := now
+	if h.blockedUntil.After(start) {
+		start = h.blockedUntil
+	}
+	if h.next.After(start) {
+		start = h.next
+	}
+	h.next = start.Add(interval)
+	return start.Sub(now)
+}
+
+// block pauses all requests to the host until the given time.
+func (h *hostLimiter) block(until time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if until.After(h.blockedUntil) {
+		h.blockedUntil = until
+	}
+}
+
+// hostLimiters is a registry of per-host limiters shared by all clients,
+// so parallel syncs against the same provider respect one budget.
+type hostLimiters struct {
+	mu     sync.Mutex
+	cfg    ThrottleConfig
+	byHost map[string]*hostLimiter
+}
+
+func newHostLimiters(cfg ThrottleConfig) *hostLimiters {
+	return &hostLimiters{cfg: cfg, byHost: make(map[string]*hostLimiter)}
+}
+
+func (l *hostLimiters) get(host string) *hostLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, exists := l.byHost[host]
+	if !exists {
+		h = &hostLimiter{}
+		l.byHost[host] = h
+	}
+	return h
+}
+
+func (l *hostLimiters) config() ThrottleConfig {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cfg
+}
+
+// sharedLimiters is used by every client created with NewClient.
+var sharedLimiters = newHostLimiters(DefaultThrottleConfig())
+
+// ConfigureThrottling replaces the throttling configuration used by all clients.
+// Zero fields fall back to the defaults.
+func ConfigureThrottling(cfg ThrottleConfig) {
+	defaults := DefaultThrottleConfig()
+	if cfg.HostRPS < 0 {
+		cfg.HostRPS = 0
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = defaults.BaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaults.MaxBackoff
+	}
+	if cfg.MaxRetryAfter <= 0 {
+		cfg.MaxRetryAfter = defaults.MaxRetryAfter
+	}
+
+	sharedLimiters.mu.Lock()
+	sharedLimiters.cfg = cfg
+	sharedLimiters.mu.Unlock()
+}
+
+// throttleTransport is an http.RoundTripper that rate-limits requests per host,
+// honours Retry-After and retries throttled idempotent requests.
+type throttleTransport struct {
+	base     http.RoundTripper
+	limiters *hostLimiters
+	sleep    func(req *http.Request, d time.Duration) error
+
+	requests  atomic.Int64
+	throttled atomic.Int64
+	retries   atomic.Int64
+	waitNanos atomic.Int64
+}
+
+func newThrottleTransport(base http.RoundTripper, limiters *hostLimiters) *throttleTransport {
+	return &throttleTransport{
+		base:     base,
+		limiters: limiters,
+		sleep:    sleepContext,
+	}
+}
+
+// Stats returns a snapshot of the throttling statistics.
+func (t *throttleTransport) Stats() ThrottleStats {
+	return ThrottleStats{
+		Requests:  t.requests.Load(),
+		Throttled: t.throttled.Load(),
+		Retries:   t.retries.Load(),
+		WaitTime:  time.Duration(t.waitNanos.Load()),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cfg := t.limiters.config()
+	limiter := t.limiters.get(req.URL.Host)
+
+	var interval time.Duration
+	if cfg.HostRPS > 0 {
+		interval = time.Duration(float64(time.Second) / cfg.HostRPS)
+	}
+
+	retryable := isIdempotentMethod(req.Method) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+
+	for attempt := 0; ; attempt++ {
+		if wait := limiter.reserve(time.Now(), interval); wait > 0 {
+			if err := t.wait(req, wait); err != nil {
+				return nil, err
+			}
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		t.requests.Add(1)
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			recordResponseStatus(req, 0)
+			return nil, err
+		}
+		recordResponseStatus(req, resp.StatusCode)
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+			return resp, nil
+		}
+
+		t.throttled.Add(1)
+
+		// A Retry-After above the limit isn't honoured, so it mustn't stall the other
+		// requests sharing the host limiter either.
+		delay, hasRetryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if hasRetryAfter && delay <= cfg.MaxRetryAfter {
+			limiter.block(time.Now().Add(delay))
+		}
+
+		if !retryable || attempt >= cfg.MaxRetries || delay > cfg.MaxRetryAfter {
+			return resp, nil
+		}
+
+		// Drain so the connection can be reused
+		drainBody(resp)
+		t.retries.Add(1)
+
+		// With Retry-After the host is blocked and the next reserve waits it out;
+		// otherwise back off exponentially.
+		if !hasRetryAfter {
+			if err := t.wait(req, backoffDelay(attempt, cfg.BaseBackoff, cfg.MaxBackoff)); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// wait sleeps for d (or until the request is cancelled) and records the time spent.
+func (t *throttleTransport) wait(req *http.Request, d time.Duration) error {
+	t.waitNanos.Add(int64(d))
+	return t.sleep(req, d)
+}
+
+func sleepContext(req *http.Request, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+func drainBody(resp *http.Response) {
+	if resp.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	resp.Body.Close()
+}
+
+// isIdempotentMethod reports whether a request can be safely repeated.
+// PROPFIND and REPORT are read-only; PUT and DELETE are idempotent per RFC 9110.
+func isIdempotentMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, "PROPFIND", "REPORT":
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given as delay-seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// backoffDelay returns the exponential backoff for the given attempt with jitter
+// in [d/2, d] so that parallel workers don't retry in lockstep.
+func backoffDelay(attempt int, base, limit time.Duration) time.Duration {
+	d := base << attempt
+	if d <= 0 || d > limit {
+		d = limit
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
@@ -0,0 +1,308 @@
+package caldav
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestThrottleTransport returns a transport that records sleeps instead of waiting.
+func newTestThrottleTransport(cfg ThrottleConfig) (*throttleTransport, *[]time.Duration) {
+	var slept []time.Duration
+	tt := newThrottleTransport(http.DefaultTransport, newHostLimiters(cfg))
+	tt.sleep = func(req *http.Request, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return tt, &slept
+}
+
+func TestThrottleTransport(t *testing.T) {
+	cfg := ThrottleConfig{MaxRetries: 3, BaseBackoff: time.Second, MaxBackoff: 10 * time.Second, MaxRetryAfter: time.Minute}
+
+	t.Run("retries throttled idempotent request until success", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) <= 2 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		tt, slept := newTestThrottleTransport(cfg)
+		client := &http.Client{Transport: tt}
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected 200, got %d", resp.StatusCode)
+		}
+		stats := tt.Stats()
+		if stats.Requests != 3 || stats.Throttled != 2 || stats.Retries != 2 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+		if len(*slept) != 2 {
+			t.Errorf("expected 2 backoff sleeps, got %d", len(*slept))
+		}
+	})
+
+	t.Run("honours Retry-After before retrying", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		tt, slept := newTestThrottleTransport(cfg)
+		client := &http.Client{Transport: tt}
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if len(*slept) != 1 {
+			t.Fatalf("expected 1 sleep, got %d", len(*slept))
+		}
+		if (*slept)[0] < 6*time.Second || (*slept)[0] > 7*time.Second {
+			t.Errorf("expected to wait about 7s, got %v", (*slept)[0])
+		}
+	})
+
+	t.Run("replays request body on retry", func(t *testing.T) {
+		var calls atomic.Int32
+		var lastBody string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			lastBody = string(body)
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		tt, _ := newTestThrottleTransport(cfg)
+		client := &http.Client{Transport: tt}
+
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/event.ics", strings.NewReader("BEGIN:VCALENDAR"))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusCreated {
+			t.Errorf("expected 201, got %d", resp.StatusCode)
+		}
+		if lastBody != "BEGIN:VCALENDAR" {
+			t.Errorf("expected body to be replayed, got %q", lastBody)
+		}
+	})
+
+	t.Run("does not retry non-idempotent requests", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		tt, _ := newTestThrottleTransport(cfg)
+		client := &http.Client{Transport: tt}
+
+		resp, err := client.Post(server.URL, "text/plain", strings.NewReader("x"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("expected 429, got %d", resp.StatusCode)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("expected 1 call, got %d", calls.Load())
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		tt, _ := newTestThrottleTransport(cfg)
+		client := &http.Client{Transport: tt}
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", resp.StatusCode)
+		}
+		if calls.Load() != 4 {
+			t.Errorf("expected 4 calls (1 + 3 retries), got %d", calls.Load())
+		}
+	})
+
+	t.Run("does not wait for Retry-After above the limit", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		tt, _ := newTestThrottleTransport(cfg)
+		client := &http.Client{Transport: tt}
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if calls.Load() != 1 {
+			t.Errorf("expected no retry, got %d calls", calls.Load())
+		}
+	})
+
+	t.Run("does not stall the host for Retry-After above the limit", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set("Retry-After", "86400")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		tt, slept := newTestThrottleTransport(cfg)
+		client := &http.Client{Transport: tt}
+
+		for _, want := range []int{http.StatusTooManyRequests, http.StatusOK} {
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != want {
+				t.Errorf("expected %d, got %d", want, resp.StatusCode)
+			}
+		}
+
+		for _, d := range *slept {
+			if d > cfg.MaxRetryAfter {
+				t.Errorf("next request waited %v for the host", d)
+			}
+		}
+	})
+}
+
+func TestHostLimiter(t *testing.T) {
+	t.Run("spaces requests by interval", func(t *testing.T) {
+		h := &hostLimiter{}
+		now := time.Now()
+
+		if wait := h.reserve(now, time.Second); wait != 0 {
+			t.Errorf("expected first request to go immediately, got %v", wait)
+		}
+		if wait := h.reserve(now, time.Second); wait != time.Second {
+			t.Errorf("expected second request to wait 1s, got %v", wait)
+		}
+	})
+
+	t.Run("waits until block expires", func(t *testing.T) {
+		h := &hostLimiter{}
+		now := time.Now()
+		h.block(now.Add(5 * time.Second))
+
+		if wait := h.reserve(now, 0); wait != 5*time.Second {
+			t.Errorf("expected to wait 5s, got %v", wait)
+		}
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"seconds", "120", 2 * time.Minute, true},
+		{"http date", "Wed, 01 Jan 2025 12:00:30 GMT", 30 * time.Second, true},
+		{"date in past", "Wed, 01 Jan 2025 11:00:00 GMT", 0, true},
+		{"empty", "", 0, false},
+		{"negative", "-5", 0, false},
+		{"garbage", "soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	t.Run("grows exponentially within jitter bounds", func(t *testing.T) {
+		for attempt := 0; attempt < 4; attempt++ {
+			full := time.Second << attempt
+			d := backoffDelay(attempt, time.Second, time.Minute)
+			if d < full/2 || d > full {
+				t.Errorf("attempt %d: delay %v outside [%v, %v]", attempt, d, full/2, full)
+			}
+		}
+	})
+
+	t.Run("is capped at max backoff", func(t *testing.T) {
+		d := backoffDelay(20, time.Second, 10*time.Second)
+		if d > 10*time.Second {
+			t.Errorf("expected delay capped at 10s, got %v", d)
+		}
+	})
+}
+
+func TestIsIdempotentMethod(t *testing.T) {
+	for _, m := range []string{"GET", "PUT", "DELETE", "PROPFIND", "REPORT", "OPTIONS"} {
+		if !isIdempotentMethod(m) {
+			t.Errorf("expected %s to be idempotent", m)
+		}
+	}
+	for _, m := range []string{"POST", "MKCALENDAR", "PROPPATCH", "MOVE"} {
+		if isIdempotentMethod(m) {
+			t.Errorf("expected %s not to be idempotent", m)
+		}
+	}
+}
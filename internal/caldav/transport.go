@@ -0,0 +1,322 @@
+package caldav
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/macjediwizard/calbridgesync/internal/crypto"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+	"github.com/macjediwizard/calbridgesync/internal/validator"
+)
+
+// ErrInvalidTransport is returned when per-source transport settings can't be applied.
+var ErrInvalidTransport = errors.New("invalid transport settings")
+
+// Limits on transport settings, mostly to keep stored settings small.
+const (
+	maxExtraHeaders   = 20
+	maxHeaderValueLen = 4096
+	maxUserAgentLen   = 256
+	maxPEMLen         = 64 << 10
+)
+
+// reservedHeaders are set by the client itself and can't be overridden.
+var reservedHeaders = map[string]bool{
+	"Authorization":     true,
+	"Host":              true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Depth":             true,
+	"Connection":        true,
+	"Transfer-Encoding": true,
+}
+
+// requestTimeout is the HTTP timeout of clients created from now on.
+var requestTimeout atomic.Int64
+
+func init() {
+	requestTimeout.Store(int64(defaultTimeout))
+}
+
+// ConfigureRequestTimeout sets the HTTP request timeout used by new clients.
+// Values of zero or less restore the default.
+func ConfigureRequestTimeout(d time.Duration) {
+	if d <= 0 {
+		d = defaultTimeout
+	}
+	requestTimeout.Store(int64(d))
+}
+
+// addressPolicy restricts the addresses new clients connect to; nil allows all.
+var addressPolicy atomic.Pointer[validator.AddressPolicy]
+
+// ConfigureAddressPolicy makes clients created from now on check every address they
+// connect to (including proxies) against p, so sources can't reach private or
+// link-local addresses that aren't allowlisted. A nil policy removes the check.
+func ConfigureAddressPolicy(p *validator.AddressPolicy) {
+	addressPolicy.Store(p)
+}
+
+// TransportOptions customizes how a client connects to its server,
+// e.g. for self-hosted servers behind a private CA or a proxy.
+type TransportOptions struct {
+	CACert             string            // PEM bundle trusted in addition to the system roots
+	ClientCert         string            // PEM client certificate for mutual TLS
+	ClientKey          string            // PEM private key of ClientCert
+	InsecureSkipVerify bool              // Don't verify the server certificate (lab environments only)
+	ProxyURL           string            // HTTP(S) or SOCKS5 proxy for all requests
+	UserAgent          string            // Replaces the default User-Agent
+	Headers            map[string]string // Extra headers sent with every request
+}
+
+// IsZero reports whether no options are set.
+func (o TransportOptions) IsZero() bool {
+	return o.CACert == "" && o.ClientCert == "" && o.ClientKey == "" && !o.InsecureSkipVerify &&
+		o.ProxyURL == "" && o.UserAgent == "" && len(o.Headers) == 0
+}
+
+// Validate checks that the options can be applied. Errors never include secret values.
+func (o TransportOptions) Validate() error {
+	if len(o.CACert) > maxPEMLen || len(o.ClientCert) > maxPEMLen || len(o.ClientKey) > maxPEMLen {
+		return fmt.Errorf("%w: certificates and keys must be at most %d KB", ErrInvalidTransport, maxPEMLen>>10)
+	}
+	if _, err := o.tlsConfig(); err != nil {
+		return err
+	}
+	if _, err := o.proxy(); err != nil {
+		return err
+	}
+	if len(o.UserAgent) > maxUserAgentLen || strings.ContainsAny(o.UserAgent, "\r\n") {
+		return fmt.Errorf("%w: user agent is invalid", ErrInvalidTransport)
+	}
+	if len(o.Headers) > maxExtraHeaders {
+		return fmt.Errorf("%w: at most %d extra headers are allowed", ErrInvalidTransport, maxExtraHeaders)
+	}
+	for name, value := range o.Headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("%w: invalid header name %q", ErrInvalidTransport, name)
+		}
+		if reservedHeaders[textproto.CanonicalMIMEHeaderKey(name)] {
+			return fmt.Errorf("%w: header %q can't be overridden", ErrInvalidTransport, name)
+		}
+		if len(value) > maxHeaderValueLen || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w: invalid value for header %q", ErrInvalidTransport, name)
+		}
+	}
+	return nil
+}
+
+// validHeaderName reports whether name is a valid HTTP header field name (RFC 7230 token).
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// tlsConfig builds the TLS configuration for the options.
+func (o TransportOptions) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         minTLSVersion,
+		InsecureSkipVerify: o.InsecureSkipVerify, //nolint:gosec // Opt-in per source for lab environments
+	}
+
+	if o.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(o.CACert)) {
+			return nil, fmt.Errorf("%w: CA bundle contains no valid PEM certificates", ErrInvalidTransport)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.ClientCert != "" || o.ClientKey != "" {
+		if o.ClientCert == "" || o.ClientKey == "" {
+			return nil, fmt.Errorf("%w: client certificate and key must be set together", ErrInvalidTransport)
+		}
+		cert, err := tls.X509KeyPair([]byte(o.ClientCert), []byte(o.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("%w: client certificate or key is invalid", ErrInvalidTransport)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// proxy returns the proxy function for the options, or nil to connect directly.
+func (o TransportOptions) proxy() (func(*http.Request) (*url.URL, error), error) {
+	if o.ProxyURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(o.ProxyURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("%w: proxy URL is invalid", ErrInvalidTransport)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("%w: proxy URL must use http, https or socks5", ErrInvalidTransport)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// Equal reports whether o and other configure the same transport.
+func (o TransportOptions) Equal(other TransportOptions) bool {
+	return o.fingerprint() == other.fingerprint()
+}
+
+// fingerprint identifies the options in client pool keys without exposing them.
+func (o TransportOptions) fingerprint() string {
+	if o.IsZero() {
+		return ""
+	}
+	data, err := json.Marshal(o) // Map keys are sorted, so equal options hash equally
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newHTTPTransport creates the base transport for a client.
+func newHTTPTransport(opts TransportOptions) (*http.Transport, error) {
+	tlsConfig, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := opts.proxy()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{
+		Proxy:               proxy,
+		TLSClientConfig:     tlsConfig,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     30 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	if policy := addressPolicy.Load(); policy != nil {
+		transport.DialContext = policy.DialContext
+	}
+	return transport, nil
+}
+
+// headerTransport adds a custom User-Agent and extra headers to every request.
+type headerTransport struct {
+	base      http.RoundTripper
+	userAgent string
+	headers   http.Header
+}
+
+// newHeaderTransport wraps base if the options set any headers.
+func newHeaderTransport(base http.RoundTripper, opts TransportOptions) http.RoundTripper {
+	if opts.UserAgent == "" && len(opts.Headers) == 0 {
+		return base
+	}
+	headers := make(http.Header, len(opts.Headers))
+	for name, value := range opts.Headers {
+		headers.Set(name, value)
+	}
+	return &headerTransport{base: base, userAgent: opts.UserAgent, headers: headers}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// EncryptTransportOptions converts transport options to their stored form, encrypting
+// the client key, the proxy URL (which may carry credentials) and header values.
+func EncryptTransportOptions(enc *crypto.Encryptor, opts TransportOptions) (db.TransportSettings, error) {
+	settings := db.TransportSettings{
+		CACert:             opts.CACert,
+		ClientCert:         opts.ClientCert,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+		UserAgent:          opts.UserAgent,
+	}
+
+	var err error
+	if settings.ClientKey, err = encryptOptional(enc, opts.ClientKey); err != nil {
+		return db.TransportSettings{}, err
+	}
+	if settings.ProxyURL, err = encryptOptional(enc, opts.ProxyURL); err != nil {
+		return db.TransportSettings{}, err
+	}
+	if len(opts.Headers) > 0 {
+		settings.Headers = make(map[string]string, len(opts.Headers))
+		for name, value := range opts.Headers {
+			if settings.Headers[name], err = encryptOptional(enc, value); err != nil {
+				return db.TransportSettings{}, err
+			}
+		}
+	}
+	return settings, nil
+}
+
+// DecryptTransportOptions converts stored transport settings back to options.
+func DecryptTransportOptions(enc *crypto.Encryptor, settings db.TransportSettings) (TransportOptions, error) {
+	opts := TransportOptions{
+		CACert:             settings.CACert,
+		ClientCert:         settings.ClientCert,
+		InsecureSkipVerify: settings.InsecureSkipVerify,
+		UserAgent:          settings.UserAgent,
+	}
+
+	var err error
+	if opts.ClientKey, err = decryptOptional(enc, settings.ClientKey); err != nil {
+		return TransportOptions{}, err
+	}
+	if opts.ProxyURL, err = decryptOptional(enc, settings.ProxyURL); err != nil {
+		return TransportOptions{}, err
+	}
+	if len(settings.Headers) > 0 {
+		opts.Headers = make(map[string]string, len(settings.Headers))
+		for name, value := range settings.Headers {
+			if opts.Headers[name], err = decryptOptional(enc, value); err != nil {
+				return TransportOptions{}, err
+			}
+		}
+	}
+	return opts, nil
+}
+
+func encryptOptional(enc *crypto.Encryptor, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	return enc.Encrypt(value)
+}
+
+func decryptOptional(enc *crypto.Encryptor, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	return enc.Decrypt(value)
+}
@@ -56,7 +56,6 @@ func (c *Client) SyncCollection(ctx context.Context, calendarPath, syncToken str
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.SetBasicAuth(c.username, c.password)
 	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
 	req.Header.Set("Depth", "1")
 
@@ -73,9 +72,9 @@ func (c *Client) SyncCollection(ctx context.Context, calendarPath, syncToken str
 		}
 		body, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
-			return nil, fmt.Errorf("%w: unexpected status %d", ErrInvalidResponse, resp.StatusCode)
+			return nil, fmt.Errorf("%w: unexpected status %d", statusSentinel(resp.StatusCode), resp.StatusCode)
 		}
-		return nil, fmt.Errorf("%w: unexpected status %d: %s", ErrInvalidResponse, resp.StatusCode, string(body))
+		return nil, fmt.Errorf("%w: unexpected status %d: %s", statusSentinel(resp.StatusCode), resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -86,16 +85,19 @@ func (c *Client) SyncCollection(ctx context.Context, calendarPath, syncToken str
 	return parseSyncResponse(body)
 }
 
-// SupportsWebDAVSync checks if the calendar supports WebDAV-Sync.
+// SupportsWebDAVSync checks if the calendar supports WebDAV-Sync. The probed server
+// profile is used when the client has one.
 func (c *Client) SupportsWebDAVSync(ctx context.Context, calendarPath string) bool {
+	if caps := c.capabilities.Load(); caps != nil {
+		return caps.SyncCollection
+	}
+
 	// Try an OPTIONS request to check for sync-collection support
 	req, err := http.NewRequestWithContext(ctx, http.MethodOptions, c.baseURL+calendarPath, nil)
 	if err != nil {
 		return false
 	}
 
-	req.SetBasicAuth(c.username, c.password)
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return false
@@ -33,6 +33,7 @@ const (
 type Config struct {
 	Server       ServerConfig
 	OIDC         OIDCConfig
+	GoogleOAuth  GoogleOAuthConfig
 	Security     SecurityConfig
 	Database     DatabaseConfig
 	CalDAV       CalDAVConfig
@@ -76,12 +77,29 @@ type OIDCConfig struct {
 	RedirectURL  string
 }
 
+// GoogleOAuthConfig holds the OAuth client used to connect Google Calendar sources.
+// It is separate from the OIDC client that signs users in to calbridge.
+type GoogleOAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // Defaults to BASE_URL + /auth/google/callback
+}
+
+// Enabled reports whether Google sources can be connected with OAuth.
+func (g GoogleOAuthConfig) Enabled() bool {
+	return g.ClientID != ""
+}
+
 // SecurityConfig holds security-related configuration.
 type SecurityConfig struct {
 	EncryptionKey       []byte
 	SessionSecret       string
 	SessionMaxAgeSecs   int // Session timeout in seconds (default: 86400 = 24 hours)
 	OAuthStateMaxAgeSecs int // OAuth state timeout in seconds (default: 300 = 5 minutes)
+
+	// Internal CIDRs, IPs and hostnames that CalDAV sources and webhooks may reach
+	// despite SSRF protection, e.g. self-hosted servers on the local network
+	OutboundAllowlist []string
 }
 
 // DatabaseConfig holds database configuration.
@@ -93,6 +111,16 @@ type DatabaseConfig struct {
 type CalDAVConfig struct {
 	DefaultDestURL     string
 	RequestTimeoutSecs int // HTTP request timeout in seconds (default: 300 = 5 minutes)
+
+	// Per-host throttling of outgoing CalDAV requests
+	HostRPS    float64 // Maximum requests per second per host (default: 5, 0 = unlimited)
+	MaxRetries int     // Retries for requests throttled with 429/503 (default: 3)
+
+	// How long calendar discovery results are reused across sync runs (default: 300, 0 = disabled)
+	DiscoveryCacheTTLSecs int
+
+	// Events whose metadata is kept between sync runs, across all calendars (default: 50000, 0 = disabled)
+	EventIndexLimit int
 }
 
 // RateLimitConfig holds rate limiting configuration.
@@ -101,10 +129,24 @@ type RateLimitConfig struct {
 	Burst int
 }
 
-// SyncConfig holds sync interval configuration.
+// SyncConfig holds sync interval and concurrency configuration.
 type SyncConfig struct {
 	MinInterval int
 	MaxInterval int
+
+	// Global caps on per-source concurrency settings (default: 4 calendars, 8 event writes)
+	MaxCalendarConcurrency int
+	MaxEventConcurrency    int
+
+	// Retries for failed scheduled syncs (default: 3 attempts, first after 60s, doubling)
+	RetryMaxAttempts   int
+	RetryBaseDelaySecs int
+
+	// Consecutive authentication failures before a source is paused (default: 3, 0 disables)
+	AuthFailureThreshold int
+
+	// Mismatches a drift check may find before the owner is alerted (default: 10)
+	DriftAlertThreshold int
 }
 
 // Load loads configuration from environment variables.
@@ -130,6 +172,19 @@ func Load() (*Config, error) {
 	cfg.OIDC.ClientSecret = getEnvRequired("OIDC_CLIENT_SECRET")
 	cfg.OIDC.RedirectURL = getEnvRequired("OIDC_REDIRECT_URL")
 
+	// Google OAuth for Google Calendar sources (optional)
+	cfg.GoogleOAuth.ClientID = getEnv("GOOGLE_OAUTH_CLIENT_ID", "")
+	cfg.GoogleOAuth.ClientSecret = getEnv("GOOGLE_OAUTH_CLIENT_SECRET", "")
+	cfg.GoogleOAuth.RedirectURL = getEnv("GOOGLE_OAUTH_REDIRECT_URL", "")
+	if cfg.GoogleOAuth.Enabled() {
+		if cfg.GoogleOAuth.ClientSecret == "" {
+			return nil, fmt.Errorf("%w: GOOGLE_OAUTH_CLIENT_SECRET is required with GOOGLE_OAUTH_CLIENT_ID", ErrInvalidConfig)
+		}
+		if cfg.GoogleOAuth.RedirectURL == "" {
+			cfg.GoogleOAuth.RedirectURL = strings.TrimSuffix(cfg.Server.BaseURL, "/") + "/auth/google/callback"
+		}
+	}
+
 	// Security configuration
 	encKeyHex := getEnvRequired("ENCRYPTION_KEY")
 	if encKeyHex != "" {
@@ -162,6 +217,17 @@ func Load() (*Config, error) {
 	}
 	cfg.Security.OAuthStateMaxAgeSecs = oauthStateMaxAge
 
+	if allowlist := getEnv("OUTBOUND_ALLOWLIST", ""); allowlist != "" {
+		for _, entry := range strings.Split(allowlist, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				cfg.Security.OutboundAllowlist = append(cfg.Security.OutboundAllowlist, entry)
+			}
+		}
+		if _, err := validator.NewAddressPolicy(cfg.Security.OutboundAllowlist); err != nil {
+			return nil, fmt.Errorf("%w: OUTBOUND_ALLOWLIST: %w", ErrInvalidConfig, err)
+		}
+	}
+
 	// Database configuration
 	cfg.Database.Path = getEnv("DATABASE_PATH", "./data/calbridgesync.db")
 
@@ -173,6 +239,42 @@ func Load() (*Config, error) {
 	}
 	cfg.CalDAV.RequestTimeoutSecs = caldavTimeout
 
+	hostRPS, err := getEnvFloat("CALDAV_HOST_RPS", 5.0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: CALDAV_HOST_RPS: %w", ErrInvalidConfig, err)
+	}
+	if hostRPS < 0 {
+		return nil, fmt.Errorf("%w: CALDAV_HOST_RPS must not be negative", ErrInvalidConfig)
+	}
+	cfg.CalDAV.HostRPS = hostRPS
+
+	maxRetries, err := getEnvInt("CALDAV_MAX_RETRIES", 3)
+	if err != nil {
+		return nil, fmt.Errorf("%w: CALDAV_MAX_RETRIES: %w", ErrInvalidConfig, err)
+	}
+	if maxRetries < 0 {
+		return nil, fmt.Errorf("%w: CALDAV_MAX_RETRIES must not be negative", ErrInvalidConfig)
+	}
+	cfg.CalDAV.MaxRetries = maxRetries
+
+	discoveryTTL, err := getEnvInt("CALDAV_DISCOVERY_CACHE_TTL", 300)
+	if err != nil {
+		return nil, fmt.Errorf("%w: CALDAV_DISCOVERY_CACHE_TTL: %w", ErrInvalidConfig, err)
+	}
+	if discoveryTTL < 0 {
+		return nil, fmt.Errorf("%w: CALDAV_DISCOVERY_CACHE_TTL must not be negative", ErrInvalidConfig)
+	}
+	cfg.CalDAV.DiscoveryCacheTTLSecs = discoveryTTL
+
+	eventIndexLimit, err := getEnvInt("CALDAV_EVENT_INDEX_LIMIT", 50000)
+	if err != nil {
+		return nil, fmt.Errorf("%w: CALDAV_EVENT_INDEX_LIMIT: %w", ErrInvalidConfig, err)
+	}
+	if eventIndexLimit < 0 {
+		return nil, fmt.Errorf("%w: CALDAV_EVENT_INDEX_LIMIT must not be negative", ErrInvalidConfig)
+	}
+	cfg.CalDAV.EventIndexLimit = eventIndexLimit
+
 	// Rate limiting configuration
 	rps, err := getEnvFloat("RATE_LIMIT_RPS", 10.0)
 	if err != nil {
@@ -199,6 +301,60 @@ func Load() (*Config, error) {
 	}
 	cfg.Sync.MaxInterval = maxInterval
 
+	maxCalendarConcurrency, err := getEnvInt("SYNC_MAX_CALENDAR_CONCURRENCY", 4)
+	if err != nil {
+		return nil, fmt.Errorf("%w: SYNC_MAX_CALENDAR_CONCURRENCY: %w", ErrInvalidConfig, err)
+	}
+	if maxCalendarConcurrency < 1 {
+		return nil, fmt.Errorf("%w: SYNC_MAX_CALENDAR_CONCURRENCY must be at least 1", ErrInvalidConfig)
+	}
+	cfg.Sync.MaxCalendarConcurrency = maxCalendarConcurrency
+
+	maxEventConcurrency, err := getEnvInt("SYNC_MAX_EVENT_CONCURRENCY", 8)
+	if err != nil {
+		return nil, fmt.Errorf("%w: SYNC_MAX_EVENT_CONCURRENCY: %w", ErrInvalidConfig, err)
+	}
+	if maxEventConcurrency < 1 {
+		return nil, fmt.Errorf("%w: SYNC_MAX_EVENT_CONCURRENCY must be at least 1", ErrInvalidConfig)
+	}
+	cfg.Sync.MaxEventConcurrency = maxEventConcurrency
+
+	retryMaxAttempts, err := getEnvInt("SYNC_RETRY_MAX_ATTEMPTS", 3)
+	if err != nil {
+		return nil, fmt.Errorf("%w: SYNC_RETRY_MAX_ATTEMPTS: %w", ErrInvalidConfig, err)
+	}
+	if retryMaxAttempts < 0 {
+		return nil, fmt.Errorf("%w: SYNC_RETRY_MAX_ATTEMPTS must not be negative", ErrInvalidConfig)
+	}
+	cfg.Sync.RetryMaxAttempts = retryMaxAttempts
+
+	retryBaseDelay, err := getEnvInt("SYNC_RETRY_BASE_DELAY", 60)
+	if err != nil {
+		return nil, fmt.Errorf("%w: SYNC_RETRY_BASE_DELAY: %w", ErrInvalidConfig, err)
+	}
+	if retryBaseDelay < 1 {
+		return nil, fmt.Errorf("%w: SYNC_RETRY_BASE_DELAY must be at least 1", ErrInvalidConfig)
+	}
+	cfg.Sync.RetryBaseDelaySecs = retryBaseDelay
+
+	authFailureThreshold, err := getEnvInt("SYNC_AUTH_FAILURE_THRESHOLD", 3)
+	if err != nil {
+		return nil, fmt.Errorf("%w: SYNC_AUTH_FAILURE_THRESHOLD: %w", ErrInvalidConfig, err)
+	}
+	if authFailureThreshold < 0 {
+		return nil, fmt.Errorf("%w: SYNC_AUTH_FAILURE_THRESHOLD must not be negative", ErrInvalidConfig)
+	}
+	cfg.Sync.AuthFailureThreshold = authFailureThreshold
+
+	driftAlertThreshold, err := getEnvInt("SYNC_DRIFT_ALERT_THRESHOLD", 10)
+	if err != nil {
+		return nil, fmt.Errorf("%w: SYNC_DRIFT_ALERT_THRESHOLD: %w", ErrInvalidConfig, err)
+	}
+	if driftAlertThreshold < 0 {
+		return nil, fmt.Errorf("%w: SYNC_DRIFT_ALERT_THRESHOLD must not be negative", ErrInvalidConfig)
+	}
+	cfg.Sync.DriftAlertThreshold = driftAlertThreshold
+
 	// Alert configuration (all optional)
 	cfg.Alerts.WebhookEnabled = getEnv("ALERT_WEBHOOK_ENABLED", "") == "true"
 	cfg.Alerts.WebhookURL = getEnv("ALERT_WEBHOOK_URL", "")
@@ -320,6 +320,12 @@ func TestLoad(t *testing.T) {
 		"DEFAULT_DEST_URL",
 		"RATE_LIMIT_RPS", "RATE_LIMIT_BURST",
 		"MIN_SYNC_INTERVAL", "MAX_SYNC_INTERVAL",
+		"SYNC_MAX_CALENDAR_CONCURRENCY", "SYNC_MAX_EVENT_CONCURRENCY",
+		"CALDAV_HOST_RPS", "CALDAV_MAX_RETRIES", "CALDAV_DISCOVERY_CACHE_TTL", "CALDAV_EVENT_INDEX_LIMIT",
+		"SYNC_RETRY_MAX_ATTEMPTS", "SYNC_RETRY_BASE_DELAY",
+		"SYNC_AUTH_FAILURE_THRESHOLD", "SYNC_DRIFT_ALERT_THRESHOLD",
+		"OUTBOUND_ALLOWLIST",
+		"GOOGLE_OAUTH_CLIENT_ID", "GOOGLE_OAUTH_CLIENT_SECRET", "GOOGLE_OAUTH_REDIRECT_URL",
 	}
 
 	cleanup := func() func() {
@@ -397,6 +403,36 @@ func TestLoad(t *testing.T) {
 		if cfg.Sync.MaxInterval != 3600 {
 			t.Errorf("expected default MaxInterval 3600, got %d", cfg.Sync.MaxInterval)
 		}
+		if cfg.Sync.MaxCalendarConcurrency != 4 {
+			t.Errorf("expected default MaxCalendarConcurrency 4, got %d", cfg.Sync.MaxCalendarConcurrency)
+		}
+		if cfg.Sync.MaxEventConcurrency != 8 {
+			t.Errorf("expected default MaxEventConcurrency 8, got %d", cfg.Sync.MaxEventConcurrency)
+		}
+		if cfg.CalDAV.HostRPS != 5.0 {
+			t.Errorf("expected default HostRPS 5.0, got %f", cfg.CalDAV.HostRPS)
+		}
+		if cfg.CalDAV.MaxRetries != 3 {
+			t.Errorf("expected default MaxRetries 3, got %d", cfg.CalDAV.MaxRetries)
+		}
+		if cfg.CalDAV.DiscoveryCacheTTLSecs != 300 {
+			t.Errorf("expected default DiscoveryCacheTTLSecs 300, got %d", cfg.CalDAV.DiscoveryCacheTTLSecs)
+		}
+		if cfg.CalDAV.EventIndexLimit != 50000 {
+			t.Errorf("expected default EventIndexLimit 50000, got %d", cfg.CalDAV.EventIndexLimit)
+		}
+		if cfg.Sync.RetryMaxAttempts != 3 {
+			t.Errorf("expected default RetryMaxAttempts 3, got %d", cfg.Sync.RetryMaxAttempts)
+		}
+		if cfg.Sync.RetryBaseDelaySecs != 60 {
+			t.Errorf("expected default RetryBaseDelaySecs 60, got %d", cfg.Sync.RetryBaseDelaySecs)
+		}
+		if cfg.Sync.AuthFailureThreshold != 3 {
+			t.Errorf("expected default AuthFailureThreshold 3, got %d", cfg.Sync.AuthFailureThreshold)
+		}
+		if cfg.Sync.DriftAlertThreshold != 10 {
+			t.Errorf("expected default DriftAlertThreshold 10, got %d", cfg.Sync.DriftAlertThreshold)
+		}
 		if cfg.Security.SessionMaxAgeSecs != 86400 {
 			t.Errorf("expected default SessionMaxAgeSecs 86400, got %d", cfg.Security.SessionMaxAgeSecs)
 		}
@@ -418,6 +454,14 @@ func TestLoad(t *testing.T) {
 		os.Setenv("RATE_LIMIT_BURST", "10")
 		os.Setenv("MIN_SYNC_INTERVAL", "60")
 		os.Setenv("MAX_SYNC_INTERVAL", "7200")
+		os.Setenv("SYNC_MAX_CALENDAR_CONCURRENCY", "2")
+		os.Setenv("SYNC_MAX_EVENT_CONCURRENCY", "16")
+		os.Setenv("CALDAV_HOST_RPS", "2.5")
+		os.Setenv("CALDAV_MAX_RETRIES", "5")
+		os.Setenv("SYNC_RETRY_MAX_ATTEMPTS", "0")
+		os.Setenv("SYNC_RETRY_BASE_DELAY", "30")
+		os.Setenv("SYNC_AUTH_FAILURE_THRESHOLD", "5")
+		os.Setenv("SYNC_DRIFT_ALERT_THRESHOLD", "0")
 		os.Setenv("SESSION_MAX_AGE_SECS", "3600")
 		os.Setenv("OAUTH_STATE_MAX_AGE_SECS", "600")
 
@@ -447,6 +491,30 @@ func TestLoad(t *testing.T) {
 		if cfg.Sync.MaxInterval != 7200 {
 			t.Errorf("expected MaxInterval 7200, got %d", cfg.Sync.MaxInterval)
 		}
+		if cfg.Sync.MaxCalendarConcurrency != 2 {
+			t.Errorf("expected MaxCalendarConcurrency 2, got %d", cfg.Sync.MaxCalendarConcurrency)
+		}
+		if cfg.Sync.MaxEventConcurrency != 16 {
+			t.Errorf("expected MaxEventConcurrency 16, got %d", cfg.Sync.MaxEventConcurrency)
+		}
+		if cfg.CalDAV.HostRPS != 2.5 {
+			t.Errorf("expected HostRPS 2.5, got %f", cfg.CalDAV.HostRPS)
+		}
+		if cfg.CalDAV.MaxRetries != 5 {
+			t.Errorf("expected MaxRetries 5, got %d", cfg.CalDAV.MaxRetries)
+		}
+		if cfg.Sync.RetryMaxAttempts != 0 {
+			t.Errorf("expected RetryMaxAttempts 0, got %d", cfg.Sync.RetryMaxAttempts)
+		}
+		if cfg.Sync.RetryBaseDelaySecs != 30 {
+			t.Errorf("expected RetryBaseDelaySecs 30, got %d", cfg.Sync.RetryBaseDelaySecs)
+		}
+		if cfg.Sync.AuthFailureThreshold != 5 {
+			t.Errorf("expected AuthFailureThreshold 5, got %d", cfg.Sync.AuthFailureThreshold)
+		}
+		if cfg.Sync.DriftAlertThreshold != 0 {
+			t.Errorf("expected DriftAlertThreshold 0, got %d", cfg.Sync.DriftAlertThreshold)
+		}
 		if cfg.Security.SessionMaxAgeSecs != 3600 {
 			t.Errorf("expected SessionMaxAgeSecs 3600, got %d", cfg.Security.SessionMaxAgeSecs)
 		}
@@ -582,6 +650,138 @@ func TestLoad(t *testing.T) {
 		}
 	})
 
+	t.Run("returns error for zero SYNC_MAX_CALENDAR_CONCURRENCY", func(t *testing.T) {
+		restore := cleanup()
+		defer restore()
+		clearAllEnvVars()
+		setRequiredEnvVars()
+		os.Setenv("SYNC_MAX_CALENDAR_CONCURRENCY", "0")
+
+		_, err := Load()
+		if err == nil {
+			t.Fatal("expected error for zero SYNC_MAX_CALENDAR_CONCURRENCY")
+		}
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig, got %v", err)
+		}
+	})
+
+	t.Run("returns error for negative CALDAV_MAX_RETRIES", func(t *testing.T) {
+		restore := cleanup()
+		defer restore()
+		clearAllEnvVars()
+		setRequiredEnvVars()
+		os.Setenv("CALDAV_MAX_RETRIES", "-1")
+
+		_, err := Load()
+		if err == nil {
+			t.Fatal("expected error for negative CALDAV_MAX_RETRIES")
+		}
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig, got %v", err)
+		}
+	})
+
+	t.Run("returns error for negative CALDAV_DISCOVERY_CACHE_TTL", func(t *testing.T) {
+		restore := cleanup()
+		defer restore()
+		clearAllEnvVars()
+		setRequiredEnvVars()
+		os.Setenv("CALDAV_DISCOVERY_CACHE_TTL", "-1")
+
+		_, err := Load()
+		if err == nil {
+			t.Fatal("expected error for negative CALDAV_DISCOVERY_CACHE_TTL")
+		}
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig, got %v", err)
+		}
+	})
+
+	t.Run("returns error for negative CALDAV_EVENT_INDEX_LIMIT", func(t *testing.T) {
+		restore := cleanup()
+		defer restore()
+		clearAllEnvVars()
+		setRequiredEnvVars()
+		os.Setenv("CALDAV_EVENT_INDEX_LIMIT", "-1")
+
+		_, err := Load()
+		if err == nil {
+			t.Fatal("expected error for negative CALDAV_EVENT_INDEX_LIMIT")
+		}
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig, got %v", err)
+		}
+	})
+
+	t.Run("parses OUTBOUND_ALLOWLIST", func(t *testing.T) {
+		restore := cleanup()
+		defer restore()
+		clearAllEnvVars()
+		setRequiredEnvVars()
+		os.Setenv("OUTBOUND_ALLOWLIST", "192.168.1.0/24, caldav.home.lan,,10.0.0.5")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{"192.168.1.0/24", "caldav.home.lan", "10.0.0.5"}
+		if len(cfg.Security.OutboundAllowlist) != len(expected) {
+			t.Fatalf("expected %v, got %v", expected, cfg.Security.OutboundAllowlist)
+		}
+		for i := range expected {
+			if cfg.Security.OutboundAllowlist[i] != expected[i] {
+				t.Errorf("entry %d: expected %q, got %q", i, expected[i], cfg.Security.OutboundAllowlist[i])
+			}
+		}
+	})
+
+	t.Run("returns error for invalid OUTBOUND_ALLOWLIST", func(t *testing.T) {
+		restore := cleanup()
+		defer restore()
+		clearAllEnvVars()
+		setRequiredEnvVars()
+		os.Setenv("OUTBOUND_ALLOWLIST", "10.0.0.0/33")
+
+		_, err := Load()
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig, got %v", err)
+		}
+	})
+
+	t.Run("derives Google OAuth redirect URL from BASE_URL", func(t *testing.T) {
+		restore := cleanup()
+		defer restore()
+		clearAllEnvVars()
+		setRequiredEnvVars()
+		os.Setenv("GOOGLE_OAUTH_CLIENT_ID", "google-client")
+		os.Setenv("GOOGLE_OAUTH_CLIENT_SECRET", "google-secret")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !cfg.GoogleOAuth.Enabled() {
+			t.Error("expected Google OAuth to be enabled")
+		}
+		if cfg.GoogleOAuth.RedirectURL != "https://example.com/auth/google/callback" {
+			t.Errorf("unexpected redirect URL %q", cfg.GoogleOAuth.RedirectURL)
+		}
+	})
+
+	t.Run("returns error for Google OAuth client without secret", func(t *testing.T) {
+		restore := cleanup()
+		defer restore()
+		clearAllEnvVars()
+		setRequiredEnvVars()
+		os.Setenv("GOOGLE_OAUTH_CLIENT_ID", "google-client")
+
+		_, err := Load()
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig, got %v", err)
+		}
+	})
+
 	t.Run("returns error for invalid MAX_SYNC_INTERVAL", func(t *testing.T) {
 		restore := cleanup()
 		defer restore()
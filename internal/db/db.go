@@ -224,6 +224,193 @@ func (db *DB) migrate() error {
 
 		// Migration: Add sync_days_past column to sources (default 30 days)
 		`ALTER TABLE sources ADD COLUMN sync_days_past INTEGER NOT NULL DEFAULT 30`,
+
+		// Migration: Add per-source concurrency limits (1 = sequential, the previous behavior)
+		`ALTER TABLE sources ADD COLUMN calendar_concurrency INTEGER NOT NULL DEFAULT 1`,
+		`ALTER TABLE sources ADD COLUMN event_concurrency INTEGER NOT NULL DEFAULT 1`,
+
+		// Migration: Add HTTP throttling stats to sync_logs
+		`ALTER TABLE sync_logs ADD COLUMN requests_throttled INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE sync_logs ADD COLUMN requests_retried INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE sync_logs ADD COLUMN throttle_wait_ms INTEGER NOT NULL DEFAULT 0`,
+
+		// Migration: Track consecutive authentication failures for the circuit breaker
+		`ALTER TABLE sources ADD COLUMN auth_failures INTEGER NOT NULL DEFAULT 0`,
+
+		// Sync journal: write-ahead log of the writes a sync run intends to make.
+		// Rows of finished runs are removed; rows left behind mark a run interrupted by a crash.
+		`CREATE TABLE IF NOT EXISTS sync_journal (
+			id TEXT PRIMARY KEY,
+			run_id TEXT NOT NULL,
+			source_id TEXT NOT NULL,
+			calendar_href TEXT NOT NULL,
+			event_uid TEXT NOT NULL,
+			operation TEXT NOT NULL,
+			event_path TEXT NOT NULL,
+			state TEXT NOT NULL DEFAULT 'pending',
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
+		)`,
+
+		// Indexes on run_id and source_id for sync_journal
+		`CREATE INDEX IF NOT EXISTS idx_sync_journal_run_id ON sync_journal(run_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_sync_journal_source_id ON sync_journal(source_id)`,
+
+		// Migration: Add per-server transport settings (JSON, sensitive values encrypted)
+		`ALTER TABLE sources ADD COLUMN source_transport TEXT`,
+		`ALTER TABLE sources ADD COLUMN dest_transport TEXT`,
+		`ALTER TABLE sources ADD COLUMN source_auth TEXT`,
+		`ALTER TABLE sources ADD COLUMN dest_auth TEXT`,
+
+		// Migration: Opt-in to creating missing destination calendars
+		`ALTER TABLE sources ADD COLUMN create_calendars INTEGER NOT NULL DEFAULT 0`,
+
+		// Calendar mappings: the destination calendar that receives each source calendar
+		`CREATE TABLE IF NOT EXISTS calendar_mappings (
+			id TEXT PRIMARY KEY,
+			source_id TEXT NOT NULL,
+			calendar_href TEXT NOT NULL,
+			dest_href TEXT NOT NULL,
+			created INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(source_id, calendar_href),
+			FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
+		)`,
+
+		// Migration: Opt-in to copying calendar metadata to destination calendars
+		`ALTER TABLE sources ADD COLUMN sync_calendar_metadata INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE calendar_mappings ADD COLUMN metadata_hash TEXT NOT NULL DEFAULT ''`,
+
+		// Calendar identities: source calendars seen during sync, to detect moved calendars
+		`CREATE TABLE IF NOT EXISTS calendar_identities (
+			id TEXT PRIMARY KEY,
+			source_id TEXT NOT NULL,
+			calendar_href TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			resource_id TEXT NOT NULL DEFAULT '',
+			fingerprint TEXT NOT NULL,
+			last_seen_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(source_id, calendar_href),
+			FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
+		)`,
+
+		// Server capabilities: probed feature profile of each endpoint of a source
+		`CREATE TABLE IF NOT EXISTS server_capabilities (
+			source_id TEXT NOT NULL,
+			endpoint TEXT NOT NULL,
+			server TEXT NOT NULL DEFAULT '',
+			sync_collection INTEGER NOT NULL DEFAULT 0,
+			calendar_query INTEGER NOT NULL DEFAULT 0,
+			time_range INTEGER NOT NULL DEFAULT 0,
+			multiget INTEGER NOT NULL DEFAULT 0,
+			ctag INTEGER NOT NULL DEFAULT 0,
+			mkcalendar INTEGER NOT NULL DEFAULT 0,
+			conditional_put INTEGER NOT NULL DEFAULT 0,
+			probed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (source_id, endpoint),
+			FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
+		)`,
+
+		// Event changes: audit trail of every event write made by sync runs
+		`CREATE TABLE IF NOT EXISTS event_changes (
+			id TEXT PRIMARY KEY,
+			run_id TEXT NOT NULL,
+			source_id TEXT NOT NULL,
+			calendar_href TEXT NOT NULL,
+			event_uid TEXT NOT NULL,
+			summary TEXT NOT NULL DEFAULT '',
+			summary_hash TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL,
+			direction TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			result TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
+		)`,
+
+		// Indexes for listing a source's changes by time and looking up an event
+		`CREATE INDEX IF NOT EXISTS idx_event_changes_source_created ON event_changes(source_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_event_changes_source_uid ON event_changes(source_id, event_uid)`,
+		`CREATE INDEX IF NOT EXISTS idx_event_changes_created_at ON event_changes(created_at)`,
+
+		// Calendar sync logs: the result of each calendar within a sync log
+		`CREATE TABLE IF NOT EXISTS calendar_sync_logs (
+			id TEXT PRIMARY KEY,
+			sync_log_id TEXT NOT NULL,
+			source_id TEXT NOT NULL,
+			calendar_href TEXT NOT NULL,
+			calendar_name TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			mode TEXT NOT NULL,
+			events_created INTEGER NOT NULL DEFAULT 0,
+			events_updated INTEGER NOT NULL DEFAULT 0,
+			events_deleted INTEGER NOT NULL DEFAULT 0,
+			events_skipped INTEGER NOT NULL DEFAULT 0,
+			events_processed INTEGER NOT NULL DEFAULT 0,
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			details TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (sync_log_id) REFERENCES sync_logs(id) ON DELETE CASCADE,
+			FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
+		)`,
+
+		// Indexes for loading the calendars of a log and the latest result of each calendar
+		`CREATE INDEX IF NOT EXISTS idx_calendar_sync_logs_sync_log ON calendar_sync_logs(sync_log_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_calendar_sync_logs_source_calendar ON calendar_sync_logs(source_id, calendar_href, created_at DESC)`,
+
+		// Migration: Per-source dedupe policy and the properties duplicates must share
+		`ALTER TABLE sources ADD COLUMN dedupe_policy TEXT NOT NULL DEFAULT 'auto'`,
+		`ALTER TABLE sources ADD COLUMN dedupe_fields TEXT NOT NULL DEFAULT ''`,
+
+		// Duplicate groups found by syncs with the report dedupe policy, awaiting review
+		`CREATE TABLE IF NOT EXISTS duplicate_groups (
+			id TEXT PRIMARY KEY,
+			source_id TEXT NOT NULL,
+			calendar_href TEXT NOT NULL,
+			dest_href TEXT NOT NULL,
+			summary TEXT NOT NULL DEFAULT '',
+			start_time TEXT NOT NULL DEFAULT '',
+			keep_event TEXT NOT NULL,
+			duplicates TEXT NOT NULL,
+			detected_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_duplicate_groups_source_calendar ON duplicate_groups(source_id, calendar_href)`,
+
+		// Migration: Keep the (encrypted) payload of malformed events and what automatic repair makes of it
+		`ALTER TABLE malformed_events ADD COLUMN raw_payload TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE malformed_events ADD COLUMN etag TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE malformed_events ADD COLUMN event_uid TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE malformed_events ADD COLUMN calendar_href TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE malformed_events ADD COLUMN dest_href TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE malformed_events ADD COLUMN repairable INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE malformed_events ADD COLUMN repairs TEXT NOT NULL DEFAULT ''`,
+
+		// Migration: Sync automatically repaired versions of malformed source events to the destination
+		`ALTER TABLE sources ADD COLUMN sync_repaired_events INTEGER NOT NULL DEFAULT 0`,
+
+		// Migration: Periodically compare sources with their destinations without syncing
+		`ALTER TABLE sources ADD COLUMN drift_check_interval INTEGER NOT NULL DEFAULT 0`,
+
+		// Drift reports: the result of each drift check of a source
+		`CREATE TABLE IF NOT EXISTS drift_reports (
+			id TEXT PRIMARY KEY,
+			source_id TEXT NOT NULL,
+			status TEXT NOT NULL,
+			message TEXT NOT NULL DEFAULT '',
+			mismatches INTEGER NOT NULL DEFAULT 0,
+			threshold INTEGER NOT NULL DEFAULT 0,
+			over_threshold INTEGER NOT NULL DEFAULT 0,
+			calendars TEXT NOT NULL DEFAULT '[]',
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_drift_reports_source_created ON drift_reports(source_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_drift_reports_created_at ON drift_reports(created_at)`,
 	}
 
 	for _, migration := range migrations {
@@ -1,7 +1,11 @@
 package db
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // SyncStatus represents the status of a sync operation.
@@ -13,6 +17,13 @@ const (
 	SyncStatusSuccess SyncStatus = "success"
 	SyncStatusPartial SyncStatus = "partial" // Sync completed with some non-critical warnings
 	SyncStatusError   SyncStatus = "error"   // Sync failed due to critical error
+
+	// SyncStatusCancelled marks a sync stopped on request before it finished.
+	SyncStatusCancelled SyncStatus = "cancelled"
+
+	// SyncStatusCredentialsInvalid marks a source paused by the circuit breaker after
+	// repeated authentication failures. Scheduled syncs are skipped until credentials change.
+	SyncStatusCredentialsInvalid SyncStatus = "credentials_invalid"
 )
 
 // ConflictStrategy represents how to handle sync conflicts.
@@ -84,12 +95,70 @@ func (sd SyncDirection) IsValid() bool {
 	return ValidSyncDirections[sd]
 }
 
+// DedupePolicy is what a sync does with duplicate events on the destination.
+type DedupePolicy string
+
+const (
+	DedupeOff    DedupePolicy = "off"    // Neither look for nor avoid duplicates
+	DedupeReport DedupePolicy = "report" // Store duplicate groups for review
+	DedupeAuto   DedupePolicy = "auto"   // Delete duplicates right away
+)
+
+// ValidDedupePolicies contains all valid dedupe policy values.
+var ValidDedupePolicies = map[DedupePolicy]bool{
+	DedupeOff:    true,
+	DedupeReport: true,
+	DedupeAuto:   true,
+}
+
+// IsValid returns true if the dedupe policy is a known valid value.
+func (p DedupePolicy) IsValid() bool {
+	return ValidDedupePolicies[p]
+}
+
+// DedupeField is an event property compared when looking for duplicates.
+type DedupeField string
+
+const (
+	DedupeSummary  DedupeField = "summary"
+	DedupeStart    DedupeField = "dtstart"
+	DedupeEnd      DedupeField = "dtend"
+	DedupeLocation DedupeField = "location"
+	DedupeRRule    DedupeField = "rrule"
+)
+
+// ValidDedupeFields contains all valid dedupe field values.
+var ValidDedupeFields = map[DedupeField]bool{
+	DedupeSummary:  true,
+	DedupeStart:    true,
+	DedupeEnd:      true,
+	DedupeLocation: true,
+	DedupeRRule:    true,
+}
+
+// IsValid returns true if the dedupe field is a known valid value.
+func (f DedupeField) IsValid() bool {
+	return ValidDedupeFields[f]
+}
+
+// DefaultDedupeFields are compared when a source doesn't configure its own.
+var DefaultDedupeFields = []DedupeField{DedupeSummary, DedupeStart}
+
+// GetDedupeFields returns the fields duplicates of the source must share.
+func (s *Source) GetDedupeFields() []DedupeField {
+	if len(s.DedupeFields) == 0 {
+		return DefaultDedupeFields
+	}
+	return s.DedupeFields
+}
+
 // SourcePreset contains preset configuration for known calendar providers.
 type SourcePreset struct {
 	Name        string
 	Type        SourceType
 	BaseURL     string
 	Description string
+	Domains     []string // Email domains hosted by the provider, for service discovery
 }
 
 // SourcePresets maps source types to their preset configurations.
@@ -99,18 +168,21 @@ var SourcePresets = map[SourceType]SourcePreset{
 		Type:        SourceTypeICloud,
 		BaseURL:     "https://caldav.icloud.com/",
 		Description: "Apple iCloud Calendar",
+		Domains:     []string{"icloud.com", "me.com", "mac.com"},
 	},
 	SourceTypeGoogle: {
 		Name:        "Google Calendar",
 		Type:        SourceTypeGoogle,
 		BaseURL:     "https://apidata.googleusercontent.com/caldav/v2/",
 		Description: "Google Calendar (requires OAuth)",
+		Domains:     []string{"gmail.com", "googlemail.com"},
 	},
 	SourceTypeFastmail: {
 		Name:        "Fastmail",
 		Type:        SourceTypeFastmail,
 		BaseURL:     "https://caldav.fastmail.com/dav/",
 		Description: "Fastmail Calendar",
+		Domains:     []string{"fastmail.com", "fastmail.fm"},
 	},
 	SourceTypeNextcloud: {
 		Name:        "Nextcloud",
@@ -135,9 +207,23 @@ var SourcePresets = map[SourceType]SourcePreset{
 		Type:        SourceTypeOutlook,
 		BaseURL:     "https://outlook.office365.com/caldav/",
 		Description: "Microsoft Outlook Calendar",
+		Domains:     []string{"outlook.com", "hotmail.com", "live.com"},
 	},
 }
 
+// PresetForDomain returns the preset of the provider hosting an email domain.
+func PresetForDomain(domain string) (SourcePreset, bool) {
+	domain = strings.ToLower(domain)
+	for _, preset := range SourcePresets {
+		for _, d := range preset.Domains {
+			if d == domain {
+				return preset, true
+			}
+		}
+	}
+	return SourcePreset{}, false
+}
+
 // User represents a user in the system.
 type User struct {
 	ID        string    `json:"id"`
@@ -162,27 +248,109 @@ type UserAlertPreferences struct {
 
 // Source represents a calendar source configuration.
 type Source struct {
-	ID                string           `json:"id"`
-	UserID            string           `json:"user_id"`
-	Name              string           `json:"name"`
-	SourceType        SourceType       `json:"source_type"`
-	SourceURL         string           `json:"source_url"`
-	SourceUsername    string           `json:"source_username"`
-	SourcePassword    string           `json:"-"` // Never include in JSON
-	DestURL           string           `json:"dest_url"`
-	DestUsername      string           `json:"dest_username"`
-	DestPassword      string           `json:"-"` // Never include in JSON
-	SyncInterval      int              `json:"sync_interval"`
-	SyncDaysPast      int              `json:"sync_days_past"` // How many days in the past to sync (0 = unlimited)
-	SyncDirection     SyncDirection    `json:"sync_direction"`
-	ConflictStrategy  ConflictStrategy `json:"conflict_strategy"`
-	SelectedCalendars []CalendarConfig `json:"selected_calendars"` // Calendar configs to sync (empty = all)
-	Enabled           bool             `json:"enabled"`
-	LastSyncAt        *time.Time       `json:"last_sync_at"`
-	LastSyncStatus    SyncStatus       `json:"last_sync_status"`
-	LastSyncMessage   string           `json:"last_sync_message"`
-	CreatedAt         time.Time        `json:"created_at"`
-	UpdatedAt         time.Time        `json:"updated_at"`
+	ID                  string            `json:"id"`
+	UserID              string            `json:"user_id"`
+	Name                string            `json:"name"`
+	SourceType          SourceType        `json:"source_type"`
+	SourceURL           string            `json:"source_url"`
+	SourceUsername      string            `json:"source_username"`
+	SourcePassword      string            `json:"-"` // Never include in JSON
+	DestURL             string            `json:"dest_url"`
+	DestUsername        string            `json:"dest_username"`
+	DestPassword        string            `json:"-"` // Never include in JSON
+	SyncInterval        int               `json:"sync_interval"`
+	SyncDaysPast        int               `json:"sync_days_past"` // How many days in the past to sync (0 = unlimited)
+	SyncDirection       SyncDirection     `json:"sync_direction"`
+	ConflictStrategy    ConflictStrategy  `json:"conflict_strategy"`
+	SelectedCalendars   []CalendarConfig  `json:"selected_calendars"`   // Calendar configs to sync (empty = all)
+	CalendarConcurrency int               `json:"calendar_concurrency"` // Calendars synced in parallel (capped globally)
+	EventConcurrency    int               `json:"event_concurrency"`    // Event writes in flight per calendar (capped globally)
+	Enabled             bool              `json:"enabled"`
+	LastSyncAt          *time.Time        `json:"last_sync_at"`
+	LastSyncStatus      SyncStatus        `json:"last_sync_status"`
+	LastSyncMessage     string            `json:"last_sync_message"`
+	AuthFailures        int               `json:"auth_failures"`          // Consecutive authentication failures (maintained by the scheduler)
+	CreateCalendars     bool              `json:"create_calendars"`       // Create missing destination calendars with MKCALENDAR
+	SyncCalendarMeta    bool              `json:"sync_calendar_metadata"` // Copy calendar name, color, description and order to the destination
+	DedupePolicy        DedupePolicy      `json:"dedupe_policy"`          // What to do with duplicate events calbridge wrote
+	DedupeFields        []DedupeField     `json:"dedupe_fields"`          // Properties duplicates must share (empty = summary and start)
+	SyncRepairedEvents  bool              `json:"sync_repaired_events"`   // Sync repaired versions of malformed source events to the destination
+	DriftCheckInterval  int               `json:"drift_check_interval"`   // Seconds between drift checks (0 = disabled)
+	SourceTransport     TransportSettings `json:"-"`                      // Connection settings for the source server
+	DestTransport       TransportSettings `json:"-"`                      // Connection settings for the destination server
+	SourceAuth          AuthSettings      `json:"-"`                      // How to authenticate to the source server
+	DestAuth            AuthSettings      `json:"-"`                      // How to authenticate to the destination server
+	CreatedAt           time.Time         `json:"created_at"`
+	UpdatedAt           time.Time         `json:"updated_at"`
+}
+
+// TransportSettings holds per-server connection settings of a source, stored as JSON.
+// ClientKey, ProxyURL and the header values are encrypted by the caller, like passwords.
+type TransportSettings struct {
+	CACert             string            `json:"ca_cert,omitempty"`              // PEM bundle trusted in addition to the system roots
+	ClientCert         string            `json:"client_cert,omitempty"`          // PEM client certificate
+	ClientKey          string            `json:"client_key,omitempty"`           // Encrypted PEM private key
+	InsecureSkipVerify bool              `json:"insecure_skip_verify,omitempty"` // Skip server certificate verification
+	ProxyURL           string            `json:"proxy_url,omitempty"`            // Encrypted proxy URL
+	UserAgent          string            `json:"user_agent,omitempty"`
+	Headers            map[string]string `json:"headers,omitempty"` // Header name -> encrypted value
+}
+
+// IsZero reports whether no settings are configured.
+func (t TransportSettings) IsZero() bool {
+	return t.CACert == "" && t.ClientCert == "" && t.ClientKey == "" && !t.InsecureSkipVerify &&
+		t.ProxyURL == "" && t.UserAgent == "" && len(t.Headers) == 0
+}
+
+// AuthMethod is how calbridge authenticates to a CalDAV server.
+type AuthMethod string
+
+const (
+	AuthMethodBasic  AuthMethod = "basic"  // Username and password (the default)
+	AuthMethodDigest AuthMethod = "digest" // Username and password with HTTP Digest
+	AuthMethodBearer AuthMethod = "bearer" // Static bearer token
+	AuthMethodOAuth2 AuthMethod = "oauth2" // Access token renewed with a refresh token
+)
+
+// ValidAuthMethods contains all valid auth method values.
+var ValidAuthMethods = map[AuthMethod]bool{
+	AuthMethodBasic:  true,
+	AuthMethodDigest: true,
+	AuthMethodBearer: true,
+	AuthMethodOAuth2: true,
+}
+
+// IsValid returns true if the auth method is a known valid value.
+func (m AuthMethod) IsValid() bool {
+	return ValidAuthMethods[m]
+}
+
+// Endpoint identifies one of the two servers of a source.
+type Endpoint string
+
+const (
+	EndpointSource Endpoint = "source"
+	EndpointDest   Endpoint = "dest"
+)
+
+// AuthSettings holds how calbridge authenticates to one server of a source, stored as
+// JSON. Basic and Digest use the username and password columns; tokens and the client
+// secret are encrypted by the caller, like passwords.
+type AuthSettings struct {
+	Method       AuthMethod `json:"method,omitempty"`        // Empty means basic
+	AccessToken  string     `json:"access_token,omitempty"`  // Encrypted bearer or OAuth2 access token
+	RefreshToken string     `json:"refresh_token,omitempty"` // Encrypted OAuth2 refresh token
+	TokenExpiry  *time.Time `json:"token_expiry,omitempty"`  // When the OAuth2 access token expires
+	TokenURL     string     `json:"token_url,omitempty"`     // OAuth2 token endpoint
+	ClientID     string     `json:"client_id,omitempty"`
+	ClientSecret string     `json:"client_secret,omitempty"` // Encrypted
+	Scopes       []string   `json:"scopes,omitempty"`
+}
+
+// IsZero reports whether the settings are the default (basic auth).
+func (a AuthSettings) IsZero() bool {
+	return (a.Method == "" || a.Method == AuthMethodBasic) && a.AccessToken == "" && a.RefreshToken == "" &&
+		a.TokenExpiry == nil && a.TokenURL == "" && a.ClientID == "" && a.ClientSecret == "" && len(a.Scopes) == 0
 }
 
 // SyncState represents the synchronization state for a calendar.
@@ -210,6 +378,106 @@ type SyncLog struct {
 	EventsProcessed int           `json:"events_processed"`
 	Duration        time.Duration `json:"duration"`
 	CreatedAt       time.Time     `json:"created_at"`
+
+	// HTTP throttling observed during the sync (429/503 responses from the servers)
+	RequestsThrottled int           `json:"requests_throttled"`
+	RequestsRetried   int           `json:"requests_retried"`
+	ThrottleWait      time.Duration `json:"throttle_wait"`
+
+	// Results of the individual calendars; stored with the log but not loaded by GetSyncLogs
+	Calendars []*CalendarSyncLog `json:"calendars,omitempty"`
+}
+
+// DuplicateGroup is a set of destination events found to be duplicates of each other
+// while the dedupe policy of their source was report, awaiting review.
+type DuplicateGroup struct {
+	ID           string           `json:"id"`
+	SourceID     string           `json:"source_id"`
+	CalendarHref string           `json:"calendar_href"` // Source calendar whose destination holds the events
+	DestHref     string           `json:"dest_href"`
+	Summary      string           `json:"summary"` // Redacted to its first characters
+	StartTime    string           `json:"start_time"`
+	Keep         DuplicateEvent   `json:"keep"`       // The event that would be kept
+	Duplicates   []DuplicateEvent `json:"duplicates"` // The events that would be deleted
+	DetectedAt   time.Time        `json:"detected_at"`
+}
+
+// DuplicateEvent identifies one event of a DuplicateGroup.
+type DuplicateEvent struct {
+	Path string `json:"path"`
+	UID  string `json:"uid"`
+	ETag string `json:"etag,omitempty"`
+}
+
+// DriftStatus is the outcome of a drift check.
+type DriftStatus string
+
+const (
+	DriftStatusOK     DriftStatus = "ok"     // Source and destination match
+	DriftStatusDrift  DriftStatus = "drift"  // Mismatches were found
+	DriftStatusFailed DriftStatus = "failed" // The check couldn't be completed
+)
+
+// DriftReport is the result of comparing the calendars of a source with their destination
+// calendars without syncing.
+type DriftReport struct {
+	ID            string          `json:"id"`
+	SourceID      string          `json:"source_id"`
+	Status        DriftStatus     `json:"status"`
+	Message       string          `json:"message"`
+	Mismatches    int             `json:"mismatches"`     // Mismatches of all calendars
+	Threshold     int             `json:"threshold"`      // Alert threshold when the check ran
+	OverThreshold bool            `json:"over_threshold"` // Mismatches exceeded the threshold; kept from the previous report when the check failed
+	Calendars     []CalendarDrift `json:"calendars"`
+	Duration      time.Duration   `json:"duration"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// CalendarDrift is the drift of one calendar, part of a DriftReport.
+type CalendarDrift struct {
+	CalendarHref    string   `json:"calendar_href"`
+	CalendarName    string   `json:"calendar_name"`
+	DestHref        string   `json:"dest_href"`
+	SourceEvents    int      `json:"source_events"`
+	DestEvents      int      `json:"dest_events"`
+	MissingOnDest   int      `json:"missing_on_dest"`   // Source events the destination lacks
+	MissingOnSource int      `json:"missing_on_source"` // Synced events still on the destination but gone from the source
+	Differs         int      `json:"differs"`           // Events whose content differs
+	Duplicates      int      `json:"duplicates"`        // Duplicates calbridge wrote to the destination
+	UIDs            []string `json:"uids,omitempty"`    // UIDs of mismatched events, capped
+	Error           string   `json:"error,omitempty"`
+}
+
+// Mismatches returns the number of mismatched events of the calendar.
+func (c CalendarDrift) Mismatches() int {
+	return c.MissingOnDest + c.MissingOnSource + c.Differs + c.Duplicates
+}
+
+// SyncMode is how a calendar was synced.
+type SyncMode string
+
+const (
+	SyncModeIncremental SyncMode = "incremental" // WebDAV-Sync changes since the last token
+	SyncModeFull        SyncMode = "full"        // All events compared
+)
+
+// CalendarSyncLog is the result of syncing one calendar, part of a SyncLog.
+type CalendarSyncLog struct {
+	ID              string        `json:"id"`
+	SyncLogID       string        `json:"sync_log_id"`
+	SourceID        string        `json:"source_id"`
+	CalendarHref    string        `json:"calendar_href"`
+	CalendarName    string        `json:"calendar_name"`
+	Status          SyncStatus    `json:"status"`
+	Mode            SyncMode      `json:"mode"`
+	EventsCreated   int           `json:"events_created"`
+	EventsUpdated   int           `json:"events_updated"`
+	EventsDeleted   int           `json:"events_deleted"`
+	EventsSkipped   int           `json:"events_skipped"`
+	EventsProcessed int           `json:"events_processed"`
+	Duration        time.Duration `json:"duration"`
+	Details         string        `json:"details"` // Errors and warnings of the calendar
+	CreatedAt       time.Time     `json:"created_at"`
 }
 
 // CalendarConfig holds per-calendar configuration including sync direction.
@@ -227,6 +495,47 @@ func (c CalendarConfig) GetSyncDirection(sourceDefault SyncDirection) SyncDirect
 	return c.SyncDirection
 }
 
+// CalendarMapping records the destination calendar that receives a source calendar's
+// events, so later runs keep using it.
+type CalendarMapping struct {
+	ID           string    `json:"id"`
+	SourceID     string    `json:"source_id"`
+	CalendarHref string    `json:"calendar_href"` // Source calendar
+	DestHref     string    `json:"dest_href"`     // Destination calendar
+	Created      bool      `json:"created"`       // calbridge created the destination calendar
+	MetadataHash string    `json:"metadata_hash"` // Fingerprint of the source metadata last copied to the destination
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ServerCapabilities is the profile of what the CalDAV server of one endpoint of a
+// source supports, detected by probing it. Clients pick their strategies from it.
+type ServerCapabilities struct {
+	SourceID       string    `json:"source_id"`
+	Endpoint       Endpoint  `json:"endpoint"`
+	Server         string    `json:"server"`          // Server header, for reference
+	SyncCollection bool      `json:"sync_collection"` // RFC 6578 WebDAV-Sync
+	CalendarQuery  bool      `json:"calendar_query"`  // calendar-query lists the same events as PROPFIND (SOGo may return none)
+	TimeRange      bool      `json:"time_range"`      // calendar-query with a time-range filter
+	MultiGet       bool      `json:"multiget"`        // calendar-multiget REPORT
+	CTag           bool      `json:"ctag"`            // getctag collection property
+	MKCalendar     bool      `json:"mkcalendar"`      // Creating calendars with MKCALENDAR
+	ConditionalPut bool      `json:"conditional_put"` // If-Match on PUT; only probed where calbridge writes
+	ProbedAt       time.Time `json:"probed_at"`
+}
+
+// CalendarIdentity records a source calendar seen during sync, so a calendar whose href
+// changed can be recognized by its fingerprint and keep its sync state.
+type CalendarIdentity struct {
+	ID           string    `json:"id"`
+	SourceID     string    `json:"source_id"`
+	CalendarHref string    `json:"calendar_href"`
+	Name         string    `json:"name"`
+	ResourceID   string    `json:"resource_id"` // Last segment of the href, kept by most servers when moving calendars
+	Fingerprint  string    `json:"fingerprint"` // Display name and resource ID
+	LastSeenAt   time.Time `json:"last_seen_at"`
+}
+
 // SyncedEvent tracks known event UIDs for deletion detection in two-way sync.
 type SyncedEvent struct {
 	ID           string    `json:"id"`
@@ -239,12 +548,150 @@ type SyncedEvent struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// JournalOperation is a kind of write recorded in the sync journal.
+type JournalOperation string
+
+const (
+	JournalPutDest      JournalOperation = "put_dest"      // Create or update an event on the destination
+	JournalDeleteDest   JournalOperation = "delete_dest"   // Delete an event from the destination
+	JournalPutSource    JournalOperation = "put_source"    // Update an event on the source (two-way sync)
+	JournalDeleteSource JournalOperation = "delete_source" // Delete an event from the source (two-way sync)
+)
+
+// IsPut reports whether the operation writes an event (as opposed to deleting one).
+func (o JournalOperation) IsPut() bool {
+	return o == JournalPutDest || o == JournalPutSource
+}
+
+// JournalState is the progress of a journaled write.
+type JournalState string
+
+const (
+	JournalPending JournalState = "pending" // Recorded but not confirmed; may or may not have reached the server
+	JournalDone    JournalState = "done"    // Write succeeded and synced_events was updated
+	JournalFailed  JournalState = "failed"  // Write was rejected by the server
+)
+
+// JournalEntry is a write-ahead record of a write a sync run intends to make.
+type JournalEntry struct {
+	ID           string           `json:"id"`
+	RunID        string           `json:"run_id"`
+	SourceID     string           `json:"source_id"`
+	CalendarHref string           `json:"calendar_href"` // Source calendar the event belongs to
+	EventUID     string           `json:"event_uid"`
+	Operation    JournalOperation `json:"operation"`
+	EventPath    string           `json:"event_path"` // Event path (or target calendar path for creates)
+	State        JournalState     `json:"state"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
+
+// ChangeAction is what a sync run did to an event.
+type ChangeAction string
+
+const (
+	ChangeCreate ChangeAction = "create"
+	ChangeUpdate ChangeAction = "update"
+	ChangeDelete ChangeAction = "delete"
+)
+
+// ChangeDirection is the server a change was made on.
+type ChangeDirection string
+
+const (
+	ChangeToDest   ChangeDirection = "to_dest"
+	ChangeToSource ChangeDirection = "to_source" // Two-way sync only
+)
+
+// ChangeReason explains why a sync run changed an event.
+type ChangeReason string
+
+const (
+	ReasonNewOnSource     ChangeReason = "new_on_source"
+	ReasonChangedOnSource ChangeReason = "changed_on_source"
+	ReasonChangedOnDest   ChangeReason = "changed_on_dest" // Conflict won by the destination
+	ReasonDeletedOnSource ChangeReason = "deleted_on_source"
+	ReasonDeletedOnDest   ChangeReason = "deleted_on_dest" // Two-way sync only
+	ReasonOrphan          ChangeReason = "orphan"          // On the destination but not the source (one-way sync)
+	ReasonDuplicate       ChangeReason = "duplicate"       // Same summary and start as another destination event
+	ReasonRepaired        ChangeReason = "repaired"        // Repaired version of a malformed source event
+)
+
+// ChangeResult is whether the server accepted a change.
+type ChangeResult string
+
+const (
+	ChangeSucceeded ChangeResult = "success"
+	ChangeFailed    ChangeResult = "failed"
+)
+
+// EventChange is an audit record of one write a sync run made to an event.
+// Summaries are stored redacted, with a hash to search by the full summary.
+type EventChange struct {
+	ID           string          `json:"id"`
+	RunID        string          `json:"run_id"`
+	SourceID     string          `json:"source_id"`
+	CalendarHref string          `json:"calendar_href"` // Source calendar the event belongs to
+	EventUID     string          `json:"event_uid"`
+	Summary      string          `json:"summary"`
+	SummaryHash  string          `json:"summary_hash"`
+	Action       ChangeAction    `json:"action"`
+	Direction    ChangeDirection `json:"direction"`
+	Reason       ChangeReason    `json:"reason"`
+	Result       ChangeResult    `json:"result"`
+	Error        string          `json:"error,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// summaryPrefixLen is how many characters of a summary are kept by RedactSummary.
+const summaryPrefixLen = 3
+
+// HashSummary returns the hash changes are searched by. Case and surrounding space
+// are ignored.
+func HashSummary(summary string) string {
+	summary = strings.ToLower(strings.TrimSpace(summary))
+	if summary == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(summary))
+	return hex.EncodeToString(sum[:16])
+}
+
+// RedactSummary keeps the first few characters of a summary, enough for its owner
+// to recognize it in an audit trail without storing it.
+func RedactSummary(summary string) string {
+	summary = strings.TrimSpace(summary)
+	if utf8.RuneCountInString(summary) <= summaryPrefixLen {
+		return summary
+	}
+	return string([]rune(summary)[:summaryPrefixLen]) + "…"
+}
+
+// EventChangeFilter selects event changes. Zero fields don't filter.
+type EventChangeFilter struct {
+	EventUID    string
+	SummaryHash string
+	Action      ChangeAction
+	Since       time.Time
+	Until       time.Time
+	Limit       int
+	Offset      int
+}
+
 // MalformedEvent tracks corrupted calendar events that cannot be synced.
+// The raw payload as served by the source is kept encrypted so the event can be repaired.
 type MalformedEvent struct {
 	ID           string    `json:"id"`
 	SourceID     string    `json:"source_id"`
 	SourceName   string    `json:"source_name"` // Populated via join
 	EventPath    string    `json:"event_path"`
 	ErrorMessage string    `json:"error_message"`
+	RawPayload   string    `json:"-"`             // Encrypted; empty if the payload couldn't be fetched
+	ETag         string    `json:"etag"`          // ETag of the payload, to repair the source event only if unchanged
+	EventUID     string    `json:"event_uid"`     // UID of the repaired event
+	CalendarHref string    `json:"calendar_href"` // Source calendar the event belongs to
+	DestHref     string    `json:"dest_href"`     // Destination calendar the calendar syncs to
+	Repairable   bool      `json:"repairable"`    // Automatic repair produced a valid event
+	Repairs      []string  `json:"repairs"`       // Repairs automatic repair made
 	DiscoveredAt time.Time `json:"discovered_at"`
 }
@@ -5,11 +5,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// sourceColumns lists the sources columns in the order expected by scanSourceFields.
+const sourceColumns = `id, user_id, name, source_type, source_url, source_username, source_password,
+		dest_url, dest_username, dest_password, sync_interval, sync_days_past, sync_direction, conflict_strategy,
+		selected_calendars, calendar_concurrency, event_concurrency, enabled, last_sync_at, last_sync_status,
+		last_sync_message, auth_failures, source_transport, dest_transport, source_auth, dest_auth,
+		create_calendars, sync_calendar_metadata, dedupe_policy, dedupe_fields, sync_repaired_events, drift_check_interval,
+		created_at, updated_at`
+
 // GetOrCreateUser returns an existing user by email or creates a new one.
 func (db *DB) GetOrCreateUser(email, name string) (*User, error) {
 	user, err := db.GetUserByEmail(email)
@@ -85,6 +94,17 @@ func (db *DB) CreateSource(source *Source) error {
 	if source.SyncDirection == "" {
 		source.SyncDirection = SyncDirectionOneWay
 	}
+	if source.DedupePolicy == "" {
+		source.DedupePolicy = DedupeAuto
+	}
+
+	// Default to sequential sync if concurrency is not set
+	if source.CalendarConcurrency < 1 {
+		source.CalendarConcurrency = 1
+	}
+	if source.EventConcurrency < 1 {
+		source.EventConcurrency = 1
+	}
 
 	// Encode selected_calendars as JSON
 	var selectedCalendarsJSON *string
@@ -97,19 +117,28 @@ func (db *DB) CreateSource(source *Source) error {
 		selectedCalendarsJSON = &s
 	}
 
+	settings, err := encodeSourceSettings(source)
+	if err != nil {
+		return err
+	}
+
 	query := `INSERT INTO sources (
 		id, user_id, name, source_type, source_url, source_username, source_password,
 		dest_url, dest_username, dest_password, sync_interval, sync_days_past, sync_direction, conflict_strategy,
-		selected_calendars, enabled, last_sync_status, created_at, updated_at
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		selected_calendars, calendar_concurrency, event_concurrency, enabled, last_sync_status,
+		source_transport, dest_transport, source_auth, dest_auth, create_calendars, sync_calendar_metadata,
+		dedupe_policy, dedupe_fields, sync_repaired_events, drift_check_interval, created_at, updated_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := db.conn.Exec(query,
+	_, err = db.conn.Exec(query,
 		source.ID, source.UserID, source.Name, source.SourceType,
 		source.SourceURL, source.SourceUsername, source.SourcePassword,
 		source.DestURL, source.DestUsername, source.DestPassword,
 		source.SyncInterval, source.SyncDaysPast, source.SyncDirection, source.ConflictStrategy,
-		selectedCalendarsJSON, source.Enabled,
-		source.LastSyncStatus, source.CreatedAt, source.UpdatedAt,
+		selectedCalendarsJSON, source.CalendarConcurrency, source.EventConcurrency, source.Enabled,
+		source.LastSyncStatus, settings.sourceTransport, settings.destTransport, settings.sourceAuth, settings.destAuth,
+		source.CreateCalendars, source.SyncCalendarMeta, source.DedupePolicy, encodeDedupeFields(source.DedupeFields),
+		source.SyncRepairedEvents, source.DriftCheckInterval, source.CreatedAt, source.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create source: %w", err)
@@ -120,10 +149,7 @@ func (db *DB) CreateSource(source *Source) error {
 
 // GetSourceByID returns a source by its ID.
 func (db *DB) GetSourceByID(id string) (*Source, error) {
-	query := `SELECT id, user_id, name, source_type, source_url, source_username, source_password,
-		dest_url, dest_username, dest_password, sync_interval, sync_days_past, sync_direction, conflict_strategy,
-		selected_calendars, enabled, last_sync_at, last_sync_status, last_sync_message, created_at, updated_at
-		FROM sources WHERE id = ?`
+	query := `SELECT ` + sourceColumns + ` FROM sources WHERE id = ?`
 
 	row := db.conn.QueryRow(query, id)
 	return scanSource(row)
@@ -132,10 +158,7 @@ func (db *DB) GetSourceByID(id string) (*Source, error) {
 // GetSourceByIDForUser returns a source by its ID only if it belongs to the user.
 // This prevents timing attacks by combining auth check with the query.
 func (db *DB) GetSourceByIDForUser(id, userID string) (*Source, error) {
-	query := `SELECT id, user_id, name, source_type, source_url, source_username, source_password,
-		dest_url, dest_username, dest_password, sync_interval, sync_days_past, sync_direction, conflict_strategy,
-		selected_calendars, enabled, last_sync_at, last_sync_status, last_sync_message, created_at, updated_at
-		FROM sources WHERE id = ? AND user_id = ?`
+	query := `SELECT ` + sourceColumns + ` FROM sources WHERE id = ? AND user_id = ?`
 
 	row := db.conn.QueryRow(query, id, userID)
 	return scanSource(row)
@@ -143,10 +166,7 @@ func (db *DB) GetSourceByIDForUser(id, userID string) (*Source, error) {
 
 // GetSourcesByUserID returns all sources for a user.
 func (db *DB) GetSourcesByUserID(userID string) ([]*Source, error) {
-	query := `SELECT id, user_id, name, source_type, source_url, source_username, source_password,
-		dest_url, dest_username, dest_password, sync_interval, sync_days_past, sync_direction, conflict_strategy,
-		selected_calendars, enabled, last_sync_at, last_sync_status, last_sync_message, created_at, updated_at
-		FROM sources WHERE user_id = ? ORDER BY name`
+	query := `SELECT ` + sourceColumns + ` FROM sources WHERE user_id = ? ORDER BY name`
 
 	rows, err := db.conn.Query(query, userID)
 	if err != nil {
@@ -172,10 +192,7 @@ func (db *DB) GetSourcesByUserID(userID string) ([]*Source, error) {
 
 // GetEnabledSources returns all enabled sources.
 func (db *DB) GetEnabledSources() ([]*Source, error) {
-	query := `SELECT id, user_id, name, source_type, source_url, source_username, source_password,
-		dest_url, dest_username, dest_password, sync_interval, sync_days_past, sync_direction, conflict_strategy,
-		selected_calendars, enabled, last_sync_at, last_sync_status, last_sync_message, created_at, updated_at
-		FROM sources WHERE enabled = 1`
+	query := `SELECT ` + sourceColumns + ` FROM sources WHERE enabled = 1`
 
 	rows, err := db.conn.Query(query)
 	if err != nil {
@@ -207,6 +224,17 @@ func (db *DB) UpdateSource(source *Source) error {
 	if source.SyncDirection == "" {
 		source.SyncDirection = SyncDirectionOneWay
 	}
+	if source.DedupePolicy == "" {
+		source.DedupePolicy = DedupeAuto
+	}
+
+	// Default to sequential sync if concurrency is not set
+	if source.CalendarConcurrency < 1 {
+		source.CalendarConcurrency = 1
+	}
+	if source.EventConcurrency < 1 {
+		source.EventConcurrency = 1
+	}
 
 	// Encode selected_calendars as JSON
 	var selectedCalendarsJSON *string
@@ -219,16 +247,28 @@ func (db *DB) UpdateSource(source *Source) error {
 		selectedCalendarsJSON = &s
 	}
 
+	settings, err := encodeSourceSettings(source)
+	if err != nil {
+		return err
+	}
+
 	query := `UPDATE sources SET
 		name = ?, source_type = ?, source_url = ?, source_username = ?, source_password = ?,
 		dest_url = ?, dest_username = ?, dest_password = ?, sync_interval = ?, sync_days_past = ?,
-		sync_direction = ?, conflict_strategy = ?, selected_calendars = ?, enabled = ?, updated_at = ?
+		sync_direction = ?, conflict_strategy = ?, selected_calendars = ?, calendar_concurrency = ?,
+		event_concurrency = ?, enabled = ?, source_transport = ?, dest_transport = ?, source_auth = ?, dest_auth = ?,
+		create_calendars = ?, sync_calendar_metadata = ?, dedupe_policy = ?, dedupe_fields = ?, sync_repaired_events = ?,
+		drift_check_interval = ?, updated_at = ?
 		WHERE id = ?`
 
 	result, err := db.conn.Exec(query,
 		source.Name, source.SourceType, source.SourceURL, source.SourceUsername, source.SourcePassword,
 		source.DestURL, source.DestUsername, source.DestPassword, source.SyncInterval, source.SyncDaysPast,
-		source.SyncDirection, source.ConflictStrategy, selectedCalendarsJSON, source.Enabled, source.UpdatedAt, source.ID,
+		source.SyncDirection, source.ConflictStrategy, selectedCalendarsJSON, source.CalendarConcurrency,
+		source.EventConcurrency, source.Enabled, settings.sourceTransport, settings.destTransport,
+		settings.sourceAuth, settings.destAuth, source.CreateCalendars, source.SyncCalendarMeta,
+		source.DedupePolicy, encodeDedupeFields(source.DedupeFields), source.SyncRepairedEvents,
+		source.DriftCheckInterval, source.UpdatedAt, source.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update source: %w", err)
@@ -245,6 +285,35 @@ func (db *DB) UpdateSource(source *Source) error {
 	return nil
 }
 
+// UpdateSourceAuth replaces the auth settings of one server of a source, e.g. to store
+// refreshed OAuth2 tokens without touching the rest of the source.
+func (db *DB) UpdateSourceAuth(id string, endpoint Endpoint, settings AuthSettings) error {
+	column := "source_auth"
+	if endpoint == EndpointDest {
+		column = "dest_auth"
+	}
+
+	value, err := encodeSettings(settings, "auth")
+	if err != nil {
+		return err
+	}
+
+	result, err := db.conn.Exec(`UPDATE sources SET `+column+` = ?, updated_at = ? WHERE id = ?`, value, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update source auth: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
 // UpdateSourceSyncStatus updates the sync status of a source.
 func (db *DB) UpdateSourceSyncStatus(id string, status SyncStatus, message string) error {
 	now := time.Now().UTC()
@@ -266,6 +335,40 @@ func (db *DB) UpdateSourceSyncStatus(id string, status SyncStatus, message strin
 	return nil
 }
 
+// IncrementAuthFailures records an authentication failure for a source and
+// returns the number of consecutive failures.
+func (db *DB) IncrementAuthFailures(id string) (int, error) {
+	var count int
+	query := `UPDATE sources SET auth_failures = auth_failures + 1 WHERE id = ? RETURNING auth_failures`
+	if err := db.conn.QueryRow(query, id).Scan(&count); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, fmt.Errorf("failed to increment auth failures: %w", err)
+	}
+	return count, nil
+}
+
+// ResetAuthFailures clears the consecutive authentication failure count for a source.
+func (db *DB) ResetAuthFailures(id string) error {
+	query := `UPDATE sources SET auth_failures = 0 WHERE id = ?`
+
+	result, err := db.conn.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to reset auth failures: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
 // ResetRunningSyncStatuses resets any sources with "running" status to "pending".
 // This should be called on startup to clean up statuses from interrupted syncs.
 func (db *DB) ResetRunningSyncStatuses() (int64, error) {
@@ -365,30 +468,313 @@ func (db *DB) UpsertSyncState(state *SyncState) error {
 	return nil
 }
 
-// CreateSyncLog creates a new sync log entry.
+// GetCalendarMapping returns the destination calendar mapped to a source calendar.
+func (db *DB) GetCalendarMapping(sourceID, calendarHref string) (*CalendarMapping, error) {
+	query := `SELECT id, source_id, calendar_href, dest_href, created, metadata_hash, created_at, updated_at
+		FROM calendar_mappings WHERE source_id = ? AND calendar_href = ?`
+
+	m := &CalendarMapping{}
+	err := db.conn.QueryRow(query, sourceID, calendarHref).Scan(
+		&m.ID, &m.SourceID, &m.CalendarHref, &m.DestHref, &m.Created, &m.MetadataHash, &m.CreatedAt, &m.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar mapping: %w", err)
+	}
+
+	return m, nil
+}
+
+// GetCalendarMappings returns all calendar mappings of a source.
+func (db *DB) GetCalendarMappings(sourceID string) ([]*CalendarMapping, error) {
+	query := `SELECT id, source_id, calendar_href, dest_href, created, metadata_hash, created_at, updated_at
+		FROM calendar_mappings WHERE source_id = ? ORDER BY calendar_href`
+
+	rows, err := db.conn.Query(query, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calendar mappings: %w", err)
+	}
+	defer rows.Close()
+
+	var mappings []*CalendarMapping
+	for rows.Next() {
+		m := &CalendarMapping{}
+		if err := rows.Scan(&m.ID, &m.SourceID, &m.CalendarHref, &m.DestHref, &m.Created, &m.MetadataHash, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan calendar mapping: %w", err)
+		}
+		mappings = append(mappings, m)
+	}
+
+	return mappings, rows.Err()
+}
+
+// UpsertCalendarMapping creates or updates the mapping of a source calendar.
+func (db *DB) UpsertCalendarMapping(m *CalendarMapping) error {
+	now := time.Now().UTC()
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+
+	query := `INSERT INTO calendar_mappings (id, source_id, calendar_href, dest_href, created, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(source_id, calendar_href) DO UPDATE SET
+			dest_href = excluded.dest_href, created = excluded.created, updated_at = excluded.updated_at,
+			metadata_hash = CASE WHEN dest_href = excluded.dest_href THEN metadata_hash ELSE '' END`
+
+	if _, err := db.conn.Exec(query, m.ID, m.SourceID, m.CalendarHref, m.DestHref, m.Created, now, now); err != nil {
+		return fmt.Errorf("failed to upsert calendar mapping: %w", err)
+	}
+	m.UpdatedAt = now
+	return nil
+}
+
+// SetCalendarMappingMetadata records the fingerprint of the metadata last copied to
+// the destination calendar of a source calendar.
+func (db *DB) SetCalendarMappingMetadata(sourceID, calendarHref, hash string) error {
+	query := `UPDATE calendar_mappings SET metadata_hash = ?, updated_at = ?
+		WHERE source_id = ? AND calendar_href = ?`
+
+	result, err := db.conn.Exec(query, hash, time.Now().UTC(), sourceID, calendarHref)
+	if err != nil {
+		return fmt.Errorf("failed to update calendar mapping: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// serverCapabilitiesColumns lists the server_capabilities columns in scan order.
+const serverCapabilitiesColumns = `source_id, endpoint, server, sync_collection, calendar_query, time_range,
+		multiget, ctag, mkcalendar, conditional_put, probed_at`
+
+// GetServerCapabilities returns the probed capabilities of one endpoint of a source.
+func (db *DB) GetServerCapabilities(sourceID string, endpoint Endpoint) (*ServerCapabilities, error) {
+	query := `SELECT ` + serverCapabilitiesColumns + ` FROM server_capabilities WHERE source_id = ? AND endpoint = ?`
+
+	caps, err := scanServerCapabilities(db.conn.QueryRow(query, sourceID, endpoint))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server capabilities: %w", err)
+	}
+	return caps, nil
+}
+
+// GetAllServerCapabilities returns the probed capabilities of all endpoints of a source.
+func (db *DB) GetAllServerCapabilities(sourceID string) ([]*ServerCapabilities, error) {
+	query := `SELECT ` + serverCapabilitiesColumns + ` FROM server_capabilities WHERE source_id = ? ORDER BY endpoint DESC`
+
+	rows, err := db.conn.Query(query, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query server capabilities: %w", err)
+	}
+	defer rows.Close()
+
+	var all []*ServerCapabilities
+	for rows.Next() {
+		caps, err := scanServerCapabilities(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan server capabilities: %w", err)
+		}
+		all = append(all, caps)
+	}
+
+	return all, rows.Err()
+}
+
+// scanServerCapabilities scans a row selected with serverCapabilitiesColumns.
+func scanServerCapabilities(row interface{ Scan(...any) error }) (*ServerCapabilities, error) {
+	caps := &ServerCapabilities{}
+	err := row.Scan(&caps.SourceID, &caps.Endpoint, &caps.Server, &caps.SyncCollection, &caps.CalendarQuery,
+		&caps.TimeRange, &caps.MultiGet, &caps.CTag, &caps.MKCalendar, &caps.ConditionalPut, &caps.ProbedAt)
+	if err != nil {
+		return nil, err
+	}
+	return caps, nil
+}
+
+// UpsertServerCapabilities stores the probed capabilities of one endpoint of a source.
+func (db *DB) UpsertServerCapabilities(caps *ServerCapabilities) error {
+	if caps.ProbedAt.IsZero() {
+		caps.ProbedAt = time.Now().UTC()
+	}
+
+	query := `INSERT INTO server_capabilities (` + serverCapabilitiesColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(source_id, endpoint) DO UPDATE SET
+			server = excluded.server, sync_collection = excluded.sync_collection,
+			calendar_query = excluded.calendar_query, time_range = excluded.time_range,
+			multiget = excluded.multiget, ctag = excluded.ctag, mkcalendar = excluded.mkcalendar,
+			conditional_put = excluded.conditional_put, probed_at = excluded.probed_at`
+
+	_, err := db.conn.Exec(query, caps.SourceID, caps.Endpoint, caps.Server, caps.SyncCollection, caps.CalendarQuery,
+		caps.TimeRange, caps.MultiGet, caps.CTag, caps.MKCalendar, caps.ConditionalPut, caps.ProbedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert server capabilities: %w", err)
+	}
+	return nil
+}
+
+// DeleteServerCapabilities forgets the capabilities of a source, so both endpoints
+// are probed again on the next sync.
+func (db *DB) DeleteServerCapabilities(sourceID string) error {
+	if _, err := db.conn.Exec(`DELETE FROM server_capabilities WHERE source_id = ?`, sourceID); err != nil {
+		return fmt.Errorf("failed to delete server capabilities: %w", err)
+	}
+	return nil
+}
+
+// GetCalendarIdentities returns the source calendars seen during previous syncs.
+func (db *DB) GetCalendarIdentities(sourceID string) ([]*CalendarIdentity, error) {
+	query := `SELECT id, source_id, calendar_href, name, resource_id, fingerprint, last_seen_at
+		FROM calendar_identities WHERE source_id = ? ORDER BY calendar_href`
+
+	rows, err := db.conn.Query(query, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calendar identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*CalendarIdentity
+	for rows.Next() {
+		ci := &CalendarIdentity{}
+		if err := rows.Scan(&ci.ID, &ci.SourceID, &ci.CalendarHref, &ci.Name, &ci.ResourceID, &ci.Fingerprint, &ci.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan calendar identity: %w", err)
+		}
+		identities = append(identities, ci)
+	}
+
+	return identities, rows.Err()
+}
+
+// UpsertCalendarIdentity records that a source calendar was seen.
+func (db *DB) UpsertCalendarIdentity(ci *CalendarIdentity) error {
+	if ci.ID == "" {
+		ci.ID = uuid.New().String()
+	}
+	ci.LastSeenAt = time.Now().UTC()
+
+	query := `INSERT INTO calendar_identities (id, source_id, calendar_href, name, resource_id, fingerprint, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(source_id, calendar_href) DO UPDATE SET
+			name = excluded.name, resource_id = excluded.resource_id,
+			fingerprint = excluded.fingerprint, last_seen_at = excluded.last_seen_at`
+
+	if _, err := db.conn.Exec(query, ci.ID, ci.SourceID, ci.CalendarHref, ci.Name, ci.ResourceID, ci.Fingerprint, ci.LastSeenAt); err != nil {
+		return fmt.Errorf("failed to upsert calendar identity: %w", err)
+	}
+	return nil
+}
+
+// MoveCalendar moves the sync state, synced events, calendar mapping, identity and
+// selection of a source calendar from oldHref to newHref in one transaction. State
+// already recorded under newHref is replaced.
+func (db *DB) MoveCalendar(sourceID, oldHref, newHref string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin calendar move: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"sync_states", "synced_events", "calendar_mappings", "calendar_identities", "sync_journal"} {
+		query := `UPDATE OR REPLACE ` + table + ` SET calendar_href = ? WHERE source_id = ? AND calendar_href = ?`
+		if _, err := tx.Exec(query, newHref, sourceID, oldHref); err != nil {
+			return fmt.Errorf("failed to move %s: %w", table, err)
+		}
+	}
+
+	var selectedCalendarsJSON sql.NullString
+	if err := tx.QueryRow(`SELECT selected_calendars FROM sources WHERE id = ?`, sourceID).Scan(&selectedCalendarsJSON); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get selected calendars: %w", err)
+	}
+	if selectedCalendarsJSON.Valid {
+		configs := parseSelectedCalendars(selectedCalendarsJSON.String)
+		moved := false
+		for i := range configs {
+			if configs[i].Path == oldHref {
+				configs[i].Path = newHref
+				moved = true
+			}
+		}
+		if moved {
+			data, err := json.Marshal(configs)
+			if err != nil {
+				return fmt.Errorf("failed to encode selected calendars: %w", err)
+			}
+			if _, err := tx.Exec(`UPDATE sources SET selected_calendars = ? WHERE id = ?`, string(data), sourceID); err != nil {
+				return fmt.Errorf("failed to update selected calendars: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit calendar move: %w", err)
+	}
+	return nil
+}
+
+// CreateSyncLog creates a new sync log entry along with the results of its calendars.
 func (db *DB) CreateSyncLog(log *SyncLog) error {
 	if log.ID == "" {
 		log.ID = uuid.New().String()
 	}
 	log.CreatedAt = time.Now().UTC()
 
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin sync log transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `INSERT INTO sync_logs (id, source_id, status, message, details, duration_ms,
-		events_created, events_updated, events_deleted, events_skipped, calendars_synced, events_processed, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		events_created, events_updated, events_deleted, events_skipped, calendars_synced, events_processed,
+		requests_throttled, requests_retried, throttle_wait_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := db.conn.Exec(query, log.ID, log.SourceID, log.Status, log.Message, log.Details, log.Duration.Milliseconds(),
-		log.EventsCreated, log.EventsUpdated, log.EventsDeleted, log.EventsSkipped, log.CalendarsSynced, log.EventsProcessed, log.CreatedAt)
+	_, err = tx.Exec(query, log.ID, log.SourceID, log.Status, log.Message, log.Details, log.Duration.Milliseconds(),
+		log.EventsCreated, log.EventsUpdated, log.EventsDeleted, log.EventsSkipped, log.CalendarsSynced, log.EventsProcessed,
+		log.RequestsThrottled, log.RequestsRetried, log.ThrottleWait.Milliseconds(), log.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create sync log: %w", err)
 	}
 
+	for _, cal := range log.Calendars {
+		if cal.ID == "" {
+			cal.ID = uuid.New().String()
+		}
+		cal.SyncLogID = log.ID
+		cal.SourceID = log.SourceID
+		cal.CreatedAt = log.CreatedAt
+
+		_, err := tx.Exec(`INSERT INTO calendar_sync_logs (id, sync_log_id, source_id, calendar_href, calendar_name, status, mode,
+			events_created, events_updated, events_deleted, events_skipped, events_processed, duration_ms, details, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			cal.ID, cal.SyncLogID, cal.SourceID, cal.CalendarHref, cal.CalendarName, cal.Status, cal.Mode,
+			cal.EventsCreated, cal.EventsUpdated, cal.EventsDeleted, cal.EventsSkipped, cal.EventsProcessed,
+			cal.Duration.Milliseconds(), cal.Details, cal.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create calendar sync log: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sync log: %w", err)
+	}
+
 	return nil
 }
 
 // GetSyncLogs returns sync logs for a source.
 func (db *DB) GetSyncLogs(sourceID string, limit int) ([]*SyncLog, error) {
 	query := `SELECT id, source_id, status, message, details, duration_ms,
-		events_created, events_updated, events_deleted, events_skipped, calendars_synced, events_processed, created_at
+		events_created, events_updated, events_deleted, events_skipped, calendars_synced, events_processed,
+		requests_throttled, requests_retried, throttle_wait_ms, created_at
 		FROM sync_logs WHERE source_id = ? ORDER BY created_at DESC LIMIT ?`
 
 	rows, err := db.conn.Query(query, sourceID, limit)
@@ -400,13 +786,15 @@ func (db *DB) GetSyncLogs(sourceID string, limit int) ([]*SyncLog, error) {
 	var logs []*SyncLog
 	for rows.Next() {
 		log := &SyncLog{}
-		var durationMs int64
+		var durationMs, throttleWaitMs int64
 		err := rows.Scan(&log.ID, &log.SourceID, &log.Status, &log.Message, &log.Details, &durationMs,
-			&log.EventsCreated, &log.EventsUpdated, &log.EventsDeleted, &log.EventsSkipped, &log.CalendarsSynced, &log.EventsProcessed, &log.CreatedAt)
+			&log.EventsCreated, &log.EventsUpdated, &log.EventsDeleted, &log.EventsSkipped, &log.CalendarsSynced, &log.EventsProcessed,
+			&log.RequestsThrottled, &log.RequestsRetried, &throttleWaitMs, &log.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan sync log: %w", err)
 		}
 		log.Duration = time.Duration(durationMs) * time.Millisecond
+		log.ThrottleWait = time.Duration(throttleWaitMs) * time.Millisecond
 		logs = append(logs, log)
 	}
 
@@ -417,6 +805,97 @@ func (db *DB) GetSyncLogs(sourceID string, limit int) ([]*SyncLog, error) {
 	return logs, nil
 }
 
+// calendarSyncLogColumns are the columns scanned by scanCalendarSyncLogs.
+const calendarSyncLogColumns = `id, sync_log_id, source_id, calendar_href, calendar_name, status, mode,
+	events_created, events_updated, events_deleted, events_skipped, events_processed, duration_ms, details, created_at`
+
+// scanCalendarSyncLogs scans calendar sync log rows.
+func scanCalendarSyncLogs(rows *sql.Rows) ([]*CalendarSyncLog, error) {
+	defer rows.Close()
+
+	var logs []*CalendarSyncLog
+	for rows.Next() {
+		cal := &CalendarSyncLog{}
+		var durationMs int64
+		err := rows.Scan(&cal.ID, &cal.SyncLogID, &cal.SourceID, &cal.CalendarHref, &cal.CalendarName, &cal.Status, &cal.Mode,
+			&cal.EventsCreated, &cal.EventsUpdated, &cal.EventsDeleted, &cal.EventsSkipped, &cal.EventsProcessed,
+			&durationMs, &cal.Details, &cal.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan calendar sync log: %w", err)
+		}
+		cal.Duration = time.Duration(durationMs) * time.Millisecond
+		logs = append(logs, cal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating calendar sync logs: %w", err)
+	}
+
+	return logs, nil
+}
+
+// LoadCalendarSyncLogs fills in the Calendars of the given sync logs.
+func (db *DB) LoadCalendarSyncLogs(logs []*SyncLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*SyncLog, len(logs))
+	args := make([]any, len(logs))
+	for i, l := range logs {
+		byID[l.ID] = l
+		args[i] = l.ID
+	}
+
+	rows, err := db.conn.Query(`SELECT `+calendarSyncLogColumns+` FROM calendar_sync_logs
+		WHERE sync_log_id IN (?`+strings.Repeat(", ?", len(logs)-1)+`)
+		ORDER BY calendar_name, calendar_href`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query calendar sync logs: %w", err)
+	}
+
+	calendars, err := scanCalendarSyncLogs(rows)
+	if err != nil {
+		return err
+	}
+	for _, cal := range calendars {
+		l := byID[cal.SyncLogID]
+		l.Calendars = append(l.Calendars, cal)
+	}
+
+	return nil
+}
+
+// GetLatestCalendarSyncLogs returns the most recent result of each calendar of a source.
+func (db *DB) GetLatestCalendarSyncLogs(sourceID string) ([]*CalendarSyncLog, error) {
+	rows, err := db.conn.Query(`SELECT `+calendarSyncLogColumns+` FROM calendar_sync_logs c
+		WHERE source_id = ? AND created_at = (
+			SELECT MAX(created_at) FROM calendar_sync_logs
+			WHERE source_id = c.source_id AND calendar_href = c.calendar_href
+		)
+		ORDER BY calendar_name, calendar_href`, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest calendar sync logs: %w", err)
+	}
+
+	calendars, err := scanCalendarSyncLogs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Results stored at the same instant would both match; keep one per calendar
+	seen := make(map[string]bool, len(calendars))
+	latest := calendars[:0]
+	for _, cal := range calendars {
+		if !seen[cal.CalendarHref] {
+			seen[cal.CalendarHref] = true
+			latest = append(latest, cal)
+		}
+	}
+
+	return latest, nil
+}
+
 // CleanOldSyncLogs deletes sync logs older than the given time.
 func (db *DB) CleanOldSyncLogs(olderThan time.Time) (int64, error) {
 	query := `DELETE FROM sync_logs WHERE created_at < ?`
@@ -434,6 +913,27 @@ func (db *DB) CleanOldSyncLogs(olderThan time.Time) (int64, error) {
 	return affected, nil
 }
 
+// encodeDedupeFields stores dedupe fields as a comma separated list.
+func encodeDedupeFields(fields []DedupeField) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = string(f)
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseDedupeFields parses a list stored by encodeDedupeFields.
+func parseDedupeFields(value string) []DedupeField {
+	if value == "" {
+		return nil
+	}
+	var fields []DedupeField
+	for _, part := range strings.Split(value, ",") {
+		fields = append(fields, DedupeField(part))
+	}
+	return fields
+}
+
 // parseSelectedCalendars parses selected_calendars JSON with backward compatibility.
 // Old format: ["path1", "path2"] (array of strings)
 // New format: [{"path": "path1", "sync_direction": "one_way"}] (array of CalendarConfig)
@@ -466,25 +966,48 @@ func parseSelectedCalendars(jsonStr string) []CalendarConfig {
 	return nil
 }
 
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 // scanSource scans a single row into a Source struct.
 func scanSource(row *sql.Row) (*Source, error) {
+	source, err := scanSourceFields(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return source, err
+}
+
+// scanSourceFromRows scans a row from sql.Rows into a Source struct.
+func scanSourceFromRows(rows *sql.Rows) (*Source, error) {
+	return scanSourceFields(rows)
+}
+
+// scanSourceFields scans the columns listed in sourceColumns into a Source struct.
+func scanSourceFields(row rowScanner) (*Source, error) {
 	source := &Source{}
 	var lastSyncAt sql.NullTime
 	var lastSyncMessage sql.NullString
 	var syncDirection sql.NullString
 	var selectedCalendarsJSON sql.NullString
+	var sourceTransportJSON, destTransportJSON, sourceAuthJSON, destAuthJSON sql.NullString
+	var dedupeFields string
 
 	err := row.Scan(
 		&source.ID, &source.UserID, &source.Name, &source.SourceType,
 		&source.SourceURL, &source.SourceUsername, &source.SourcePassword,
 		&source.DestURL, &source.DestUsername, &source.DestPassword,
 		&source.SyncInterval, &source.SyncDaysPast, &syncDirection, &source.ConflictStrategy,
-		&selectedCalendarsJSON, &source.Enabled,
-		&lastSyncAt, &source.LastSyncStatus, &lastSyncMessage,
-		&source.CreatedAt, &source.UpdatedAt,
+		&selectedCalendarsJSON, &source.CalendarConcurrency, &source.EventConcurrency, &source.Enabled,
+		&lastSyncAt, &source.LastSyncStatus, &lastSyncMessage, &source.AuthFailures,
+		&sourceTransportJSON, &destTransportJSON, &sourceAuthJSON, &destAuthJSON,
+		&source.CreateCalendars, &source.SyncCalendarMeta, &source.DedupePolicy, &dedupeFields,
+		&source.SyncRepairedEvents, &source.DriftCheckInterval, &source.CreatedAt, &source.UpdatedAt,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, ErrNotFound
+		return nil, err
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan source: %w", err)
@@ -503,46 +1026,72 @@ func scanSource(row *sql.Row) (*Source, error) {
 	if selectedCalendarsJSON.Valid {
 		source.SelectedCalendars = parseSelectedCalendars(selectedCalendarsJSON.String)
 	}
+	source.DedupeFields = parseDedupeFields(dedupeFields)
+
+	if err := decodeSettings(sourceTransportJSON, &source.SourceTransport, "transport"); err != nil {
+		return nil, err
+	}
+	if err := decodeSettings(destTransportJSON, &source.DestTransport, "transport"); err != nil {
+		return nil, err
+	}
+	if err := decodeSettings(sourceAuthJSON, &source.SourceAuth, "auth"); err != nil {
+		return nil, err
+	}
+	if err := decodeSettings(destAuthJSON, &source.DestAuth, "auth"); err != nil {
+		return nil, err
+	}
 
 	return source, nil
 }
 
-// scanSourceFromRows scans a row from sql.Rows into a Source struct.
-func scanSourceFromRows(rows *sql.Rows) (*Source, error) {
-	source := &Source{}
-	var lastSyncAt sql.NullTime
-	var lastSyncMessage sql.NullString
-	var syncDirection sql.NullString
-	var selectedCalendarsJSON sql.NullString
+// sourceSettingsJSON holds the JSON-encoded settings columns of a source.
+type sourceSettingsJSON struct {
+	sourceTransport, destTransport *string
+	sourceAuth, destAuth           *string
+}
 
-	err := rows.Scan(
-		&source.ID, &source.UserID, &source.Name, &source.SourceType,
-		&source.SourceURL, &source.SourceUsername, &source.SourcePassword,
-		&source.DestURL, &source.DestUsername, &source.DestPassword,
-		&source.SyncInterval, &source.SyncDaysPast, &syncDirection, &source.ConflictStrategy,
-		&selectedCalendarsJSON, &source.Enabled,
-		&lastSyncAt, &source.LastSyncStatus, &lastSyncMessage,
-		&source.CreatedAt, &source.UpdatedAt,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan source: %w", err)
+// encodeSourceSettings encodes the transport and auth settings of a source as JSON
+// (NULL when unset).
+func encodeSourceSettings(source *Source) (sourceSettingsJSON, error) {
+	var out sourceSettingsJSON
+	var err error
+	if out.sourceTransport, err = encodeSettings(source.SourceTransport, "transport"); err != nil {
+		return out, err
 	}
-
-	if lastSyncAt.Valid {
-		source.LastSyncAt = &lastSyncAt.Time
+	if out.destTransport, err = encodeSettings(source.DestTransport, "transport"); err != nil {
+		return out, err
 	}
-	source.LastSyncMessage = lastSyncMessage.String
-	source.SyncDirection = SyncDirection(syncDirection.String)
-	if source.SyncDirection == "" {
-		source.SyncDirection = SyncDirectionOneWay
+	if out.sourceAuth, err = encodeSettings(source.SourceAuth, "auth"); err != nil {
+		return out, err
 	}
+	if out.destAuth, err = encodeSettings(source.DestAuth, "auth"); err != nil {
+		return out, err
+	}
+	return out, nil
+}
 
-	// Decode selected_calendars from JSON (backward compatible)
-	if selectedCalendarsJSON.Valid {
-		source.SelectedCalendars = parseSelectedCalendars(selectedCalendarsJSON.String)
+// encodeSettings encodes a settings struct stored in a JSON column, or nil when unset.
+func encodeSettings[T interface{ IsZero() bool }](settings T, what string) (*string, error) {
+	if settings.IsZero() {
+		return nil, nil
 	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s settings: %w", what, err)
+	}
+	s := string(data)
+	return &s, nil
+}
 
-	return source, nil
+// decodeSettings decodes a JSON settings column; NULL leaves settings unchanged.
+func decodeSettings[T any](value sql.NullString, settings *T, what string) error {
+	if !value.Valid || value.String == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(value.String), settings); err != nil {
+		return fmt.Errorf("failed to decode %s settings: %w", what, err)
+	}
+	return nil
 }
 
 // GetSyncedEvents returns all synced event UIDs for a source and calendar.
@@ -550,7 +1099,20 @@ func (db *DB) GetSyncedEvents(sourceID, calendarHref string) ([]*SyncedEvent, er
 	query := `SELECT id, source_id, calendar_href, event_uid, source_etag, dest_etag, created_at, updated_at
 		FROM synced_events WHERE source_id = ? AND calendar_href = ?`
 
-	rows, err := db.conn.Query(query, sourceID, calendarHref)
+	return db.querySyncedEvents(query, sourceID, calendarHref)
+}
+
+// GetSyncedEventsForSource returns the synced events of every calendar of a source.
+func (db *DB) GetSyncedEventsForSource(sourceID string) ([]*SyncedEvent, error) {
+	query := `SELECT id, source_id, calendar_href, event_uid, source_etag, dest_etag, created_at, updated_at
+		FROM synced_events WHERE source_id = ? ORDER BY calendar_href, event_uid`
+
+	return db.querySyncedEvents(query, sourceID)
+}
+
+// querySyncedEvents runs a query selecting synced_events columns.
+func (db *DB) querySyncedEvents(query string, args ...any) ([]*SyncedEvent, error) {
+	rows, err := db.conn.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query synced events: %w", err)
 	}
@@ -579,13 +1141,22 @@ func (db *DB) GetSyncedEvents(sourceID, calendarHref string) ([]*SyncedEvent, er
 
 // UpsertSyncedEvent creates or updates a synced event record.
 func (db *DB) UpsertSyncedEvent(event *SyncedEvent) error {
+	return upsertSyncedEvent(db.conn, event)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func upsertSyncedEvent(conn execer, event *SyncedEvent) error {
 	now := time.Now().UTC()
 
 	// Try to update first
 	query := `UPDATE synced_events SET source_etag = ?, dest_etag = ?, updated_at = ?
 		WHERE source_id = ? AND calendar_href = ? AND event_uid = ?`
 
-	result, err := db.conn.Exec(query, event.SourceETag, event.DestETag, now,
+	result, err := conn.Exec(query, event.SourceETag, event.DestETag, now,
 		event.SourceID, event.CalendarHref, event.EventUID)
 	if err != nil {
 		return fmt.Errorf("failed to update synced event: %w", err)
@@ -607,7 +1178,7 @@ func (db *DB) UpsertSyncedEvent(event *SyncedEvent) error {
 		insertQuery := `INSERT INTO synced_events (id, source_id, calendar_href, event_uid, source_etag, dest_etag, created_at, updated_at)
 			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 
-		_, err = db.conn.Exec(insertQuery, event.ID, event.SourceID, event.CalendarHref,
+		_, err = conn.Exec(insertQuery, event.ID, event.SourceID, event.CalendarHref,
 			event.EventUID, event.SourceETag, event.DestETag, event.CreatedAt, event.UpdatedAt)
 		if err != nil {
 			return fmt.Errorf("failed to insert synced event: %w", err)
@@ -641,16 +1212,567 @@ func (db *DB) DeleteSyncedEventsForCalendar(sourceID, calendarHref string) error
 	return nil
 }
 
-// SaveMalformedEvent saves or updates a malformed event record.
-func (db *DB) SaveMalformedEvent(sourceID, eventPath, errorMessage string) error {
-	// Use INSERT OR REPLACE to handle the unique constraint
-	query := `INSERT OR REPLACE INTO malformed_events (id, source_id, event_path, error_message, discovered_at)
-		VALUES (?, ?, ?, ?, ?)`
+// DeleteSyncState removes the sync state of a calendar so its next sync is a full comparison.
+func (db *DB) DeleteSyncState(sourceID, calendarHref string) error {
+	query := `DELETE FROM sync_states WHERE source_id = ? AND calendar_href = ?`
+
+	_, err := db.conn.Exec(query, sourceID, calendarHref)
+	if err != nil {
+		return fmt.Errorf("failed to delete sync state: %w", err)
+	}
+
+	return nil
+}
+
+// CreateJournalEntries records the writes a sync run is about to make.
+// All entries are written in one transaction before any of the writes is sent.
+func (db *DB) CreateJournalEntries(entries []*JournalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin journal transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO sync_journal (id, run_id, source_id, calendar_href, event_uid, operation, event_path, state, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare journal insert: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	for _, entry := range entries {
+		if entry.ID == "" {
+			entry.ID = uuid.New().String()
+		}
+		entry.State = JournalPending
+		entry.CreatedAt = now
+		entry.UpdatedAt = now
+
+		if _, err := stmt.Exec(entry.ID, entry.RunID, entry.SourceID, entry.CalendarHref, entry.EventUID,
+			entry.Operation, entry.EventPath, entry.State, entry.CreatedAt, entry.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to insert journal entry: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit journal entries: %w", err)
+	}
+
+	return nil
+}
+
+// CompleteJournalEntry marks a journaled write as done. For writes that put an event,
+// the synced_events record is updated in the same transaction so the two never disagree.
+func (db *DB) CompleteJournalEntry(entry *JournalEntry) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin journal transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := setJournalState(tx, entry.ID, JournalDone); err != nil {
+		return err
+	}
+
+	if entry.Operation.IsPut() {
+		if err := upsertSyncedEvent(tx, &SyncedEvent{
+			SourceID:     entry.SourceID,
+			CalendarHref: entry.CalendarHref,
+			EventUID:     entry.EventUID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit journal entry: %w", err)
+	}
+
+	entry.State = JournalDone
+	return nil
+}
+
+// FailJournalEntry marks a journaled write as rejected by the server.
+func (db *DB) FailJournalEntry(entry *JournalEntry) error {
+	if err := setJournalState(db.conn, entry.ID, JournalFailed); err != nil {
+		return err
+	}
+	entry.State = JournalFailed
+	return nil
+}
+
+func setJournalState(conn execer, id string, state JournalState) error {
+	query := `UPDATE sync_journal SET state = ?, updated_at = ? WHERE id = ?`
+
+	result, err := conn.Exec(query, state, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update journal entry: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetUnfinishedJournalEntries returns the journal entries of all runs that did not finish,
+// ordered by source and creation time.
+func (db *DB) GetUnfinishedJournalEntries() ([]*JournalEntry, error) {
+	query := `SELECT id, run_id, source_id, calendar_href, event_uid, operation, event_path, state, created_at, updated_at
+		FROM sync_journal
+		ORDER BY source_id, created_at`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync journal: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*JournalEntry
+	for rows.Next() {
+		entry := &JournalEntry{}
+		if err := rows.Scan(&entry.ID, &entry.RunID, &entry.SourceID, &entry.CalendarHref, &entry.EventUID,
+			&entry.Operation, &entry.EventPath, &entry.State, &entry.CreatedAt, &entry.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan journal entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync journal: %w", err)
+	}
+
+	return entries, nil
+}
+
+// DeleteJournalRun removes the journal of a finished sync run.
+func (db *DB) DeleteJournalRun(runID string) error {
+	query := `DELETE FROM sync_journal WHERE run_id = ?`
+
+	_, err := db.conn.Exec(query, runID)
+	if err != nil {
+		return fmt.Errorf("failed to delete sync journal: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteFinishedJournalEntries removes the done and failed entries of a sync run,
+// keeping its pending writes.
+func (db *DB) DeleteFinishedJournalEntries(runID string) error {
+	query := `DELETE FROM sync_journal WHERE run_id = ? AND state != ?`
+
+	_, err := db.conn.Exec(query, runID, JournalPending)
+	if err != nil {
+		return fmt.Errorf("failed to delete sync journal: %w", err)
+	}
+
+	return nil
+}
+
+// HasInterruptedJournalEntries reports whether runs other than runID left pending
+// writes for a source calendar.
+func (db *DB) HasInterruptedJournalEntries(sourceID, calendarHref, runID string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM sync_journal
+		WHERE source_id = ? AND calendar_href = ? AND run_id != ? AND state = ?)`
+
+	var exists bool
+	if err := db.conn.QueryRow(query, sourceID, calendarHref, runID, JournalPending).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to query sync journal: %w", err)
+	}
+
+	return exists, nil
+}
+
+// DeleteInterruptedJournalEntries removes the entries runs other than runID left for
+// a source calendar, once a full comparison has reconciled them.
+func (db *DB) DeleteInterruptedJournalEntries(sourceID, calendarHref, runID string) error {
+	query := `DELETE FROM sync_journal WHERE source_id = ? AND calendar_href = ? AND run_id != ?`
+
+	_, err := db.conn.Exec(query, sourceID, calendarHref, runID)
+	if err != nil {
+		return fmt.Errorf("failed to delete sync journal: %w", err)
+	}
+
+	return nil
+}
+
+// CreateEventChanges records the changes a sync run made, in one transaction.
+func (db *DB) CreateEventChanges(changes []*EventChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin event change transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO event_changes (id, run_id, source_id, calendar_href, event_uid, summary, summary_hash,
+		action, direction, reason, result, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare event change insert: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	for _, change := range changes {
+		if change.ID == "" {
+			change.ID = uuid.New().String()
+		}
+		if change.CreatedAt.IsZero() {
+			change.CreatedAt = now
+		}
+
+		if _, err := stmt.Exec(change.ID, change.RunID, change.SourceID, change.CalendarHref, change.EventUID, change.Summary,
+			change.SummaryHash, change.Action, change.Direction, change.Reason, change.Result, change.Error, change.CreatedAt); err != nil {
+			return fmt.Errorf("failed to insert event change: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit event changes: %w", err)
+	}
+
+	return nil
+}
+
+// GetEventChanges returns the changes of a source that match filter, newest first,
+// and the total number of matching changes.
+func (db *DB) GetEventChanges(sourceID string, filter EventChangeFilter) ([]*EventChange, int, error) {
+	where := []string{"source_id = ?"}
+	args := []any{sourceID}
+	if filter.EventUID != "" {
+		where = append(where, "event_uid = ?")
+		args = append(args, filter.EventUID)
+	}
+	if filter.SummaryHash != "" {
+		where = append(where, "summary_hash = ?")
+		args = append(args, filter.SummaryHash)
+	}
+	if filter.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+	conditions := strings.Join(where, " AND ")
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM event_changes WHERE `+conditions, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count event changes: %w", err)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = -1 // No limit
+	}
+	query := `SELECT id, run_id, source_id, calendar_href, event_uid, summary, summary_hash,
+		action, direction, reason, result, error, created_at
+		FROM event_changes WHERE ` + conditions + ` ORDER BY created_at DESC, id LIMIT ? OFFSET ?`
+
+	rows, err := db.conn.Query(query, append(args, limit, max(filter.Offset, 0))...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query event changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []*EventChange
+	for rows.Next() {
+		change := &EventChange{}
+		if err := rows.Scan(&change.ID, &change.RunID, &change.SourceID, &change.CalendarHref, &change.EventUID,
+			&change.Summary, &change.SummaryHash, &change.Action, &change.Direction, &change.Reason, &change.Result,
+			&change.Error, &change.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan event change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating event changes: %w", err)
+	}
+
+	return changes, total, nil
+}
+
+// CleanOldEventChanges deletes event changes older than the given time.
+func (db *DB) CleanOldEventChanges(olderThan time.Time) (int64, error) {
+	query := `DELETE FROM event_changes WHERE created_at < ?`
+
+	result, err := db.conn.Exec(query, olderThan.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean old event changes: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return affected, nil
+}
+
+// ReplaceDuplicateGroups replaces the duplicate groups stored for a calendar of a source
+// with groups; nil clears them.
+func (db *DB) ReplaceDuplicateGroups(sourceID, calendarHref string, groups []*DuplicateGroup) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin duplicate group transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM duplicate_groups WHERE source_id = ? AND calendar_href = ?`, sourceID, calendarHref); err != nil {
+		return fmt.Errorf("failed to clear duplicate groups: %w", err)
+	}
 
-	id := uuid.New().String()
 	now := time.Now().UTC()
+	for _, group := range groups {
+		if group.ID == "" {
+			group.ID = uuid.New().String()
+		}
+		group.SourceID = sourceID
+		group.CalendarHref = calendarHref
+		group.DetectedAt = now
+
+		keep, err := json.Marshal(group.Keep)
+		if err != nil {
+			return fmt.Errorf("failed to encode kept event: %w", err)
+		}
+		duplicates, err := json.Marshal(group.Duplicates)
+		if err != nil {
+			return fmt.Errorf("failed to encode duplicates: %w", err)
+		}
+
+		_, err = tx.Exec(`INSERT INTO duplicate_groups (id, source_id, calendar_href, dest_href, summary, start_time,
+			keep_event, duplicates, detected_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			group.ID, group.SourceID, group.CalendarHref, group.DestHref, group.Summary, group.StartTime,
+			string(keep), string(duplicates), group.DetectedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert duplicate group: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit duplicate groups: %w", err)
+	}
+
+	return nil
+}
+
+// GetDuplicateGroups returns the duplicate groups awaiting review for a source.
+func (db *DB) GetDuplicateGroups(sourceID string) ([]*DuplicateGroup, error) {
+	rows, err := db.conn.Query(`SELECT id, source_id, calendar_href, dest_href, summary, start_time, keep_event, duplicates, detected_at
+		FROM duplicate_groups WHERE source_id = ? ORDER BY calendar_href, start_time, id`, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*DuplicateGroup
+	for rows.Next() {
+		group, err := scanDuplicateGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating duplicate groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+// GetDuplicateGroup returns one duplicate group of a source.
+func (db *DB) GetDuplicateGroup(sourceID, id string) (*DuplicateGroup, error) {
+	row := db.conn.QueryRow(`SELECT id, source_id, calendar_href, dest_href, summary, start_time, keep_event, duplicates, detected_at
+		FROM duplicate_groups WHERE id = ? AND source_id = ?`, id, sourceID)
+
+	group, err := scanDuplicateGroup(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return group, err
+}
+
+// DeleteDuplicateGroup deletes a duplicate group once it has been dealt with.
+func (db *DB) DeleteDuplicateGroup(id string) error {
+	if _, err := db.conn.Exec(`DELETE FROM duplicate_groups WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete duplicate group: %w", err)
+	}
+	return nil
+}
+
+// scanDuplicateGroup scans a duplicate group row.
+func scanDuplicateGroup(row rowScanner) (*DuplicateGroup, error) {
+	group := &DuplicateGroup{}
+	var keep, duplicates string
+	err := row.Scan(&group.ID, &group.SourceID, &group.CalendarHref, &group.DestHref, &group.Summary, &group.StartTime,
+		&keep, &duplicates, &group.DetectedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan duplicate group: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(keep), &group.Keep); err != nil {
+		return nil, fmt.Errorf("failed to decode kept event: %w", err)
+	}
+	if err := json.Unmarshal([]byte(duplicates), &group.Duplicates); err != nil {
+		return nil, fmt.Errorf("failed to decode duplicates: %w", err)
+	}
+
+	return group, nil
+}
+
+// CreateDriftReport stores the report of a drift check.
+func (db *DB) CreateDriftReport(report *DriftReport) error {
+	if report.ID == "" {
+		report.ID = uuid.New().String()
+	}
+	report.CreatedAt = time.Now().UTC()
+
+	calendars, err := json.Marshal(report.Calendars)
+	if err != nil {
+		return fmt.Errorf("failed to encode drift calendars: %w", err)
+	}
+
+	_, err = db.conn.Exec(`INSERT INTO drift_reports (id, source_id, status, message, mismatches, threshold,
+		over_threshold, calendars, duration_ms, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		report.ID, report.SourceID, report.Status, report.Message, report.Mismatches, report.Threshold,
+		report.OverThreshold, string(calendars), report.Duration.Milliseconds(), report.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create drift report: %w", err)
+	}
+
+	return nil
+}
+
+// GetDriftReports returns the most recent drift reports of a source, newest first.
+func (db *DB) GetDriftReports(sourceID string, limit int) ([]*DriftReport, error) {
+	rows, err := db.conn.Query(`SELECT id, source_id, status, message, mismatches, threshold, over_threshold,
+		calendars, duration_ms, created_at FROM drift_reports
+		WHERE source_id = ? ORDER BY created_at DESC LIMIT ?`, sourceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query drift reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []*DriftReport
+	for rows.Next() {
+		report, err := scanDriftReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating drift reports: %w", err)
+	}
+
+	return reports, nil
+}
+
+// GetLatestDriftReport returns the most recent drift report of a source.
+func (db *DB) GetLatestDriftReport(sourceID string) (*DriftReport, error) {
+	row := db.conn.QueryRow(`SELECT id, source_id, status, message, mismatches, threshold, over_threshold,
+		calendars, duration_ms, created_at FROM drift_reports
+		WHERE source_id = ? ORDER BY created_at DESC LIMIT 1`, sourceID)
+
+	report, err := scanDriftReport(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return report, err
+}
+
+// CleanOldDriftReports deletes drift reports older than the given time.
+func (db *DB) CleanOldDriftReports(olderThan time.Time) (int64, error) {
+	result, err := db.conn.Exec(`DELETE FROM drift_reports WHERE created_at < ?`, olderThan.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean old drift reports: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return affected, nil
+}
 
-	_, err := db.conn.Exec(query, id, sourceID, eventPath, errorMessage, now)
+// scanDriftReport scans a drift report row.
+func scanDriftReport(row rowScanner) (*DriftReport, error) {
+	report := &DriftReport{}
+	var calendars string
+	var durationMs int64
+	err := row.Scan(&report.ID, &report.SourceID, &report.Status, &report.Message, &report.Mismatches, &report.Threshold,
+		&report.OverThreshold, &calendars, &durationMs, &report.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan drift report: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(calendars), &report.Calendars); err != nil {
+		return nil, fmt.Errorf("failed to decode drift calendars: %w", err)
+	}
+	report.Duration = time.Duration(durationMs) * time.Millisecond
+
+	return report, nil
+}
+
+// malformedEventColumns are the columns scanned by scanMalformedEvent; s is the joined source.
+const malformedEventColumns = `m.id, m.source_id, s.name, m.event_path, m.error_message, m.raw_payload, m.etag,
+		m.event_uid, m.calendar_href, m.dest_href, m.repairable, m.repairs, m.discovered_at`
+
+// SaveMalformedEvent saves or updates a malformed event record without a payload.
+func (db *DB) SaveMalformedEvent(sourceID, eventPath, errorMessage string) error {
+	return db.RecordMalformedEvent(&MalformedEvent{
+		SourceID:     sourceID,
+		EventPath:    eventPath,
+		ErrorMessage: errorMessage,
+	})
+}
+
+// RecordMalformedEvent saves or updates a malformed event record. An earlier record of
+// the same event is updated in place and keeps its ID and discovery time, so a record
+// under review survives later syncs. RawPayload must already be encrypted.
+func (db *DB) RecordMalformedEvent(event *MalformedEvent) error {
+	query := `INSERT INTO malformed_events (id, source_id, event_path, error_message, raw_payload,
+		etag, event_uid, calendar_href, dest_href, repairable, repairs, discovered_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(source_id, event_path) DO UPDATE SET
+			error_message = excluded.error_message, raw_payload = excluded.raw_payload, etag = excluded.etag,
+			event_uid = excluded.event_uid, calendar_href = excluded.calendar_href, dest_href = excluded.dest_href,
+			repairable = excluded.repairable, repairs = excluded.repairs
+		RETURNING id, discovered_at`
+
+	err := db.conn.QueryRow(query, uuid.New().String(), event.SourceID, event.EventPath, event.ErrorMessage,
+		event.RawPayload, event.ETag, event.EventUID, event.CalendarHref, event.DestHref, event.Repairable,
+		strings.Join(event.Repairs, ","), time.Now().UTC()).Scan(&event.ID, &event.DiscoveredAt)
 	if err != nil {
 		return fmt.Errorf("failed to save malformed event: %w", err)
 	}
@@ -660,7 +1782,7 @@ func (db *DB) SaveMalformedEvent(sourceID, eventPath, errorMessage string) error
 
 // GetMalformedEvents returns all malformed events for a user (via their sources).
 func (db *DB) GetMalformedEvents(userID string) ([]*MalformedEvent, error) {
-	query := `SELECT m.id, m.source_id, s.name, m.event_path, m.error_message, m.discovered_at
+	query := `SELECT ` + malformedEventColumns + `
 		FROM malformed_events m
 		JOIN sources s ON m.source_id = s.id
 		WHERE s.user_id = ?
@@ -674,11 +1796,9 @@ func (db *DB) GetMalformedEvents(userID string) ([]*MalformedEvent, error) {
 
 	var events []*MalformedEvent
 	for rows.Next() {
-		event := &MalformedEvent{}
-		err := rows.Scan(&event.ID, &event.SourceID, &event.SourceName,
-			&event.EventPath, &event.ErrorMessage, &event.DiscoveredAt)
+		event, err := scanMalformedEvent(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan malformed event: %w", err)
+			return nil, err
 		}
 		events = append(events, event)
 	}
@@ -692,19 +1812,17 @@ func (db *DB) GetMalformedEvents(userID string) ([]*MalformedEvent, error) {
 
 // GetMalformedEventByID returns a single malformed event by ID.
 func (db *DB) GetMalformedEventByID(id string) (*MalformedEvent, error) {
-	query := `SELECT m.id, m.source_id, s.name, m.event_path, m.error_message, m.discovered_at
+	query := `SELECT ` + malformedEventColumns + `
 		FROM malformed_events m
 		JOIN sources s ON m.source_id = s.id
 		WHERE m.id = ?`
 
-	event := &MalformedEvent{}
-	err := db.conn.QueryRow(query, id).Scan(&event.ID, &event.SourceID, &event.SourceName,
-		&event.EventPath, &event.ErrorMessage, &event.DiscoveredAt)
+	event, err := scanMalformedEvent(db.conn.QueryRow(query, id))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get malformed event: %w", err)
+		return nil, err
 	}
 
 	return event, nil
@@ -713,24 +1831,42 @@ func (db *DB) GetMalformedEventByID(id string) (*MalformedEvent, error) {
 // GetMalformedEventByIDForUser returns a malformed event by ID only if it belongs to the user.
 // This prevents timing attacks by combining auth check with the query.
 func (db *DB) GetMalformedEventByIDForUser(id, userID string) (*MalformedEvent, error) {
-	query := `SELECT m.id, m.source_id, s.name, m.event_path, m.error_message, m.discovered_at
+	query := `SELECT ` + malformedEventColumns + `
 		FROM malformed_events m
 		JOIN sources s ON m.source_id = s.id
 		WHERE m.id = ? AND s.user_id = ?`
 
-	event := &MalformedEvent{}
-	err := db.conn.QueryRow(query, id, userID).Scan(&event.ID, &event.SourceID, &event.SourceName,
-		&event.EventPath, &event.ErrorMessage, &event.DiscoveredAt)
+	event, err := scanMalformedEvent(db.conn.QueryRow(query, id, userID))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get malformed event: %w", err)
+		return nil, err
 	}
 
 	return event, nil
 }
 
+// scanMalformedEvent scans the columns listed in malformedEventColumns.
+func scanMalformedEvent(row rowScanner) (*MalformedEvent, error) {
+	event := &MalformedEvent{}
+	var repairs string
+	err := row.Scan(&event.ID, &event.SourceID, &event.SourceName, &event.EventPath, &event.ErrorMessage,
+		&event.RawPayload, &event.ETag, &event.EventUID, &event.CalendarHref, &event.DestHref,
+		&event.Repairable, &repairs, &event.DiscoveredAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan malformed event: %w", err)
+	}
+
+	if repairs != "" {
+		event.Repairs = strings.Split(repairs, ",")
+	}
+	return event, nil
+}
+
 // DeleteMalformedEvent removes a malformed event record.
 func (db *DB) DeleteMalformedEvent(id string) error {
 	query := `DELETE FROM malformed_events WHERE id = ?`
@@ -764,6 +1900,26 @@ func (db *DB) ClearMalformedEventsForSource(sourceID string) error {
 	return nil
 }
 
+// ClearResolvedMalformedEvents removes the malformed event records of a source calendar
+// whose events are no longer malformed, keeping those at the paths still malformed.
+// It must only be called after all events of the calendar were read.
+func (db *DB) ClearResolvedMalformedEvents(sourceID, calendarHref string, stillMalformed []string) error {
+	query := `DELETE FROM malformed_events WHERE source_id = ? AND calendar_href = ?`
+	args := []any{sourceID, calendarHref}
+	if len(stillMalformed) > 0 {
+		query += ` AND event_path NOT IN (?` + strings.Repeat(", ?", len(stillMalformed)-1) + `)`
+		for _, p := range stillMalformed {
+			args = append(args, p)
+		}
+	}
+
+	if _, err := db.conn.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to clear resolved malformed events: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteAllMalformedEventsForUser removes all malformed events for a user's sources.
 // Returns the number of events deleted.
 func (db *DB) DeleteAllMalformedEventsForUser(userID string) (int64, error) {
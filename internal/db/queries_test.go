@@ -354,6 +354,9 @@ func TestCreateSource(t *testing.T) {
 		if retrieved.SyncDirection != SyncDirectionOneWay {
 			t.Errorf("expected one_way, got %q", retrieved.SyncDirection)
 		}
+		if retrieved.CalendarConcurrency != 1 || retrieved.EventConcurrency != 1 {
+			t.Errorf("expected concurrency 1/1, got %d/%d", retrieved.CalendarConcurrency, retrieved.EventConcurrency)
+		}
 	})
 }
 
@@ -528,6 +531,150 @@ func TestUpdateSource(t *testing.T) {
 		}
 	})
 
+	t.Run("updates concurrency settings", func(t *testing.T) {
+		source.CalendarConcurrency = 3
+		source.EventConcurrency = 6
+
+		if err := db.UpdateSource(source); err != nil {
+			t.Fatalf("failed to update source: %v", err)
+		}
+
+		updated, _ := db.GetSourceByID(source.ID)
+		if updated.CalendarConcurrency != 3 {
+			t.Errorf("expected calendar concurrency 3, got %d", updated.CalendarConcurrency)
+		}
+		if updated.EventConcurrency != 6 {
+			t.Errorf("expected event concurrency 6, got %d", updated.EventConcurrency)
+		}
+	})
+
+	t.Run("updates calendar creation opt-in", func(t *testing.T) {
+		source.CreateCalendars = true
+		source.SyncCalendarMeta = true
+
+		if err := db.UpdateSource(source); err != nil {
+			t.Fatalf("failed to update source: %v", err)
+		}
+
+		updated, _ := db.GetSourceByID(source.ID)
+		if !updated.CreateCalendars || !updated.SyncCalendarMeta {
+			t.Error("expected calendar creation and metadata sync to be enabled")
+		}
+	})
+
+	t.Run("updates repaired event sync opt-in", func(t *testing.T) {
+		source.SyncRepairedEvents = true
+
+		if err := db.UpdateSource(source); err != nil {
+			t.Fatalf("failed to update source: %v", err)
+		}
+
+		updated, _ := db.GetSourceByID(source.ID)
+		if !updated.SyncRepairedEvents {
+			t.Error("expected repaired events to be synced")
+		}
+	})
+
+	t.Run("updates drift check interval", func(t *testing.T) {
+		if source.DriftCheckInterval != 0 {
+			t.Fatalf("expected drift checks to be disabled by default, got %d", source.DriftCheckInterval)
+		}
+		source.DriftCheckInterval = 86400
+
+		if err := db.UpdateSource(source); err != nil {
+			t.Fatalf("failed to update source: %v", err)
+		}
+
+		updated, _ := db.GetSourceByID(source.ID)
+		if updated.DriftCheckInterval != 86400 {
+			t.Errorf("expected drift check interval 86400, got %d", updated.DriftCheckInterval)
+		}
+	})
+
+	t.Run("updates dedupe settings", func(t *testing.T) {
+		if source.DedupePolicy != DedupeAuto || len(source.GetDedupeFields()) != 2 {
+			t.Fatalf("expected auto dedupe on summary and start by default, got %s %v", source.DedupePolicy, source.GetDedupeFields())
+		}
+		source.DedupePolicy = DedupeReport
+		source.DedupeFields = []DedupeField{DedupeSummary, DedupeStart, DedupeLocation}
+
+		if err := db.UpdateSource(source); err != nil {
+			t.Fatalf("failed to update source: %v", err)
+		}
+
+		updated, _ := db.GetSourceByID(source.ID)
+		if updated.DedupePolicy != DedupeReport || len(updated.DedupeFields) != 3 || updated.DedupeFields[2] != DedupeLocation {
+			t.Errorf("unexpected dedupe settings: %s %v", updated.DedupePolicy, updated.DedupeFields)
+		}
+	})
+
+	t.Run("updates transport settings", func(t *testing.T) {
+		source.SourceTransport = TransportSettings{
+			CACert:    "ca-pem",
+			ProxyURL:  "encrypted-proxy",
+			UserAgent: "calbridge",
+			Headers:   map[string]string{"X-Api-Key": "encrypted-value"},
+		}
+		source.DestTransport = TransportSettings{InsecureSkipVerify: true}
+
+		if err := db.UpdateSource(source); err != nil {
+			t.Fatalf("failed to update source: %v", err)
+		}
+
+		updated, _ := db.GetSourceByID(source.ID)
+		if updated.SourceTransport.CACert != "ca-pem" || updated.SourceTransport.ProxyURL != "encrypted-proxy" {
+			t.Errorf("unexpected source transport: %+v", updated.SourceTransport)
+		}
+		if updated.SourceTransport.Headers["X-Api-Key"] != "encrypted-value" {
+			t.Errorf("expected header to round trip, got %+v", updated.SourceTransport.Headers)
+		}
+		if !updated.DestTransport.InsecureSkipVerify {
+			t.Error("expected destination to skip TLS verification")
+		}
+
+		source.SourceTransport = TransportSettings{}
+		source.DestTransport = TransportSettings{}
+		if err := db.UpdateSource(source); err != nil {
+			t.Fatalf("failed to update source: %v", err)
+		}
+		updated, _ = db.GetSourceByID(source.ID)
+		if !updated.SourceTransport.IsZero() || !updated.DestTransport.IsZero() {
+			t.Error("expected transport settings to be cleared")
+		}
+	})
+
+	t.Run("updates auth settings", func(t *testing.T) {
+		source.SourceAuth = AuthSettings{Method: AuthMethodBearer, AccessToken: "encrypted-token"}
+		if err := db.UpdateSource(source); err != nil {
+			t.Fatalf("failed to update source: %v", err)
+		}
+
+		expiry := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		destAuth := AuthSettings{
+			Method:       AuthMethodOAuth2,
+			AccessToken:  "encrypted-access",
+			RefreshToken: "encrypted-refresh",
+			TokenExpiry:  &expiry,
+			TokenURL:     "https://oauth.example.com/token",
+			Scopes:       []string{"calendar"},
+		}
+		if err := db.UpdateSourceAuth(source.ID, EndpointDest, destAuth); err != nil {
+			t.Fatalf("failed to update auth: %v", err)
+		}
+
+		updated, _ := db.GetSourceByID(source.ID)
+		if updated.SourceAuth.Method != AuthMethodBearer || updated.SourceAuth.AccessToken != "encrypted-token" {
+			t.Errorf("unexpected source auth: %+v", updated.SourceAuth)
+		}
+		if updated.DestAuth.RefreshToken != "encrypted-refresh" || updated.DestAuth.TokenExpiry == nil || !updated.DestAuth.TokenExpiry.Equal(expiry) {
+			t.Errorf("unexpected destination auth: %+v", updated.DestAuth)
+		}
+
+		if err := db.UpdateSourceAuth("nonexistent-id", EndpointSource, destAuth); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
 	t.Run("returns ErrNotFound for nonexistent source", func(t *testing.T) {
 		nonexistent := &Source{ID: "nonexistent-id"}
 		err := db.UpdateSource(nonexistent)
@@ -570,6 +717,51 @@ func TestUpdateSourceSyncStatus(t *testing.T) {
 	})
 }
 
+func TestAuthFailures(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := createTestUser(t, db, "authfail@example.com")
+	source := createTestSource(t, db, userID, "Auth Failure Test")
+
+	t.Run("increments consecutive failures", func(t *testing.T) {
+		for want := 1; want <= 3; want++ {
+			got, err := db.IncrementAuthFailures(source.ID)
+			if err != nil {
+				t.Fatalf("failed to increment auth failures: %v", err)
+			}
+			if got != want {
+				t.Errorf("expected %d failures, got %d", want, got)
+			}
+		}
+
+		updated, _ := db.GetSourceByID(source.ID)
+		if updated.AuthFailures != 3 {
+			t.Errorf("expected AuthFailures 3, got %d", updated.AuthFailures)
+		}
+	})
+
+	t.Run("resets failures", func(t *testing.T) {
+		if err := db.ResetAuthFailures(source.ID); err != nil {
+			t.Fatalf("failed to reset auth failures: %v", err)
+		}
+
+		updated, _ := db.GetSourceByID(source.ID)
+		if updated.AuthFailures != 0 {
+			t.Errorf("expected AuthFailures 0, got %d", updated.AuthFailures)
+		}
+	})
+
+	t.Run("returns ErrNotFound for nonexistent source", func(t *testing.T) {
+		if _, err := db.IncrementAuthFailures("nonexistent-id"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		if err := db.ResetAuthFailures("nonexistent-id"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
 func TestDeleteSource(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -658,6 +850,160 @@ func TestSyncState(t *testing.T) {
 	})
 }
 
+func TestCalendarMapping(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := createTestUser(t, db, "mapping@example.com")
+	source := createTestSource(t, db, userID, "Mapping Test")
+
+	t.Run("get returns ErrNotFound for unmapped calendar", func(t *testing.T) {
+		_, err := db.GetCalendarMapping(source.ID, "/calendars/work/")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("upsert creates and updates a mapping", func(t *testing.T) {
+		m := &CalendarMapping{SourceID: source.ID, CalendarHref: "/calendars/work/", DestHref: "/dest/work/", Created: true}
+		if err := db.UpsertCalendarMapping(m); err != nil {
+			t.Fatalf("failed to upsert: %v", err)
+		}
+
+		m = &CalendarMapping{SourceID: source.ID, CalendarHref: "/calendars/work/", DestHref: "/dest/other/"}
+		if err := db.UpsertCalendarMapping(m); err != nil {
+			t.Fatalf("failed to upsert: %v", err)
+		}
+
+		retrieved, err := db.GetCalendarMapping(source.ID, "/calendars/work/")
+		if err != nil {
+			t.Fatalf("failed to get mapping: %v", err)
+		}
+		if retrieved.DestHref != "/dest/other/" || retrieved.Created {
+			t.Errorf("unexpected mapping: %+v", retrieved)
+		}
+	})
+
+	t.Run("lists mappings of a source", func(t *testing.T) {
+		db.UpsertCalendarMapping(&CalendarMapping{SourceID: source.ID, CalendarHref: "/calendars/home/", DestHref: "/dest/home/"})
+
+		mappings, err := db.GetCalendarMappings(source.ID)
+		if err != nil {
+			t.Fatalf("failed to list mappings: %v", err)
+		}
+		if len(mappings) != 2 || mappings[0].CalendarHref != "/calendars/home/" {
+			t.Errorf("unexpected mappings: %+v", mappings)
+		}
+	})
+
+	t.Run("metadata hash is reset when the destination changes", func(t *testing.T) {
+		if err := db.SetCalendarMappingMetadata(source.ID, "/calendars/home/", "abc"); err != nil {
+			t.Fatalf("failed to set metadata hash: %v", err)
+		}
+		db.UpsertCalendarMapping(&CalendarMapping{SourceID: source.ID, CalendarHref: "/calendars/home/", DestHref: "/dest/home/"})
+		if m, _ := db.GetCalendarMapping(source.ID, "/calendars/home/"); m.MetadataHash != "abc" {
+			t.Errorf("expected hash to be kept, got %q", m.MetadataHash)
+		}
+
+		db.UpsertCalendarMapping(&CalendarMapping{SourceID: source.ID, CalendarHref: "/calendars/home/", DestHref: "/dest/moved/"})
+		if m, _ := db.GetCalendarMapping(source.ID, "/calendars/home/"); m.MetadataHash != "" {
+			t.Errorf("expected hash to be reset, got %q", m.MetadataHash)
+		}
+
+		if err := db.SetCalendarMappingMetadata(source.ID, "/calendars/none/", "abc"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestServerCapabilities(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := createTestUser(t, db, "caps@example.com")
+	source := createTestSource(t, db, userID, "Capabilities Test")
+
+	t.Run("get returns ErrNotFound before probing", func(t *testing.T) {
+		if _, err := db.GetServerCapabilities(source.ID, EndpointSource); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("stores a profile per endpoint", func(t *testing.T) {
+		db.UpsertServerCapabilities(&ServerCapabilities{SourceID: source.ID, Endpoint: EndpointSource, MultiGet: true})
+		db.UpsertServerCapabilities(&ServerCapabilities{SourceID: source.ID, Endpoint: EndpointDest, MKCalendar: true})
+		db.UpsertServerCapabilities(&ServerCapabilities{SourceID: source.ID, Endpoint: EndpointSource, Server: "SOGo", CalendarQuery: false})
+
+		caps, err := db.GetServerCapabilities(source.ID, EndpointSource)
+		if err != nil {
+			t.Fatalf("failed to get capabilities: %v", err)
+		}
+		if caps.Server != "SOGo" || caps.MultiGet || caps.ProbedAt.IsZero() {
+			t.Errorf("unexpected capabilities: %+v", caps)
+		}
+
+		all, _ := db.GetAllServerCapabilities(source.ID)
+		if len(all) != 2 || all[0].Endpoint != EndpointSource || !all[1].MKCalendar {
+			t.Errorf("unexpected profiles: %+v", all)
+		}
+	})
+
+	t.Run("delete forgets all endpoints", func(t *testing.T) {
+		if err := db.DeleteServerCapabilities(source.ID); err != nil {
+			t.Fatalf("failed to delete capabilities: %v", err)
+		}
+		if all, _ := db.GetAllServerCapabilities(source.ID); len(all) != 0 {
+			t.Errorf("expected no profiles, got %d", len(all))
+		}
+	})
+}
+
+func TestCalendarIdentity(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := createTestUser(t, db, "identity@example.com")
+	source := createTestSource(t, db, userID, "Identity Test")
+
+	t.Run("upsert records and updates identities", func(t *testing.T) {
+		db.UpsertCalendarIdentity(&CalendarIdentity{SourceID: source.ID, CalendarHref: "/old/work/", Name: "Work", ResourceID: "work", Fingerprint: "work"})
+		db.UpsertCalendarIdentity(&CalendarIdentity{SourceID: source.ID, CalendarHref: "/old/work/", Name: "Office", ResourceID: "work", Fingerprint: "office"})
+
+		identities, err := db.GetCalendarIdentities(source.ID)
+		if err != nil {
+			t.Fatalf("failed to list identities: %v", err)
+		}
+		if len(identities) != 1 || identities[0].Name != "Office" || identities[0].Fingerprint != "office" {
+			t.Errorf("unexpected identities: %+v", identities)
+		}
+	})
+
+	t.Run("move carries state and selection to the new href", func(t *testing.T) {
+		source.SelectedCalendars = []CalendarConfig{{Path: "/old/work/"}, {Path: "/old/home/"}}
+		db.UpdateSource(source)
+		db.UpsertSyncState(&SyncState{SourceID: source.ID, CalendarHref: "/old/work/", SyncToken: "token"})
+		db.UpsertCalendarMapping(&CalendarMapping{SourceID: source.ID, CalendarHref: "/old/work/", DestHref: "/dest/work/"})
+
+		if err := db.MoveCalendar(source.ID, "/old/work/", "/new/work/"); err != nil {
+			t.Fatalf("failed to move calendar: %v", err)
+		}
+
+		if state, err := db.GetSyncState(source.ID, "/new/work/"); err != nil || state.SyncToken != "token" {
+			t.Errorf("expected sync state to move, got %+v (%v)", state, err)
+		}
+		if m, err := db.GetCalendarMapping(source.ID, "/new/work/"); err != nil || m.DestHref != "/dest/work/" {
+			t.Errorf("expected mapping to move, got %+v (%v)", m, err)
+		}
+		if identities, _ := db.GetCalendarIdentities(source.ID); len(identities) != 1 || identities[0].CalendarHref != "/new/work/" {
+			t.Errorf("expected identity to move, got %+v", identities)
+		}
+		updated, _ := db.GetSourceByID(source.ID)
+		if updated.SelectedCalendars[0].Path != "/new/work/" || updated.SelectedCalendars[1].Path != "/old/home/" {
+			t.Errorf("unexpected selection: %+v", updated.SelectedCalendars)
+		}
+	})
+}
+
 // ============================================================================
 // SyncLog Tests
 // ============================================================================
@@ -682,6 +1028,10 @@ func TestSyncLog(t *testing.T) {
 			EventsSkipped:   1,
 			CalendarsSynced: 3,
 			EventsProcessed: 18,
+
+			RequestsThrottled: 4,
+			RequestsRetried:   3,
+			ThrottleWait:      1500 * time.Millisecond,
 		}
 
 		err := db.CreateSyncLog(log)
@@ -709,6 +1059,12 @@ func TestSyncLog(t *testing.T) {
 		if logs[0].Duration != 5*time.Second {
 			t.Errorf("expected 5s duration, got %v", logs[0].Duration)
 		}
+		if logs[0].RequestsThrottled != 4 || logs[0].RequestsRetried != 3 {
+			t.Errorf("expected throttle stats 4/3, got %d/%d", logs[0].RequestsThrottled, logs[0].RequestsRetried)
+		}
+		if logs[0].ThrottleWait != 1500*time.Millisecond {
+			t.Errorf("expected 1.5s throttle wait, got %v", logs[0].ThrottleWait)
+		}
 	})
 
 	t.Run("get logs respects limit", func(t *testing.T) {
@@ -740,6 +1096,180 @@ func TestSyncLog(t *testing.T) {
 	})
 }
 
+func TestDuplicateGroups(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := createTestUser(t, db, "duplicates@example.com")
+	source := createTestSource(t, db, userID, "Duplicate Test")
+
+	group := func(uid string) *DuplicateGroup {
+		return &DuplicateGroup{
+			DestHref:   "/dest/work/",
+			Summary:    "Lun…",
+			StartTime:  "20240115T120000Z",
+			Keep:       DuplicateEvent{Path: "/dest/work/a.ics", UID: "a"},
+			Duplicates: []DuplicateEvent{{Path: "/dest/work/" + uid + ".ics", UID: uid, ETag: `"1"`}},
+		}
+	}
+
+	t.Run("replaces the groups of a calendar", func(t *testing.T) {
+		db.ReplaceDuplicateGroups(source.ID, "/cal/work/", []*DuplicateGroup{group("b"), group("c")})
+		db.ReplaceDuplicateGroups(source.ID, "/cal/home/", []*DuplicateGroup{group("d")})
+		if err := db.ReplaceDuplicateGroups(source.ID, "/cal/work/", []*DuplicateGroup{group("e")}); err != nil {
+			t.Fatalf("failed to replace groups: %v", err)
+		}
+
+		groups, err := db.GetDuplicateGroups(source.ID)
+		if err != nil {
+			t.Fatalf("failed to get groups: %v", err)
+		}
+		if len(groups) != 2 || groups[0].CalendarHref != "/cal/home/" || groups[1].Duplicates[0].UID != "e" {
+			t.Fatalf("expected the home group and the new work group, got %+v", groups)
+		}
+		if groups[1].Keep.UID != "a" || groups[1].Duplicates[0].ETag != `"1"` {
+			t.Errorf("unexpected events: %+v", groups[1])
+		}
+	})
+
+	t.Run("gets and deletes one group", func(t *testing.T) {
+		groups, _ := db.GetDuplicateGroups(source.ID)
+		if _, err := db.GetDuplicateGroup("other-source", groups[0].ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound for another source, got %v", err)
+		}
+
+		if err := db.DeleteDuplicateGroup(groups[0].ID); err != nil {
+			t.Fatalf("failed to delete group: %v", err)
+		}
+		if _, err := db.GetDuplicateGroup(source.ID, groups[0].ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound after deleting, got %v", err)
+		}
+	})
+}
+
+func TestDriftReports(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := createTestUser(t, db, "drift@example.com")
+	source := createTestSource(t, db, userID, "Drift Test")
+
+	t.Run("no report yet", func(t *testing.T) {
+		if _, err := db.GetLatestDriftReport(source.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("stores reports with their calendars", func(t *testing.T) {
+		db.CreateDriftReport(&DriftReport{SourceID: source.ID, Status: DriftStatusOK, Threshold: 10})
+		time.Sleep(10 * time.Millisecond)
+		err := db.CreateDriftReport(&DriftReport{
+			SourceID:      source.ID,
+			Status:        DriftStatusDrift,
+			Mismatches:    12,
+			Threshold:     10,
+			OverThreshold: true,
+			Calendars: []CalendarDrift{
+				{CalendarHref: "/cal/work/", DestHref: "/dest/work/", MissingOnDest: 11, Differs: 1, UIDs: []string{"a", "b"}},
+			},
+			Duration: 1500 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("failed to create report: %v", err)
+		}
+
+		latest, err := db.GetLatestDriftReport(source.ID)
+		if err != nil {
+			t.Fatalf("failed to get latest report: %v", err)
+		}
+		if latest.Status != DriftStatusDrift || !latest.OverThreshold || latest.Duration != 1500*time.Millisecond {
+			t.Errorf("unexpected report: %+v", latest)
+		}
+		if len(latest.Calendars) != 1 || latest.Calendars[0].Mismatches() != 12 || len(latest.Calendars[0].UIDs) != 2 {
+			t.Errorf("unexpected calendars: %+v", latest.Calendars)
+		}
+
+		reports, _ := db.GetDriftReports(source.ID, 10)
+		if len(reports) != 2 || reports[0].ID != latest.ID || reports[1].Status != DriftStatusOK {
+			t.Errorf("expected reports newest first, got %+v", reports)
+		}
+	})
+
+	t.Run("cleans old reports", func(t *testing.T) {
+		deleted, err := db.CleanOldDriftReports(time.Now().Add(time.Hour))
+		if err != nil || deleted != 2 {
+			t.Errorf("expected 2 reports deleted, got %d (%v)", deleted, err)
+		}
+	})
+}
+
+func TestCalendarSyncLogs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := createTestUser(t, db, "calendarlog@example.com")
+	source := createTestSource(t, db, userID, "Calendar Log Test")
+
+	newLog := func(workStatus SyncStatus, workCreated int) *SyncLog {
+		log := &SyncLog{
+			SourceID: source.ID,
+			Status:   SyncStatusPartial,
+			Calendars: []*CalendarSyncLog{
+				{CalendarHref: "/cal/work/", CalendarName: "Work", Status: workStatus, Mode: SyncModeFull,
+					EventsCreated: workCreated, Duration: 2 * time.Second, Details: "Warnings: [Failed to sync event]"},
+				{CalendarHref: "/cal/home/", CalendarName: "Home", Status: SyncStatusSuccess, Mode: SyncModeIncremental},
+			},
+		}
+		if err := db.CreateSyncLog(log); err != nil {
+			t.Fatalf("failed to create log: %v", err)
+		}
+		return log
+	}
+
+	t.Run("stores calendars with their log", func(t *testing.T) {
+		created := newLog(SyncStatusPartial, 3)
+
+		logs, _ := db.GetSyncLogs(source.ID, 10)
+		if len(logs) != 1 || logs[0].Calendars != nil {
+			t.Fatalf("expected 1 log without calendars loaded, got %+v", logs)
+		}
+		if err := db.LoadCalendarSyncLogs(logs); err != nil {
+			t.Fatalf("failed to load calendars: %v", err)
+		}
+
+		calendars := logs[0].Calendars
+		if len(calendars) != 2 || calendars[0].CalendarName != "Home" || calendars[1].CalendarName != "Work" {
+			t.Fatalf("expected Home and Work, got %+v", calendars)
+		}
+		work := calendars[1]
+		if work.SyncLogID != created.ID || work.Status != SyncStatusPartial || work.Mode != SyncModeFull ||
+			work.EventsCreated != 3 || work.Duration != 2*time.Second || work.Details == "" {
+			t.Errorf("unexpected calendar log: %+v", work)
+		}
+	})
+
+	t.Run("returns the latest result of each calendar", func(t *testing.T) {
+		newLog(SyncStatusError, 7)
+
+		latest, err := db.GetLatestCalendarSyncLogs(source.ID)
+		if err != nil {
+			t.Fatalf("failed to get latest: %v", err)
+		}
+		if len(latest) != 2 || latest[1].Status != SyncStatusError || latest[1].EventsCreated != 7 {
+			t.Errorf("expected the second result of Work, got %+v", latest)
+		}
+	})
+
+	t.Run("removes calendars with their log", func(t *testing.T) {
+		if _, err := db.CleanOldSyncLogs(time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("failed to clean logs: %v", err)
+		}
+		if latest, _ := db.GetLatestCalendarSyncLogs(source.ID); len(latest) != 0 {
+			t.Errorf("expected calendar logs to be removed, got %d", len(latest))
+		}
+	})
+}
+
 // ============================================================================
 // SyncedEvent Tests
 // ============================================================================
@@ -826,6 +1356,19 @@ func TestSyncedEvent(t *testing.T) {
 			t.Error("all events should be deleted")
 		}
 	})
+
+	t.Run("get events of every calendar", func(t *testing.T) {
+		db.UpsertSyncedEvent(&SyncedEvent{SourceID: source.ID, CalendarHref: "/calendars/user/work/", EventUID: "uid1"})
+		db.UpsertSyncedEvent(&SyncedEvent{SourceID: source.ID, CalendarHref: "/calendars/user/home/", EventUID: "uid2"})
+
+		events, err := db.GetSyncedEventsForSource(source.ID)
+		if err != nil {
+			t.Fatalf("failed to get events: %v", err)
+		}
+		if len(events) != 2 || events[0].CalendarHref != "/calendars/user/home/" || events[1].EventUID != "uid1" {
+			t.Errorf("unexpected events: %+v", events)
+		}
+	})
 }
 
 // ============================================================================
@@ -860,6 +1403,77 @@ func TestMalformedEvent(t *testing.T) {
 		}
 	})
 
+	t.Run("record malformed event with repair details", func(t *testing.T) {
+		event := &MalformedEvent{
+			SourceID:     source.ID,
+			EventPath:    "/calendar/repairable.ics",
+			ErrorMessage: "empty iCalendar data",
+			RawPayload:   "encrypted-payload",
+			ETag:         `"e1"`,
+			EventUID:     "event-1",
+			CalendarHref: "/calendar/",
+			DestHref:     "/dest/",
+			Repairable:   true,
+			Repairs:      []string{"line_endings", "missing_dtstamp"},
+		}
+		if err := db.RecordMalformedEvent(event); err != nil {
+			t.Fatalf("failed to record: %v", err)
+		}
+
+		got, err := db.GetMalformedEventByIDForUser(event.ID, userID)
+		if err != nil {
+			t.Fatalf("failed to get event: %v", err)
+		}
+		if got.RawPayload != "encrypted-payload" || got.ETag != `"e1"` || got.EventUID != "event-1" ||
+			got.CalendarHref != "/calendar/" || got.DestHref != "/dest/" || !got.Repairable ||
+			len(got.Repairs) != 2 || got.Repairs[1] != "missing_dtstamp" {
+			t.Errorf("unexpected event: %+v", got)
+		}
+		db.DeleteMalformedEvent(event.ID)
+	})
+
+	t.Run("recording an event again keeps its ID", func(t *testing.T) {
+		first := &MalformedEvent{SourceID: source.ID, EventPath: "/calendar/again.ics", ErrorMessage: "Error 1", CalendarHref: "/calendar/"}
+		db.RecordMalformedEvent(first)
+		second := &MalformedEvent{SourceID: source.ID, EventPath: "/calendar/again.ics", ErrorMessage: "Error 2", CalendarHref: "/calendar/"}
+		if err := db.RecordMalformedEvent(second); err != nil {
+			t.Fatalf("failed to record: %v", err)
+		}
+
+		if second.ID != first.ID {
+			t.Errorf("expected ID %s to be kept, got %s", first.ID, second.ID)
+		}
+		got, err := db.GetMalformedEventByID(first.ID)
+		if err != nil || got.ErrorMessage != "Error 2" {
+			t.Errorf("expected the record to be updated, got %+v (%v)", got, err)
+		}
+		db.DeleteMalformedEvent(first.ID)
+	})
+
+	t.Run("clear resolved malformed events of a calendar", func(t *testing.T) {
+		for _, path := range []string{"/calendar/a.ics", "/calendar/b.ics"} {
+			db.RecordMalformedEvent(&MalformedEvent{SourceID: source.ID, EventPath: path, ErrorMessage: "Error", CalendarHref: "/calendar/"})
+		}
+		other := &MalformedEvent{SourceID: source.ID, EventPath: "/other/c.ics", ErrorMessage: "Error", CalendarHref: "/other/"}
+		db.RecordMalformedEvent(other)
+
+		if err := db.ClearResolvedMalformedEvents(source.ID, "/calendar/", []string{"/calendar/b.ics"}); err != nil {
+			t.Fatalf("failed to clear: %v", err)
+		}
+
+		events, _ := db.GetMalformedEvents(userID)
+		paths := make(map[string]bool)
+		for _, e := range events {
+			paths[e.EventPath] = true
+		}
+		if paths["/calendar/a.ics"] || !paths["/calendar/b.ics"] || !paths["/other/c.ics"] {
+			t.Errorf("expected only the resolved event to be cleared, got %v", paths)
+		}
+
+		db.ClearResolvedMalformedEvents(source.ID, "/calendar/", nil)
+		db.DeleteMalformedEvent(other.ID)
+	})
+
 	t.Run("get malformed event by ID for user", func(t *testing.T) {
 		events, _ := db.GetMalformedEvents(userID)
 		eventID := events[0].ID
@@ -938,3 +1552,173 @@ func TestDatabaseConnection(t *testing.T) {
 		}
 	})
 }
+
+func TestSyncJournal(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := createTestUser(t, db, "journal@example.com")
+	source := createTestSource(t, db, userID, "Journal Test")
+
+	newEntries := func(runID string) []*JournalEntry {
+		return []*JournalEntry{
+			{RunID: runID, SourceID: source.ID, CalendarHref: "/cal/", EventUID: "uid-1", Operation: JournalPutDest, EventPath: "/dest/"},
+			{RunID: runID, SourceID: source.ID, CalendarHref: "/cal/", EventUID: "uid-2", Operation: JournalDeleteDest, EventPath: "/dest/uid-2.ics"},
+		}
+	}
+
+	t.Run("records entries as pending", func(t *testing.T) {
+		entries := newEntries("run-1")
+		if err := db.CreateJournalEntries(entries); err != nil {
+			t.Fatalf("failed to create journal entries: %v", err)
+		}
+
+		got, err := db.GetUnfinishedJournalEntries()
+		if err != nil {
+			t.Fatalf("failed to get journal entries: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("expected 2 entries, got %d", len(got))
+		}
+		for _, entry := range got {
+			if entry.State != JournalPending {
+				t.Errorf("expected pending state, got %q", entry.State)
+			}
+		}
+
+		if err := db.DeleteJournalRun("run-1"); err != nil {
+			t.Fatalf("failed to delete journal run: %v", err)
+		}
+	})
+
+	t.Run("completing a put records the synced event", func(t *testing.T) {
+		entries := newEntries("run-2")
+		if err := db.CreateJournalEntries(entries); err != nil {
+			t.Fatalf("failed to create journal entries: %v", err)
+		}
+
+		if err := db.CompleteJournalEntry(entries[0]); err != nil {
+			t.Fatalf("failed to complete entry: %v", err)
+		}
+		if err := db.FailJournalEntry(entries[1]); err != nil {
+			t.Fatalf("failed to fail entry: %v", err)
+		}
+
+		synced, _ := db.GetSyncedEvents(source.ID, "/cal/")
+		if len(synced) != 1 || synced[0].EventUID != "uid-1" {
+			t.Errorf("expected uid-1 in synced_events, got %+v", synced)
+		}
+
+		got, _ := db.GetUnfinishedJournalEntries()
+		states := make(map[string]JournalState)
+		for _, entry := range got {
+			states[entry.EventUID] = entry.State
+		}
+		if states["uid-1"] != JournalDone || states["uid-2"] != JournalFailed {
+			t.Errorf("unexpected journal states: %v", states)
+		}
+	})
+
+	t.Run("deleting a run leaves other runs", func(t *testing.T) {
+		if err := db.CreateJournalEntries(newEntries("run-3")); err != nil {
+			t.Fatalf("failed to create journal entries: %v", err)
+		}
+		if err := db.DeleteJournalRun("run-2"); err != nil {
+			t.Fatalf("failed to delete journal run: %v", err)
+		}
+
+		got, _ := db.GetUnfinishedJournalEntries()
+		if len(got) != 2 || got[0].RunID != "run-3" {
+			t.Errorf("expected only run-3 entries, got %d entries", len(got))
+		}
+	})
+
+	t.Run("tracks pending writes of interrupted runs", func(t *testing.T) {
+		entries := newEntries("run-4")
+		db.CreateJournalEntries(entries)
+		db.CompleteJournalEntry(entries[0])
+		if err := db.DeleteFinishedJournalEntries("run-4"); err != nil {
+			t.Fatalf("failed to delete finished entries: %v", err)
+		}
+
+		if pending, err := db.HasInterruptedJournalEntries(source.ID, "/cal/", "run-5"); err != nil || !pending {
+			t.Errorf("expected pending writes of other runs, got %v (%v)", pending, err)
+		}
+		if err := db.DeleteInterruptedJournalEntries(source.ID, "/cal/", "run-5"); err != nil {
+			t.Fatalf("failed to delete interrupted entries: %v", err)
+		}
+		if pending, _ := db.HasInterruptedJournalEntries(source.ID, "/cal/", "run-5"); pending {
+			t.Error("expected interrupted entries to be deleted")
+		}
+	})
+
+	t.Run("returns ErrNotFound for unknown entry", func(t *testing.T) {
+		err := db.CompleteJournalEntry(&JournalEntry{ID: "nonexistent", Operation: JournalPutDest})
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestEventChanges(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := createTestUser(t, db, "test@example.com")
+	source := createTestSource(t, db, userID, "Test Source")
+
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	changes := []*EventChange{
+		{RunID: "run-1", SourceID: source.ID, CalendarHref: "/cal/", EventUID: "dentist", Summary: RedactSummary("Dentist appointment"),
+			SummaryHash: HashSummary("Dentist appointment"), Action: ChangeCreate, Direction: ChangeToDest,
+			Reason: ReasonNewOnSource, Result: ChangeSucceeded, CreatedAt: old},
+		{RunID: "run-2", SourceID: source.ID, CalendarHref: "/cal/", EventUID: "dentist", Summary: RedactSummary("Dentist appointment"),
+			SummaryHash: HashSummary("Dentist appointment"), Action: ChangeDelete, Direction: ChangeToDest,
+			Reason: ReasonOrphan, Result: ChangeSucceeded},
+		{RunID: "run-2", SourceID: source.ID, CalendarHref: "/cal/", EventUID: "standup", Action: ChangeUpdate,
+			Direction: ChangeToDest, Reason: ReasonChangedOnSource, Result: ChangeFailed, Error: "403 Forbidden"},
+	}
+	if err := db.CreateEventChanges(changes); err != nil {
+		t.Fatalf("CreateEventChanges failed: %v", err)
+	}
+
+	t.Run("filters and paginates", func(t *testing.T) {
+		got, total, err := db.GetEventChanges(source.ID, EventChangeFilter{EventUID: "dentist"})
+		if err != nil || total != 2 || len(got) != 2 {
+			t.Fatalf("expected 2 changes of the event, got %d of %d (%v)", len(got), total, err)
+		}
+		if got[0].Action != ChangeDelete || got[0].Reason != ReasonOrphan {
+			t.Errorf("expected newest change first, got %+v", got[0])
+		}
+
+		got, _, _ = db.GetEventChanges(source.ID, EventChangeFilter{SummaryHash: HashSummary("  dentist APPOINTMENT ")})
+		if len(got) != 2 || got[0].Summary != "Den…" {
+			t.Errorf("expected changes found by summary with redacted summaries, got %+v", got)
+		}
+
+		got, total, _ = db.GetEventChanges(source.ID, EventChangeFilter{Action: ChangeUpdate})
+		if total != 1 || got[0].Error != "403 Forbidden" || got[0].Result != ChangeFailed {
+			t.Errorf("expected the failed update, got %+v", got)
+		}
+
+		got, total, _ = db.GetEventChanges(source.ID, EventChangeFilter{Since: time.Now().Add(-time.Hour)})
+		if total != 2 {
+			t.Errorf("expected 2 recent changes, got %d", total)
+		}
+
+		got, total, _ = db.GetEventChanges(source.ID, EventChangeFilter{Limit: 1, Offset: 2})
+		if total != 3 || len(got) != 1 || got[0].RunID != "run-1" {
+			t.Errorf("expected the oldest change on the last page, got %+v (total %d)", got, total)
+		}
+	})
+
+	t.Run("cleans old changes", func(t *testing.T) {
+		deleted, err := db.CleanOldEventChanges(time.Now().Add(-24 * time.Hour))
+		if err != nil || deleted != 1 {
+			t.Fatalf("expected 1 old change deleted, got %d (%v)", deleted, err)
+		}
+		if _, total, _ := db.GetEventChanges(source.ID, EventChangeFilter{}); total != 2 {
+			t.Errorf("expected 2 changes left, got %d", total)
+		}
+	})
+}
@@ -14,6 +14,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/macjediwizard/calbridgesync/internal/validator"
 )
 
 var (
@@ -25,9 +27,11 @@ var (
 type AlertType string
 
 const (
-	AlertTypeStale    AlertType = "stale"
-	AlertTypeRecovery AlertType = "recovery"
-	AlertTypeError    AlertType = "error"
+	AlertTypeStale       AlertType = "stale"
+	AlertTypeRecovery    AlertType = "recovery"
+	AlertTypeError       AlertType = "error"
+	AlertTypeCredentials AlertType = "credentials_invalid"
+	AlertTypeDrift       AlertType = "drift"
 )
 
 // Alert represents a notification alert.
@@ -46,6 +50,7 @@ type Config struct {
 	// Webhook settings
 	WebhookEnabled bool
 	WebhookURL     string
+	AddressPolicy  *validator.AddressPolicy // Checked on every webhook connection; nil allows all
 
 	// Email settings
 	EmailEnabled   bool
@@ -83,11 +88,19 @@ type Notifier struct {
 
 // New creates a new Notifier.
 func New(cfg *Config) *Notifier {
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+	// Webhook URLs are only checked by name; the policy also covers what they resolve to
+	if cfg.AddressPolicy != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = cfg.AddressPolicy.DialContext
+		httpClient.Transport = transport
+	}
+
 	return &Notifier{
-		cfg: cfg,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		cfg:            cfg,
+		httpClient:     httpClient,
 		lastAlertTimes: make(map[string]time.Time),
 		staleState:     make(map[string]bool),
 	}
@@ -323,6 +336,10 @@ func (n *Notifier) sendWebhook(ctx context.Context, alert Alert) error {
 		emoji = ":white_check_mark:"
 	case AlertTypeError:
 		emoji = ":x:"
+	case AlertTypeCredentials:
+		emoji = ":lock:"
+	case AlertTypeDrift:
+		emoji = ":mag:"
 	}
 
 	payload := WebhookPayload{
@@ -541,6 +558,42 @@ func (n *Notifier) SendRecoveryAlertWithPrefs(ctx context.Context, sourceID, sou
 	return true
 }
 
+// SendCredentialsAlertWithPrefs tells the owner that a source was paused after repeated
+// authentication failures and needs new credentials. Not subject to cooldown: the
+// circuit breaker only trips once until the credentials are updated.
+// userPrefs can be nil to use global defaults only.
+func (n *Notifier) SendCredentialsAlertWithPrefs(ctx context.Context, sourceID, sourceName, userEmail string, failures int, userPrefs *UserPreferences) {
+	alert := Alert{
+		Type:       AlertTypeCredentials,
+		SourceID:   sourceID,
+		SourceName: sourceName,
+		UserEmail:  userEmail,
+		Message:    fmt.Sprintf("Source '%s' paused: credentials rejected", sourceName),
+		Details:    fmt.Sprintf("Authentication failed %d times in a row. Scheduled syncs are paused until you update the credentials for this source.", failures),
+		Timestamp:  time.Now(),
+	}
+
+	go n.sendWithPrefs(ctx, alert, userPrefs)
+}
+
+// SendDriftAlertWithPrefs tells the owner that a drift check found more mismatches between
+// a source and its destination than the threshold allows. Not subject to cooldown: the
+// scheduler only alerts when a source crosses the threshold.
+// userPrefs can be nil to use global defaults only.
+func (n *Notifier) SendDriftAlertWithPrefs(ctx context.Context, sourceID, sourceName, userEmail string, mismatches, threshold int, userPrefs *UserPreferences) {
+	alert := Alert{
+		Type:       AlertTypeDrift,
+		SourceID:   sourceID,
+		SourceName: sourceName,
+		UserEmail:  userEmail,
+		Message:    fmt.Sprintf("Source '%s' has drifted from its destination", sourceName),
+		Details:    fmt.Sprintf("A drift check found %d mismatched events (threshold: %d). The drift report lists the affected calendars and events.", mismatches, threshold),
+		Timestamp:  time.Now(),
+	}
+
+	go n.sendWithPrefs(ctx, alert, userPrefs)
+}
+
 // getCooldownPeriod returns the effective cooldown period, considering user preferences.
 func (n *Notifier) getCooldownPeriod(userPrefs *UserPreferences) time.Duration {
 	if userPrefs != nil && userPrefs.CooldownMinutes != nil {
@@ -627,6 +680,10 @@ func (n *Notifier) sendWebhookToURL(ctx context.Context, alert Alert, webhookURL
 		emoji = ":white_check_mark:"
 	case AlertTypeError:
 		emoji = ":x:"
+	case AlertTypeCredentials:
+		emoji = ":lock:"
+	case AlertTypeDrift:
+		emoji = ":mag:"
 	}
 
 	payload := WebhookPayload{
@@ -1,8 +1,16 @@
 package notify
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/macjediwizard/calbridgesync/internal/validator"
 )
 
 func TestValidateConfig(t *testing.T) {
@@ -263,6 +271,34 @@ func TestValidateWebhookURL(t *testing.T) {
 	}
 }
 
+func TestWebhookAddressPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Run("blocks private addresses", func(t *testing.T) {
+		policy, _ := validator.NewAddressPolicy(nil)
+		n := New(&Config{AddressPolicy: policy, CooldownPeriod: time.Hour})
+
+		_, err := n.httpClient.Post(server.URL, "application/json", nil)
+		if !errors.Is(err, validator.ErrPrivateIP) {
+			t.Errorf("expected ErrPrivateIP, got %v", err)
+		}
+	})
+
+	t.Run("allows allowlisted addresses", func(t *testing.T) {
+		policy, _ := validator.NewAddressPolicy([]string{"127.0.0.1"})
+		n := New(&Config{AddressPolicy: policy, CooldownPeriod: time.Hour})
+
+		resp, err := n.httpClient.Post(server.URL, "application/json", nil)
+		if err != nil {
+			t.Fatalf("expected request to succeed, got %v", err)
+		}
+		resp.Body.Close()
+	})
+}
+
 func TestNotifierCooldown(t *testing.T) {
 	cfg := &Config{
 		WebhookEnabled: false,
@@ -354,3 +390,28 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestSendDriftAlert(t *testing.T) {
+	payloads := make(chan WebhookPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	n := New(&Config{WebhookEnabled: true, WebhookURL: server.URL, CooldownPeriod: time.Hour})
+	n.SendDriftAlertWithPrefs(context.Background(), "source1", "Work", "user@example.com", 12, 10, nil)
+
+	select {
+	case payload := <-payloads:
+		if payload.AlertType != string(AlertTypeDrift) || payload.SourceID != "source1" {
+			t.Errorf("unexpected payload: %+v", payload)
+		}
+		if !strings.Contains(payload.Details, "12 mismatched events (threshold: 10)") {
+			t.Errorf("unexpected details: %q", payload.Details)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a webhook request")
+	}
+}
@@ -2,7 +2,10 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"math"
+	"sort"
 	"sync"
 	"time"
 
@@ -18,8 +21,30 @@ const (
 	healthLogInterval   = 5 * time.Minute   // Interval for scheduler health logging
 	staleMultiplier     = 2                 // Source is stale if last sync > staleMultiplier * interval
 	startupStagger      = 30 * time.Second  // Delay between starting each source's first sync
+
+	defaultRetryMaxAttempts = 3               // Retries after a failed scheduled sync
+	defaultRetryBaseDelay   = 1 * time.Minute // Delay before the first retry, doubled per attempt
+
+	defaultAuthFailureThreshold = 3 // Consecutive auth failures before a source is paused
+
+	driftCheckTick             = 1 * time.Minute  // How often sources are checked for a due drift check
+	driftTimeout               = 30 * time.Minute // Maximum time for a single drift check
+	defaultDriftAlertThreshold = 10               // Mismatches a drift check may find before alerting
 )
 
+// RetryPolicy controls how failed scheduled syncs are retried before the next regular tick.
+type RetryPolicy struct {
+	MaxAttempts int           // Retries per failure episode (0 disables retries)
+	BaseDelay   time.Duration // Delay before the first retry; doubles with each attempt
+}
+
+// RetryState describes the retry status of a source's job.
+type RetryState struct {
+	Attempt     int       // Retries made since the last failed scheduled sync
+	MaxAttempts int       // Configured maximum retries
+	RetryAt     time.Time // When the next retry runs; zero if none is pending
+}
+
 // Job represents a scheduled sync job.
 type Job struct {
 	sourceID   string
@@ -27,6 +52,14 @@ type Job struct {
 	ticker     *time.Ticker
 	stopCh     chan struct{}
 	nextSyncAt time.Time
+
+	retryAttempt int       // Retries made in the current failure episode
+	retryAt      time.Time // Pending retry time; zero if none
+}
+
+// runningSync holds the cancel function of a sync in progress.
+type runningSync struct {
+	cancel context.CancelCauseFunc
 }
 
 // Scheduler manages background sync jobs.
@@ -37,11 +70,16 @@ type Scheduler struct {
 
 	mu        sync.RWMutex
 	jobs      map[string]*Job
-	syncLocks map[string]*sync.Mutex // Per-source locks to prevent concurrent syncs
+	syncLocks map[string]*sync.Mutex  // Per-source locks to prevent concurrent syncs
+	running   map[string]*runningSync // Running syncs by source, for cancellation
 	wg        sync.WaitGroup
 	ctx       context.Context
 	cancel    context.CancelFunc
 	started   bool
+
+	retryPolicy          RetryPolicy
+	authFailureThreshold int // 0 disables the circuit breaker
+	driftAlertThreshold  int // Alert when a drift check finds more mismatches
 }
 
 // New creates a new scheduler.
@@ -53,9 +91,55 @@ func New(database *db.DB, syncEngine *caldav.SyncEngine, notifier *notify.Notifi
 		notifier:   notifier,
 		jobs:       make(map[string]*Job),
 		syncLocks:  make(map[string]*sync.Mutex),
+		running:    make(map[string]*runningSync),
 		ctx:        ctx,
 		cancel:     cancel,
+		retryPolicy: RetryPolicy{
+			MaxAttempts: defaultRetryMaxAttempts,
+			BaseDelay:   defaultRetryBaseDelay,
+		},
+		authFailureThreshold: defaultAuthFailureThreshold,
+		driftAlertThreshold:  defaultDriftAlertThreshold,
+	}
+}
+
+// SetAuthFailureThreshold sets how many consecutive authentication failures pause
+// a source with the credentials_invalid status. Zero disables the circuit breaker.
+func (s *Scheduler) SetAuthFailureThreshold(threshold int) {
+	if threshold < 0 {
+		threshold = 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authFailureThreshold = threshold
+}
+
+// SetDriftAlertThreshold sets how many mismatches a drift check may find before the
+// owner of the source is alerted.
+func (s *Scheduler) SetDriftAlertThreshold(threshold int) {
+	if threshold < 0 {
+		threshold = 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.driftAlertThreshold = threshold
+}
+
+// SetRetryPolicy configures retries for failed scheduled syncs.
+// A non-positive base delay keeps the default.
+func (s *Scheduler) SetRetryPolicy(policy RetryPolicy) {
+	if policy.MaxAttempts < 0 {
+		policy.MaxAttempts = 0
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = defaultRetryBaseDelay
 	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retryPolicy = policy
 }
 
 // Start loads all enabled sources and starts their sync jobs.
@@ -68,6 +152,18 @@ func (s *Scheduler) Start() error {
 	s.started = true
 	s.mu.Unlock()
 
+	// Reconcile journals of sync runs that were interrupted by a crash
+	recovered := make(map[string]bool)
+	if s.syncEngine != nil {
+		recoveries, err := s.syncEngine.RecoverJournals()
+		if err != nil {
+			log.Printf("Warning: failed to recover sync journals: %v", err)
+		}
+		for _, r := range recoveries {
+			recovered[r.SourceID] = true
+		}
+	}
+
 	// Reset any "running" statuses from previous interrupted runs
 	if count, err := s.db.ResetRunningSyncStatuses(); err != nil {
 		log.Printf("Warning: failed to reset running sync statuses: %v", err)
@@ -81,6 +177,11 @@ func (s *Scheduler) Start() error {
 		return err
 	}
 
+	// Sources with an interrupted run go first so their pending writes are re-applied promptly
+	sort.SliceStable(sources, func(i, j int) bool {
+		return recovered[sources[i].ID] && !recovered[sources[j].ID]
+	})
+
 	// Start jobs with staggered initial sync to avoid resource contention
 	for i, source := range sources {
 		interval := time.Duration(source.SyncInterval) * time.Second
@@ -100,6 +201,10 @@ func (s *Scheduler) Start() error {
 	s.wg.Add(1)
 	go s.staleDetectionRoutine()
 
+	// Start drift check goroutine
+	s.wg.Add(1)
+	go s.driftRoutine()
+
 	log.Printf("Scheduler started with %d jobs", len(sources))
 	return nil
 }
@@ -204,6 +309,9 @@ func (s *Scheduler) RemoveJob(sourceID string) {
 		log.Printf("Removed sync job for source %s", sourceID)
 	}
 
+	// Stop a sync that is still running for the removed or disabled source
+	s.cancelSyncLocked(sourceID)
+
 	// Clear stale state in notifier if configured
 	if s.notifier != nil {
 		s.notifier.ClearStaleState(sourceID)
@@ -250,7 +358,7 @@ func (s *Scheduler) TriggerSync(sourceID string) {
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		s.executeSync(sourceID)
+		s.executeSync(sourceID, false)
 	}()
 }
 
@@ -266,20 +374,9 @@ func (s *Scheduler) runJob(job *Job) {
 	defer s.wg.Done()
 
 	// Run immediately on start
-	s.executeSync(job.sourceID)
-	s.updateNextSyncAt(job.sourceID)
+	retryIn := s.runScheduledSync(job, false)
 
-	for {
-		select {
-		case <-s.ctx.Done():
-			return
-		case <-job.stopCh:
-			return
-		case <-job.ticker.C:
-			s.executeSync(job.sourceID)
-			s.updateNextSyncAt(job.sourceID)
-		}
-	}
+	s.jobLoop(job, retryIn)
 }
 
 // runJobFromTicker runs the sync job loop, starting from the next ticker tick.
@@ -287,17 +384,7 @@ func (s *Scheduler) runJob(job *Job) {
 func (s *Scheduler) runJobFromTicker(job *Job) {
 	defer s.wg.Done()
 
-	for {
-		select {
-		case <-s.ctx.Done():
-			return
-		case <-job.stopCh:
-			return
-		case <-job.ticker.C:
-			s.executeSync(job.sourceID)
-			s.updateNextSyncAt(job.sourceID)
-		}
-	}
+	s.jobLoop(job, 0)
 }
 
 // runJobWithDelay runs the sync job loop with an initial delay.
@@ -317,10 +404,30 @@ func (s *Scheduler) runJobWithDelay(job *Job, initialDelay time.Duration) {
 	}
 
 	// Run first sync
-	s.executeSync(job.sourceID)
-	s.updateNextSyncAt(job.sourceID)
+	retryIn := s.runScheduledSync(job, false)
 
 	// Continue with regular interval
+	s.jobLoop(job, retryIn)
+}
+
+// jobLoop runs syncs on every ticker tick and on pending retries until the job is stopped.
+// retryIn schedules an initial retry when positive.
+func (s *Scheduler) jobLoop(job *Job, retryIn time.Duration) {
+	var retryTimer *time.Timer
+	var retryC <-chan time.Time
+	scheduleRetry := func(delay time.Duration) {
+		if retryTimer != nil {
+			retryTimer.Stop()
+		}
+		retryTimer, retryC = nil, nil
+		if delay > 0 {
+			retryTimer = time.NewTimer(delay)
+			retryC = retryTimer.C
+		}
+	}
+	defer scheduleRetry(0)
+
+	scheduleRetry(retryIn)
 	for {
 		select {
 		case <-s.ctx.Done():
@@ -328,20 +435,87 @@ func (s *Scheduler) runJobWithDelay(job *Job, initialDelay time.Duration) {
 		case <-job.stopCh:
 			return
 		case <-job.ticker.C:
-			s.executeSync(job.sourceID)
-			s.updateNextSyncAt(job.sourceID)
+			scheduleRetry(s.runScheduledSync(job, false))
+		case <-retryC:
+			scheduleRetry(s.runScheduledSync(job, true))
 		}
 	}
 }
 
-// updateNextSyncAt updates the next sync time for a job after execution.
-func (s *Scheduler) updateNextSyncAt(sourceID string) {
+// runScheduledSync executes a scheduled sync (or retry) for the job and returns
+// the delay until the next retry, or zero if the job should wait for its next tick.
+func (s *Scheduler) runScheduledSync(job *Job, isRetry bool) time.Duration {
+	result := s.executeSync(job.sourceID, true)
+
+	// A retry shifts the schedule: the regular interval restarts from this run
+	if isRetry {
+		select {
+		case <-job.stopCh:
+		default:
+			job.ticker.Reset(job.interval)
+		}
+	}
+
+	return s.recordSyncOutcome(job, result, isRetry)
+}
+
+// recordSyncOutcome updates the job's next sync time and retry state after a run.
+// result is nil when the sync was skipped. Returns the delay until the next retry, or zero.
+func (s *Scheduler) recordSyncOutcome(job *Job, result *caldav.SyncResult, isRetry bool) time.Duration {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if job, exists := s.jobs[sourceID]; exists {
-		job.nextSyncAt = time.Now().Add(job.interval)
+	now := time.Now()
+	resetRetry := func() time.Duration {
+		job.retryAttempt = 0
+		job.retryAt = time.Time{}
+		job.nextSyncAt = now.Add(job.interval)
+		return 0
+	}
+
+	// Successful (or skipped) runs, cancellations and shutdowns restore the normal interval.
+	// Rejected credentials won't fix themselves, so those aren't retried either.
+	if result == nil || result.Success || result.Cancelled || s.ctx.Err() != nil || result.FailureKind == caldav.FailureAuth {
+		return resetRetry()
+	}
+
+	// A failed regular run starts a new failure episode
+	if !isRetry {
+		job.retryAttempt = 0
+	}
+
+	if job.retryAttempt >= s.retryPolicy.MaxAttempts {
+		if s.retryPolicy.MaxAttempts > 0 {
+			log.Printf("Sync for source %s still failing after %d retries, waiting for next interval", job.sourceID, job.retryAttempt)
+		}
+		job.retryAt = time.Time{}
+		job.nextSyncAt = now.Add(job.interval)
+		return 0
+	}
+
+	delay := retryDelay(s.retryPolicy.BaseDelay, job.retryAttempt)
+	if delay >= job.interval {
+		// The regular tick comes first - no point scheduling a retry after it
+		job.retryAt = time.Time{}
+		job.nextSyncAt = now.Add(job.interval)
+		return 0
 	}
+
+	job.retryAttempt++
+	job.retryAt = now.Add(delay)
+	job.nextSyncAt = job.retryAt
+	log.Printf("Sync failed for source %s, retry %d/%d in %v", job.sourceID, job.retryAttempt, s.retryPolicy.MaxAttempts, delay)
+	return delay
+}
+
+// retryDelay returns the exponential backoff delay for the given retry attempt (0-based).
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	delay := base << attempt
+	if delay <= 0 || delay>>attempt != base {
+		// Overflow - effectively "never"; the regular interval takes over
+		return time.Duration(math.MaxInt64)
+	}
+	return delay
 }
 
 // getSyncLock returns the mutex for a source, creating one if needed.
@@ -358,15 +532,17 @@ func (s *Scheduler) getSyncLock(sourceID string) *sync.Mutex {
 	return lock
 }
 
-// executeSync runs the sync for a source.
-func (s *Scheduler) executeSync(sourceID string) {
+// executeSync runs the sync for a source. scheduled is false for manually triggered syncs,
+// which also run for sources paused by the auth circuit breaker.
+// Returns nil if the sync was skipped (already running, source missing, disabled or paused).
+func (s *Scheduler) executeSync(sourceID string, scheduled bool) *caldav.SyncResult {
 	// Get per-source lock to prevent concurrent syncs
 	lock := s.getSyncLock(sourceID)
 
 	// Try to acquire lock without blocking - skip if another sync is in progress
 	if !lock.TryLock() {
 		log.Printf("Skipping sync for source %s - another sync is already in progress", sourceID)
-		return
+		return nil
 	}
 	defer lock.Unlock()
 
@@ -374,24 +550,46 @@ func (s *Scheduler) executeSync(sourceID string) {
 	source, err := s.db.GetSourceByID(sourceID)
 	if err != nil {
 		log.Printf("Failed to get source %s: %v", sourceID, err)
-		return
+		return nil
 	}
 
 	// Skip if disabled
 	if !source.Enabled {
-		return
+		return nil
+	}
+
+	// Skip scheduled syncs while the circuit breaker has the source paused
+	if scheduled && source.LastSyncStatus == db.SyncStatusCredentialsInvalid {
+		log.Printf("Skipping sync for source %s - paused until credentials are updated", source.Name)
+		return nil
 	}
 
 	log.Printf("Starting sync for source %s (%s)", source.Name, sourceID)
 
-	// Create a timeout context for this sync operation
-	ctx, cancel := context.WithTimeout(s.ctx, syncTimeout)
-	defer cancel()
+	// Create a timeout context for this sync operation that can also be cancelled on request
+	timeoutCtx, cancelTimeout := context.WithTimeout(s.ctx, syncTimeout)
+	defer cancelTimeout()
+	ctx, cancel := context.WithCancelCause(timeoutCtx)
+	defer cancel(nil)
+
+	run := &runningSync{cancel: cancel}
+	s.mu.Lock()
+	s.running[sourceID] = run
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if s.running[sourceID] == run {
+			delete(s.running, sourceID)
+		}
+		s.mu.Unlock()
+	}()
 
 	// Execute sync with timeout context
 	result := s.syncEngine.SyncSource(ctx, source)
 
-	if result.Success {
+	if result.Cancelled {
+		log.Printf("Sync cancelled for source %s: %s", source.Name, result.Message)
+	} else if result.Success {
 		log.Printf("Sync completed for source %s: %d created, %d updated, %d deleted, %d duplicates removed in %v",
 			source.Name, result.Created, result.Updated, result.Deleted, result.DuplicatesRemoved, result.Duration)
 
@@ -410,6 +608,82 @@ func (s *Scheduler) executeSync(sourceID string) {
 	} else {
 		log.Printf("Sync failed for source %s: %s", source.Name, result.Message)
 	}
+
+	s.trackAuthFailures(source, result)
+
+	return result
+}
+
+// CancelSync stops the running sync of a source. The sync engine finishes the
+// writes in flight, records the run as cancelled and starts no new work.
+// Returns false if no sync is running for the source.
+func (s *Scheduler) CancelSync(sourceID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cancelSyncLocked(sourceID)
+}
+
+// cancelSyncLocked cancels the running sync of a source. Caller must hold s.mu.
+func (s *Scheduler) cancelSyncLocked(sourceID string) bool {
+	run, exists := s.running[sourceID]
+	if !exists {
+		return false
+	}
+	run.cancel(caldav.ErrSyncCancelled)
+	delete(s.running, sourceID)
+	log.Printf("Cancelling running sync for source %s", sourceID)
+	return true
+}
+
+// trackAuthFailures counts consecutive authentication failures for a source and
+// trips the circuit breaker once the threshold is reached, or at once if the account
+// must be reconnected: the source is marked credentials_invalid (pausing scheduled
+// syncs) and the owner is alerted.
+func (s *Scheduler) trackAuthFailures(source *db.Source, result *caldav.SyncResult) {
+	if result.FailureKind != caldav.FailureAuth {
+		if source.AuthFailures > 0 {
+			if err := s.db.ResetAuthFailures(source.ID); err != nil {
+				log.Printf("Failed to reset auth failures for source %s: %v", source.ID, err)
+			}
+		}
+		return
+	}
+
+	failures, err := s.db.IncrementAuthFailures(source.ID)
+	if err != nil {
+		log.Printf("Failed to record auth failure for source %s: %v", source.ID, err)
+		return
+	}
+
+	s.mu.RLock()
+	threshold := s.authFailureThreshold
+	s.mu.RUnlock()
+
+	// A revoked OAuth2 grant won't recover by retrying, so pause right away
+	if !result.ReconnectRequired && (threshold == 0 || failures < threshold) {
+		log.Printf("Authentication failed for source %s (%d/%d)", source.Name, failures, threshold)
+		return
+	}
+
+	message := fmt.Sprintf("Paused after %d consecutive authentication failures - update credentials to resume", failures)
+	if result.ReconnectRequired {
+		message = "Paused because account access was revoked or has expired - reconnect the account to resume"
+	}
+	if err := s.db.UpdateSourceSyncStatus(source.ID, db.SyncStatusCredentialsInvalid, message); err != nil {
+		log.Printf("Failed to pause source %s: %v", source.ID, err)
+		return
+	}
+	log.Printf("[CIRCUIT BREAKER] Source '%s' (ID: %s) paused after %d consecutive authentication failures", source.Name, source.ID, failures)
+
+	// Alert only when the source transitions into the paused state
+	wasPaused := source.LastSyncStatus == db.SyncStatusCredentialsInvalid
+	if !wasPaused && s.notifier != nil && s.notifier.IsEnabled() {
+		userEmail := ""
+		if user, err := s.db.GetUserByID(source.UserID); err == nil {
+			userEmail = user.Email
+		}
+		s.notifier.SendCredentialsAlertWithPrefs(s.ctx, source.ID, source.Name, userEmail, failures, s.getUserAlertPrefs(source.UserID))
+	}
 }
 
 // cleanupRoutine runs periodic cleanup of old sync logs.
@@ -429,17 +703,29 @@ func (s *Scheduler) cleanupRoutine() {
 	}
 }
 
-// cleanupOldLogs deletes sync logs older than retention period.
+// cleanupOldLogs deletes sync logs, event changes and drift reports older than retention period.
 func (s *Scheduler) cleanupOldLogs() {
 	cutoff := time.Now().AddDate(0, 0, -logRetentionDays)
 	deleted, err := s.db.CleanOldSyncLogs(cutoff)
 	if err != nil {
 		log.Printf("Failed to clean old sync logs: %v", err)
-		return
-	}
-	if deleted > 0 {
+	} else if deleted > 0 {
 		log.Printf("Cleaned %d old sync logs", deleted)
 	}
+
+	deleted, err = s.db.CleanOldEventChanges(cutoff)
+	if err != nil {
+		log.Printf("Failed to clean old event changes: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Cleaned %d old event changes", deleted)
+	}
+
+	deleted, err = s.db.CleanOldDriftReports(cutoff)
+	if err != nil {
+		log.Printf("Failed to clean old drift reports: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Cleaned %d old drift reports", deleted)
+	}
 }
 
 // healthLogRoutine periodically logs scheduler health information.
@@ -543,6 +829,119 @@ func (s *Scheduler) checkStaleSources() {
 	}
 }
 
+// driftRoutine periodically runs the drift checks that are due.
+func (s *Scheduler) driftRoutine() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(driftCheckTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkDrift()
+		}
+	}
+}
+
+// checkDrift runs a drift check for every scheduled source whose drift check interval
+// has passed since its last report. Checks run one after another to limit the load
+// they put on the servers.
+func (s *Scheduler) checkDrift() {
+	s.mu.RLock()
+	sourceIDs := make([]string, 0, len(s.jobs))
+	for id := range s.jobs {
+		sourceIDs = append(sourceIDs, id)
+	}
+	s.mu.RUnlock()
+
+	for _, sourceID := range sourceIDs {
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		source, err := s.db.GetSourceByID(sourceID)
+		if err != nil || !source.Enabled || source.DriftCheckInterval <= 0 {
+			continue
+		}
+		interval := time.Duration(source.DriftCheckInterval) * time.Second
+		if latest, err := s.db.GetLatestDriftReport(sourceID); err == nil && time.Since(latest.CreatedAt) < interval {
+			continue
+		}
+
+		s.verifyDrift(source)
+	}
+}
+
+// verifyDrift compares a source with its destination, stores the drift report and alerts
+// the owner when the mismatches cross the threshold. Returns nil if the check was skipped
+// because a sync is running (which would show as drift) or the source is paused.
+// The source's sync lock is held until the report is saved, so no sync can start
+// writing to the destination while it is compared.
+func (s *Scheduler) verifyDrift(source *db.Source) *db.DriftReport {
+	if source.LastSyncStatus == db.SyncStatusCredentialsInvalid {
+		return nil
+	}
+
+	lock := s.getSyncLock(source.ID)
+	if !lock.TryLock() {
+		log.Printf("Skipping drift check for source %s - a sync is in progress", source.ID)
+		return nil
+	}
+
+	s.mu.RLock()
+	threshold := s.driftAlertThreshold
+	s.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(s.ctx, driftTimeout)
+	defer cancel()
+	report := s.syncEngine.VerifyDrift(ctx, source)
+
+	previous, _ := s.db.GetLatestDriftReport(source.ID) // nil before the first report
+	alert := applyDriftThreshold(report, previous, threshold)
+	if err := s.db.CreateDriftReport(report); err != nil {
+		log.Printf("Failed to save drift report for source %s: %v", source.ID, err)
+	}
+	lock.Unlock()
+
+	if report.Status == db.DriftStatusFailed {
+		log.Printf("Drift check failed for source %s: %s", source.Name, report.Message)
+	} else {
+		log.Printf("Drift check for source %s: %s in %v", source.Name, report.Message, report.Duration)
+	}
+
+	if alert {
+		log.Printf("[DRIFT WARNING] Source '%s' (ID: %s) has %d mismatched events (threshold: %d)",
+			source.Name, source.ID, report.Mismatches, threshold)
+		if s.notifier != nil && s.notifier.IsEnabled() {
+			userEmail := ""
+			if user, err := s.db.GetUserByID(source.UserID); err == nil {
+				userEmail = user.Email
+			}
+			s.notifier.SendDriftAlertWithPrefs(s.ctx, source.ID, source.Name, userEmail, report.Mismatches, threshold, s.getUserAlertPrefs(source.UserID))
+		}
+	}
+
+	return report
+}
+
+// applyDriftThreshold sets the threshold fields of a drift report and reports whether
+// the owner should be alerted: only when the source crosses the threshold, not on every
+// check that finds it over. A failed check keeps the state of the previous report.
+func applyDriftThreshold(report, previous *db.DriftReport, threshold int) bool {
+	wasOver := previous != nil && previous.OverThreshold
+
+	report.Threshold = threshold
+	if report.Status == db.DriftStatusFailed {
+		report.OverThreshold = wasOver
+		return false
+	}
+	report.OverThreshold = report.Mismatches > threshold
+	return report.OverThreshold && !wasOver
+}
+
 // GetNextSyncAt returns the next scheduled sync time for a source.
 // Returns zero time if job doesn't exist.
 func (s *Scheduler) GetNextSyncAt(sourceID string) time.Time {
@@ -555,6 +954,23 @@ func (s *Scheduler) GetNextSyncAt(sourceID string) time.Time {
 	return time.Time{}
 }
 
+// GetRetryState returns the retry state for a source's job.
+// Returns a zero state if the job doesn't exist.
+func (s *Scheduler) GetRetryState(sourceID string) RetryState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, exists := s.jobs[sourceID]
+	if !exists {
+		return RetryState{}
+	}
+	return RetryState{
+		Attempt:     job.retryAttempt,
+		MaxAttempts: s.retryPolicy.MaxAttempts,
+		RetryAt:     job.retryAt,
+	}
+}
+
 // IsSourceStale checks if a source is considered stale (hasn't synced in 2x interval).
 func (s *Scheduler) IsSourceStale(source *db.Source) bool {
 	if !source.Enabled {
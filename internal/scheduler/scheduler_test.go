@@ -1,9 +1,14 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/macjediwizard/calbridgesync/internal/caldav"
+	"github.com/macjediwizard/calbridgesync/internal/db"
 )
 
 func TestNew(t *testing.T) {
@@ -564,3 +569,276 @@ func TestContextCancellation(t *testing.T) {
 		sched.cancel()
 	})
 }
+
+func TestRetryDelay(t *testing.T) {
+	t.Run("doubles with each attempt", func(t *testing.T) {
+		expected := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute}
+		for attempt, want := range expected {
+			if got := retryDelay(time.Minute, attempt); got != want {
+				t.Errorf("attempt %d: expected %v, got %v", attempt, want, got)
+			}
+		}
+	})
+
+	t.Run("saturates instead of overflowing", func(t *testing.T) {
+		if got := retryDelay(time.Minute, 62); got <= 0 {
+			t.Errorf("expected positive delay, got %v", got)
+		}
+	})
+}
+
+func TestSetRetryPolicy(t *testing.T) {
+	t.Run("defaults are applied", func(t *testing.T) {
+		sched := New(nil, nil, nil)
+
+		if sched.retryPolicy.MaxAttempts != defaultRetryMaxAttempts {
+			t.Errorf("expected %d max attempts, got %d", defaultRetryMaxAttempts, sched.retryPolicy.MaxAttempts)
+		}
+		if sched.retryPolicy.BaseDelay != defaultRetryBaseDelay {
+			t.Errorf("expected %v base delay, got %v", defaultRetryBaseDelay, sched.retryPolicy.BaseDelay)
+		}
+	})
+
+	t.Run("invalid values are normalized", func(t *testing.T) {
+		sched := New(nil, nil, nil)
+		sched.SetRetryPolicy(RetryPolicy{MaxAttempts: -1, BaseDelay: 0})
+
+		if sched.retryPolicy.MaxAttempts != 0 {
+			t.Errorf("expected 0 max attempts, got %d", sched.retryPolicy.MaxAttempts)
+		}
+		if sched.retryPolicy.BaseDelay != defaultRetryBaseDelay {
+			t.Errorf("expected default base delay, got %v", sched.retryPolicy.BaseDelay)
+		}
+	})
+}
+
+func TestRecordSyncOutcome(t *testing.T) {
+	failed := &caldav.SyncResult{Success: false}
+	succeeded := &caldav.SyncResult{Success: true}
+
+	newRetryScheduler := func(maxAttempts int) (*Scheduler, *Job) {
+		sched := New(nil, nil, nil)
+		sched.SetRetryPolicy(RetryPolicy{MaxAttempts: maxAttempts, BaseDelay: time.Minute})
+		addJobDirectly(sched, "source-1", time.Hour)
+		return sched, sched.jobs["source-1"]
+	}
+
+	t.Run("schedules retries with exponential backoff", func(t *testing.T) {
+		sched, job := newRetryScheduler(3)
+
+		if delay := sched.recordSyncOutcome(job, failed, false); delay != time.Minute {
+			t.Errorf("expected first retry in 1m, got %v", delay)
+		}
+		if delay := sched.recordSyncOutcome(job, failed, true); delay != 2*time.Minute {
+			t.Errorf("expected second retry in 2m, got %v", delay)
+		}
+
+		state := sched.GetRetryState("source-1")
+		if state.Attempt != 2 || state.MaxAttempts != 3 {
+			t.Errorf("unexpected retry state: %+v", state)
+		}
+		if state.RetryAt.IsZero() {
+			t.Error("expected pending retry time")
+		}
+		if !sched.GetNextSyncAt("source-1").Equal(state.RetryAt) {
+			t.Error("expected next sync time to be the retry time")
+		}
+	})
+
+	t.Run("stops after max attempts", func(t *testing.T) {
+		sched, job := newRetryScheduler(1)
+
+		sched.recordSyncOutcome(job, failed, false)
+		if delay := sched.recordSyncOutcome(job, failed, true); delay != 0 {
+			t.Errorf("expected no further retry, got %v", delay)
+		}
+		if !sched.GetRetryState("source-1").RetryAt.IsZero() {
+			t.Error("expected no pending retry")
+		}
+	})
+
+	t.Run("success restores normal interval", func(t *testing.T) {
+		sched, job := newRetryScheduler(3)
+
+		sched.recordSyncOutcome(job, failed, false)
+		before := time.Now()
+		if delay := sched.recordSyncOutcome(job, succeeded, true); delay != 0 {
+			t.Errorf("expected no retry after success, got %v", delay)
+		}
+
+		state := sched.GetRetryState("source-1")
+		if state.Attempt != 0 || !state.RetryAt.IsZero() {
+			t.Errorf("expected retry state to be cleared, got %+v", state)
+		}
+		if next := sched.GetNextSyncAt("source-1"); next.Before(before.Add(time.Hour)) {
+			t.Errorf("expected next sync an interval from now, got %v", next)
+		}
+	})
+
+	t.Run("does not retry past the next regular tick", func(t *testing.T) {
+		sched := New(nil, nil, nil)
+		sched.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Minute})
+		addJobDirectly(sched, "source-1", 5*time.Minute)
+
+		if delay := sched.recordSyncOutcome(sched.jobs["source-1"], failed, false); delay != 0 {
+			t.Errorf("expected no retry, got %v", delay)
+		}
+	})
+
+	t.Run("authentication failures are not retried", func(t *testing.T) {
+		sched, job := newRetryScheduler(3)
+
+		authFailed := &caldav.SyncResult{Success: false, FailureKind: caldav.FailureAuth}
+		if delay := sched.recordSyncOutcome(job, authFailed, false); delay != 0 {
+			t.Errorf("expected no retry for rejected credentials, got %v", delay)
+		}
+	})
+
+	t.Run("skipped sync is not retried", func(t *testing.T) {
+		sched, job := newRetryScheduler(3)
+
+		if delay := sched.recordSyncOutcome(job, nil, false); delay != 0 {
+			t.Errorf("expected no retry for skipped sync, got %v", delay)
+		}
+	})
+
+	t.Run("no retries during shutdown", func(t *testing.T) {
+		sched, job := newRetryScheduler(3)
+		sched.cancel()
+
+		if delay := sched.recordSyncOutcome(job, failed, false); delay != 0 {
+			t.Errorf("expected no retry after shutdown, got %v", delay)
+		}
+	})
+}
+
+func TestSetAuthFailureThreshold(t *testing.T) {
+	sched := New(nil, nil, nil)
+	if sched.authFailureThreshold != defaultAuthFailureThreshold {
+		t.Errorf("expected default threshold %d, got %d", defaultAuthFailureThreshold, sched.authFailureThreshold)
+	}
+
+	sched.SetAuthFailureThreshold(5)
+	if sched.authFailureThreshold != 5 {
+		t.Errorf("expected threshold 5, got %d", sched.authFailureThreshold)
+	}
+
+	sched.SetAuthFailureThreshold(-1)
+	if sched.authFailureThreshold != 0 {
+		t.Errorf("expected negative threshold to disable the breaker, got %d", sched.authFailureThreshold)
+	}
+}
+
+func TestSetDriftAlertThreshold(t *testing.T) {
+	sched := New(nil, nil, nil)
+	if sched.driftAlertThreshold != defaultDriftAlertThreshold {
+		t.Errorf("expected default threshold %d, got %d", defaultDriftAlertThreshold, sched.driftAlertThreshold)
+	}
+
+	sched.SetDriftAlertThreshold(-1)
+	if sched.driftAlertThreshold != 0 {
+		t.Errorf("expected negative threshold to alert on any drift, got %d", sched.driftAlertThreshold)
+	}
+}
+
+func TestApplyDriftThreshold(t *testing.T) {
+	over := &db.DriftReport{Status: db.DriftStatusDrift, Mismatches: 11, OverThreshold: true}
+	under := &db.DriftReport{Status: db.DriftStatusDrift, Mismatches: 3}
+
+	tests := []struct {
+		name     string
+		report   db.DriftReport
+		previous *db.DriftReport
+		alert    bool
+		isOver   bool
+	}{
+		{"first report over threshold", db.DriftReport{Status: db.DriftStatusDrift, Mismatches: 11}, nil, true, true},
+		{"crosses the threshold", db.DriftReport{Status: db.DriftStatusDrift, Mismatches: 11}, under, true, true},
+		{"stays over the threshold", db.DriftReport{Status: db.DriftStatusDrift, Mismatches: 20}, over, false, true},
+		{"at the threshold", db.DriftReport{Status: db.DriftStatusDrift, Mismatches: 10}, nil, false, false},
+		{"recovers", db.DriftReport{Status: db.DriftStatusOK}, over, false, false},
+		{"failed check keeps the state", db.DriftReport{Status: db.DriftStatusFailed}, over, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := tt.report
+			if alert := applyDriftThreshold(&report, tt.previous, 10); alert != tt.alert {
+				t.Errorf("expected alert %v, got %v", tt.alert, alert)
+			}
+			if report.OverThreshold != tt.isOver || report.Threshold != 10 {
+				t.Errorf("unexpected threshold state: %+v", report)
+			}
+		})
+	}
+}
+
+func TestCancelSync(t *testing.T) {
+	// registerRunningSync simulates executeSync registering a sync in progress.
+	registerRunningSync := func(s *Scheduler, sourceID string) context.Context {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		s.mu.Lock()
+		s.running[sourceID] = &runningSync{cancel: cancel}
+		s.mu.Unlock()
+		return ctx
+	}
+
+	t.Run("cancels running sync with ErrSyncCancelled", func(t *testing.T) {
+		sched := New(nil, nil, nil)
+		ctx := registerRunningSync(sched, "source-1")
+
+		if !sched.CancelSync("source-1") {
+			t.Fatal("expected running sync to be cancelled")
+		}
+		if !errors.Is(context.Cause(ctx), caldav.ErrSyncCancelled) {
+			t.Errorf("expected cause ErrSyncCancelled, got %v", context.Cause(ctx))
+		}
+		if sched.CancelSync("source-1") {
+			t.Error("expected second cancel to report no running sync")
+		}
+	})
+
+	t.Run("returns false when nothing is running", func(t *testing.T) {
+		sched := New(nil, nil, nil)
+
+		if sched.CancelSync("source-1") {
+			t.Error("expected false for source without a running sync")
+		}
+	})
+
+	t.Run("removing a job cancels its sync", func(t *testing.T) {
+		sched := New(nil, nil, nil)
+		addJobDirectly(sched, "source-1", time.Hour)
+		ctx := registerRunningSync(sched, "source-1")
+
+		sched.RemoveJob("source-1")
+
+		if !errors.Is(context.Cause(ctx), caldav.ErrSyncCancelled) {
+			t.Errorf("expected sync to be cancelled on removal, got %v", context.Cause(ctx))
+		}
+	})
+
+	t.Run("cancelled sync is not retried", func(t *testing.T) {
+		sched := New(nil, nil, nil)
+		addJobDirectly(sched, "source-1", time.Hour)
+
+		cancelled := &caldav.SyncResult{Success: false, Cancelled: true}
+		if delay := sched.recordSyncOutcome(sched.jobs["source-1"], cancelled, false); delay != 0 {
+			t.Errorf("expected no retry after cancellation, got %v", delay)
+		}
+	})
+}
+
+func TestVerifyDriftSkipsRunningSync(t *testing.T) {
+	sched := New(nil, nil, nil)
+	source := &db.Source{ID: "source-1", Name: "Work"}
+
+	lock := sched.getSyncLock(source.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Neither the engine nor the database is touched while a sync holds the lock
+	if report := sched.verifyDrift(source); report != nil {
+		t.Errorf("expected the check to be skipped during a sync, got %+v", report)
+	}
+}
@@ -0,0 +1,151 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAllowlist is returned when an allowlist entry is neither a CIDR, an IP nor a hostname.
+var ErrInvalidAllowlist = errors.New("invalid allowlist entry")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which some cloud
+// providers use for metadata services.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// AddressPolicy decides which addresses outbound connections to user-supplied URLs
+// may reach. Private, loopback and link-local addresses are blocked unless they are
+// in an allowed network or the hostname itself is allowed.
+type AddressPolicy struct {
+	allowedNets  []*net.IPNet
+	allowedHosts []string // Lower-case; entries starting with "." match subdomains
+	resolver     *net.Resolver
+}
+
+// NewAddressPolicy creates a policy from allowlist entries. Each entry is a CIDR
+// ("10.0.0.0/8"), an IP ("192.168.1.10"), a hostname ("caldav.lan") or a domain
+// suffix (".lan", matching any subdomain).
+func NewAddressPolicy(allowlist []string) (*AddressPolicy, error) {
+	p := &AddressPolicy{resolver: net.DefaultResolver}
+
+	for _, entry := range allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			p.allowedNets = append(p.allowedNets, ipNet)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			p.allowedNets = append(p.allowedNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		if !isHostname(strings.TrimPrefix(entry, ".")) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAllowlist, entry)
+		}
+		p.allowedHosts = append(p.allowedHosts, entry)
+	}
+
+	return p, nil
+}
+
+// isHostname reports whether s looks like a DNS name.
+func isHostname(s string) bool {
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hostAllowed reports whether a hostname is on the allowlist.
+func (p *AddressPolicy) hostAllowed(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, allowed := range p.allowedHosts {
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsIP reports whether connections to ip are permitted.
+func (p *AddressPolicy) AllowsIP(ip net.IP) bool {
+	for _, ipNet := range p.allowedNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return !isPrivateIP(ip) && !sharedAddressSpace.Contains(ip)
+}
+
+// DialContext resolves the host, checks every address against the policy and then
+// connects to a checked address directly, so a DNS answer that changes between the
+// check and the connection (DNS rebinding) can't reach a blocked address.
+// Allowlisted hostnames are dialed as is.
+func (p *AddressPolicy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   defaultTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	if p.hostAllowed(host) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := p.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("DNS resolution failed: %w", err)
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("DNS resolution failed: no addresses for %s", host)
+	}
+
+	// Refuse the host if any of its addresses is blocked, rather than picking an
+	// allowed one, so a mixed answer doesn't hide an internal target
+	for _, ip := range ips {
+		if !p.AllowsIP(ip) {
+			return nil, fmt.Errorf("%w: %s resolves to %s", ErrPrivateIP, host, ip)
+		}
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
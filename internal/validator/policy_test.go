@@ -0,0 +1,121 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestNewAddressPolicy(t *testing.T) {
+	t.Run("parses CIDRs, IPs and hostnames", func(t *testing.T) {
+		p, err := NewAddressPolicy([]string{"10.0.0.0/8", " 192.168.1.10 ", "fd00::/8", "CalDAV.Home.Lan", ".internal", ""})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(p.allowedNets) != 3 {
+			t.Errorf("expected 3 networks, got %d", len(p.allowedNets))
+		}
+		if len(p.allowedHosts) != 2 || p.allowedHosts[0] != "caldav.home.lan" {
+			t.Errorf("expected lower-cased hosts, got %v", p.allowedHosts)
+		}
+	})
+
+	for _, entry := range []string{"10.0.0.0/33", "http://host", "host name", "-bad.example"} {
+		t.Run("rejects "+entry, func(t *testing.T) {
+			if _, err := NewAddressPolicy([]string{entry}); !errors.Is(err, ErrInvalidAllowlist) {
+				t.Errorf("expected ErrInvalidAllowlist, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAddressPolicyAllowsIP(t *testing.T) {
+	p, _ := NewAddressPolicy([]string{"192.168.1.0/24", "10.0.0.5"})
+
+	testCases := []struct {
+		ip       string
+		expected bool
+	}{
+		{"93.184.216.34", true},
+		{"192.168.1.20", true},
+		{"10.0.0.5", true},
+		{"10.0.0.6", false},
+		{"192.168.2.1", false},
+		{"127.0.0.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.ip, func(t *testing.T) {
+			if got := p.AllowsIP(net.ParseIP(tc.ip)); got != tc.expected {
+				t.Errorf("AllowsIP(%s) = %v, expected %v", tc.ip, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAddressPolicyDialContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	_, port, _ := net.SplitHostPort(serverURL.Host)
+
+	dial := func(p *AddressPolicy, host string) error {
+		conn, err := p.DialContext(context.Background(), "tcp", net.JoinHostPort(host, port))
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	t.Run("blocks loopback addresses by default", func(t *testing.T) {
+		p, _ := NewAddressPolicy(nil)
+		if err := dial(p, "127.0.0.1"); !errors.Is(err, ErrPrivateIP) {
+			t.Errorf("expected ErrPrivateIP, got %v", err)
+		}
+	})
+
+	t.Run("blocks hostnames resolving to loopback", func(t *testing.T) {
+		p, _ := NewAddressPolicy(nil)
+		if err := dial(p, "localhost"); !errors.Is(err, ErrPrivateIP) {
+			t.Errorf("expected ErrPrivateIP, got %v", err)
+		}
+	})
+
+	t.Run("allows allowlisted networks", func(t *testing.T) {
+		p, _ := NewAddressPolicy([]string{"127.0.0.0/8"})
+		if err := dial(p, "127.0.0.1"); err != nil {
+			t.Errorf("expected connection to succeed, got %v", err)
+		}
+	})
+
+	t.Run("allows allowlisted hostnames", func(t *testing.T) {
+		p, _ := NewAddressPolicy([]string{"localhost"})
+		if err := dial(p, "localhost"); err != nil {
+			t.Errorf("expected connection to succeed, got %v", err)
+		}
+	})
+
+	t.Run("applies to validator connections", func(t *testing.T) {
+		v := New()
+		if err := v.TestConnection(context.Background(), server.URL); !errors.Is(err, ErrPrivateIP) {
+			t.Errorf("expected ErrPrivateIP, got %v", err)
+		}
+
+		p, _ := NewAddressPolicy([]string{"127.0.0.1"})
+		v = New(WithAddressPolicy(p))
+		if err := v.TestConnection(context.Background(), server.URL); err != nil {
+			t.Errorf("expected allowlisted connection to succeed, got %v", err)
+		}
+	})
+}
@@ -32,23 +32,34 @@ const (
 type Validator struct {
 	client          *http.Client
 	allowPrivateIPs bool
+	policy          *AddressPolicy
 }
 
 // Option configures a Validator.
 type Option func(*Validator)
 
-// WithAllowPrivateIPs allows connections to private IP addresses.
-// This is useful for Docker internal networking.
+// WithAllowPrivateIPs allows connections to private IP addresses on the Docker host
+// port (20000), for Docker internal networking. Other private addresses must be on
+// the address policy's allowlist.
 func WithAllowPrivateIPs() Option {
 	return func(v *Validator) {
 		v.allowPrivateIPs = true
 	}
 }
 
+// WithAddressPolicy sets the policy used to check the addresses the validator
+// connects to, e.g. to allow internal hosts from the admin allowlist.
+func WithAddressPolicy(p *AddressPolicy) Option {
+	return func(v *Validator) {
+		v.policy = p
+	}
+}
+
 // New creates a new Validator with the given options.
 func New(opts ...Option) *Validator {
 	v := &Validator{
 		allowPrivateIPs: false,
+		policy:          &AddressPolicy{resolver: net.DefaultResolver},
 	}
 
 	for _, opt := range opts {
@@ -89,14 +100,19 @@ func (v *Validator) createHTTPClient() *http.Client {
 	}
 }
 
+// dockerHostPort is the port exempt from the address policy when private IPs are
+// allowed, for Docker host networking.
+const dockerHostPort = "20000"
+
 func (v *Validator) dialWithIPCheck(ctx context.Context, network, addr string) (net.Conn, error) {
-	host, port, err := net.SplitHostPort(addr)
+	_, port, err := net.SplitHostPort(addr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid address: %w", err)
 	}
 
-	// Allow Docker host networking (typically port 20000)
-	if v.allowPrivateIPs && port == "20000" {
+	// Allow Docker host networking (typically port 20000); everything else,
+	// including other private addresses, goes through the policy
+	if v.allowPrivateIPs && port == dockerHostPort {
 		dialer := &net.Dialer{
 			Timeout:   defaultTimeout,
 			KeepAlive: 30 * time.Second,
@@ -104,23 +120,7 @@ func (v *Validator) dialWithIPCheck(ctx context.Context, network, addr string) (
 		return dialer.DialContext(ctx, network, addr)
 	}
 
-	// Resolve the hostname to check IP addresses
-	ips, err := net.LookupIP(host)
-	if err != nil {
-		return nil, fmt.Errorf("DNS resolution failed: %w", err)
-	}
-
-	for _, ip := range ips {
-		if !v.allowPrivateIPs && isPrivateIP(ip) {
-			return nil, ErrPrivateIP
-		}
-	}
-
-	dialer := &net.Dialer{
-		Timeout:   defaultTimeout,
-		KeepAlive: 30 * time.Second,
-	}
-	return dialer.DialContext(ctx, network, addr)
+	return v.policy.DialContext(ctx, network, addr)
 }
 
 // isPrivateIP checks if an IP address is private or reserved.
@@ -141,6 +141,23 @@ func TestWithAllowPrivateIPs(t *testing.T) {
 			t.Error("expected allowPrivateIPs to be true")
 		}
 	})
+
+	t.Run("only the Docker host port is exempt", func(t *testing.T) {
+		v := New(WithAllowPrivateIPs())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		if _, err := v.dialWithIPCheck(ctx, "tcp", "127.0.0.1:8080"); !errors.Is(err, ErrPrivateIP) {
+			t.Errorf("expected ErrPrivateIP for another port, got %v", err)
+		}
+		conn, err := v.dialWithIPCheck(ctx, "tcp", "127.0.0.1:"+dockerHostPort)
+		if errors.Is(err, ErrPrivateIP) {
+			t.Errorf("expected the Docker host port to be exempt, got %v", err)
+		}
+		if conn != nil {
+			conn.Close()
+		}
+	})
 }
 
 func TestNewValidator(t *testing.T) {
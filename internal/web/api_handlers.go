@@ -2,6 +2,8 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -14,6 +16,7 @@ import (
 	"github.com/macjediwizard/calbridgesync/internal/caldav"
 	"github.com/macjediwizard/calbridgesync/internal/db"
 	"github.com/macjediwizard/calbridgesync/internal/notify"
+	"github.com/macjediwizard/calbridgesync/internal/validator"
 )
 
 // sanitizeError returns a user-safe error message without exposing internal details.
@@ -35,6 +38,8 @@ func categorizeConnectionError(err error) string {
 
 	// Categorize without exposing internal details
 	switch {
+	case errors.Is(err, validator.ErrPrivateIP) || strings.Contains(errStr, validator.ErrPrivateIP.Error()):
+		return "Connections to private or internal addresses are not allowed. Ask your administrator to allowlist this server."
 	case strings.Contains(errStr, "no such host") || strings.Contains(errStr, "lookup"):
 		return "Server not found. Please check the URL."
 	case strings.Contains(errStr, "connection refused"):
@@ -60,8 +65,56 @@ const (
 	maxURLLength      = 500
 	maxUsernameLength = 100
 	maxPasswordLength = 500
+	maxTokenLength    = 8192
+	maxConcurrency    = 32
+
+	minDriftCheckInterval = 3600           // 1 hour
+	maxDriftCheckInterval = 30 * 24 * 3600 // 30 days
 )
 
+// validateConcurrency validates per-source concurrency settings.
+// Zero means "keep the current value" (or the default of 1 on create).
+func validateConcurrency(calendarConcurrency, eventConcurrency int) string {
+	if calendarConcurrency < 0 || calendarConcurrency > maxConcurrency {
+		return fmt.Sprintf("Calendar concurrency must be between 1 and %d", maxConcurrency)
+	}
+	if eventConcurrency < 0 || eventConcurrency > maxConcurrency {
+		return fmt.Sprintf("Event concurrency must be between 1 and %d", maxConcurrency)
+	}
+	return ""
+}
+
+// validateDedupe validates the dedupe settings of a source. Empty values keep the
+// current settings (or the defaults on create).
+func validateDedupe(policy string, fields []string) string {
+	if policy != "" && !db.DedupePolicy(policy).IsValid() {
+		return "Dedupe policy must be off, report or auto"
+	}
+	for _, field := range fields {
+		if !db.DedupeField(field).IsValid() {
+			return "Dedupe fields must be summary, dtstart, dtend, location or rrule"
+		}
+	}
+	return ""
+}
+
+// validateDriftCheckInterval validates the drift check interval of a source (0 disables checks).
+func validateDriftCheckInterval(interval int) string {
+	if interval != 0 && (interval < minDriftCheckInterval || interval > maxDriftCheckInterval) {
+		return fmt.Sprintf("Drift check interval must be 0 (disabled) or between %d and %d seconds", minDriftCheckInterval, maxDriftCheckInterval)
+	}
+	return ""
+}
+
+// dedupeFieldsFromAPI converts dedupe field names to db.DedupeField.
+func dedupeFieldsFromAPI(fields []string) []db.DedupeField {
+	var result []db.DedupeField
+	for _, field := range fields {
+		result = append(result, db.DedupeField(field))
+	}
+	return result
+}
+
 // validateSourceInput validates source input fields for length and enum values.
 // Returns an error message if validation fails, empty string if valid.
 func validateSourceInput(name, sourceType, syncDirection, conflictStrategy, sourceURL, destURL, sourceUsername, destUsername string) string {
@@ -98,25 +151,78 @@ func validateSourceInput(name, sourceType, syncDirection, conflictStrategy, sour
 
 // APISource represents a source in JSON format for the API.
 type APISource struct {
-	ID                string              `json:"id"`
-	Name              string              `json:"name"`
-	SourceType        string              `json:"source_type"`
-	SourceURL         string              `json:"source_url"`
-	SourceUsername    string              `json:"source_username"`
-	DestURL           string              `json:"dest_url"`
-	DestUsername      string              `json:"dest_username"`
-	SyncInterval      int                 `json:"sync_interval"`
-	SyncDaysPast      int                 `json:"sync_days_past"`
-	SyncDirection     string              `json:"sync_direction"`
-	ConflictStrategy  string              `json:"conflict_strategy"`
-	SelectedCalendars []APICalendarConfig `json:"selected_calendars"`
-	Enabled           bool                `json:"enabled"`
-	SyncStatus        string              `json:"sync_status"`
-	LastSyncAt        *string             `json:"last_sync_at"`
-	NextSyncAt        *string             `json:"next_sync_at"`
-	IsStale           bool                `json:"is_stale"`
-	CreatedAt         string              `json:"created_at"`
-	UpdatedAt         string              `json:"updated_at"`
+	ID                  string              `json:"id"`
+	Name                string              `json:"name"`
+	SourceType          string              `json:"source_type"`
+	SourceURL           string              `json:"source_url"`
+	SourceUsername      string              `json:"source_username"`
+	DestURL             string              `json:"dest_url"`
+	DestUsername        string              `json:"dest_username"`
+	SyncInterval        int                 `json:"sync_interval"`
+	SyncDaysPast        int                 `json:"sync_days_past"`
+	SyncDirection       string              `json:"sync_direction"`
+	ConflictStrategy    string              `json:"conflict_strategy"`
+	SelectedCalendars   []APICalendarConfig `json:"selected_calendars"`
+	CalendarConcurrency int                 `json:"calendar_concurrency"`
+	EventConcurrency    int                 `json:"event_concurrency"`
+	CreateCalendars     bool                `json:"create_calendars"`
+	SyncCalendarMeta    bool                `json:"sync_calendar_metadata"`
+	DedupePolicy        string              `json:"dedupe_policy"` // off, report or auto
+	DedupeFields        []string            `json:"dedupe_fields"` // Properties duplicates share
+	SyncRepairedEvents  bool                `json:"sync_repaired_events"`
+	DriftCheckInterval  int                 `json:"drift_check_interval"` // Seconds between drift checks; 0 = disabled
+	Enabled             bool                `json:"enabled"`
+	SyncStatus          string              `json:"sync_status"`
+	LastSyncAt          *string             `json:"last_sync_at"`
+	NextSyncAt          *string             `json:"next_sync_at"`
+	RetryAttempt        int                 `json:"retry_attempt,omitempty"`      // Retries made after a failed scheduled sync
+	RetryMaxAttempts    int                 `json:"retry_max_attempts,omitempty"` // Set while a retry is pending
+	IsStale             bool                `json:"is_stale"`
+	SourceTransport     *APITransport       `json:"source_transport,omitempty"`
+	DestTransport       *APITransport       `json:"dest_transport,omitempty"`
+	SourceAuth          *APIAuth            `json:"source_auth,omitempty"`
+	DestAuth            *APIAuth            `json:"dest_auth,omitempty"`
+	ReconnectRequired   bool                `json:"reconnect_required,omitempty"` // An OAuth account must be connected again
+	CreatedAt           string              `json:"created_at"`
+	UpdatedAt           string              `json:"updated_at"`
+
+	// Only set when getting a single source
+	CalendarMappings []APICalendarMapping    `json:"calendar_mappings,omitempty"`
+	Capabilities     []APIServerCapabilities `json:"capabilities,omitempty"`
+	CalendarStatus   []*APICalendarSyncLog   `json:"calendar_status,omitempty"` // Last sync result of each calendar
+}
+
+// APITransport represents the connection settings for one server of a source.
+// Secrets are write-only: responses report whether a client key or proxy is set and
+// list header names with empty values.
+type APITransport struct {
+	CACert             string            `json:"ca_cert,omitempty"`
+	ClientCert         string            `json:"client_cert,omitempty"`
+	ClientKey          *string           `json:"client_key,omitempty"` // Omit to keep the stored key
+	HasClientKey       bool              `json:"has_client_key,omitempty"`
+	InsecureSkipVerify bool              `json:"insecure_skip_verify,omitempty"`
+	ProxyURL           *string           `json:"proxy_url,omitempty"` // Omit to keep the stored proxy, "" to remove it
+	HasProxy           bool              `json:"has_proxy,omitempty"`
+	UserAgent          string            `json:"user_agent,omitempty"`
+	Headers            map[string]string `json:"headers,omitempty"` // An empty value keeps the stored value
+}
+
+// APIAuth represents how calbridge authenticates to one server of a source. Basic and
+// Digest use the username and password fields of the source. Tokens and the client
+// secret are write-only: responses only report whether they are set.
+type APIAuth struct {
+	Method          string     `json:"method"`          // basic (default), digest, bearer or oauth2
+	Token           *string    `json:"token,omitempty"` // Bearer or OAuth2 access token; omit to keep the stored one
+	HasToken        bool       `json:"has_token,omitempty"`
+	RefreshToken    *string    `json:"refresh_token,omitempty"` // OAuth2 only; omit to keep the stored one
+	HasRefreshToken bool       `json:"has_refresh_token,omitempty"`
+	TokenExpiry     *time.Time `json:"token_expiry,omitempty"` // Reported only
+	TokenURL        string     `json:"token_url,omitempty"`
+	ClientID        string     `json:"client_id,omitempty"`
+	ClientSecret    *string    `json:"client_secret,omitempty"` // Omit to keep the stored secret
+	HasClientSecret bool       `json:"has_client_secret,omitempty"`
+	Scopes          []string   `json:"scopes,omitempty"`
+	Grant           string     `json:"grant,omitempty"` // Account connected with the OAuth flow, for new sources
 }
 
 // APICalendar represents a calendar discovered on a CalDAV server.
@@ -132,6 +238,28 @@ type APICalendarConfig struct {
 	SyncDirection string `json:"sync_direction,omitempty"` // empty = use source default
 }
 
+// APICalendarMapping represents the destination calendar used for a source calendar.
+type APICalendarMapping struct {
+	Path     string `json:"path"`
+	DestPath string `json:"dest_path"`
+	Created  bool   `json:"created"` // calbridge created the destination calendar
+}
+
+// APIServerCapabilities represents what the server of one endpoint of a source supports,
+// as detected by the last probe.
+type APIServerCapabilities struct {
+	Endpoint       string `json:"endpoint"`
+	Server         string `json:"server,omitempty"`
+	SyncCollection bool   `json:"sync_collection"`
+	CalendarQuery  bool   `json:"calendar_query"`
+	TimeRange      bool   `json:"time_range"`
+	MultiGet       bool   `json:"multiget"`
+	CTag           bool   `json:"ctag"`
+	MKCalendar     bool   `json:"mkcalendar"`
+	ConditionalPut bool   `json:"conditional_put"`
+	ProbedAt       string `json:"probed_at"`
+}
+
 // APISyncLog represents a sync log in JSON format for the API.
 type APISyncLog struct {
 	ID              string   `json:"id"`
@@ -147,6 +275,28 @@ type APISyncLog struct {
 	EventsProcessed int      `json:"events_processed"`
 	Duration        *float64 `json:"duration"`
 	CreatedAt       string   `json:"created_at"`
+
+	RequestsThrottled int     `json:"requests_throttled"`
+	RequestsRetried   int     `json:"requests_retried"`
+	ThrottleWait      float64 `json:"throttle_wait"` // Seconds spent waiting on rate limits
+
+	Calendars []*APICalendarSyncLog `json:"calendars,omitempty"` // Only set when listing the logs of a source
+}
+
+// APICalendarSyncLog represents the result of syncing one calendar in JSON format.
+type APICalendarSyncLog struct {
+	CalendarHref    string  `json:"calendar_href"`
+	CalendarName    string  `json:"calendar_name"`
+	Status          string  `json:"status"`
+	Mode            string  `json:"mode"` // incremental or full
+	EventsCreated   int     `json:"events_created"`
+	EventsUpdated   int     `json:"events_updated"`
+	EventsDeleted   int     `json:"events_deleted"`
+	EventsSkipped   int     `json:"events_skipped"`
+	EventsProcessed int     `json:"events_processed"`
+	Duration        float64 `json:"duration"` // Seconds
+	Details         *string `json:"details"`
+	CreatedAt       string  `json:"created_at"`
 }
 
 // APIDashboardStats represents dashboard statistics.
@@ -210,34 +360,228 @@ func sourceToAPI(s *db.Source) *APISource {
 	}
 
 	api := &APISource{
-		ID:                s.ID,
-		Name:              s.Name,
-		SourceType:        string(s.SourceType),
-		SourceURL:         s.SourceURL,
-		SourceUsername:    s.SourceUsername,
-		DestURL:           s.DestURL,
-		DestUsername:      s.DestUsername,
-		SyncInterval:      s.SyncInterval,
-		SyncDaysPast:      s.SyncDaysPast,
-		SyncDirection:     string(s.SyncDirection),
-		ConflictStrategy:  string(s.ConflictStrategy),
-		SelectedCalendars: apiCalendars,
-		Enabled:           s.Enabled,
-		SyncStatus:        string(s.LastSyncStatus),
-		CreatedAt:         s.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:         s.UpdatedAt.Format(time.RFC3339),
+		ID:                  s.ID,
+		Name:                s.Name,
+		SourceType:          string(s.SourceType),
+		SourceURL:           s.SourceURL,
+		SourceUsername:      s.SourceUsername,
+		DestURL:             s.DestURL,
+		DestUsername:        s.DestUsername,
+		SyncInterval:        s.SyncInterval,
+		SyncDaysPast:        s.SyncDaysPast,
+		SyncDirection:       string(s.SyncDirection),
+		ConflictStrategy:    string(s.ConflictStrategy),
+		SelectedCalendars:   apiCalendars,
+		CalendarConcurrency: s.CalendarConcurrency,
+		EventConcurrency:    s.EventConcurrency,
+		CreateCalendars:     s.CreateCalendars,
+		SyncCalendarMeta:    s.SyncCalendarMeta,
+		DedupePolicy:        string(s.DedupePolicy),
+		SyncRepairedEvents:  s.SyncRepairedEvents,
+		DriftCheckInterval:  s.DriftCheckInterval,
+		Enabled:             s.Enabled,
+		SyncStatus:          string(s.LastSyncStatus),
+		CreatedAt:           s.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:           s.UpdatedAt.Format(time.RFC3339),
 	}
 	if s.LastSyncAt != nil {
 		ts := s.LastSyncAt.Format(time.RFC3339)
 		api.LastSyncAt = &ts
 	}
+	for _, field := range s.GetDedupeFields() {
+		api.DedupeFields = append(api.DedupeFields, string(field))
+	}
 	// Ensure selected_calendars is never null in JSON
 	if api.SelectedCalendars == nil {
 		api.SelectedCalendars = []APICalendarConfig{}
 	}
+	api.SourceTransport = transportToAPI(s.SourceTransport)
+	api.DestTransport = transportToAPI(s.DestTransport)
+	api.SourceAuth = authToAPI(s.SourceAuth)
+	api.DestAuth = authToAPI(s.DestAuth)
+	api.ReconnectRequired = reconnectRequired(s)
+	return api
+}
+
+// transportToAPI converts stored transport settings to their API form without secrets.
+func transportToAPI(t db.TransportSettings) *APITransport {
+	if t.IsZero() {
+		return nil
+	}
+	api := &APITransport{
+		CACert:             t.CACert,
+		ClientCert:         t.ClientCert,
+		HasClientKey:       t.ClientKey != "",
+		InsecureSkipVerify: t.InsecureSkipVerify,
+		HasProxy:           t.ProxyURL != "",
+		UserAgent:          t.UserAgent,
+	}
+	if len(t.Headers) > 0 {
+		api.Headers = make(map[string]string, len(t.Headers))
+		for name := range t.Headers {
+			api.Headers[name] = ""
+		}
+	}
 	return api
 }
 
+// transportChanged reports whether opts differ from the stored connection settings.
+// Settings that can't be decrypted count as changed.
+func (h *Handlers) transportChanged(opts caldav.TransportOptions, stored db.TransportSettings) bool {
+	current, err := caldav.DecryptTransportOptions(h.encryptor, stored)
+	return err != nil || !opts.Equal(current)
+}
+
+// transportOptions merges API transport settings with the stored ones and validates the result.
+// Secrets that the request leaves out are taken from the stored settings.
+func (h *Handlers) transportOptions(req *APITransport, stored db.TransportSettings) (caldav.TransportOptions, error) {
+	if req == nil {
+		return caldav.TransportOptions{}, nil
+	}
+
+	current, err := caldav.DecryptTransportOptions(h.encryptor, stored)
+	if err != nil {
+		return caldav.TransportOptions{}, fmt.Errorf("failed to decrypt stored settings: %w", err)
+	}
+
+	opts := caldav.TransportOptions{
+		CACert:             req.CACert,
+		ClientCert:         req.ClientCert,
+		ClientKey:          current.ClientKey,
+		InsecureSkipVerify: req.InsecureSkipVerify,
+		ProxyURL:           current.ProxyURL,
+		UserAgent:          req.UserAgent,
+	}
+	if req.ClientKey != nil {
+		opts.ClientKey = *req.ClientKey
+	}
+	if opts.ClientCert == "" {
+		opts.ClientKey = ""
+	}
+	if req.ProxyURL != nil {
+		opts.ProxyURL = *req.ProxyURL
+	}
+	if len(req.Headers) > 0 {
+		opts.Headers = make(map[string]string, len(req.Headers))
+		for name, value := range req.Headers {
+			if value == "" {
+				value = current.Headers[name]
+			}
+			opts.Headers[name] = value
+		}
+	}
+
+	if err := opts.Validate(); err != nil {
+		return caldav.TransportOptions{}, err
+	}
+	return opts, nil
+}
+
+// authToAPI converts stored auth settings to their API form, without secrets.
+func authToAPI(a db.AuthSettings) *APIAuth {
+	if a.IsZero() {
+		return nil
+	}
+	return &APIAuth{
+		Method:          string(a.Method),
+		HasToken:        a.AccessToken != "",
+		HasRefreshToken: a.RefreshToken != "",
+		TokenExpiry:     a.TokenExpiry,
+		TokenURL:        a.TokenURL,
+		ClientID:        a.ClientID,
+		HasClientSecret: a.ClientSecret != "",
+		Scopes:          a.Scopes,
+	}
+}
+
+// credentials merges API auth settings with the stored ones and validates the result.
+// Secrets that the request leaves out are taken from the stored settings if the method
+// is unchanged. A nil request means basic auth.
+func (h *Handlers) credentials(userID string, req *APIAuth, stored db.AuthSettings, username, password string) (caldav.Credentials, error) {
+	if req != nil && req.Grant != "" {
+		creds, ok := h.grants.get(userID, req.Grant)
+		if !ok {
+			return caldav.Credentials{}, errors.New("authorization expired, connect the account again")
+		}
+		// Login flows that report the account name bind the grant to it
+		if creds.Username != "" && creds.Username != username {
+			return caldav.Credentials{}, errors.New("username doesn't match the connected account")
+		}
+		creds.Username = username
+		return creds, nil
+	}
+
+	if req == nil || req.Method == "" || db.AuthMethod(req.Method) == db.AuthMethodBasic {
+		return caldav.BasicCredentials(username, password), nil
+	}
+
+	method := db.AuthMethod(req.Method)
+	if !method.IsValid() {
+		return caldav.Credentials{}, fmt.Errorf("unsupported auth method %q", req.Method)
+	}
+
+	if stored.Method != method {
+		stored = db.AuthSettings{}
+	}
+	creds, err := caldav.DecryptCredentials(h.encryptor, username, password, stored)
+	if err != nil {
+		return caldav.Credentials{}, fmt.Errorf("failed to decrypt stored settings: %w", err)
+	}
+	creds.Method = method
+
+	if req.Token != nil {
+		creds.Token = *req.Token
+	}
+	if method == db.AuthMethodOAuth2 {
+		if creds.OAuth2 == nil {
+			creds.OAuth2 = &caldav.OAuth2Credentials{}
+		}
+		creds.OAuth2.TokenURL = req.TokenURL
+		creds.OAuth2.ClientID = req.ClientID
+		creds.OAuth2.Scopes = req.Scopes
+		if req.Token != nil {
+			creds.OAuth2.Expiry = time.Time{} // Unknown for a pasted token
+		}
+		if req.RefreshToken != nil {
+			creds.OAuth2.RefreshToken = *req.RefreshToken
+		}
+		if req.ClientSecret != nil {
+			creds.OAuth2.ClientSecret = *req.ClientSecret
+		}
+	}
+
+	if len(creds.Token) > maxTokenLength || (creds.OAuth2 != nil && len(creds.OAuth2.RefreshToken) > maxTokenLength) {
+		return caldav.Credentials{}, errors.New("token is too long")
+	}
+	if err := creds.Validate(); err != nil {
+		return caldav.Credentials{}, err
+	}
+	return creds, nil
+}
+
+// usesPassword reports whether credentials of this method authenticate with a password
+// from the request, rather than one obtained through a login flow.
+func usesPassword(req *APIAuth) bool {
+	if req != nil && req.Grant != "" {
+		return false
+	}
+	return req == nil || req.Method == "" || db.AuthMethod(req.Method) == db.AuthMethodBasic ||
+		db.AuthMethod(req.Method) == db.AuthMethodDigest
+}
+
+// newSourceClient creates a client for the source server of a saved source.
+func (h *Handlers) newSourceClient(source *db.Source) (*caldav.Client, error) {
+	opts, err := caldav.DecryptTransportOptions(h.encryptor, source.SourceTransport)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := caldav.SourceCredentials(h.db, h.encryptor, source, db.EndpointSource)
+	if err != nil {
+		return nil, err
+	}
+	return caldav.NewClientWithCredentials(source.SourceURL, creds, opts)
+}
+
 // sourceToAPIWithScheduler converts a db.Source to APISource with scheduler information.
 func (h *Handlers) sourceToAPIWithScheduler(s *db.Source) *APISource {
 	api := sourceToAPI(s)
@@ -249,6 +593,12 @@ func (h *Handlers) sourceToAPIWithScheduler(s *db.Source) *APISource {
 			ts := nextSync.Format(time.RFC3339)
 			api.NextSyncAt = &ts
 		}
+
+		// Expose retry state while a failed sync is being retried
+		if retry := h.scheduler.GetRetryState(s.ID); !retry.RetryAt.IsZero() {
+			api.RetryAttempt = retry.Attempt
+			api.RetryMaxAttempts = retry.MaxAttempts
+		}
 	}
 
 	// Check if source is stale
@@ -271,6 +621,10 @@ func syncLogToAPI(l *db.SyncLog) *APISyncLog {
 		CalendarsSynced: l.CalendarsSynced,
 		EventsProcessed: l.EventsProcessed,
 		CreatedAt:       l.CreatedAt.Format(time.RFC3339),
+
+		RequestsThrottled: l.RequestsThrottled,
+		RequestsRetried:   l.RequestsRetried,
+		ThrottleWait:      l.ThrottleWait.Seconds(),
 	}
 	if l.Details != "" {
 		api.Details = &l.Details
@@ -279,6 +633,30 @@ func syncLogToAPI(l *db.SyncLog) *APISyncLog {
 		dur := l.Duration.Seconds()
 		api.Duration = &dur
 	}
+	for _, cal := range l.Calendars {
+		api.Calendars = append(api.Calendars, calendarSyncLogToAPI(cal))
+	}
+	return api
+}
+
+// calendarSyncLogToAPI converts a db.CalendarSyncLog to APICalendarSyncLog.
+func calendarSyncLogToAPI(l *db.CalendarSyncLog) *APICalendarSyncLog {
+	api := &APICalendarSyncLog{
+		CalendarHref:    l.CalendarHref,
+		CalendarName:    l.CalendarName,
+		Status:          string(l.Status),
+		Mode:            string(l.Mode),
+		EventsCreated:   l.EventsCreated,
+		EventsUpdated:   l.EventsUpdated,
+		EventsDeleted:   l.EventsDeleted,
+		EventsSkipped:   l.EventsSkipped,
+		EventsProcessed: l.EventsProcessed,
+		Duration:        l.Duration.Seconds(),
+		CreatedAt:       l.CreatedAt.Format(time.RFC3339),
+	}
+	if l.Details != "" {
+		api.Details = &l.Details
+	}
 	return api
 }
 
@@ -490,24 +868,70 @@ func (h *Handlers) APIGetSource(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, h.sourceToAPIWithScheduler(source))
+	api := h.sourceToAPIWithScheduler(source)
+	mappings, err := h.db.GetCalendarMappings(source.ID)
+	if err != nil {
+		log.Printf("Failed to get calendar mappings for source %s: %v", source.ID, err)
+	}
+	for _, m := range mappings {
+		api.CalendarMappings = append(api.CalendarMappings, APICalendarMapping{Path: m.CalendarHref, DestPath: m.DestHref, Created: m.Created})
+	}
+	capabilities, err := h.db.GetAllServerCapabilities(source.ID)
+	if err != nil {
+		log.Printf("Failed to get server capabilities for source %s: %v", source.ID, err)
+	}
+	for _, caps := range capabilities {
+		api.Capabilities = append(api.Capabilities, APIServerCapabilities{
+			Endpoint:       string(caps.Endpoint),
+			Server:         caps.Server,
+			SyncCollection: caps.SyncCollection,
+			CalendarQuery:  caps.CalendarQuery,
+			TimeRange:      caps.TimeRange,
+			MultiGet:       caps.MultiGet,
+			CTag:           caps.CTag,
+			MKCalendar:     caps.MKCalendar,
+			ConditionalPut: caps.ConditionalPut,
+			ProbedAt:       caps.ProbedAt.Format(time.RFC3339),
+		})
+	}
+	calendarStatus, err := h.db.GetLatestCalendarSyncLogs(source.ID)
+	if err != nil {
+		log.Printf("Failed to get calendar sync status for source %s: %v", source.ID, err)
+	}
+	for _, cal := range calendarStatus {
+		api.CalendarStatus = append(api.CalendarStatus, calendarSyncLogToAPI(cal))
+	}
+
+	c.JSON(http.StatusOK, api)
 }
 
 // APICreateSourceRequest represents the request body for creating a source.
 type APICreateSourceRequest struct {
-	Name              string              `json:"name"`
-	SourceType        string              `json:"source_type"`
-	SourceURL         string              `json:"source_url"`
-	SourceUsername    string              `json:"source_username"`
-	SourcePassword    string              `json:"source_password"`
-	DestURL           string              `json:"dest_url"`
-	DestUsername      string              `json:"dest_username"`
-	DestPassword      string              `json:"dest_password"`
-	SyncInterval      int                 `json:"sync_interval"`
-	SyncDaysPast      int                 `json:"sync_days_past"`
-	SyncDirection     string              `json:"sync_direction"`
-	ConflictStrategy  string              `json:"conflict_strategy"`
-	SelectedCalendars []APICalendarConfig `json:"selected_calendars"`
+	Name                string              `json:"name"`
+	SourceType          string              `json:"source_type"`
+	SourceURL           string              `json:"source_url"`
+	SourceUsername      string              `json:"source_username"`
+	SourcePassword      string              `json:"source_password"`
+	DestURL             string              `json:"dest_url"`
+	DestUsername        string              `json:"dest_username"`
+	DestPassword        string              `json:"dest_password"`
+	SyncInterval        int                 `json:"sync_interval"`
+	SyncDaysPast        int                 `json:"sync_days_past"`
+	SyncDirection       string              `json:"sync_direction"`
+	ConflictStrategy    string              `json:"conflict_strategy"`
+	SelectedCalendars   []APICalendarConfig `json:"selected_calendars"`
+	CalendarConcurrency int                 `json:"calendar_concurrency"`
+	EventConcurrency    int                 `json:"event_concurrency"`
+	CreateCalendars     bool                `json:"create_calendars"`
+	SyncCalendarMeta    bool                `json:"sync_calendar_metadata"`
+	DedupePolicy        string              `json:"dedupe_policy,omitempty"` // auto if empty
+	DedupeFields        []string            `json:"dedupe_fields,omitempty"` // summary and dtstart if empty
+	SyncRepairedEvents  bool                `json:"sync_repaired_events"`
+	DriftCheckInterval  int                 `json:"drift_check_interval"`
+	SourceTransport     *APITransport       `json:"source_transport,omitempty"`
+	DestTransport       *APITransport       `json:"dest_transport,omitempty"`
+	SourceAuth          *APIAuth            `json:"source_auth,omitempty"`
+	DestAuth            *APIAuth            `json:"dest_auth,omitempty"`
 }
 
 // APICreateSource creates a new source.
@@ -524,7 +948,7 @@ func (h *Handlers) APICreateSource(c *gin.Context) {
 		return
 	}
 
-	if req.Name == "" || req.SourceURL == "" || req.SourceUsername == "" || req.SourcePassword == "" {
+	if req.Name == "" || req.SourceURL == "" || req.SourceUsername == "" || (req.SourcePassword == "" && usesPassword(req.SourceAuth)) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields"})
 		return
 	}
@@ -535,6 +959,21 @@ func (h *Handlers) APICreateSource(c *gin.Context) {
 		return
 	}
 
+	if validationErr := validateConcurrency(req.CalendarConcurrency, req.EventConcurrency); validationErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr})
+		return
+	}
+
+	if validationErr := validateDedupe(req.DedupePolicy, req.DedupeFields); validationErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr})
+		return
+	}
+
+	if validationErr := validateDriftCheckInterval(req.DriftCheckInterval); validationErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr})
+		return
+	}
+
 	// Validate password lengths
 	if len(req.SourcePassword) > maxPasswordLength {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Source password is too long"})
@@ -545,31 +984,77 @@ func (h *Handlers) APICreateSource(c *gin.Context) {
 		return
 	}
 
+	sourceTransport, err := h.transportOptions(req.SourceTransport, db.TransportSettings{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source connection settings: " + err.Error()})
+		return
+	}
+	destTransport, err := h.transportOptions(req.DestTransport, db.TransportSettings{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid destination connection settings: " + err.Error()})
+		return
+	}
+
+	sourceCreds, err := h.credentials(session.UserID, req.SourceAuth, db.AuthSettings{}, req.SourceUsername, req.SourcePassword)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source authentication settings: " + err.Error()})
+		return
+	}
+	destCreds, err := h.credentials(session.UserID, req.DestAuth, db.AuthSettings{}, req.DestUsername, req.DestPassword)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid destination authentication settings: " + err.Error()})
+		return
+	}
+
 	// Test source connection
 	ctx := c.Request.Context()
-	if err := h.syncEngine.TestConnection(ctx, req.SourceURL, req.SourceUsername, req.SourcePassword); err != nil {
+	if err := h.syncEngine.TestConnection(ctx, req.SourceURL, sourceCreds, sourceTransport); err != nil {
 		log.Printf("Source connection test failed for %s: %v", req.SourceURL, err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to connect to source: " + categorizeConnectionError(err)})
 		return
 	}
 
 	// Test destination if provided
-	if req.DestURL != "" && req.DestUsername != "" && req.DestPassword != "" {
-		if err := h.syncEngine.TestConnection(ctx, req.DestURL, req.DestUsername, req.DestPassword); err != nil {
+	if req.DestURL != "" && req.DestUsername != "" && (req.DestPassword != "" || !usesPassword(req.DestAuth)) {
+		if err := h.syncEngine.TestConnection(ctx, req.DestURL, destCreds, destTransport); err != nil {
 			log.Printf("Destination connection test failed for %s: %v", req.DestURL, err)
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to connect to destination: " + categorizeConnectionError(err)})
 			return
 		}
 	}
 
-	// Encrypt passwords
-	encSourcePwd, err := h.encryptor.Encrypt(req.SourcePassword)
+	// Encrypt passwords; login flows provide them through the credentials
+	encSourcePwd, err := h.encryptor.Encrypt(sourceCreds.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
 		return
 	}
 
-	encDestPwd, err := h.encryptor.Encrypt(req.DestPassword)
+	encDestPwd, err := h.encryptor.Encrypt(destCreds.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
+		return
+	}
+
+	encSourceTransport, err := caldav.EncryptTransportOptions(h.encryptor, sourceTransport)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
+		return
+	}
+
+	encDestTransport, err := caldav.EncryptTransportOptions(h.encryptor, destTransport)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
+		return
+	}
+
+	encSourceAuth, err := caldav.EncryptCredentials(h.encryptor, sourceCreds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
+		return
+	}
+
+	encDestAuth, err := caldav.EncryptCredentials(h.encryptor, destCreds)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
 		return
@@ -596,27 +1081,40 @@ func (h *Handlers) APICreateSource(c *gin.Context) {
 	}
 
 	source := &db.Source{
-		UserID:            session.UserID,
-		Name:              req.Name,
-		SourceType:        db.SourceType(req.SourceType),
-		SourceURL:         req.SourceURL,
-		SourceUsername:    req.SourceUsername,
-		SourcePassword:    encSourcePwd,
-		DestURL:           req.DestURL,
-		DestUsername:      req.DestUsername,
-		DestPassword:      encDestPwd,
-		SyncInterval:      syncInterval,
-		SyncDaysPast:      syncDaysPast,
-		SyncDirection:     db.SyncDirection(req.SyncDirection),
-		ConflictStrategy:  db.ConflictStrategy(req.ConflictStrategy),
-		SelectedCalendars: dbCalendars,
-		Enabled:           true,
+		UserID:              session.UserID,
+		Name:                req.Name,
+		SourceType:          db.SourceType(req.SourceType),
+		SourceURL:           req.SourceURL,
+		SourceUsername:      req.SourceUsername,
+		SourcePassword:      encSourcePwd,
+		DestURL:             req.DestURL,
+		DestUsername:        req.DestUsername,
+		DestPassword:        encDestPwd,
+		SyncInterval:        syncInterval,
+		SyncDaysPast:        syncDaysPast,
+		SyncDirection:       db.SyncDirection(req.SyncDirection),
+		ConflictStrategy:    db.ConflictStrategy(req.ConflictStrategy),
+		SelectedCalendars:   dbCalendars,
+		CalendarConcurrency: req.CalendarConcurrency,
+		EventConcurrency:    req.EventConcurrency,
+		CreateCalendars:     req.CreateCalendars,
+		SyncCalendarMeta:    req.SyncCalendarMeta,
+		DedupePolicy:        db.DedupePolicy(req.DedupePolicy),
+		DedupeFields:        dedupeFieldsFromAPI(req.DedupeFields),
+		SyncRepairedEvents:  req.SyncRepairedEvents,
+		DriftCheckInterval:  req.DriftCheckInterval,
+		SourceTransport:     encSourceTransport,
+		DestTransport:       encDestTransport,
+		SourceAuth:          encSourceAuth,
+		DestAuth:            encDestAuth,
+		Enabled:             true,
 	}
 
 	if err := h.db.CreateSource(source); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create source"})
 		return
 	}
+	h.grants.drop(req.SourceAuth, req.DestAuth)
 
 	h.scheduler.AddJob(source.ID, time.Duration(source.SyncInterval)*time.Second)
 
@@ -625,19 +1123,31 @@ func (h *Handlers) APICreateSource(c *gin.Context) {
 
 // APIUpdateSourceRequest represents the request body for updating a source.
 type APIUpdateSourceRequest struct {
-	Name              string              `json:"name"`
-	SourceType        string              `json:"source_type"`
-	SourceURL         string              `json:"source_url"`
-	SourceUsername    string              `json:"source_username"`
-	SourcePassword    string              `json:"source_password,omitempty"`
-	DestURL           string              `json:"dest_url"`
-	DestUsername      string              `json:"dest_username"`
-	DestPassword      string              `json:"dest_password,omitempty"`
-	SyncInterval      int                 `json:"sync_interval"`
-	SyncDaysPast      int                 `json:"sync_days_past"`
-	SyncDirection     string              `json:"sync_direction"`
-	ConflictStrategy  string              `json:"conflict_strategy"`
-	SelectedCalendars []APICalendarConfig `json:"selected_calendars"`
+	Name                string              `json:"name"`
+	SourceType          string              `json:"source_type"`
+	SourceURL           string              `json:"source_url"`
+	SourceUsername      string              `json:"source_username"`
+	SourcePassword      string              `json:"source_password,omitempty"`
+	DestURL             string              `json:"dest_url"`
+	DestUsername        string              `json:"dest_username"`
+	DestPassword        string              `json:"dest_password,omitempty"`
+	SyncInterval        int                 `json:"sync_interval"`
+	SyncDaysPast        int                 `json:"sync_days_past"`
+	SyncDirection       string              `json:"sync_direction"`
+	ConflictStrategy    string              `json:"conflict_strategy"`
+	SelectedCalendars   []APICalendarConfig `json:"selected_calendars"`
+	CalendarConcurrency int                 `json:"calendar_concurrency"`
+	EventConcurrency    int                 `json:"event_concurrency"`
+	CreateCalendars     *bool               `json:"create_calendars,omitempty"` // Omit to keep the current setting
+	SyncCalendarMeta    *bool               `json:"sync_calendar_metadata,omitempty"`
+	DedupePolicy        string              `json:"dedupe_policy,omitempty"`    // Omit to keep the current policy
+	DedupeFields        []string            `json:"dedupe_fields"`              // Omit to keep, [] for the defaults
+	SyncRepairedEvents  *bool               `json:"sync_repaired_events,omitempty"`
+	DriftCheckInterval  *int                `json:"drift_check_interval,omitempty"` // Omit to keep, 0 to disable
+	SourceTransport     *APITransport       `json:"source_transport,omitempty"` // Omit to keep the current settings
+	DestTransport       *APITransport       `json:"dest_transport,omitempty"`
+	SourceAuth          *APIAuth            `json:"source_auth,omitempty"` // Omit to keep the current settings
+	DestAuth            *APIAuth            `json:"dest_auth,omitempty"`
 }
 
 // APIUpdateSource updates an existing source.
@@ -668,6 +1178,23 @@ func (h *Handlers) APIUpdateSource(c *gin.Context) {
 		return
 	}
 
+	if validationErr := validateConcurrency(req.CalendarConcurrency, req.EventConcurrency); validationErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr})
+		return
+	}
+
+	if validationErr := validateDedupe(req.DedupePolicy, req.DedupeFields); validationErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr})
+		return
+	}
+
+	if req.DriftCheckInterval != nil {
+		if validationErr := validateDriftCheckInterval(*req.DriftCheckInterval); validationErr != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr})
+			return
+		}
+	}
+
 	// Validate password lengths if provided
 	if req.SourcePassword != "" && len(req.SourcePassword) > maxPasswordLength {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Source password is too long"})
@@ -687,6 +1214,15 @@ func (h *Handlers) APIUpdateSource(c *gin.Context) {
 		})
 	}
 
+	// Any change to the connection details clears a tripped auth circuit breaker
+	credentialsChanged := req.SourcePassword != "" || req.DestPassword != "" ||
+		req.SourceAuth != nil || req.DestAuth != nil ||
+		req.SourceURL != source.SourceURL || req.SourceUsername != source.SourceUsername ||
+		req.DestURL != source.DestURL || req.DestUsername != source.DestUsername
+	serversChanged := req.SourceURL != source.SourceURL || req.DestURL != source.DestURL
+	oldSourceURL, oldSourceUsername := source.SourceURL, source.SourceUsername
+	oldDestURL, oldDestUsername := source.DestURL, source.DestUsername
+
 	// Update fields
 	source.Name = req.Name
 	source.SourceType = db.SourceType(req.SourceType)
@@ -703,6 +1239,30 @@ func (h *Handlers) APIUpdateSource(c *gin.Context) {
 	if req.SyncDaysPast > 0 {
 		source.SyncDaysPast = req.SyncDaysPast
 	}
+	if req.CalendarConcurrency > 0 {
+		source.CalendarConcurrency = req.CalendarConcurrency
+	}
+	if req.EventConcurrency > 0 {
+		source.EventConcurrency = req.EventConcurrency
+	}
+	if req.CreateCalendars != nil {
+		source.CreateCalendars = *req.CreateCalendars
+	}
+	if req.SyncCalendarMeta != nil {
+		source.SyncCalendarMeta = *req.SyncCalendarMeta
+	}
+	if req.DedupePolicy != "" {
+		source.DedupePolicy = db.DedupePolicy(req.DedupePolicy)
+	}
+	if req.DedupeFields != nil {
+		source.DedupeFields = dedupeFieldsFromAPI(req.DedupeFields)
+	}
+	if req.SyncRepairedEvents != nil {
+		source.SyncRepairedEvents = *req.SyncRepairedEvents
+	}
+	if req.DriftCheckInterval != nil {
+		source.DriftCheckInterval = *req.DriftCheckInterval
+	}
 
 	// Update passwords if provided
 	if req.SourcePassword != "" {
@@ -723,16 +1283,122 @@ func (h *Handlers) APIUpdateSource(c *gin.Context) {
 		source.DestPassword = encPassword
 	}
 
+	// Replace connection settings if provided; stored secrets are kept unless overwritten
+	var sourceTransportChanged, destTransportChanged bool
+	if req.SourceTransport != nil {
+		opts, err := h.transportOptions(req.SourceTransport, source.SourceTransport)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source connection settings: " + err.Error()})
+			return
+		}
+		sourceTransportChanged = h.transportChanged(opts, source.SourceTransport)
+		if source.SourceTransport, err = caldav.EncryptTransportOptions(h.encryptor, opts); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
+			return
+		}
+	}
+
+	if req.DestTransport != nil {
+		opts, err := h.transportOptions(req.DestTransport, source.DestTransport)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid destination connection settings: " + err.Error()})
+			return
+		}
+		destTransportChanged = h.transportChanged(opts, source.DestTransport)
+		if source.DestTransport, err = caldav.EncryptTransportOptions(h.encryptor, opts); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
+			return
+		}
+	}
+
+	// Replace authentication settings if provided; the password stays in its own column
+	if req.SourceAuth != nil {
+		creds, err := h.credentials(session.UserID, req.SourceAuth, source.SourceAuth, source.SourceUsername, "")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source authentication settings: " + err.Error()})
+			return
+		}
+		if source.SourceAuth, err = caldav.EncryptCredentials(h.encryptor, creds); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
+			return
+		}
+		if creds.Password != "" { // From a login flow grant
+			if source.SourcePassword, err = h.encryptor.Encrypt(creds.Password); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
+				return
+			}
+		}
+	}
+
+	if req.DestAuth != nil {
+		creds, err := h.credentials(session.UserID, req.DestAuth, source.DestAuth, source.DestUsername, "")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid destination authentication settings: " + err.Error()})
+			return
+		}
+		if source.DestAuth, err = caldav.EncryptCredentials(h.encryptor, creds); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
+			return
+		}
+		if creds.Password != "" { // From a login flow grant
+			if source.DestPassword, err = h.encryptor.Encrypt(creds.Password); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
+				return
+			}
+		}
+	}
+
 	if err := h.db.UpdateSource(source); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update source"})
 		return
 	}
+	h.grants.drop(req.SourceAuth, req.DestAuth)
+
+	// Pooled clients for the old connection details or settings must not be reused
+	if h.syncEngine != nil {
+		if credentialsChanged || sourceTransportChanged {
+			h.syncEngine.InvalidateClients(oldSourceURL, oldSourceUsername)
+		}
+		if credentialsChanged || destTransportChanged {
+			h.syncEngine.InvalidateClients(oldDestURL, oldDestUsername)
+		}
+	}
+
+	if credentialsChanged {
+		h.resetAuthCircuitBreaker(source)
+	}
+
+	// A different server has to be probed again
+	if serversChanged {
+		if err := h.db.DeleteServerCapabilities(source.ID); err != nil {
+			log.Printf("Failed to clear server capabilities for source %s: %v", source.ID, err)
+		}
+	}
 
 	h.scheduler.UpdateJobInterval(source.ID, time.Duration(source.SyncInterval)*time.Second)
 
 	c.JSON(http.StatusOK, h.sourceToAPIWithScheduler(source))
 }
 
+// resetAuthCircuitBreaker clears the auth failure count of a source whose credentials
+// changed and resumes it if the circuit breaker had paused it.
+func (h *Handlers) resetAuthCircuitBreaker(source *db.Source) {
+	if source.AuthFailures == 0 && source.LastSyncStatus != db.SyncStatusCredentialsInvalid {
+		return
+	}
+	if err := h.db.ResetAuthFailures(source.ID); err != nil {
+		log.Printf("Failed to reset auth failures for source %s: %v", source.ID, err)
+	}
+	if source.LastSyncStatus == db.SyncStatusCredentialsInvalid {
+		if err := h.db.UpdateSourceSyncStatus(source.ID, db.SyncStatusPending, "Credentials updated"); err != nil {
+			log.Printf("Failed to resume source %s: %v", source.ID, err)
+		}
+		source.LastSyncStatus = db.SyncStatusPending
+		source.LastSyncMessage = "Credentials updated"
+	}
+	source.AuthFailures = 0
+}
+
 // APIDeleteSource deletes a source.
 func (h *Handlers) APIDeleteSource(c *gin.Context) {
 	session := auth.GetCurrentUser(c)
@@ -811,6 +1477,30 @@ func (h *Handlers) APITriggerSync(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Sync triggered"})
 }
 
+// APICancelSync stops the running sync of a source.
+func (h *Handlers) APICancelSync(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	sourceID := c.Param("id")
+	// Use timing-safe query that combines ID and user check
+	_, err := h.db.GetSourceByIDForUser(sourceID, session.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	}
+
+	if !h.scheduler.CancelSync(sourceID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "No sync in progress"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Sync cancellation requested"})
+}
+
 // APIGetSourceLogs returns logs for a source.
 func (h *Handlers) APIGetSourceLogs(c *gin.Context) {
 	session := auth.GetCurrentUser(c)
@@ -858,6 +1548,9 @@ func (h *Handlers) APIGetSourceLogs(c *gin.Context) {
 		end = len(logs)
 	}
 	paginatedLogs := logs[start:end]
+	if err := h.db.LoadCalendarSyncLogs(paginatedLogs); err != nil {
+		log.Printf("Failed to load calendar sync logs for source %s: %v", sourceID, err)
+	}
 
 	apiLogs := make([]*APISyncLog, len(paginatedLogs))
 	for i, l := range paginatedLogs {
@@ -871,14 +1564,125 @@ func (h *Handlers) APIGetSourceLogs(c *gin.Context) {
 	})
 }
 
+// APIEventChange represents an audit record of an event write in JSON format.
+type APIEventChange struct {
+	ID           string `json:"id"`
+	RunID        string `json:"run_id"`
+	CalendarHref string `json:"calendar_href"`
+	EventUID     string `json:"event_uid"`
+	Summary      string `json:"summary"` // Redacted to its first characters
+	Action       string `json:"action"`
+	Direction    string `json:"direction"`
+	Reason       string `json:"reason"`
+	Result       string `json:"result"`
+	Error        string `json:"error,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// Page sizes of the event change list
+const (
+	defaultChangesPageSize = 50
+	maxChangesPageSize     = 200
+)
+
+// APIGetSourceChanges returns the audit trail of a source's event writes, newest first.
+// It can be filtered by event UID, full summary, action and time range (RFC 3339).
+func (h *Handlers) APIGetSourceChanges(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	sourceID := c.Param("id")
+	if _, err := h.db.GetSourceByIDForUser(sourceID, session.UserID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	}
+
+	filter := db.EventChangeFilter{
+		EventUID:    c.Query("uid"),
+		SummaryHash: db.HashSummary(c.Query("summary")),
+		Action:      db.ChangeAction(c.Query("action")),
+	}
+	switch filter.Action {
+	case "", db.ChangeCreate, db.ChangeUpdate, db.ChangeDelete:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Action must be create, update or delete"})
+		return
+	}
+	for param, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := c.Query(param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " time, use RFC 3339"})
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	page := 1
+	if p := c.Query("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+	limit := defaultChangesPageSize
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = min(parsed, maxChangesPageSize)
+		}
+	}
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	changes, total, err := h.db.GetEventChanges(sourceID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load changes"})
+		return
+	}
+
+	totalPages := max((total+limit-1)/limit, 1)
+
+	apiChanges := make([]*APIEventChange, len(changes))
+	for i, ch := range changes {
+		apiChanges[i] = &APIEventChange{
+			ID:           ch.ID,
+			RunID:        ch.RunID,
+			CalendarHref: ch.CalendarHref,
+			EventUID:     ch.EventUID,
+			Summary:      ch.Summary,
+			Action:       string(ch.Action),
+			Direction:    string(ch.Direction),
+			Reason:       string(ch.Reason),
+			Result:       string(ch.Result),
+			Error:        ch.Error,
+			CreatedAt:    ch.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"changes":     apiChanges,
+		"page":        page,
+		"total_pages": totalPages,
+		"total":       total,
+	})
+}
+
 // APIMalformedEvent represents a malformed event in API responses.
 type APIMalformedEvent struct {
-	ID           string `json:"id"`
-	SourceID     string `json:"source_id"`
-	SourceName   string `json:"source_name"`
-	EventPath    string `json:"event_path"`
-	ErrorMessage string `json:"error_message"`
-	DiscoveredAt string `json:"discovered_at"`
+	ID           string   `json:"id"`
+	SourceID     string   `json:"source_id"`
+	SourceName   string   `json:"source_name"`
+	EventPath    string   `json:"event_path"`
+	ErrorMessage string   `json:"error_message"`
+	EventUID     string   `json:"event_uid,omitempty"`
+	CalendarHref string   `json:"calendar_href,omitempty"`
+	HasPayload   bool     `json:"has_payload"` // The raw payload was stored and can be repaired
+	Repairable   bool     `json:"repairable"`
+	Repairs      []string `json:"repairs"` // Repairs automatic repair makes
+	DiscoveredAt string   `json:"discovered_at"`
 }
 
 // malformedEventToAPI converts a db.MalformedEvent to API format.
@@ -889,6 +1693,11 @@ func malformedEventToAPI(e *db.MalformedEvent) *APIMalformedEvent {
 		SourceName:   e.SourceName,
 		EventPath:    e.EventPath,
 		ErrorMessage: e.ErrorMessage,
+		EventUID:     e.EventUID,
+		CalendarHref: e.CalendarHref,
+		HasPayload:   e.RawPayload != "",
+		Repairable:   e.Repairable,
+		Repairs:      e.Repairs,
 		DiscoveredAt: e.DiscoveredAt.Format(time.RFC3339),
 	}
 }
@@ -940,17 +1749,14 @@ func (h *Handlers) APIDeleteMalformedEvent(c *gin.Context) {
 	}
 
 	// Try to delete the event from the source calendar
-	sourcePassword, err := h.encryptor.Decrypt(source.SourcePassword)
+	client, err := h.newSourceClient(source)
 	if err == nil {
-		client, err := caldav.NewClient(source.SourceURL, source.SourceUsername, sourcePassword)
-		if err == nil {
-			ctx := c.Request.Context()
-			if err := client.DeleteEvent(ctx, event.EventPath); err != nil {
-				log.Printf("Failed to delete malformed event from source: %v", err)
-				// Continue to delete the record anyway
-			} else {
-				log.Printf("Deleted malformed event from source: %s", event.EventPath)
-			}
+		ctx := c.Request.Context()
+		if err := client.DeleteEvent(ctx, event.EventPath); err != nil {
+			log.Printf("Failed to delete malformed event from source: %v", err)
+			// Continue to delete the record anyway
+		} else {
+			log.Printf("Deleted malformed event from source: %s", event.EventPath)
 		}
 	}
 
@@ -985,9 +1791,11 @@ func (h *Handlers) APIDeleteAllMalformedEvents(c *gin.Context) {
 
 // APIDiscoverCalendarsRequest represents the request body for discovering calendars.
 type APIDiscoverCalendarsRequest struct {
-	URL      string `json:"url"`
-	Username string `json:"username"`
-	Password string `json:"password"`
+	URL       string        `json:"url"`
+	Username  string        `json:"username"`
+	Password  string        `json:"password"`
+	Transport *APITransport `json:"transport,omitempty"`
+	Auth      *APIAuth      `json:"auth,omitempty"`
 }
 
 // APIDiscoverCalendars discovers calendars on a CalDAV server.
@@ -1004,13 +1812,25 @@ func (h *Handlers) APIDiscoverCalendars(c *gin.Context) {
 		return
 	}
 
-	if req.URL == "" || req.Username == "" || req.Password == "" {
+	if req.URL == "" || req.Username == "" || (req.Password == "" && usesPassword(req.Auth)) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "URL, username and password are required"})
 		return
 	}
 
+	opts, err := h.transportOptions(req.Transport, db.TransportSettings{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection settings: " + err.Error()})
+		return
+	}
+
+	creds, err := h.credentials(session.UserID, req.Auth, db.AuthSettings{}, req.Username, req.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication settings: " + err.Error()})
+		return
+	}
+
 	// Create CalDAV client and discover calendars
-	client, err := caldav.NewClient(req.URL, req.Username, req.Password)
+	client, err := caldav.NewClientWithCredentials(req.URL, creds, opts)
 	if err != nil {
 		log.Printf("CalDAV client creation failed for %s: %v", req.URL, err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to connect: " + categorizeConnectionError(err)})
@@ -1043,6 +1863,104 @@ func (h *Handlers) APIDiscoverCalendars(c *gin.Context) {
 	c.JSON(http.StatusOK, apiCalendars)
 }
 
+// APIDiscoverServiceRequest represents the request body for discovering a CalDAV service.
+type APIDiscoverServiceRequest struct {
+	Target     string        `json:"target"`                // Email address, domain or URL
+	SourceType string        `json:"source_type,omitempty"` // Preset to fall back to
+	Username   string        `json:"username"`              // Defaults to target when it is an email address
+	Password   string        `json:"password"`
+	Transport  *APITransport `json:"transport,omitempty"`
+	Auth       *APIAuth      `json:"auth,omitempty"`
+}
+
+// APIServiceDiscovery represents a discovered CalDAV service in JSON format.
+type APIServiceDiscovery struct {
+	URL          string         `json:"url"`
+	PrincipalURL string         `json:"principal_url"`
+	Method       string         `json:"method"`
+	Calendars    []*APICalendar `json:"calendars"`
+}
+
+// APIDiscoverService finds the CalDAV URL for an email address, domain or URL using
+// RFC 6764 discovery, falling back to the matching preset's base URL.
+func (h *Handlers) APIDiscoverService(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req APIDiscoverServiceRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	req.Target = strings.TrimSpace(req.Target)
+	if req.Username == "" && strings.Contains(req.Target, "@") && !strings.Contains(req.Target, "://") {
+		req.Username = req.Target
+	}
+	if req.Target == "" || req.Username == "" || (req.Password == "" && usesPassword(req.Auth)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email address or server, username and password are required"})
+		return
+	}
+	if len(req.Target) > maxURLLength || len(req.Username) > maxUsernameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email address, server or username is too long"})
+		return
+	}
+
+	opts, err := h.transportOptions(req.Transport, db.TransportSettings{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection settings: " + err.Error()})
+		return
+	}
+
+	creds, err := h.credentials(session.UserID, req.Auth, db.AuthSettings{}, req.Username, req.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication settings: " + err.Error()})
+		return
+	}
+
+	result, err := caldav.DiscoverService(c.Request.Context(), req.Target, creds, opts, discoveryFallbacks(db.SourceType(req.SourceType), req.Target))
+	if err != nil {
+		log.Printf("CalDAV service discovery failed for %s: %v", req.Target, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to discover server: " + categorizeConnectionError(err)})
+		return
+	}
+
+	apiCalendars := make([]*APICalendar, len(result.Calendars))
+	for i, cal := range result.Calendars {
+		apiCalendars[i] = &APICalendar{
+			Name:  cal.Name,
+			Path:  cal.Path,
+			Color: cal.Color,
+		}
+	}
+
+	c.JSON(http.StatusOK, APIServiceDiscovery{
+		URL:          result.ServiceURL,
+		PrincipalURL: result.PrincipalURL,
+		Method:       result.Method,
+		Calendars:    apiCalendars,
+	})
+}
+
+// discoveryFallbacks returns the preset base URLs to try when a domain doesn't
+// advertise its CalDAV service: the selected source type's first, then the one of
+// the provider hosting the email domain.
+func discoveryFallbacks(sourceType db.SourceType, target string) []string {
+	var urls []string
+	if preset, ok := db.SourcePresets[sourceType]; ok && preset.BaseURL != "" {
+		urls = append(urls, preset.BaseURL)
+	}
+	if i := strings.LastIndex(target, "@"); i >= 0 && !strings.Contains(target, "://") {
+		if preset, ok := db.PresetForDomain(target[i+1:]); ok && preset.BaseURL != "" && preset.Type != sourceType {
+			urls = append(urls, preset.BaseURL)
+		}
+	}
+	return urls
+}
+
 // APIAlertPreferences represents user alert preferences in JSON format.
 type APIAlertPreferences struct {
 	EmailEnabled    *bool  `json:"email_enabled"`
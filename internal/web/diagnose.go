@@ -0,0 +1,218 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/auth"
+	"github.com/macjediwizard/calbridgesync/internal/caldav"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// APIDiagnosticStage represents one stage of a connection diagnosis in JSON format.
+type APIDiagnosticStage struct {
+	Name       string   `json:"name"`
+	Status     string   `json:"status"` // ok, warning, failed or skipped
+	Message    string   `json:"message"`
+	Details    []string `json:"details,omitempty"`
+	DurationMs int64    `json:"duration_ms"`
+}
+
+// APITranscriptEntry represents one redacted HTTP exchange of a diagnosis in JSON format.
+type APITranscriptEntry struct {
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	Status          int               `json:"status,omitempty"`
+	DurationMs      int64             `json:"duration_ms"`
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	Error           string            `json:"error,omitempty"`
+}
+
+// APIDiagnosticReport represents a connection diagnosis in JSON format.
+type APIDiagnosticReport struct {
+	URL        string                `json:"url"`
+	Success    bool                  `json:"success"`
+	Stages     []*APIDiagnosticStage `json:"stages"`
+	Transcript []*APITranscriptEntry `json:"transcript"`
+}
+
+// APISourceDiagnosis holds the diagnoses of the servers of a saved source.
+type APISourceDiagnosis struct {
+	Source *APIDiagnosticReport `json:"source,omitempty"`
+	Dest   *APIDiagnosticReport `json:"dest,omitempty"`
+}
+
+// APIDiagnoseSourceRequest represents the optional request body for diagnosing a source.
+type APIDiagnoseSourceRequest struct {
+	Endpoint string `json:"endpoint,omitempty"` // source or dest; both if empty
+}
+
+// APIDiagnoseRequest represents the request body for diagnosing an unsaved server.
+type APIDiagnoseRequest struct {
+	URL       string        `json:"url"`
+	Username  string        `json:"username"`
+	Password  string        `json:"password"`
+	Endpoint  string        `json:"endpoint,omitempty"` // The destination is also tested for writes
+	Transport *APITransport `json:"transport,omitempty"`
+	Auth      *APIAuth      `json:"auth,omitempty"`
+}
+
+// diagnosticReportToAPI converts a caldav.DiagnosticReport to APIDiagnosticReport.
+func diagnosticReportToAPI(r *caldav.DiagnosticReport) *APIDiagnosticReport {
+	api := &APIDiagnosticReport{
+		URL:        r.URL,
+		Success:    r.Success,
+		Stages:     make([]*APIDiagnosticStage, len(r.Stages)),
+		Transcript: make([]*APITranscriptEntry, len(r.Transcript)),
+	}
+	for i, s := range r.Stages {
+		api.Stages[i] = &APIDiagnosticStage{
+			Name:       s.Name,
+			Status:     string(s.Status),
+			Message:    s.Message,
+			Details:    s.Details,
+			DurationMs: s.Duration.Milliseconds(),
+		}
+	}
+	for i, e := range r.Transcript {
+		api.Transcript[i] = &APITranscriptEntry{
+			Method:          e.Method,
+			URL:             e.URL,
+			Status:          e.Status,
+			DurationMs:      e.Duration.Milliseconds(),
+			RequestHeaders:  e.RequestHeaders,
+			ResponseHeaders: e.ResponseHeaders,
+			Error:           e.Error,
+		}
+	}
+	return api
+}
+
+// APIDiagnoseSource checks the connections of a saved source step by step. The
+// destination is also tested with a test event that is deleted right away.
+func (h *Handlers) APIDiagnoseSource(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req APIDiagnoseSourceRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	endpoints := []db.Endpoint{db.EndpointSource, db.EndpointDest}
+	switch db.Endpoint(req.Endpoint) {
+	case "":
+	case db.EndpointSource, db.EndpointDest:
+		endpoints = []db.Endpoint{db.Endpoint(req.Endpoint)}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Endpoint must be source or dest"})
+		return
+	}
+
+	source, err := h.db.GetSourceByIDForUser(c.Param("id"), session.UserID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load source"})
+		return
+	}
+
+	var resp APISourceDiagnosis
+	for _, endpoint := range endpoints {
+		serverURL, settings, dopts := source.SourceURL, source.SourceTransport, caldav.DiagnoseOptions{}
+		if endpoint == db.EndpointDest {
+			serverURL, settings = source.DestURL, source.DestTransport
+			dopts = caldav.DiagnoseOptions{Write: true, WriteCalendar: h.diagnosticWriteCalendar(source.ID)}
+		}
+
+		opts, err := caldav.DecryptTransportOptions(h.encryptor, settings)
+		if err != nil {
+			log.Printf("Failed to decrypt %s transport options of source %s: %v", endpoint, source.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load connection settings"})
+			return
+		}
+		creds, err := caldav.SourceCredentials(h.db, h.encryptor, source, endpoint)
+		if err != nil {
+			log.Printf("Failed to decrypt %s credentials of source %s: %v", endpoint, source.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load credentials"})
+			return
+		}
+
+		report := diagnosticReportToAPI(caldav.Diagnose(c.Request.Context(), serverURL, creds, opts, dopts))
+		if endpoint == db.EndpointDest {
+			resp.Dest = report
+		} else {
+			resp.Source = report
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// diagnosticWriteCalendar returns the destination calendar synced events are written
+// to: the first mapped one, or "" for the destination URL itself.
+func (h *Handlers) diagnosticWriteCalendar(sourceID string) string {
+	mappings, err := h.db.GetCalendarMappings(sourceID)
+	if err != nil {
+		log.Printf("Failed to get calendar mappings: %v", err)
+		return ""
+	}
+	if len(mappings) > 0 {
+		return mappings[0].DestHref
+	}
+	return ""
+}
+
+// APIDiagnose checks the connection to a server entered in the source form step by
+// step, before the source is saved.
+func (h *Handlers) APIDiagnose(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req APIDiagnoseRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if req.URL == "" || req.Username == "" || (req.Password == "" && usesPassword(req.Auth)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "URL, username and password are required"})
+		return
+	}
+	if len(req.URL) > maxURLLength || len(req.Username) > maxUsernameLength || len(req.Password) > maxPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "URL, username or password is too long"})
+		return
+	}
+	if req.Endpoint != "" && db.Endpoint(req.Endpoint) != db.EndpointSource && db.Endpoint(req.Endpoint) != db.EndpointDest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Endpoint must be source or dest"})
+		return
+	}
+
+	opts, err := h.transportOptions(req.Transport, db.TransportSettings{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection settings: " + err.Error()})
+		return
+	}
+
+	creds, err := h.credentials(session.UserID, req.Auth, db.AuthSettings{}, req.Username, req.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication settings: " + err.Error()})
+		return
+	}
+
+	dopts := caldav.DiagnoseOptions{Write: db.Endpoint(req.Endpoint) == db.EndpointDest}
+	c.JSON(http.StatusOK, diagnosticReportToAPI(caldav.Diagnose(c.Request.Context(), req.URL, creds, opts, dopts)))
+}
@@ -0,0 +1,146 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/crypto"
+)
+
+// unauthorizedServer rejects every request with 401 Unauthorized.
+func unauthorizedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="dav"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// stageStatus returns the status of the named stage of a report.
+func stageStatus(report *APIDiagnosticReport, name string) string {
+	for _, stage := range report.Stages {
+		if stage.Name == name {
+			return stage.Status
+		}
+	}
+	return ""
+}
+
+func TestAPIDiagnose(t *testing.T) {
+	t.Run("reports each stage for form data", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+		server := unauthorizedServer(t)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body := `{"url": "` + server.URL + `/dav/", "username": "alice", "password": "secret"}`
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/diagnose", strings.NewReader(body))
+		setAuthContext(c, "user-1", "test@example.com")
+		th.handlers.APIDiagnose(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var report APIDiagnosticReport
+		json.Unmarshal(w.Body.Bytes(), &report)
+		if report.Success || stageStatus(&report, "tcp") != "ok" || stageStatus(&report, "auth") != "failed" ||
+			stageStatus(&report, "principal") != "skipped" {
+			t.Errorf("unexpected report: %s", w.Body.String())
+		}
+		if len(report.Transcript) == 0 || strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("expected a redacted transcript, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("requires credentials", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/diagnose", strings.NewReader(`{"url": "https://example.com/"}`))
+		setAuthContext(c, "user-1", "test@example.com")
+		th.handlers.APIDiagnose(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestAPIDiagnoseSource(t *testing.T) {
+	t.Run("diagnoses the requested endpoint of a saved source", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+		enc, _ := crypto.NewEncryptor(make([]byte, 32))
+		th.handlers.encryptor = enc
+		server := unauthorizedServer(t)
+
+		userID, source := createTestUserAndSource(t, th.db, "test@example.com", "Test Source")
+		source.SourceURL = server.URL + "/dav/"
+		source.SourcePassword, _ = enc.Encrypt("secret")
+		th.db.UpdateSource(source)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/sources/"+source.ID+"/diagnose", strings.NewReader(`{"endpoint": "source"}`))
+		c.Params = gin.Params{{Key: "id", Value: source.ID}}
+		setAuthContext(c, userID, "test@example.com")
+		th.handlers.APIDiagnoseSource(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp APISourceDiagnosis
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Source == nil || resp.Dest != nil {
+			t.Fatalf("expected only the source to be diagnosed, got %s", w.Body.String())
+		}
+		if stageStatus(resp.Source, "auth") != "failed" {
+			t.Errorf("expected authentication to fail, got %+v", resp.Source.Stages)
+		}
+	})
+
+	t.Run("hides sources of other users", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+
+		_, source := createTestUserAndSource(t, th.db, "test@example.com", "Test Source")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/sources/"+source.ID+"/diagnose", nil)
+		c.Params = gin.Params{{Key: "id", Value: source.ID}}
+		setAuthContext(c, "other-user", "other@example.com")
+		th.handlers.APIDiagnoseSource(c)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("rejects unknown endpoints", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+
+		userID, source := createTestUserAndSource(t, th.db, "test@example.com", "Test Source")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/sources/"+source.ID+"/diagnose", strings.NewReader(`{"endpoint": "both"}`))
+		c.Params = gin.Params{{Key: "id", Value: source.ID}}
+		setAuthContext(c, userID, "test@example.com")
+		th.handlers.APIDiagnoseSource(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+}
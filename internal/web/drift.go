@@ -0,0 +1,114 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/auth"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// driftReportHistory is the number of drift reports APIGetSourceDrift returns.
+const driftReportHistory = 10
+
+// APIDriftReport represents a drift report in JSON format.
+type APIDriftReport struct {
+	ID            string             `json:"id"`
+	Status        string             `json:"status"` // ok, drift or failed
+	Message       string             `json:"message"`
+	Mismatches    int                `json:"mismatches"`
+	Threshold     int                `json:"threshold"`
+	OverThreshold bool               `json:"over_threshold"`
+	Calendars     []APICalendarDrift `json:"calendars"`
+	Duration      float64            `json:"duration"` // Seconds
+	CreatedAt     string             `json:"created_at"`
+}
+
+// APICalendarDrift represents the drift of one calendar in JSON format.
+type APICalendarDrift struct {
+	CalendarHref    string   `json:"calendar_href"`
+	CalendarName    string   `json:"calendar_name"`
+	DestHref        string   `json:"dest_href"`
+	SourceEvents    int      `json:"source_events"`
+	DestEvents      int      `json:"dest_events"`
+	MissingOnDest   int      `json:"missing_on_dest"`
+	MissingOnSource int      `json:"missing_on_source"` // Synced events gone from the source
+	Differs         int      `json:"differs"`
+	Duplicates      int      `json:"duplicates"`
+	UIDs            []string `json:"uids"` // Mismatched events, capped
+	Error           string   `json:"error,omitempty"`
+}
+
+// driftReportToAPI converts a db.DriftReport to APIDriftReport.
+func driftReportToAPI(r *db.DriftReport) *APIDriftReport {
+	api := &APIDriftReport{
+		ID:            r.ID,
+		Status:        string(r.Status),
+		Message:       r.Message,
+		Mismatches:    r.Mismatches,
+		Threshold:     r.Threshold,
+		OverThreshold: r.OverThreshold,
+		Calendars:     make([]APICalendarDrift, len(r.Calendars)),
+		Duration:      r.Duration.Seconds(),
+		CreatedAt:     r.CreatedAt.Format(time.RFC3339),
+	}
+	for i, c := range r.Calendars {
+		api.Calendars[i] = APICalendarDrift{
+			CalendarHref:    c.CalendarHref,
+			CalendarName:    c.CalendarName,
+			DestHref:        c.DestHref,
+			SourceEvents:    c.SourceEvents,
+			DestEvents:      c.DestEvents,
+			MissingOnDest:   c.MissingOnDest,
+			MissingOnSource: c.MissingOnSource,
+			Differs:         c.Differs,
+			Duplicates:      c.Duplicates,
+			UIDs:            c.UIDs,
+			Error:           c.Error,
+		}
+		if api.Calendars[i].UIDs == nil {
+			api.Calendars[i].UIDs = []string{}
+		}
+	}
+	return api
+}
+
+// APIGetSourceDrift returns the recent drift reports of a source, newest first, along
+// with its drift check settings.
+func (h *Handlers) APIGetSourceDrift(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Use timing-safe query that combines ID and user check
+	source, err := h.db.GetSourceByIDForUser(c.Param("id"), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	}
+
+	reports, err := h.db.GetDriftReports(source.ID, driftReportHistory)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get drift reports"})
+		return
+	}
+
+	apiReports := make([]*APIDriftReport, len(reports))
+	for i, r := range reports {
+		apiReports[i] = driftReportToAPI(r)
+	}
+	var latest *APIDriftReport
+	if len(apiReports) > 0 {
+		latest = apiReports[0]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"drift_check_interval": source.DriftCheckInterval,
+		"threshold":            h.cfg.Sync.DriftAlertThreshold,
+		"latest":               latest,
+		"reports":              apiReports,
+	})
+}
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/config"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+func TestValidateDriftCheckInterval(t *testing.T) {
+	tests := []struct {
+		interval int
+		valid    bool
+	}{
+		{0, true},
+		{3600, true},
+		{86400, true},
+		{60, false},
+		{-1, false},
+		{31 * 24 * 3600, false},
+	}
+
+	for _, tt := range tests {
+		if got := validateDriftCheckInterval(tt.interval) == ""; got != tt.valid {
+			t.Errorf("validateDriftCheckInterval(%d) valid = %v, want %v", tt.interval, got, tt.valid)
+		}
+	}
+}
+
+func TestAPIGetSourceDrift(t *testing.T) {
+	th := setupTestHandlers(t)
+	defer th.cleanup()
+	th.handlers.cfg = &config.Config{Sync: config.SyncConfig{DriftAlertThreshold: 10}}
+
+	userID, source := createTestUserAndSource(t, th.db, "test@example.com", "Test Source")
+	source.DriftCheckInterval = 86400
+	th.db.UpdateSource(source)
+
+	get := func(userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/sources/"+source.ID+"/drift", nil)
+		c.Params = gin.Params{{Key: "id", Value: source.ID}}
+		setAuthContext(c, userID, "test@example.com")
+		th.handlers.APIGetSourceDrift(c)
+		return w
+	}
+
+	type response struct {
+		DriftCheckInterval int               `json:"drift_check_interval"`
+		Threshold          int               `json:"threshold"`
+		Latest             *APIDriftReport   `json:"latest"`
+		Reports            []*APIDriftReport `json:"reports"`
+	}
+
+	t.Run("no reports yet", func(t *testing.T) {
+		w := get(userID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.DriftCheckInterval != 86400 || resp.Threshold != 10 || resp.Latest != nil || len(resp.Reports) != 0 {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("returns the latest report", func(t *testing.T) {
+		th.db.CreateDriftReport(&db.DriftReport{
+			SourceID:      source.ID,
+			Status:        db.DriftStatusDrift,
+			Mismatches:    12,
+			Threshold:     10,
+			OverThreshold: true,
+			Calendars: []db.CalendarDrift{
+				{CalendarHref: "/cal/work/", DestHref: "/dest/work/", MissingOnDest: 12},
+				{CalendarHref: "/cal/home/", Error: "timeout"},
+			},
+		})
+
+		w := get(userID)
+		var resp response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Latest == nil || resp.Latest.Status != "drift" || !resp.Latest.OverThreshold || len(resp.Reports) != 1 {
+			t.Fatalf("unexpected response: %s", w.Body.String())
+		}
+		if cals := resp.Latest.Calendars; len(cals) != 2 || cals[0].MissingOnDest != 12 || cals[1].Error != "timeout" {
+			t.Errorf("unexpected calendars: %+v", cals)
+		}
+	})
+
+	t.Run("hides sources of other users", func(t *testing.T) {
+		if w := get("other-user"); w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package web
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/auth"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// APIDuplicateEvent represents one event of a duplicate group in JSON format.
+type APIDuplicateEvent struct {
+	Path string `json:"path"`
+	UID  string `json:"uid"`
+}
+
+// APIDuplicateGroup represents a group of duplicate destination events awaiting review.
+type APIDuplicateGroup struct {
+	ID           string              `json:"id"`
+	CalendarHref string              `json:"calendar_href"`
+	DestHref     string              `json:"dest_href"`
+	Summary      string              `json:"summary"` // Redacted to its first characters
+	StartTime    string              `json:"start_time"`
+	Keep         APIDuplicateEvent   `json:"keep"`
+	Duplicates   []APIDuplicateEvent `json:"duplicates"` // Deleted when the group is resolved
+	DetectedAt   string              `json:"detected_at"`
+}
+
+// duplicateGroupToAPI converts a db.DuplicateGroup to APIDuplicateGroup.
+func duplicateGroupToAPI(g *db.DuplicateGroup) *APIDuplicateGroup {
+	api := &APIDuplicateGroup{
+		ID:           g.ID,
+		CalendarHref: g.CalendarHref,
+		DestHref:     g.DestHref,
+		Summary:      g.Summary,
+		StartTime:    g.StartTime,
+		Keep:         APIDuplicateEvent{Path: g.Keep.Path, UID: g.Keep.UID},
+		Duplicates:   make([]APIDuplicateEvent, len(g.Duplicates)),
+		DetectedAt:   g.DetectedAt.Format(time.RFC3339),
+	}
+	for i, d := range g.Duplicates {
+		api.Duplicates[i] = APIDuplicateEvent{Path: d.Path, UID: d.UID}
+	}
+	return api
+}
+
+// APIGetSourceDuplicates lists the duplicate groups found by the last syncs of a source
+// with the report dedupe policy.
+func (h *Handlers) APIGetSourceDuplicates(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Use timing-safe query that combines ID and user check
+	source, err := h.db.GetSourceByIDForUser(c.Param("id"), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	}
+
+	groups, err := h.db.GetDuplicateGroups(source.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load duplicates"})
+		return
+	}
+
+	apiGroups := make([]*APIDuplicateGroup, len(groups))
+	for i, g := range groups {
+		apiGroups[i] = duplicateGroupToAPI(g)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"policy": source.DedupePolicy,
+		"groups": apiGroups,
+	})
+}
+
+// APIResolveDuplicateGroup approves a reported duplicate group: its duplicates are
+// deleted from the destination and the kept event stays.
+func (h *Handlers) APIResolveDuplicateGroup(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Use timing-safe query that combines ID and user check
+	source, err := h.db.GetSourceByIDForUser(c.Param("id"), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	}
+
+	group, err := h.db.GetDuplicateGroup(source.ID, c.Param("groupId"))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Duplicate group not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load duplicate group"})
+		return
+	}
+
+	removed, err := h.syncEngine.ResolveDuplicateGroup(c.Request.Context(), source, group)
+	if err != nil {
+		log.Printf("Failed to resolve duplicate group %s of source %s: %v", group.ID, source.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to delete duplicates: " + err.Error(), "removed": removed})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Duplicates deleted", "removed": removed})
+}
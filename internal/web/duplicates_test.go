@@ -0,0 +1,87 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+func TestAPIGetSourceDuplicates(t *testing.T) {
+	t.Run("lists reported duplicate groups", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+
+		userID, source := createTestUserAndSource(t, th.db, "test@example.com", "Test Source")
+		th.db.ReplaceDuplicateGroups(source.ID, "/cal/work/", []*db.DuplicateGroup{{
+			DestHref:   "/dest/work/",
+			Summary:    "Lun…",
+			StartTime:  "20240115T120000Z",
+			Keep:       db.DuplicateEvent{Path: "/dest/work/a.ics", UID: "a"},
+			Duplicates: []db.DuplicateEvent{{Path: "/dest/work/b.ics", UID: "b"}},
+		}})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/sources/"+source.ID+"/duplicates", nil)
+		c.Params = gin.Params{{Key: "id", Value: source.ID}}
+		setAuthContext(c, userID, "test@example.com")
+		th.handlers.APIGetSourceDuplicates(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response struct {
+			Policy string               `json:"policy"`
+			Groups []*APIDuplicateGroup `json:"groups"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Policy != "auto" || len(response.Groups) != 1 {
+			t.Fatalf("unexpected response: %s", w.Body.String())
+		}
+		if g := response.Groups[0]; g.Keep.UID != "a" || len(g.Duplicates) != 1 || g.Duplicates[0].Path != "/dest/work/b.ics" {
+			t.Errorf("unexpected group: %+v", g)
+		}
+	})
+
+	t.Run("hides sources of other users", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+
+		_, source := createTestUserAndSource(t, th.db, "test@example.com", "Test Source")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/sources/"+source.ID+"/duplicates", nil)
+		c.Params = gin.Params{{Key: "id", Value: source.ID}}
+		setAuthContext(c, "other-user", "other@example.com")
+		th.handlers.APIGetSourceDuplicates(c)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestAPIResolveDuplicateGroup(t *testing.T) {
+	t.Run("returns 404 for unknown groups", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+
+		userID, source := createTestUserAndSource(t, th.db, "test@example.com", "Test Source")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/sources/"+source.ID+"/duplicates/missing/resolve", nil)
+		c.Params = gin.Params{{Key: "id", Value: source.ID}, {Key: "groupId", Value: "missing"}}
+		setAuthContext(c, userID, "test@example.com")
+		th.handlers.APIResolveDuplicateGroup(c)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package web
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/auth"
+	"github.com/macjediwizard/calbridgesync/internal/caldav"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// APIBrowsedEvent represents an event of a calendar as calbridge sees it in JSON format.
+type APIBrowsedEvent struct {
+	Path         string  `json:"path"`
+	UID          string  `json:"uid"`
+	Summary      string  `json:"summary"`
+	StartTime    string  `json:"start_time"`
+	ETag         string  `json:"etag"`
+	Synced       bool    `json:"synced"`                  // Recorded in synced_events
+	CalendarHref string  `json:"calendar_href,omitempty"` // Source calendar the event was synced from
+	SyncedAt     *string `json:"synced_at,omitempty"`
+}
+
+// APIEventDifference represents an event whose source and destination copies differ.
+type APIEventDifference struct {
+	UID        string   `json:"uid"`
+	Summary    string   `json:"summary"`
+	SourcePath string   `json:"source_path"`
+	DestPath   string   `json:"dest_path"`
+	Fields     []string `json:"fields"` // summary, dtstart, dtend, location or rrule
+}
+
+// APICalendarDiff represents the diff of a source calendar and its destination in JSON format.
+type APICalendarDiff struct {
+	CalendarHref    string               `json:"calendar_href"`
+	DestHref        string               `json:"dest_href"`
+	SourceEvents    int                  `json:"source_events"`
+	DestEvents      int                  `json:"dest_events"`
+	MissingOnDest   []APIBrowsedEvent    `json:"missing_on_dest"`
+	MissingOnSource []APIBrowsedEvent    `json:"missing_on_source"`
+	Differs         []APIEventDifference `json:"differs"`
+	Duplicates      []*APIDuplicateGroup `json:"duplicates"`
+}
+
+// browsedEventToAPI converts an event and its synced_events record, if any, to APIBrowsedEvent.
+func browsedEventToAPI(e caldav.Event, synced *db.SyncedEvent) APIBrowsedEvent {
+	api := APIBrowsedEvent{
+		Path:      e.Path,
+		UID:       e.UID,
+		Summary:   e.Summary,
+		StartTime: e.StartTime,
+		ETag:      e.ETag,
+	}
+	if synced != nil {
+		api.Synced = true
+		api.CalendarHref = synced.CalendarHref
+		ts := synced.UpdatedAt.Format(time.RFC3339)
+		api.SyncedAt = &ts
+	}
+	return api
+}
+
+// calendarDiffToAPI converts a caldav.CalendarDiff to APICalendarDiff.
+func calendarDiffToAPI(d *caldav.CalendarDiff) *APICalendarDiff {
+	api := &APICalendarDiff{
+		CalendarHref:    d.CalendarHref,
+		DestHref:        d.DestHref,
+		SourceEvents:    d.SourceEvents,
+		DestEvents:      d.DestEvents,
+		MissingOnDest:   make([]APIBrowsedEvent, len(d.MissingOnDest)),
+		MissingOnSource: make([]APIBrowsedEvent, len(d.MissingOnSource)),
+		Differs:         make([]APIEventDifference, len(d.Differs)),
+		Duplicates:      make([]*APIDuplicateGroup, len(d.Duplicates)),
+	}
+	for i, e := range d.MissingOnDest {
+		api.MissingOnDest[i] = browsedEventToAPI(e.Event, nil)
+		api.MissingOnDest[i].Synced = e.Synced
+	}
+	for i, e := range d.MissingOnSource {
+		api.MissingOnSource[i] = browsedEventToAPI(e.Event, nil)
+		api.MissingOnSource[i].Synced = e.Synced
+	}
+	for i, diff := range d.Differs {
+		fields := make([]string, len(diff.Fields))
+		for j, f := range diff.Fields {
+			fields[j] = string(f)
+		}
+		api.Differs[i] = APIEventDifference{
+			UID:        diff.UID,
+			Summary:    diff.Summary,
+			SourcePath: diff.SourcePath,
+			DestPath:   diff.DestPath,
+			Fields:     fields,
+		}
+	}
+	for i, g := range d.Duplicates {
+		api.Duplicates[i] = duplicateGroupToAPI(g)
+	}
+	return api
+}
+
+// browseEndpoint returns the endpoint named by the endpoint query parameter; source if empty.
+func browseEndpoint(c *gin.Context) (db.Endpoint, bool) {
+	endpoint := db.Endpoint(c.DefaultQuery("endpoint", string(db.EndpointSource)))
+	return endpoint, endpoint == db.EndpointSource || endpoint == db.EndpointDest
+}
+
+// APIGetSourceEvents lists the events of a source or destination calendar of a source
+// with their sync status. The calendar query parameter is the calendar's path.
+func (h *Handlers) APIGetSourceEvents(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	endpoint, ok := browseEndpoint(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint (must be 'source' or 'dest')"})
+		return
+	}
+	calendarHref := c.Query("calendar")
+	if calendarHref == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Calendar is required"})
+		return
+	}
+
+	// Use timing-safe query that combines ID and user check
+	source, err := h.db.GetSourceByIDForUser(c.Param("id"), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	}
+
+	events, err := h.syncEngine.ListCalendarEvents(c.Request.Context(), source, endpoint, calendarHref)
+	if err != nil {
+		log.Printf("Failed to list events of %s calendar %s of source %s: %v", endpoint, calendarHref, source.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to list events: " + err.Error()})
+		return
+	}
+
+	// Destination calendars may hold the events of several source calendars
+	var synced []*db.SyncedEvent
+	if endpoint == db.EndpointSource {
+		synced, err = h.db.GetSyncedEvents(source.ID, calendarHref)
+	} else {
+		synced, err = h.db.GetSyncedEventsForSource(source.ID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load sync status"})
+		return
+	}
+	syncedByUID := make(map[string]*db.SyncedEvent, len(synced))
+	for _, s := range synced {
+		syncedByUID[s.EventUID] = s
+	}
+
+	apiEvents := make([]APIBrowsedEvent, len(events))
+	for i, e := range events {
+		apiEvents[i] = browsedEventToAPI(e, syncedByUID[e.UID])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"endpoint": endpoint,
+		"calendar": calendarHref,
+		"events":   apiEvents,
+	})
+}
+
+// APIGetSourceEvent returns the iCalendar data of one event on the source or destination
+// server of a source, as the server serves it. The path query parameter is the event's path.
+func (h *Handlers) APIGetSourceEvent(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	endpoint, ok := browseEndpoint(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint (must be 'source' or 'dest')"})
+		return
+	}
+	eventPath := c.Query("path")
+	if !strings.HasPrefix(eventPath, "/") || strings.Contains(eventPath, "..") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event path"})
+		return
+	}
+
+	// Use timing-safe query that combines ID and user check
+	source, err := h.db.GetSourceByIDForUser(c.Param("id"), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	}
+
+	data, etag, err := h.syncEngine.GetCalendarEvent(c.Request.Context(), source, endpoint, eventPath)
+	if err != nil {
+		if errors.Is(err, caldav.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		log.Printf("Failed to get event %s of source %s: %v", eventPath, source.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to get event: " + err.Error()})
+		return
+	}
+
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(data))
+}
+
+// APIGetSourceDiff compares a source calendar with the destination calendar it syncs to.
+// The calendar query parameter is the source calendar's path.
+func (h *Handlers) APIGetSourceDiff(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	calendarHref := c.Query("calendar")
+	if calendarHref == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Calendar is required"})
+		return
+	}
+
+	// Use timing-safe query that combines ID and user check
+	source, err := h.db.GetSourceByIDForUser(c.Param("id"), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	}
+
+	diff, err := h.syncEngine.DiffCalendar(c.Request.Context(), source, calendarHref)
+	if err != nil {
+		log.Printf("Failed to diff calendar %s of source %s: %v", calendarHref, source.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to compare calendars: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, calendarDiffToAPI(diff))
+}
@@ -0,0 +1,57 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/caldav"
+	"github.com/macjediwizard/calbridgesync/internal/crypto"
+)
+
+func TestBrowseSourceEvents(t *testing.T) {
+	th := setupTestHandlers(t)
+	defer th.cleanup()
+
+	enc, _ := crypto.NewEncryptor(make([]byte, 32))
+	th.handlers.encryptor = enc
+	th.handlers.syncEngine = caldav.NewSyncEngine(th.db, enc)
+	userID, source := createTestUserAndSource(t, th.db, "test@example.com", "Test Source")
+
+	get := func(handler gin.HandlerFunc, userID, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/sources/"+source.ID+"/events?"+query, nil)
+		c.Params = gin.Params{{Key: "id", Value: source.ID}}
+		setAuthContext(c, userID, "test@example.com")
+		handler(c)
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		userID  string
+		query   string
+		status  int
+	}{
+		{"events with invalid endpoint", th.handlers.APIGetSourceEvents, userID, "endpoint=both&calendar=/cal/", http.StatusBadRequest},
+		{"events without calendar", th.handlers.APIGetSourceEvents, userID, "endpoint=dest", http.StatusBadRequest},
+		{"events of another user's source", th.handlers.APIGetSourceEvents, "other-user", "calendar=/cal/", http.StatusNotFound},
+		{"event with relative path", th.handlers.APIGetSourceEvent, userID, "path=cal/1.ics", http.StatusBadRequest},
+		{"event with path traversal", th.handlers.APIGetSourceEvent, userID, "path=/cal/../1.ics", http.StatusBadRequest},
+		{"event of another user's source", th.handlers.APIGetSourceEvent, "other-user", "path=/cal/1.ics", http.StatusNotFound},
+		{"diff without calendar", th.handlers.APIGetSourceDiff, userID, "", http.StatusBadRequest},
+		{"diff of another user's source", th.handlers.APIGetSourceDiff, "other-user", "calendar=/cal/", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(tt.handler, tt.userID, tt.query)
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/macjediwizard/calbridgesync/internal/caldav"
+)
+
+// grantTTL is how long credentials obtained for a source that doesn't exist yet are
+// kept while the user finishes the add source form.
+const grantTTL = 15 * time.Minute
+
+// grantStore holds credentials obtained through a login flow (Google OAuth, Nextcloud
+// Login Flow) until the add source form is submitted with the grant ID, so the
+// secrets never pass through the browser. Grants are kept in memory only.
+// The zero value is ready to use.
+type grantStore struct {
+	mu     sync.Mutex
+	grants map[string]credentialGrant
+}
+
+// credentialGrant holds credentials for a source that is still being created.
+type credentialGrant struct {
+	userID  string
+	creds   caldav.Credentials
+	expires time.Time
+}
+
+// store keeps credentials for a user and returns the grant ID.
+func (g *grantStore) store(userID string, creds caldav.Credentials) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate grant ID: %w", err)
+	}
+	id := hex.EncodeToString(b)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.grants == nil {
+		g.grants = make(map[string]credentialGrant)
+	}
+	now := time.Now()
+	for key, grant := range g.grants {
+		if now.After(grant.expires) {
+			delete(g.grants, key)
+		}
+	}
+	g.grants[id] = credentialGrant{userID: userID, creds: creds, expires: now.Add(grantTTL)}
+	return id, nil
+}
+
+// get returns the credentials of an unexpired grant of the user. Grants stay
+// available until dropped, so a failed connection test doesn't use them up.
+func (g *grantStore) get(userID, id string) (caldav.Credentials, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	grant, ok := g.grants[id]
+	if !ok || grant.userID != userID || time.Now().After(grant.expires) {
+		return caldav.Credentials{}, false
+	}
+	return grant.creds, true
+}
+
+// drop forgets the grants used by a saved source.
+func (g *grantStore) drop(auths ...*APIAuth) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, a := range auths {
+		if a != nil && a.Grant != "" {
+			delete(g.grants, a.Grant)
+		}
+	}
+}
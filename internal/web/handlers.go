@@ -27,6 +27,11 @@ type Handlers struct {
 	scheduler  *scheduler.Scheduler
 	health     *health.Checker
 	notifier   *notify.Notifier
+
+	googleOAuth *sourceOAuth // Nil unless Google OAuth is configured
+	grants      grantStore   // Credentials from login flows for sources being created
+
+	nextcloudLogins nextcloudLoginStore
 }
 
 // NewHandlers creates a new Handlers instance.
@@ -41,7 +46,7 @@ func NewHandlers(
 	healthChecker *health.Checker,
 	notifier *notify.Notifier,
 ) *Handlers {
-	return &Handlers{
+	h := &Handlers{
 		cfg:        cfg,
 		db:         database,
 		oidc:       oidc,
@@ -52,6 +57,11 @@ func NewHandlers(
 		health:     healthChecker,
 		notifier:   notifier,
 	}
+	if cfg != nil && cfg.GoogleOAuth.Enabled() {
+		provider := caldav.NewGoogleOAuthProvider(cfg.GoogleOAuth.ClientID, cfg.GoogleOAuth.ClientSecret, cfg.GoogleOAuth.RedirectURL)
+		h.googleOAuth = newSourceOAuth(provider, time.Duration(cfg.Security.OAuthStateMaxAgeSecs)*time.Second)
+	}
+	return h
 }
 
 // HealthCheck returns a full health report.
@@ -326,14 +336,14 @@ func (h *Handlers) testAndCreateSource(c *gin.Context, userID string, form *sour
 	ctx := c.Request.Context()
 
 	// Test source connection
-	if err := h.syncEngine.TestConnection(ctx, form.SourceURL, form.SourceUsername, form.SourcePassword); err != nil {
+	if err := h.syncEngine.TestConnection(ctx, form.SourceURL, caldav.BasicCredentials(form.SourceUsername, form.SourcePassword), caldav.TransportOptions{}); err != nil {
 		h.respondError(c, http.StatusBadRequest, "Failed to connect to source: "+err.Error())
 		return err
 	}
 
 	// Test destination if provided
 	if form.hasDestCredentials() {
-		if err := h.syncEngine.TestConnection(ctx, form.DestURL, form.DestUsername, form.DestPassword); err != nil {
+		if err := h.syncEngine.TestConnection(ctx, form.DestURL, caldav.BasicCredentials(form.DestUsername, form.DestPassword), caldav.TransportOptions{}); err != nil {
 			h.respondError(c, http.StatusBadRequest, "Failed to connect to destination: "+err.Error())
 			return err
 		}
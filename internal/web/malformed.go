@@ -0,0 +1,133 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/auth"
+	"github.com/macjediwizard/calbridgesync/internal/caldav"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// APIRepairRequest is the body of a malformed event repair.
+type APIRepairRequest struct {
+	Apply  bool   `json:"apply"`  // Write the repaired event; it is only previewed if false
+	Target string `json:"target"` // source or dest, required to apply
+}
+
+// APIRepairResult is a repaired malformed event.
+type APIRepairResult struct {
+	Repaired string   `json:"repaired"` // Repaired iCalendar data
+	UID      string   `json:"uid"`
+	Fixes    []string `json:"fixes"` // Repairs that were made
+	Applied  bool     `json:"applied"`
+	Target   string   `json:"target,omitempty"`
+}
+
+// APIGetMalformedEventRaw returns the payload of a malformed event as the source served it.
+func (h *Handlers) APIGetMalformedEventRaw(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Use timing-safe query that combines ID and user check
+	event, err := h.db.GetMalformedEventByIDForUser(c.Param("id"), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Malformed event not found"})
+		return
+	}
+
+	raw, err := h.syncEngine.MalformedEventPayload(event)
+	if err != nil {
+		if errors.Is(err, caldav.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Raw payload of this event wasn't stored"})
+			return
+		}
+		log.Printf("Failed to read payload of malformed event %s: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read raw payload"})
+		return
+	}
+
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(raw))
+}
+
+// APIRepairMalformedEvent repairs the stored payload of a malformed event. Without apply
+// the repaired event is only returned for preview. Applied to the source, the event on
+// the source is replaced if it hasn't changed since it was found; applied to the
+// destination, the repaired event is synced there and the source is left as is.
+func (h *Handlers) APIRepairMalformedEvent(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req APIRepairRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	target := caldav.RepairTarget(req.Target)
+	if req.Apply && target != caldav.RepairToSource && target != caldav.RepairToDest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target (must be 'source' or 'dest')"})
+		return
+	}
+
+	// Use timing-safe query that combines ID and user check
+	event, err := h.db.GetMalformedEventByIDForUser(c.Param("id"), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Malformed event not found"})
+		return
+	}
+
+	var result *caldav.RepairResult
+	if req.Apply {
+		var source *db.Source
+		source, err = h.db.GetSourceByIDForUser(event.SourceID, session.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+			return
+		}
+		result, err = h.syncEngine.ApplyMalformedRepair(c.Request.Context(), source, event, target)
+		if err != nil && !errors.Is(err, caldav.ErrNotRepairable) && !errors.Is(err, caldav.ErrNotFound) {
+			log.Printf("Failed to apply repair of malformed event %s: %v", event.ID, err)
+			if errors.Is(err, caldav.ErrPreconditionFailed) {
+				c.JSON(http.StatusConflict, gin.H{"error": "The event changed on the source since it was found; sync again and retry"})
+				return
+			}
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to write repaired event: " + categorizeConnectionError(err)})
+			return
+		}
+	} else {
+		result, err = h.syncEngine.RepairMalformedEvent(event)
+	}
+	if errors.Is(err, caldav.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, caldav.ErrNotRepairable) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to repair malformed event %s: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to repair event"})
+		return
+	}
+
+	response := APIRepairResult{
+		Repaired: result.Event.Data,
+		UID:      result.Event.UID,
+		Fixes:    result.Fixes,
+		Applied:  req.Apply,
+	}
+	if req.Apply {
+		response.Target = req.Target
+	}
+	c.JSON(http.StatusOK, response)
+}
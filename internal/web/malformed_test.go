@@ -0,0 +1,156 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/caldav"
+	"github.com/macjediwizard/calbridgesync/internal/crypto"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// brokenEventData has bare line feeds and no DTSTAMP.
+const brokenEventData = "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:-//Test//EN\nBEGIN:VEVENT\nUID:event-1\nDTSTART:20240101T100000Z\nSUMMARY:Standup\nEND:VEVENT\nEND:VCALENDAR\n"
+
+// setupMalformedTest records a malformed event with brokenEventData as its payload.
+func setupMalformedTest(t *testing.T) (*testHandlers, string, *db.MalformedEvent) {
+	t.Helper()
+	th := setupTestHandlers(t)
+	t.Cleanup(th.cleanup)
+
+	enc, _ := crypto.NewEncryptor(make([]byte, 32))
+	th.handlers.encryptor = enc
+	th.handlers.syncEngine = caldav.NewSyncEngine(th.db, enc)
+
+	userID, source := createTestUserAndSource(t, th.db, "test@example.com", "Test Source")
+	payload, _ := enc.Encrypt(brokenEventData)
+	event := &db.MalformedEvent{
+		SourceID:     source.ID,
+		EventPath:    "/cal/work/1.ics",
+		ErrorMessage: "empty iCalendar data",
+		RawPayload:   payload,
+		EventUID:     "event-1",
+		Repairable:   true,
+	}
+	if err := th.db.RecordMalformedEvent(event); err != nil {
+		t.Fatalf("failed to record malformed event: %v", err)
+	}
+	return th, userID, event
+}
+
+func TestAPIGetMalformedEventRaw(t *testing.T) {
+	t.Run("returns the decrypted payload", func(t *testing.T) {
+		th, userID, event := setupMalformedTest(t)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/malformed-events/"+event.ID+"/raw", nil)
+		c.Params = gin.Params{{Key: "id", Value: event.ID}}
+		setAuthContext(c, userID, "test@example.com")
+		th.handlers.APIGetMalformedEventRaw(c)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/calendar") || w.Body.String() != brokenEventData {
+			t.Errorf("unexpected response %q: %q", w.Header().Get("Content-Type"), w.Body.String())
+		}
+	})
+
+	t.Run("hides events of other users", func(t *testing.T) {
+		th, _, event := setupMalformedTest(t)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/malformed-events/"+event.ID+"/raw", nil)
+		c.Params = gin.Params{{Key: "id", Value: event.ID}}
+		setAuthContext(c, "other-user", "other@example.com")
+		th.handlers.APIGetMalformedEventRaw(c)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestAPIRepairMalformedEvent(t *testing.T) {
+	repair := func(th *testHandlers, userID, eventID, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/malformed-events/"+eventID+"/repair", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: eventID}}
+		setAuthContext(c, userID, "test@example.com")
+		th.handlers.APIRepairMalformedEvent(c)
+		return w
+	}
+
+	t.Run("previews the repaired event", func(t *testing.T) {
+		th, userID, event := setupMalformedTest(t)
+
+		w := repair(th, userID, event.ID, `{}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var result APIRepairResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		if result.Applied || result.UID != "event-1" || !strings.Contains(result.Repaired, "DTSTAMP:") {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if len(result.Fixes) != 2 || result.Fixes[0] != caldav.RepairLineEndings || result.Fixes[1] != caldav.RepairMissingDTStamp {
+			t.Errorf("unexpected fixes: %v", result.Fixes)
+		}
+	})
+
+	t.Run("rejects applying without a target", func(t *testing.T) {
+		th, userID, event := setupMalformedTest(t)
+
+		w := repair(th, userID, event.ID, `{"apply": true}`)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("reports events that can't be repaired", func(t *testing.T) {
+		th, userID, _ := setupMalformedTest(t)
+		events, _ := th.db.GetMalformedEvents(userID)
+		payload, _ := th.handlers.encryptor.Encrypt("<html>Not found</html>")
+		broken := &db.MalformedEvent{SourceID: events[0].SourceID, EventPath: "/cal/work/2.ics", ErrorMessage: "missing colon", RawPayload: payload}
+		th.db.RecordMalformedEvent(broken)
+
+		w := repair(th, userID, broken.ID, `{}`)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("reports events without a stored payload", func(t *testing.T) {
+		th, userID, _ := setupMalformedTest(t)
+		events, _ := th.db.GetMalformedEvents(userID)
+		th.db.SaveMalformedEvent(events[0].SourceID, "/cal/work/3.ics", "missing colon")
+		events, _ = th.db.GetMalformedEvents(userID)
+
+		var bare *db.MalformedEvent
+		for _, e := range events {
+			if e.RawPayload == "" {
+				bare = e
+			}
+		}
+		w := repair(th, userID, bare.ID, `{}`)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+	t.Run("reports applying to an unknown destination", func(t *testing.T) {
+		th, userID, event := setupMalformedTest(t)
+
+		w := repair(th, userID, event.ID, `{"apply": true, "target": "dest"}`)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
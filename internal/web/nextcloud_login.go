@@ -0,0 +1,283 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/macjediwizard/calbridgesync/internal/auth"
+	"github.com/macjediwizard/calbridgesync/internal/caldav"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+	"github.com/macjediwizard/calbridgesync/internal/validator"
+)
+
+// nextcloudLoginTTL matches how long Nextcloud keeps a Login Flow v2 open.
+const nextcloudLoginTTL = 20 * time.Minute
+
+// nextcloudLoginStore tracks Nextcloud logins started by users until they are polled
+// to completion. The zero value is ready to use.
+type nextcloudLoginStore struct {
+	mu     sync.Mutex
+	logins map[string]nextcloudLogin
+}
+
+// nextcloudLogin is a login flow waiting for the user to approve it in Nextcloud.
+type nextcloudLogin struct {
+	userID   string
+	sourceID string // Empty when connecting an account for a new source
+	endpoint db.Endpoint
+	host     string // Host the flow was started against; the login must name the same
+	flow     *caldav.NextcloudLoginFlow
+	opts     caldav.TransportOptions
+	expires  time.Time
+}
+
+// add stores a login and returns its ID.
+func (s *nextcloudLoginStore) add(login nextcloudLogin) string {
+	id := uuid.New().String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.logins == nil {
+		s.logins = make(map[string]nextcloudLogin)
+	}
+	now := time.Now()
+	for key, l := range s.logins {
+		if now.After(l.expires) {
+			delete(s.logins, key)
+		}
+	}
+	s.logins[id] = login
+	return id
+}
+
+// get returns an unexpired login of the user.
+func (s *nextcloudLoginStore) get(id, userID string) (nextcloudLogin, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	login, ok := s.logins[id]
+	if !ok || login.userID != userID || time.Now().After(login.expires) {
+		return nextcloudLogin{}, false
+	}
+	return login, true
+}
+
+// remove forgets a login once it completed.
+func (s *nextcloudLoginStore) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.logins, id)
+}
+
+// APIStartNextcloudLoginRequest represents the request body for starting a Nextcloud login.
+type APIStartNextcloudLoginRequest struct {
+	ServerURL string        `json:"server_url"`          // Nextcloud base URL or any URL below it; defaults to the source's URL
+	SourceID  string        `json:"source_id,omitempty"` // Replaces the credentials of an existing source
+	Endpoint  string        `json:"endpoint,omitempty"`  // "source" (default) or "dest"
+	Transport *APITransport `json:"transport,omitempty"` // For new sources; existing sources use their stored settings
+}
+
+// APIStartNextcloudLogin starts a Nextcloud Login Flow v2 so the user can grant calbridge
+// an app password instead of entering their password. The client opens login_url and
+// then polls APIPollNextcloudLogin.
+func (h *Handlers) APIStartNextcloudLogin(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req APIStartNextcloudLoginRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	endpoint := db.Endpoint(req.Endpoint)
+	if endpoint == "" {
+		endpoint = db.EndpointSource
+	}
+	if endpoint != db.EndpointSource && endpoint != db.EndpointDest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint"})
+		return
+	}
+
+	var opts caldav.TransportOptions
+	if req.SourceID != "" {
+		source, err := h.db.GetSourceByIDForUser(req.SourceID, session.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+			return
+		}
+		if endpoint == db.EndpointSource && source.SourceType != db.SourceTypeNextcloud {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Nextcloud login is only available for Nextcloud sources"})
+			return
+		}
+
+		settings, serverURL := source.SourceTransport, source.SourceURL
+		if endpoint == db.EndpointDest {
+			settings, serverURL = source.DestTransport, source.DestURL
+		}
+		if req.ServerURL == "" {
+			req.ServerURL = serverURL
+		}
+		if opts, err = caldav.DecryptTransportOptions(h.encryptor, settings); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decrypt connection settings"})
+			return
+		}
+	} else {
+		var err error
+		if opts, err = h.transportOptions(req.Transport, db.TransportSettings{}); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection settings: " + err.Error()})
+			return
+		}
+	}
+
+	if req.ServerURL == "" || len(req.ServerURL) > maxURLLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid server URL is required"})
+		return
+	}
+
+	flow, err := caldav.StartNextcloudLogin(c.Request.Context(), req.ServerURL, opts)
+	if err != nil {
+		log.Printf("Nextcloud login flow failed to start for %s: %v", req.ServerURL, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to start Nextcloud login: " + categorizeConnectionError(err)})
+		return
+	}
+
+	// StartNextcloudLogin only succeeds for URLs that parse with a host
+	serverURL, _ := url.Parse(req.ServerURL)
+
+	expires := time.Now().Add(nextcloudLoginTTL)
+	id := h.nextcloudLogins.add(nextcloudLogin{
+		userID:   session.UserID,
+		sourceID: req.SourceID,
+		endpoint: endpoint,
+		host:     serverURL.Host,
+		flow:     flow,
+		opts:     opts,
+		expires:  expires,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"login_id":   id,
+		"login_url":  flow.LoginURL,
+		"expires_at": expires.UTC().Format(time.RFC3339),
+	})
+}
+
+// APIPollNextcloudLogin checks whether the user approved a Nextcloud login. While they
+// haven't, it answers 202 with status "pending". Once they have, the app password is
+// stored on the source, or kept as a grant for the add source form to submit together
+// with the returned CalDAV URL and username. The server Nextcloud names must be on the
+// host the login was started against.
+func (h *Handlers) APIPollNextcloudLogin(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	pending, ok := h.nextcloudLogins.get(id, session.UserID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Login not found or expired"})
+		return
+	}
+
+	login, err := caldav.PollNextcloudLogin(c.Request.Context(), pending.flow, pending.opts)
+	if errors.Is(err, caldav.ErrLoginPending) {
+		c.JSON(http.StatusAccepted, gin.H{"status": "pending"})
+		return
+	}
+	if err != nil {
+		log.Printf("Nextcloud login poll failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to check Nextcloud login: " + categorizeConnectionError(err)})
+		return
+	}
+	h.nextcloudLogins.remove(id)
+
+	caldavURL := login.CalDAVURL()
+	if len(caldavURL) > maxURLLength || len(login.LoginName) > maxUsernameLength ||
+		validator.New().ValidateURL(caldavURL, false) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nextcloud returned an unsupported server URL or username"})
+		return
+	}
+	if u, _ := url.Parse(caldavURL); !strings.EqualFold(u.Host, pending.host) {
+		log.Printf("Nextcloud login for %s named another server: %s", pending.host, u.Host)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nextcloud returned a server on another host"})
+		return
+	}
+	creds := caldav.BasicCredentials(login.LoginName, login.AppPassword)
+
+	if pending.sourceID == "" {
+		grantID, err := h.grants.store(session.UserID, creds)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store login"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":   "complete",
+			"grant":    grantID,
+			"url":      caldavURL,
+			"username": login.LoginName,
+		})
+		return
+	}
+
+	source, err := h.db.GetSourceByIDForUser(pending.sourceID, session.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	}
+
+	encPassword, err := h.encryptor.Encrypt(login.AppPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
+		return
+	}
+
+	oldURL, oldUsername := source.SourceURL, source.SourceUsername
+	if pending.endpoint == db.EndpointDest {
+		oldURL, oldUsername = source.DestURL, source.DestUsername
+		source.DestURL = nextcloudEndpointURL(source.DestURL, caldavURL)
+		source.DestUsername, source.DestPassword = login.LoginName, encPassword
+		source.DestAuth = db.AuthSettings{}
+	} else {
+		source.SourceURL = nextcloudEndpointURL(source.SourceURL, caldavURL)
+		source.SourceUsername, source.SourcePassword = login.LoginName, encPassword
+		source.SourceAuth = db.AuthSettings{}
+	}
+
+	if err := h.db.UpdateSource(source); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update source"})
+		return
+	}
+	if h.syncEngine != nil {
+		h.syncEngine.InvalidateClients(oldURL, oldUsername)
+	}
+	h.resetAuthCircuitBreaker(source)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "complete",
+		"source": h.sourceToAPIWithScheduler(source),
+	})
+}
+
+// nextcloudEndpointURL returns the URL to store for an endpoint after a Nextcloud login.
+// An existing URL at or below the CalDAV base URL is kept, so the login only replaces
+// the credentials; otherwise the base URL is used.
+func nextcloudEndpointURL(existing, caldavURL string) string {
+	if strings.HasPrefix(strings.TrimRight(existing, "/")+"/", caldavURL) {
+		return existing
+	}
+	return caldavURL
+}
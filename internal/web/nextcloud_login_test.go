@@ -0,0 +1,216 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/crypto"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+)
+
+// nextcloudLoginServer stands in for a Nextcloud server whose user already approved
+// the login after the first poll. The login names loginServer, or the server itself
+// if it's empty.
+func nextcloudLoginServer(t *testing.T, loginServer string) *httptest.Server {
+	t.Helper()
+	polls := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.php/login/v2":
+			w.Write([]byte(`{"poll":{"token":"poll-token","endpoint":"` + server.URL + `/index.php/login/v2/poll"},"login":"` + server.URL + `/login"}`))
+		case "/index.php/login/v2/poll":
+			if polls++; polls == 1 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if loginServer == "" {
+				loginServer = server.URL
+			}
+			w.Write([]byte(`{"server":"` + loginServer + `","loginName":"alice","appPassword":"app-password"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// startNextcloudLogin starts a login and returns its ID.
+func startNextcloudLogin(t *testing.T, th *testHandlers, userID, body string) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/nextcloud/login", strings.NewReader(body))
+	setAuthContext(c, userID, "test@example.com")
+	th.handlers.APIStartNextcloudLogin(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		LoginID  string `json:"login_id"`
+		LoginURL string `json:"login_url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.LoginID == "" || resp.LoginURL == "" {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+	return resp.LoginID
+}
+
+// pollNextcloudLogin polls a login and returns the recorder.
+func pollNextcloudLogin(th *testHandlers, userID, loginID string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/nextcloud/login/"+loginID+"/poll", nil)
+	c.Params = gin.Params{{Key: "id", Value: loginID}}
+	setAuthContext(c, userID, "test@example.com")
+	th.handlers.APIPollNextcloudLogin(c)
+	return w
+}
+
+func TestNextcloudLoginFlow(t *testing.T) {
+	t.Run("keeps a grant for a new source", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+		server := nextcloudLoginServer(t, "")
+
+		user, _ := th.db.GetOrCreateUser("test@example.com", "Test User")
+		loginID := startNextcloudLogin(t, th, user.ID, `{"server_url": "`+server.URL+`"}`)
+
+		if w := pollNextcloudLogin(th, "other-user", loginID); w.Code != http.StatusNotFound {
+			t.Errorf("expected login of another user to be hidden, got %d", w.Code)
+		}
+		if w := pollNextcloudLogin(th, user.ID, loginID); w.Code != http.StatusAccepted {
+			t.Fatalf("expected pending login, got %d: %s", w.Code, w.Body.String())
+		}
+
+		w := pollNextcloudLogin(th, user.ID, loginID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Status   string `json:"status"`
+			Grant    string `json:"grant"`
+			URL      string `json:"url"`
+			Username string `json:"username"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Status != "complete" || resp.URL != server.URL+"/remote.php/dav/" || resp.Username != "alice" {
+			t.Fatalf("unexpected response: %s", w.Body.String())
+		}
+
+		creds, err := th.handlers.credentials(user.ID, &APIAuth{Grant: resp.Grant}, db.AuthSettings{}, "alice", "")
+		if err != nil {
+			t.Fatalf("expected grant to resolve, got %v", err)
+		}
+		if creds.Password != "app-password" {
+			t.Errorf("expected app password, got %+v", creds)
+		}
+		if _, err := th.handlers.credentials(user.ID, &APIAuth{Grant: resp.Grant}, db.AuthSettings{}, "bob", ""); err == nil {
+			t.Error("expected grant to be rejected for another username")
+		}
+
+		if w := pollNextcloudLogin(th, user.ID, loginID); w.Code != http.StatusNotFound {
+			t.Errorf("expected completed login to be forgotten, got %d", w.Code)
+		}
+	})
+
+	t.Run("updates an existing source", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+		enc, _ := crypto.NewEncryptor(make([]byte, 32))
+		th.handlers.encryptor = enc
+		server := nextcloudLoginServer(t, "")
+
+		userID, source := createTestUserAndSource(t, th.db, "test@example.com", "Nextcloud")
+		source.SourceType = db.SourceTypeNextcloud
+		source.SourceURL = server.URL + "/remote.php/dav/calendars/old/"
+		th.db.UpdateSource(source)
+		th.db.UpdateSourceSyncStatus(source.ID, db.SyncStatusCredentialsInvalid, "Paused")
+
+		loginID := startNextcloudLogin(t, th, userID, `{"source_id": "`+source.ID+`"}`)
+		pollNextcloudLogin(th, userID, loginID)
+		if w := pollNextcloudLogin(th, userID, loginID); w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		stored, _ := th.db.GetSourceByID(source.ID)
+		if stored.SourceURL != server.URL+"/remote.php/dav/calendars/old/" || stored.SourceUsername != "alice" {
+			t.Errorf("unexpected source server: %s as %s", stored.SourceURL, stored.SourceUsername)
+		}
+		if password, _ := enc.Decrypt(stored.SourcePassword); password != "app-password" {
+			t.Errorf("expected encrypted app password, got %q", password)
+		}
+		if stored.LastSyncStatus != db.SyncStatusPending {
+			t.Errorf("expected paused source to resume, got %s", stored.LastSyncStatus)
+		}
+	})
+
+	t.Run("rejects a login naming another host", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+		enc, _ := crypto.NewEncryptor(make([]byte, 32))
+		th.handlers.encryptor = enc
+		server := nextcloudLoginServer(t, "https://attacker.example.com")
+
+		userID, source := createTestUserAndSource(t, th.db, "test@example.com", "Nextcloud")
+		source.SourceType = db.SourceTypeNextcloud
+		source.SourceURL = server.URL + "/remote.php/dav/"
+		th.db.UpdateSource(source)
+
+		loginID := startNextcloudLogin(t, th, userID, `{"source_id": "`+source.ID+`"}`)
+		pollNextcloudLogin(th, userID, loginID)
+		if w := pollNextcloudLogin(th, userID, loginID); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d: %s", w.Code, w.Body.String())
+		}
+
+		stored, _ := th.db.GetSourceByID(source.ID)
+		if stored.SourceURL != source.SourceURL || stored.SourceUsername != source.SourceUsername {
+			t.Errorf("expected source to be unchanged, got %s as %s", stored.SourceURL, stored.SourceUsername)
+		}
+	})
+
+	t.Run("rejects sources that aren't Nextcloud", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+
+		userID, source := createTestUserAndSource(t, th.db, "test@example.com", "Custom")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/nextcloud/login", strings.NewReader(`{"source_id": "`+source.ID+`"}`))
+		setAuthContext(c, userID, "test@example.com")
+		th.handlers.APIStartNextcloudLogin(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestNextcloudEndpointURL(t *testing.T) {
+	const base = "https://cloud.example.com/remote.php/dav/"
+	tests := []struct {
+		existing string
+		expected string
+	}{
+		{"https://cloud.example.com/remote.php/dav/calendars/alice/", "https://cloud.example.com/remote.php/dav/calendars/alice/"},
+		{"https://cloud.example.com/remote.php/dav", "https://cloud.example.com/remote.php/dav"},
+		{"https://cloud.example.com/nextcloud/remote.php/dav/", base},
+		{"https://cloud.example.com/remote.php/davx/", base},
+		{"", base},
+	}
+
+	for _, tt := range tests {
+		if got := nextcloudEndpointURL(tt.existing, base); got != tt.expected {
+			t.Errorf("nextcloudEndpointURL(%q) = %q, want %q", tt.existing, got, tt.expected)
+		}
+	}
+}
@@ -33,6 +33,7 @@ func SetupRoutes(r *gin.Engine, h *Handlers, sm *auth.SessionManager) {
 		authGroup.POST("/login", h.Login)
 		authGroup.GET("/callback", h.Callback)
 		authGroup.POST("/logout", h.Logout)
+		authGroup.GET("/google/callback", auth.RequireAuth(sm), h.GoogleOAuthCallback) // Connects Google Calendar sources
 	}
 
 	// General API routes - 30 req/s handles typical SPA usage (page loads fetch multiple endpoints)
@@ -60,10 +61,18 @@ func SetupRoutes(r *gin.Engine, h *Handlers, sm *auth.SessionManager) {
 		protectedAPI.DELETE("/sources/:id", h.APIDeleteSource)
 		protectedAPI.POST("/sources/:id/toggle", h.APIToggleSource)
 		protectedAPI.POST("/sources/:id/sync", h.APITriggerSync)
+		protectedAPI.POST("/sources/:id/sync/cancel", h.APICancelSync)
 		protectedAPI.GET("/sources/:id/logs", h.APIGetSourceLogs)
+		protectedAPI.GET("/sources/:id/changes", h.APIGetSourceChanges)
+		protectedAPI.GET("/sources/:id/duplicates", h.APIGetSourceDuplicates)
+		protectedAPI.GET("/sources/:id/drift", h.APIGetSourceDrift)
+		protectedAPI.DELETE("/sources/:id/oauth", h.APIDisconnectOAuth)
+		protectedAPI.POST("/oauth/google/start", h.APIStartGoogleOAuth)
+		protectedAPI.POST("/nextcloud/login/:id/poll", h.APIPollNextcloudLogin)
 		protectedAPI.GET("/malformed-events", h.APIGetMalformedEvents)
 		protectedAPI.DELETE("/malformed-events", h.APIDeleteAllMalformedEvents)
 		protectedAPI.DELETE("/malformed-events/:id", h.APIDeleteMalformedEvent)
+		protectedAPI.GET("/malformed-events/:id/raw", h.APIGetMalformedEventRaw)
 		protectedAPI.GET("/settings/alerts", h.APIGetAlertPreferences)
 		protectedAPI.PUT("/settings/alerts", h.APIUpdateAlertPreferences)
 		protectedAPI.GET("/activity", h.APIGetActivity)
@@ -80,7 +89,22 @@ func SetupRoutes(r *gin.Engine, h *Handlers, sm *auth.SessionManager) {
 	{
 		expensiveAPI.POST("/sources", h.APICreateSource)                      // Tests connections to CalDAV servers
 		expensiveAPI.POST("/calendars/discover", h.APIDiscoverCalendars)      // Discovers calendars via network
+		expensiveAPI.POST("/discover", h.APIDiscoverService)                  // Looks up DNS records and probes servers
+		expensiveAPI.POST("/diagnose", h.APIDiagnose)                         // Checks a connection step by step
+		expensiveAPI.POST("/sources/:id/diagnose", h.APIDiagnoseSource)       // Checks a source's connections step by step
+		expensiveAPI.POST("/nextcloud/login", h.APIStartNextcloudLogin)       // Starts a login flow on the Nextcloud server
 		expensiveAPI.POST("/settings/alerts/test-webhook", h.APITestWebhook)  // Tests webhook via network
+
+		// Deletes reviewed duplicates from the destination
+		expensiveAPI.POST("/sources/:id/duplicates/:groupId/resolve", h.APIResolveDuplicateGroup)
+
+		// Read-only views of the calendars of a source as calbridge sees them
+		expensiveAPI.GET("/sources/:id/events", h.APIGetSourceEvents)
+		expensiveAPI.GET("/sources/:id/events/raw", h.APIGetSourceEvent)
+		expensiveAPI.GET("/sources/:id/diff", h.APIGetSourceDiff)
+
+		// Previews a repaired malformed event, or writes it to the source or destination
+		expensiveAPI.POST("/malformed-events/:id/repair", h.APIRepairMalformedEvent)
 	}
 
 	// Serve React app static files
@@ -0,0 +1,292 @@
+package web
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/auth"
+	"github.com/macjediwizard/calbridgesync/internal/caldav"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+	"golang.org/x/oauth2"
+)
+
+// sourceOAuth tracks OAuth authorization code flows that connect sources to a calendar
+// provider account. Pending flows are kept in memory only, so a restart just asks the
+// user to connect again.
+type sourceOAuth struct {
+	provider *caldav.OAuthProvider
+	stateTTL time.Duration
+
+	mu    sync.Mutex
+	flows map[string]oauthFlow // By state parameter
+}
+
+// oauthFlow is an authorization started by a user and not yet completed.
+type oauthFlow struct {
+	userID   string
+	sourceID string // Empty when connecting an account for a new source
+	endpoint db.Endpoint
+	verifier string // PKCE code verifier
+	expires  time.Time
+}
+
+// newSourceOAuth creates the flow tracker for a provider.
+func newSourceOAuth(provider *caldav.OAuthProvider, stateTTL time.Duration) *sourceOAuth {
+	return &sourceOAuth{
+		provider: provider,
+		stateTTL: stateTTL,
+		flows:    make(map[string]oauthFlow),
+	}
+}
+
+// begin starts a flow and returns the provider URL to send the user to.
+func (o *sourceOAuth) begin(userID, sourceID string, endpoint db.Endpoint) (string, error) {
+	state, err := auth.GenerateState()
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.prune(time.Now())
+	o.flows[state] = oauthFlow{
+		userID:   userID,
+		sourceID: sourceID,
+		endpoint: endpoint,
+		verifier: verifier,
+		expires:  time.Now().Add(o.stateTTL),
+	}
+	return o.provider.AuthCodeURL(state, verifier), nil
+}
+
+// finish removes and returns the flow for a state, if it belongs to the user and
+// hasn't expired.
+func (o *sourceOAuth) finish(state, userID string) (oauthFlow, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	flow, ok := o.flows[state]
+	delete(o.flows, state)
+	if !ok || flow.userID != userID || time.Now().After(flow.expires) {
+		return oauthFlow{}, false
+	}
+	return flow, true
+}
+
+// prune removes expired flows. The caller holds o.mu.
+func (o *sourceOAuth) prune(now time.Time) {
+	for state, flow := range o.flows {
+		if now.After(flow.expires) {
+			delete(o.flows, state)
+		}
+	}
+}
+
+// APIStartOAuthRequest represents the request body for starting an OAuth connection.
+type APIStartOAuthRequest struct {
+	SourceID string `json:"source_id,omitempty"` // Reconnects an existing source; omit for a new source
+	Endpoint string `json:"endpoint,omitempty"`  // "source" (default) or "dest"
+}
+
+// APIStartGoogleOAuth starts connecting a Google account. The client sends the user to
+// the returned URL; Google then redirects back to GoogleOAuthCallback.
+func (h *Handlers) APIStartGoogleOAuth(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if h.googleOAuth == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Google OAuth is not configured"})
+		return
+	}
+
+	var req APIStartOAuthRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	endpoint := db.Endpoint(req.Endpoint)
+	if endpoint == "" {
+		endpoint = db.EndpointSource
+	}
+	if endpoint != db.EndpointSource && endpoint != db.EndpointDest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint"})
+		return
+	}
+
+	if req.SourceID != "" {
+		if _, err := h.db.GetSourceByIDForUser(req.SourceID, session.UserID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+			return
+		}
+	}
+
+	authURL, err := h.googleOAuth.begin(session.UserID, req.SourceID, endpoint)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start authorization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"auth_url": authURL})
+}
+
+// GoogleOAuthCallback completes a Google connection started with APIStartGoogleOAuth.
+// Tokens for an existing source are stored on it right away; tokens for a new source
+// are kept as a grant that the add source form submits. Either way the user is sent
+// back to the source form with the outcome in the query string.
+func (h *Handlers) GoogleOAuthCallback(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil || h.googleOAuth == nil {
+		c.Redirect(http.StatusFound, "/sources")
+		return
+	}
+
+	flow, ok := h.googleOAuth.finish(c.Query("state"), session.UserID)
+	if !ok {
+		c.Redirect(http.StatusFound, "/sources?"+url.Values{"oauth_error": {"Authorization expired, please try again"}}.Encode())
+		return
+	}
+
+	returnTo := "/sources/add"
+	if flow.sourceID != "" {
+		returnTo = "/sources/" + url.PathEscape(flow.sourceID) + "/edit"
+	}
+	fail := func(message string) {
+		c.Redirect(http.StatusFound, returnTo+"?"+url.Values{"oauth_error": {message}}.Encode())
+	}
+
+	if errParam := c.Query("error"); errParam != "" {
+		fail("Authorization was not granted: " + errParam)
+		return
+	}
+
+	creds, err := h.googleOAuth.provider.Exchange(c.Request.Context(), c.Query("code"), flow.verifier)
+	if err != nil {
+		log.Printf("Google OAuth code exchange failed: %v", err)
+		fail("Failed to complete authorization: " + categorizeConnectionError(err))
+		return
+	}
+
+	if flow.sourceID == "" {
+		grantID, err := h.grants.store(session.UserID, creds)
+		if err != nil {
+			fail("Failed to complete authorization")
+			return
+		}
+		c.Redirect(http.StatusFound, returnTo+"?"+url.Values{"oauth_grant": {grantID}, "endpoint": {string(flow.endpoint)}}.Encode())
+		return
+	}
+
+	source, err := h.db.GetSourceByIDForUser(flow.sourceID, session.UserID)
+	if err != nil {
+		fail("Source not found")
+		return
+	}
+	if err := h.storeSourceOAuth(source, flow.endpoint, creds); err != nil {
+		log.Printf("Failed to store OAuth tokens for source %s: %v", source.ID, err)
+		fail("Failed to save authorization")
+		return
+	}
+
+	c.Redirect(http.StatusFound, returnTo+"?oauth=connected")
+}
+
+// storeSourceOAuth saves new OAuth credentials for one server of a source and resumes
+// the source if it was paused because the previous grant stopped working.
+func (h *Handlers) storeSourceOAuth(source *db.Source, endpoint db.Endpoint, creds caldav.Credentials) error {
+	settings, err := caldav.EncryptCredentials(h.encryptor, creds)
+	if err != nil {
+		return err
+	}
+	if err := h.db.UpdateSourceAuth(source.ID, endpoint, settings); err != nil {
+		return err
+	}
+
+	if h.syncEngine != nil {
+		if endpoint == db.EndpointDest {
+			h.syncEngine.InvalidateClients(source.DestURL, source.DestUsername)
+		} else {
+			h.syncEngine.InvalidateClients(source.SourceURL, source.SourceUsername)
+		}
+	}
+	h.resetAuthCircuitBreaker(source)
+	return nil
+}
+
+// reconnectRequired reports whether an OAuth account of a source has to be connected
+// again: it was disconnected, or the circuit breaker paused the source after the
+// provider rejected the grant.
+func reconnectRequired(s *db.Source) bool {
+	for _, a := range []db.AuthSettings{s.SourceAuth, s.DestAuth} {
+		if a.Method != db.AuthMethodOAuth2 {
+			continue
+		}
+		if (a.RefreshToken == "" && a.AccessToken == "") || s.LastSyncStatus == db.SyncStatusCredentialsInvalid {
+			return true
+		}
+	}
+	return false
+}
+
+// APIDisconnectOAuth revokes the OAuth grant of one server of a source and deletes the
+// stored tokens. The source can't sync until it is connected again.
+func (h *Handlers) APIDisconnectOAuth(c *gin.Context) {
+	session := auth.GetCurrentUser(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	source, err := h.db.GetSourceByIDForUser(c.Param("id"), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	}
+
+	endpoint := db.Endpoint(c.DefaultQuery("endpoint", string(db.EndpointSource)))
+	settings, serverURL, username := source.SourceAuth, source.SourceURL, source.SourceUsername
+	switch endpoint {
+	case db.EndpointSource:
+	case db.EndpointDest:
+		settings, serverURL, username = source.DestAuth, source.DestURL, source.DestUsername
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint"})
+		return
+	}
+
+	if settings.Method != db.AuthMethodOAuth2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This server is not connected with OAuth"})
+		return
+	}
+
+	// Revoking is best effort: the tokens are deleted either way
+	if h.googleOAuth != nil {
+		if refreshToken, err := h.encryptor.Decrypt(settings.RefreshToken); err == nil && refreshToken != "" {
+			if err := h.googleOAuth.provider.Revoke(c.Request.Context(), refreshToken); err != nil {
+				log.Printf("Failed to revoke OAuth token for source %s: %v", source.ID, err)
+			}
+		}
+	}
+
+	settings.AccessToken = ""
+	settings.RefreshToken = ""
+	settings.TokenExpiry = nil
+	if err := h.db.UpdateSourceAuth(source.ID, endpoint, settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update source"})
+		return
+	}
+	if h.syncEngine != nil {
+		h.syncEngine.InvalidateClients(serverURL, username)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account disconnected"})
+}
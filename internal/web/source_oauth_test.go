@@ -0,0 +1,215 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/macjediwizard/calbridgesync/internal/caldav"
+	"github.com/macjediwizard/calbridgesync/internal/crypto"
+	"github.com/macjediwizard/calbridgesync/internal/db"
+	"golang.org/x/oauth2"
+)
+
+// setupGoogleOAuth points the handlers at a local stand-in for Google's token and
+// revocation endpoints and returns the revoked tokens.
+func setupGoogleOAuth(t *testing.T, th *testHandlers) *[]string {
+	t.Helper()
+
+	revoked := new([]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.URL.Path == "/revoke" {
+			*revoked = append(*revoked, r.Form.Get("token"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Form.Get("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"google-access","refresh_token":"google-refresh","token_type":"Bearer","expires_in":3600}`))
+	}))
+	t.Cleanup(server.Close)
+
+	enc, _ := crypto.NewEncryptor(make([]byte, 32))
+	th.handlers.encryptor = enc
+	provider := caldav.NewOAuthProvider("client", "secret", "https://calbridge.example.com/auth/google/callback",
+		oauth2.Endpoint{AuthURL: server.URL + "/auth", TokenURL: server.URL + "/token"}, server.URL+"/revoke", []string{caldav.GoogleCalendarScope})
+	th.handlers.googleOAuth = newSourceOAuth(provider, 5*time.Minute)
+	return revoked
+}
+
+// startGoogleOAuth starts a flow and returns its state parameter.
+func startGoogleOAuth(t *testing.T, th *testHandlers, userID, body string) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/oauth/google/start", strings.NewReader(body))
+	setAuthContext(c, userID, "test@example.com")
+	th.handlers.APIStartGoogleOAuth(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		AuthURL string `json:"auth_url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	u, err := url.Parse(resp.AuthURL)
+	if err != nil || u.Query().Get("state") == "" {
+		t.Fatalf("expected auth URL with state, got %q", resp.AuthURL)
+	}
+	return u.Query().Get("state")
+}
+
+// googleCallback calls the OAuth callback and returns the redirect location.
+func googleCallback(th *testHandlers, userID, query string) *url.URL {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/auth/google/callback?"+query, nil)
+	setAuthContext(c, userID, "test@example.com")
+	th.handlers.GoogleOAuthCallback(c)
+	location, _ := url.Parse(w.Header().Get("Location"))
+	return location
+}
+
+func TestGoogleOAuthFlow(t *testing.T) {
+	t.Run("returns not found when not configured", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/oauth/google/start", strings.NewReader(`{}`))
+		setAuthContext(c, "user-1", "test@example.com")
+		th.handlers.APIStartGoogleOAuth(c)
+
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("connects an existing source", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+		setupGoogleOAuth(t, th)
+
+		userID, source := createTestUserAndSource(t, th.db, "test@example.com", "Google")
+		th.db.UpdateSourceSyncStatus(source.ID, db.SyncStatusCredentialsInvalid, "Paused")
+
+		state := startGoogleOAuth(t, th, userID, `{"source_id": "`+source.ID+`"}`)
+		location := googleCallback(th, userID, url.Values{"state": {state}, "code": {"good-code"}}.Encode())
+		if location.Path != "/sources/"+source.ID+"/edit" || location.Query().Get("oauth") != "connected" {
+			t.Fatalf("unexpected redirect: %s", location)
+		}
+
+		stored, _ := th.db.GetSourceByID(source.ID)
+		if stored.SourceAuth.Method != db.AuthMethodOAuth2 || stored.SourceAuth.ClientID != "client" {
+			t.Errorf("unexpected auth settings: %+v", stored.SourceAuth)
+		}
+		if token, _ := th.handlers.encryptor.Decrypt(stored.SourceAuth.RefreshToken); token != "google-refresh" {
+			t.Errorf("expected encrypted refresh token, got %q", token)
+		}
+		if stored.LastSyncStatus != db.SyncStatusPending {
+			t.Errorf("expected paused source to resume, got %s", stored.LastSyncStatus)
+		}
+
+		// The state can only be used once
+		location = googleCallback(th, userID, url.Values{"state": {state}, "code": {"good-code"}}.Encode())
+		if location.Query().Get("oauth_error") == "" {
+			t.Errorf("expected error for reused state, got %s", location)
+		}
+	})
+
+	t.Run("keeps a grant for a new source", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+		setupGoogleOAuth(t, th)
+
+		user, _ := th.db.GetOrCreateUser("test@example.com", "Test User")
+		state := startGoogleOAuth(t, th, user.ID, `{}`)
+		location := googleCallback(th, user.ID, url.Values{"state": {state}, "code": {"good-code"}}.Encode())
+		if location.Path != "/sources/add" {
+			t.Fatalf("unexpected redirect: %s", location)
+		}
+
+		grantID := location.Query().Get("oauth_grant")
+		creds, err := th.handlers.credentials(user.ID, &APIAuth{Grant: grantID}, db.AuthSettings{}, "me@gmail.com", "")
+		if err != nil {
+			t.Fatalf("expected grant to resolve, got %v", err)
+		}
+		if creds.Username != "me@gmail.com" || creds.OAuth2 == nil || creds.OAuth2.RefreshToken != "google-refresh" {
+			t.Errorf("unexpected credentials: %+v", creds)
+		}
+
+		if _, err := th.handlers.credentials("other-user", &APIAuth{Grant: grantID}, db.AuthSettings{}, "me@gmail.com", ""); err == nil {
+			t.Error("expected grant of another user to be rejected")
+		}
+	})
+
+	t.Run("rejects a state started by another user", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+		setupGoogleOAuth(t, th)
+
+		user, _ := th.db.GetOrCreateUser("test@example.com", "Test User")
+		state := startGoogleOAuth(t, th, user.ID, `{}`)
+		location := googleCallback(th, "other-user", url.Values{"state": {state}, "code": {"good-code"}}.Encode())
+		if location.Path != "/sources" || location.Query().Get("oauth_error") == "" {
+			t.Errorf("unexpected redirect: %s", location)
+		}
+	})
+
+	t.Run("reports a rejected code", func(t *testing.T) {
+		th := setupTestHandlers(t)
+		defer th.cleanup()
+		setupGoogleOAuth(t, th)
+
+		user, _ := th.db.GetOrCreateUser("test@example.com", "Test User")
+		state := startGoogleOAuth(t, th, user.ID, `{}`)
+		location := googleCallback(th, user.ID, url.Values{"state": {state}, "code": {"bad-code"}}.Encode())
+		if location.Path != "/sources/add" || location.Query().Get("oauth_error") == "" {
+			t.Errorf("unexpected redirect: %s", location)
+		}
+	})
+}
+
+func TestAPIDisconnectOAuth(t *testing.T) {
+	th := setupTestHandlers(t)
+	defer th.cleanup()
+	revoked := setupGoogleOAuth(t, th)
+
+	userID, source := createTestUserAndSource(t, th.db, "test@example.com", "Google")
+	state := startGoogleOAuth(t, th, userID, `{"source_id": "`+source.ID+`"}`)
+	googleCallback(th, userID, url.Values{"state": {state}, "code": {"good-code"}}.Encode())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/api/sources/"+source.ID+"/oauth", nil)
+	c.Params = gin.Params{{Key: "id", Value: source.ID}}
+	setAuthContext(c, userID, "test@example.com")
+	th.handlers.APIDisconnectOAuth(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(*revoked) != 1 || (*revoked)[0] != "google-refresh" {
+		t.Errorf("expected refresh token to be revoked, got %v", *revoked)
+	}
+
+	stored, _ := th.db.GetSourceByID(source.ID)
+	if stored.SourceAuth.RefreshToken != "" || stored.SourceAuth.AccessToken != "" {
+		t.Errorf("expected tokens to be deleted, got %+v", stored.SourceAuth)
+	}
+	if !reconnectRequired(stored) {
+		t.Error("expected disconnected source to require reconnecting")
+	}
+}
@@ -1,6 +1,6 @@
 import { useState, useEffect } from 'react';
 import { Link } from 'react-router-dom';
-import { getSources, toggleSource, deleteSource, triggerSync } from '../services/api';
+import { getSources, toggleSource, deleteSource, triggerSync, cancelSync } from '../services/api';
 import type { Source } from '../types';
 
 export default function SourcesList() {
@@ -62,6 +62,18 @@ export default function SourcesList() {
     }
   };
 
+  const handleCancelSync = async (id: string) => {
+    setActionId(id);
+    try {
+      await cancelSync(id);
+      await loadSources();
+    } catch (err) {
+      console.error('Cancel failed:', err);
+    } finally {
+      setActionId(null);
+    }
+  };
+
   const formatInterval = (seconds: number) => {
     if (seconds >= 3600) return `${Math.floor(seconds / 3600)}h`;
     if (seconds >= 60) return `${Math.floor(seconds / 60)}m`;
@@ -150,7 +162,7 @@ export default function SourcesList() {
                                   ? 'text-green-400'
                                   : source.sync_status === 'partial'
                                   ? 'text-yellow-400'
-                                  : source.sync_status === 'error'
+                                  : source.sync_status === 'error' || source.sync_status === 'credentials_invalid'
                                   ? 'text-red-400'
                                   : source.sync_status === 'running'
                                   ? 'text-blue-400'
@@ -163,6 +175,8 @@ export default function SourcesList() {
                                 ? 'Warn'
                                 : source.sync_status === 'error'
                                 ? 'Err'
+                                : source.sync_status === 'credentials_invalid'
+                                ? 'Auth'
                                 : source.sync_status === 'running'
                                 ? '...'
                                 : '-'}
@@ -181,13 +195,23 @@ export default function SourcesList() {
                     </td>
                     <td className="px-4 py-3">
                       <div className="flex items-center space-x-3">
-                        <button
-                          onClick={() => handleSync(source.id)}
-                          disabled={!source.enabled || actionId === source.id}
-                          className="text-red-400 hover:text-red-300 text-xs font-medium disabled:opacity-50"
-                        >
-                          Sync
-                        </button>
+                        {source.sync_status === 'running' ? (
+                          <button
+                            onClick={() => handleCancelSync(source.id)}
+                            disabled={actionId === source.id}
+                            className="text-yellow-400 hover:text-yellow-300 text-xs font-medium disabled:opacity-50"
+                          >
+                            Cancel
+                          </button>
+                        ) : (
+                          <button
+                            onClick={() => handleSync(source.id)}
+                            disabled={!source.enabled || actionId === source.id}
+                            className="text-red-400 hover:text-red-300 text-xs font-medium disabled:opacity-50"
+                          >
+                            Sync
+                          </button>
+                        )}
                         <Link
                           to={`/sources/${source.id}/edit`}
                           className="text-gray-400 hover:text-white text-xs font-medium"
@@ -1,5 +1,5 @@
 import axios from 'axios';
-import type { Source, SyncLog, DashboardStats, SourceFormData, AuthStatus, SyncHistory, MalformedEvent, Calendar, AlertPreferences, ActivityData } from '../types';
+import type { Source, SyncLog, DashboardStats, SourceFormData, AuthStatus, SyncHistory, MalformedEvent, Calendar, AlertPreferences, ActivityData, NextcloudLoginStart, NextcloudLoginResult, ServiceDiscovery, DiagnosticReport, SourceDiagnosis, EventChange, EventChangeFilter, DedupePolicy, DuplicateGroup, RepairResult, RepairTarget, BrowsedEvent, CalendarDiff, DriftReport } from '../types';
 
 const api = axios.create({
   baseURL: '/api',
@@ -18,6 +18,31 @@ export const logout = async (): Promise<void> => {
   await api.post('/auth/logout');
 };
 
+// OAuth connections for sources (e.g. Google Calendar)
+export const startGoogleOAuth = async (sourceId?: string, endpoint: 'source' | 'dest' = 'source'): Promise<string> => {
+  const response = await api.post('/oauth/google/start', { source_id: sourceId, endpoint });
+  return response.data.auth_url;
+};
+
+export const disconnectOAuth = async (sourceId: string, endpoint: 'source' | 'dest' = 'source'): Promise<void> => {
+  await api.delete(`/sources/${sourceId}/oauth`, { params: { endpoint } });
+};
+
+export const startNextcloudLogin = async (
+  serverUrl: string,
+  sourceId?: string,
+  endpoint: 'source' | 'dest' = 'source'
+): Promise<NextcloudLoginStart> => {
+  const response = await api.post('/nextcloud/login', { server_url: serverUrl, source_id: sourceId, endpoint });
+  return response.data;
+};
+
+// Resolves with status 'pending' until the user approved the login in Nextcloud.
+export const pollNextcloudLogin = async (loginId: string): Promise<NextcloudLoginResult> => {
+  const response = await api.post(`/nextcloud/login/${loginId}/poll`);
+  return response.data;
+};
+
 // Dashboard
 export const getDashboardStats = async (): Promise<DashboardStats> => {
   const response = await api.get('/dashboard/stats');
@@ -63,12 +88,60 @@ export const triggerSync = async (id: string): Promise<void> => {
   await api.post(`/sources/${id}/sync`);
 };
 
+export const cancelSync = async (id: string): Promise<void> => {
+  await api.post(`/sources/${id}/sync/cancel`);
+};
+
 // Logs
 export const getSourceLogs = async (sourceId: string, page: number = 1): Promise<{ logs: SyncLog[]; total_pages: number; page: number }> => {
   const response = await api.get(`/sources/${sourceId}/logs`, { params: { page } });
   return response.data;
 };
 
+export const getSourceChanges = async (
+  sourceId: string,
+  filter: EventChangeFilter = {}
+): Promise<{ changes: EventChange[]; total: number; total_pages: number; page: number }> => {
+  const response = await api.get(`/sources/${sourceId}/changes`, { params: filter });
+  return response.data;
+};
+
+// Duplicate groups found while the source's dedupe policy is report
+export const getSourceDuplicates = async (sourceId: string): Promise<{ policy: DedupePolicy; groups: DuplicateGroup[] }> => {
+  const response = await api.get(`/sources/${sourceId}/duplicates`);
+  return response.data;
+};
+
+// Deletes the duplicates of a reviewed group from the destination
+export const resolveDuplicateGroup = async (sourceId: string, groupId: string): Promise<{ message: string; removed: number }> => {
+  const response = await api.post(`/sources/${sourceId}/duplicates/${groupId}/resolve`);
+  return response.data;
+};
+
+// Events of a source or destination calendar with their sync status
+export const getSourceEvents = async (sourceId: string, calendar: string, endpoint: 'source' | 'dest' = 'source'): Promise<{ endpoint: 'source' | 'dest'; calendar: string; events: BrowsedEvent[] }> => {
+  const response = await api.get(`/sources/${sourceId}/events`, { params: { endpoint, calendar } });
+  return response.data;
+};
+
+// iCalendar data of one event as the server serves it
+export const getSourceEvent = async (sourceId: string, path: string, endpoint: 'source' | 'dest' = 'source'): Promise<string> => {
+  const response = await api.get(`/sources/${sourceId}/events/raw`, { params: { endpoint, path }, responseType: 'text' });
+  return response.data;
+};
+
+// Compares a source calendar with its destination calendar without syncing
+export const getSourceDiff = async (sourceId: string, calendar: string): Promise<CalendarDiff> => {
+  const response = await api.get(`/sources/${sourceId}/diff`, { params: { calendar } });
+  return response.data;
+};
+
+// Recent drift reports, newest first, with the source's drift check settings
+export const getSourceDrift = async (sourceId: string): Promise<{ drift_check_interval: number; threshold: number; latest: DriftReport | null; reports: DriftReport[] }> => {
+  const response = await api.get(`/sources/${sourceId}/drift`);
+  return response.data;
+};
+
 // Malformed Events
 export const getMalformedEvents = async (): Promise<MalformedEvent[]> => {
   const response = await api.get('/malformed-events');
@@ -84,12 +157,53 @@ export const deleteAllMalformedEvents = async (): Promise<{ deleted: number }> =
   return response.data;
 };
 
+// The payload of a malformed event as the source served it
+export const getMalformedEventRaw = async (id: string): Promise<string> => {
+  const response = await api.get(`/malformed-events/${id}/raw`, { responseType: 'text' });
+  return response.data;
+};
+
+// Previews the repaired event, or writes it to the source or (leaving the source as is) the destination
+export const repairMalformedEvent = async (id: string, apply = false, target?: RepairTarget): Promise<RepairResult> => {
+  const response = await api.post(`/malformed-events/${id}/repair`, { apply, target });
+  return response.data;
+};
+
 // Calendar Discovery
 export const discoverCalendars = async (url: string, username: string, password: string): Promise<Calendar[]> => {
   const response = await api.post('/calendars/discover', { url, username, password });
   return response.data;
 };
 
+// Finds the CalDAV URL for an email address, domain or URL. The username defaults to
+// the target when it is an email address.
+export const discoverService = async (
+  target: string,
+  password: string,
+  username?: string,
+  sourceType?: string
+): Promise<ServiceDiscovery> => {
+  const response = await api.post('/discover', { target, username, password, source_type: sourceType });
+  return response.data;
+};
+
+// Checks the connections of a saved source step by step; both servers unless one is given
+export const diagnoseSource = async (id: string, endpoint?: 'source' | 'dest'): Promise<SourceDiagnosis> => {
+  const response = await api.post(`/sources/${id}/diagnose`, { endpoint });
+  return response.data;
+};
+
+// Checks a server entered in the source form; the destination also gets a test write
+export const diagnoseConnection = async (
+  url: string,
+  username: string,
+  password: string,
+  endpoint?: 'source' | 'dest'
+): Promise<DiagnosticReport> => {
+  const response = await api.post('/diagnose', { url, username, password, endpoint });
+  return response.data;
+};
+
 // Alert Preferences
 export const getAlertPreferences = async (): Promise<AlertPreferences> => {
   const response = await api.get('/settings/alerts');
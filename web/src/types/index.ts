@@ -18,10 +18,28 @@ export interface Source {
   sync_direction: 'one_way' | 'two_way';
   conflict_strategy: string;
   selected_calendars: CalendarConfig[];
+  calendar_concurrency: number;
+  event_concurrency: number;
+  create_calendars: boolean; // Create missing destination calendars
+  sync_calendar_metadata: boolean; // Copy calendar name, color, description and order to the destination
+  dedupe_policy: DedupePolicy;
+  dedupe_fields: DedupeField[]; // Properties duplicates must share
+  sync_repaired_events: boolean; // Sync repaired versions of malformed source events to the destination
+  drift_check_interval: number; // Seconds between drift checks; 0 = disabled
+  calendar_mappings?: CalendarMapping[]; // Only when getting a single source
+  capabilities?: ServerCapabilities[]; // Only when getting a single source
+  calendar_status?: CalendarSyncLog[]; // Last sync result of each calendar; only when getting a single source
+  source_transport?: TransportSettings;
+  dest_transport?: TransportSettings;
+  source_auth?: AuthSettings;
+  dest_auth?: AuthSettings;
+  reconnect_required?: boolean; // An OAuth account must be connected again
   enabled: boolean;
   sync_status: string;
   last_sync_at: string | null;
   next_sync_at: string | null;
+  retry_attempt?: number;
+  retry_max_attempts?: number;
   is_stale: boolean;
   created_at: string;
   updated_at: string;
@@ -33,6 +51,223 @@ export interface Calendar {
   color?: string;
 }
 
+// The destination calendar that receives a source calendar's events.
+export interface CalendarMapping {
+  path: string;
+  dest_path: string;
+  created: boolean; // Created by calbridge
+}
+
+// What the server of one endpoint supports, as detected by the last probe.
+export interface ServerCapabilities {
+  endpoint: 'source' | 'dest';
+  server?: string;
+  sync_collection: boolean;
+  calendar_query: boolean; // False for servers like SOGo whose calendar-query misses events
+  time_range: boolean;
+  multiget: boolean;
+  ctag: boolean;
+  mkcalendar: boolean;
+  conditional_put: boolean;
+  probed_at: string;
+}
+
+export interface ServiceDiscovery {
+  url: string; // CalDAV URL to use for the source
+  principal_url: string;
+  method: 'direct' | 'well-known' | 'srv' | 'preset';
+  calendars: Calendar[];
+}
+
+// Result of one stage of a connection diagnosis
+export interface DiagnosticStage {
+  name: 'dns' | 'tcp' | 'tls' | 'auth' | 'principal' | 'home_set' | 'calendars' | 'sync_collection' | 'write';
+  status: 'ok' | 'warning' | 'failed' | 'skipped';
+  message: string;
+  details?: string[];
+  duration_ms: number;
+}
+
+// One HTTP exchange of a diagnosis; secrets in headers are redacted, bodies omitted
+export interface TranscriptEntry {
+  method: string;
+  url: string;
+  status?: number;
+  duration_ms: number;
+  request_headers?: Record<string, string>;
+  response_headers?: Record<string, string>;
+  error?: string;
+}
+
+export interface DiagnosticReport {
+  url: string;
+  success: boolean;
+  stages: DiagnosticStage[];
+  transcript: TranscriptEntry[];
+}
+
+export interface SourceDiagnosis {
+  source?: DiagnosticReport;
+  dest?: DiagnosticReport;
+}
+
+// off: leave duplicates alone; report: list them for review; auto: delete them.
+// Only events calbridge wrote are ever considered.
+export type DedupePolicy = 'off' | 'report' | 'auto';
+
+export type DedupeField = 'summary' | 'dtstart' | 'dtend' | 'location' | 'rrule';
+
+export interface DuplicateEvent {
+  path: string;
+  uid: string;
+}
+
+// Destination events found to be duplicates, awaiting review
+export interface DuplicateGroup {
+  id: string;
+  calendar_href: string;
+  dest_href: string;
+  summary: string; // Redacted to its first characters
+  start_time: string;
+  keep: DuplicateEvent;
+  duplicates: DuplicateEvent[]; // Deleted when the group is resolved
+  detected_at: string;
+}
+
+// An event of a calendar as calbridge sees it; data is fetched separately
+export interface BrowsedEvent {
+  path: string;
+  uid: string;
+  summary: string;
+  start_time: string;
+  etag: string;
+  synced: boolean; // Recorded as synced by calbridge
+  calendar_href?: string; // Source calendar the event was synced from
+  synced_at?: string;
+}
+
+// An event on both sides of a calendar diff whose content differs
+export interface EventDifference {
+  uid: string;
+  summary: string;
+  source_path: string;
+  dest_path: string;
+  fields: DedupeField[];
+}
+
+// A source calendar compared with the destination calendar it syncs to
+export interface CalendarDiff {
+  calendar_href: string;
+  dest_href: string;
+  source_events: number;
+  dest_events: number;
+  missing_on_dest: BrowsedEvent[]; // Synced ones were deleted on the destination
+  missing_on_source: BrowsedEvent[]; // Unsynced ones come from elsewhere
+  differs: EventDifference[];
+  duplicates: DuplicateGroup[];
+}
+
+export type DriftStatus = 'ok' | 'drift' | 'failed';
+
+// The drift of one calendar found by a drift check
+export interface CalendarDrift {
+  calendar_href: string;
+  calendar_name: string;
+  dest_href: string;
+  source_events: number;
+  dest_events: number;
+  missing_on_dest: number;
+  missing_on_source: number; // Synced events gone from the source
+  differs: number;
+  duplicates: number;
+  uids: string[]; // Mismatched events, capped
+  error?: string;
+}
+
+// A read-only comparison of a source with its destination
+export interface DriftReport {
+  id: string;
+  status: DriftStatus;
+  message: string;
+  mismatches: number;
+  threshold: number;
+  over_threshold: boolean; // The owner was alerted when the source crossed the threshold
+  calendars: CalendarDrift[];
+  duration: number; // Seconds
+  created_at: string;
+}
+
+// One event written by a sync run; the summary is redacted to its first characters
+export interface EventChange {
+  id: string;
+  run_id: string;
+  calendar_href: string;
+  event_uid: string;
+  summary: string;
+  action: 'create' | 'update' | 'delete';
+  direction: 'to_dest' | 'to_source';
+  reason: string;
+  result: 'success' | 'failed';
+  error?: string;
+  created_at: string;
+}
+
+export interface EventChangeFilter {
+  uid?: string;
+  summary?: string;
+  action?: 'create' | 'update' | 'delete';
+  since?: string;
+  until?: string;
+  page?: number;
+  limit?: number;
+}
+
+// Connection settings for one side of a source. Secrets are write-only:
+// responses report has_client_key/has_proxy and header names with empty values.
+export interface TransportSettings {
+  ca_cert?: string;
+  client_cert?: string;
+  client_key?: string;
+  has_client_key?: boolean;
+  insecure_skip_verify?: boolean;
+  proxy_url?: string;
+  has_proxy?: boolean;
+  user_agent?: string;
+  headers?: Record<string, string>;
+}
+
+// Authentication for one side of a source. Basic and digest use the username and
+// password; tokens and the client secret are write-only (see the has_* flags).
+export interface AuthSettings {
+  method: 'basic' | 'digest' | 'bearer' | 'oauth2';
+  token?: string;
+  has_token?: boolean;
+  refresh_token?: string;
+  has_refresh_token?: boolean;
+  token_expiry?: string;
+  token_url?: string;
+  client_id?: string;
+  client_secret?: string;
+  has_client_secret?: boolean;
+  scopes?: string[];
+  grant?: string; // From the oauth_grant query parameter or a completed Nextcloud login
+}
+
+export interface NextcloudLoginStart {
+  login_id: string;
+  login_url: string;
+  expires_at: string;
+}
+
+// A completed login either updated an existing source or returns a grant for a new one.
+export interface NextcloudLoginResult {
+  status: 'pending' | 'complete';
+  grant?: string;
+  url?: string;
+  username?: string;
+  source?: Source;
+}
+
 export interface CalendarConfig {
   path: string;
   sync_direction?: 'one_way' | 'two_way' | ''; // empty = use source default
@@ -52,6 +287,26 @@ export interface SyncLog {
   events_processed: number;
   duration: number | null;
   created_at: string;
+  requests_throttled: number;
+  requests_retried: number;
+  throttle_wait: number;
+  calendars?: CalendarSyncLog[]; // Only when listing the logs of a source
+}
+
+// Result of syncing one calendar within a sync log
+export interface CalendarSyncLog {
+  calendar_href: string;
+  calendar_name: string;
+  status: string;
+  mode: 'incremental' | 'full' | '';
+  events_created: number;
+  events_updated: number;
+  events_deleted: number;
+  events_skipped: number;
+  events_processed: number;
+  duration: number;
+  details: string | null;
+  created_at: string;
 }
 
 export interface DashboardStats {
@@ -75,6 +330,18 @@ export interface SourceFormData {
   sync_direction: 'one_way' | 'two_way';
   conflict_strategy: string;
   selected_calendars: CalendarConfig[];
+  calendar_concurrency?: number;
+  event_concurrency?: number;
+  create_calendars?: boolean;
+  sync_calendar_metadata?: boolean;
+  dedupe_policy?: DedupePolicy;
+  dedupe_fields?: DedupeField[];
+  sync_repaired_events?: boolean;
+  drift_check_interval?: number; // 0 or 3600 to 2592000 seconds
+  source_transport?: TransportSettings;
+  dest_transport?: TransportSettings;
+  source_auth?: AuthSettings;
+  dest_auth?: AuthSettings;
 }
 
 export interface ApiResponse<T> {
@@ -111,15 +378,32 @@ export interface SyncHistory {
   summary: SyncSummary;
 }
 
+export type RepairFix = 'line_endings' | 'line_folding' | 'escaping' | 'duplicate_properties' | 'missing_dtstamp' | 'missing_uid';
+
 export interface MalformedEvent {
   id: string;
   source_id: string;
   source_name: string;
   event_path: string;
   error_message: string;
+  event_uid?: string;
+  calendar_href?: string;
+  has_payload: boolean; // The raw payload was stored and can be repaired
+  repairable: boolean;
+  repairs: RepairFix[] | null; // Repairs automatic repair makes
   discovered_at: string;
 }
 
+export type RepairTarget = 'source' | 'dest';
+
+export interface RepairResult {
+  repaired: string; // Repaired iCalendar data
+  uid: string;
+  fixes: RepairFix[] | null;
+  applied: boolean;
+  target?: RepairTarget;
+}
+
 export interface AlertPreferences {
   email_enabled: boolean | null;
   webhook_enabled: boolean | null;
@@ -130,8 +414,9 @@ export interface AlertPreferences {
 export interface SyncActivity {
   source_id: string;
   source_name: string;
-  status: 'running' | 'completed' | 'error' | 'partial';
+  status: 'running' | 'completed' | 'error' | 'partial' | 'cancelled';
   current_calendar?: string;
+  active_calendars?: string[];
   total_calendars: number;
   calendars_synced: number;
   events_processed: number;